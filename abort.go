@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go-tps/logger"
+)
+
+// abortController stops a run part-way through. It is triggered by SIGINT or
+// SIGTERM and, when an address is configured, by POST /abort on a small HTTP
+// control endpoint. A second signal exits immediately.
+type abortController struct {
+	once   sync.Once
+	done   chan struct{}
+	server *http.Server
+}
+
+func newAbortController(addr string) *abortController {
+	a := &abortController{done: make(chan struct{})}
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		a.Trigger(sig.String())
+		<-signals
+		fmt.Println("\nSecond interrupt, exiting immediately")
+		os.Exit(130)
+	}()
+
+	if addr != "" {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/abort", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "use POST", http.StatusMethodNotAllowed)
+				return
+			}
+			a.Trigger("control API")
+			fmt.Fprintln(w, "aborting")
+		})
+		a.server = &http.Server{Addr: addr, Handler: mux}
+		go func() {
+			if err := a.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Warn("Control endpoint on %s stopped: %v\n", addr, err)
+			}
+		}()
+		logger.Info("🛑 Abort with: curl -X POST http://%s/abort\n", addr)
+	}
+	return a
+}
+
+// Trigger aborts the run; later calls are no-ops.
+func (a *abortController) Trigger(reason string) {
+	a.once.Do(func() {
+		fmt.Printf("\n🛑 Abort requested (%s): no new transactions will be sent\n", reason)
+		close(a.done)
+	})
+}
+
+func (a *abortController) Aborted() bool {
+	select {
+	case <-a.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Sleep waits for d or until the run is aborted, whichever comes first.
+func (a *abortController) Sleep(d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-a.done:
+	}
+}
+
+func (a *abortController) Done() <-chan struct{} {
+	return a.done
+}
+
+func (a *abortController) Close() {
+	if a.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		a.server.Shutdown(ctx)
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go-tps/config"
+	dbpkg "go-tps/db"
+	"go-tps/logger"
+	"go-tps/notify"
+	"go-tps/report"
+	txpkg "go-tps/tx"
+)
+
+// newWebhook returns the webhook run events are sent to, or nil without
+// WEBHOOK_URL.
+func newWebhook(config *config.Config) (*notify.Webhook, error) {
+	if config.WebhookURL == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(config.WebhookURL)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid WEBHOOK_URL: want an http(s) URL")
+	}
+	headers, err := txpkg.ParseHeaders(config.WebhookHeaders, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid WEBHOOK_HEADERS: %w", err)
+	}
+	return notify.NewWebhook(config.WebhookURL, headers), nil
+}
+
+// alertThresholds describes the watched thresholds for the startup log.
+func alertThresholds(config *config.Config) string {
+	var watched []string
+	if config.AlertErrorRate > 0 {
+		watched = append(watched, fmt.Sprintf("error rate above %g%%", config.AlertErrorRate))
+	}
+	if config.AlertP95Latency > 0 {
+		watched = append(watched, fmt.Sprintf("p95 latency above %gs", config.AlertP95Latency))
+	}
+	return strings.Join(watched, " or ")
+}
+
+// notifyRunCompleted sends the run's summary to the webhook.
+func notifyRunCompleted(webhook *notify.Webhook, summary *runSummary) {
+	outcome := "finished"
+	if summary.Aborted {
+		outcome = "aborted"
+	}
+	t := summary.Totals
+	event := notify.Event{
+		Event: notify.RunCompleted,
+		Message: fmt.Sprintf("go-tps: %s run %s after %s: %s transactions, %s included, %s TPS, %s failed",
+			summary.Mode, outcome, summary.GeneratedAt.Sub(summary.StartedAt).Round(time.Second),
+			report.Int(t.Transactions), report.Int(t.Included), report.Float(t.TPS, 2), report.Percent(t.FailureRate, 1)),
+		Summary: summary,
+	}
+	if summary.SLA != nil && !summary.SLA.Passed {
+		event.Message += "; SLA not met"
+	}
+	if err := webhook.Send(event); err != nil {
+		logger.Warn("Could not notify the webhook: %v\n", err)
+		return
+	}
+	fmt.Println("✓ Run summary sent to the webhook")
+}
+
+// alertCheckInterval is how often the thresholds are checked.
+const alertCheckInterval = 10 * time.Second
+
+// Metrics alert thresholds apply to.
+const (
+	alertErrorRate  = "error_rate"
+	alertP95Latency = "p95_latency"
+)
+
+// alertWatcher watches the transactions the workers store and resolve and
+// logs and calls the webhook (if any) once when the failure rate or the p95 confirmation
+// latency over the window goes above its threshold, and once more when it
+// is back under. Unlike STOP_ON_ERROR_RATE it never stops the run.
+type alertWatcher struct {
+	webhook    *notify.Webhook
+	errorRate  float64 // percent, 0 = not watched
+	p95Latency float64 // seconds, 0 = not watched
+	window     time.Duration
+	minSamples int
+
+	mu       sync.Mutex
+	outcomes []alertOutcome
+	breached map[string]bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// alertOutcome is one transaction's outcome: a failed send, or a receipt.
+type alertOutcome struct {
+	at      time.Time
+	failed  bool
+	latency float64 // confirmation seconds, -1 without a receipt
+}
+
+// newAlertWatcher starts checking the thresholds, or returns nil if neither
+// is set.
+func newAlertWatcher(webhook *notify.Webhook, errorRate, p95Latency float64, window time.Duration, minSamples int) *alertWatcher {
+	if errorRate <= 0 && p95Latency <= 0 {
+		return nil
+	}
+	w := &alertWatcher{
+		webhook:    webhook,
+		errorRate:  errorRate,
+		p95Latency: p95Latency,
+		window:     window,
+		minSamples: max(minSamples, 1),
+		breached:   make(map[string]bool),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Stored counts sends that failed; submitted transactions count once their
+// receipt is in.
+func (w *alertWatcher) Stored(tx *dbpkg.Transaction) {
+	if tx.TxHash != "" || tx.Status != "failed" {
+		return
+	}
+	w.add(alertOutcome{at: time.Now(), failed: true, latency: -1})
+}
+
+// Resolved counts a receipt outcome.
+func (w *alertWatcher) Resolved(tx *dbpkg.Transaction) {
+	outcome := alertOutcome{at: time.Now(), failed: tx.Status != "success", latency: -1}
+	if tx.ConfirmedAt != nil {
+		outcome.latency = tx.ConfirmedAt.Sub(tx.SubmittedAt).Seconds()
+	}
+	w.add(outcome)
+}
+
+func (w *alertWatcher) add(outcome alertOutcome) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.outcomes = append(w.outcomes, outcome)
+}
+
+func (w *alertWatcher) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(alertCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.check()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// Stop stops checking; a breach still open is not reported as recovered.
+func (w *alertWatcher) Stop() {
+	if w == nil {
+		return
+	}
+	close(w.stop)
+	<-w.done
+}
+
+// check measures the window and notifies about thresholds that were
+// crossed since the last check.
+func (w *alertWatcher) check() {
+	w.mu.Lock()
+	cutoff := time.Now().Add(-w.window)
+	drop := 0
+	for drop < len(w.outcomes) && w.outcomes[drop].at.Before(cutoff) {
+		drop++
+	}
+	w.outcomes = w.outcomes[drop:]
+	failed := 0
+	var latencies []float64
+	for _, o := range w.outcomes {
+		if o.failed {
+			failed++
+		}
+		if o.latency >= 0 {
+			latencies = append(latencies, o.latency)
+		}
+	}
+	samples := len(w.outcomes)
+	w.mu.Unlock()
+
+	if w.errorRate > 0 && samples >= w.minSamples {
+		w.judge(alertErrorRate, float64(failed)/float64(samples)*100, w.errorRate, samples)
+	}
+	if w.p95Latency > 0 && len(latencies) >= w.minSamples {
+		w.judge(alertP95Latency, report.Percentile(latencies, 95), w.p95Latency, len(latencies))
+	}
+}
+
+// judge notifies when metric crosses threshold in either direction.
+func (w *alertWatcher) judge(metric string, value, threshold float64, samples int) {
+	over := value > threshold
+	if over == w.breached[metric] {
+		return
+	}
+	w.breached[metric] = over
+
+	event := notify.Event{
+		Event: notify.ThresholdRecovered,
+		Alert: &notify.Alert{
+			Metric:        metric,
+			Value:         value,
+			Threshold:     threshold,
+			WindowSeconds: int(w.window.Seconds()),
+			Samples:       samples,
+		},
+	}
+	label, format := "p95 confirmation latency", func(v float64) string { return report.Seconds(v, 2) }
+	if metric == alertErrorRate {
+		label, format = "error rate", func(v float64) string { return report.Percent(v, 1) }
+	}
+	if over {
+		event.Event = notify.ThresholdBreached
+		event.Message = fmt.Sprintf("go-tps: %s %s over the last %s, above the %s threshold", label, format(value), w.window, format(threshold))
+		logger.Warn("🚨 %s\n", event.Message)
+	} else {
+		event.Message = fmt.Sprintf("go-tps: %s back to %s over the last %s, within the %s threshold", label, format(value), w.window, format(threshold))
+		logger.Info("✓ %s\n", event.Message)
+	}
+	if w.webhook == nil {
+		return
+	}
+	if err := w.webhook.Send(event); err != nil {
+		logger.Warn("Could not notify the webhook: %v\n", err)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"go-tps/config"
+	dbpkg "go-tps/db"
+	"go-tps/logger"
+	"go-tps/report"
+	txpkg "go-tps/tx"
+	"go-tps/wallet"
+)
+
+// balanceSnapshot is the balance of every wallet of the run at one block.
+type balanceSnapshot struct {
+	phase    string
+	block    uint64
+	at       time.Time
+	balances map[string]*big.Int // by address
+}
+
+// balanceRecorder records the wallets' balances in the balances table
+// before the run, every BALANCE_SNAPSHOT_MINUTES while it goes on and after
+// it, so leaking funds and starving wallets show up in the end-of-run
+// report.
+type balanceRecorder struct {
+	config   *config.Config
+	txSender *txpkg.TransactionSender
+	db       dbpkg.Store
+	runID    int64
+	wallets  []*wallet.Wallet
+
+	mu        sync.Mutex
+	snapshots []*balanceSnapshot // in the order they were taken
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newBalanceRecorder takes the snapshot before the run and starts the
+// periodic ones, or returns nil if BALANCE_SNAPSHOTS is off.
+func newBalanceRecorder(config *config.Config, txSender *txpkg.TransactionSender, db dbpkg.Store, runID int64, wallets []*wallet.Wallet) *balanceRecorder {
+	if !config.BalanceSnapshots {
+		return nil
+	}
+	r := &balanceRecorder{
+		config:   config,
+		txSender: txSender,
+		db:       db,
+		runID:    runID,
+		wallets:  wallets,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	r.record(dbpkg.BalanceBefore)
+	if config.BalanceSnapshotMins > 0 {
+		go r.run(time.Duration(config.BalanceSnapshotMins) * time.Minute)
+	} else {
+		close(r.done)
+	}
+	return r
+}
+
+func (r *balanceRecorder) run(interval time.Duration) {
+	defer close(r.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.record(dbpkg.BalancePeriodic)
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Stop stops the periodic snapshots and takes the one after the run.
+func (r *balanceRecorder) Stop() {
+	if r == nil {
+		return
+	}
+	close(r.stop)
+	<-r.done
+	r.record(dbpkg.BalanceAfter)
+}
+
+// record reads every wallet's balance at the latest block and stores it. A
+// snapshot that cannot be read is logged and left out.
+func (r *balanceRecorder) record(phase string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.config.ContextTimeout)*time.Second)
+	defer cancel()
+
+	snapshot, err := takeBalanceSnapshot(ctx, r.txSender, r.wallets, phase)
+	if err != nil {
+		logger.Warn("Could not take the %s balance snapshot: %v\n", phase, err)
+		return
+	}
+	r.mu.Lock()
+	r.snapshots = append(r.snapshots, snapshot)
+	r.mu.Unlock()
+
+	rows := make([]*dbpkg.BalanceSnapshot, 0, len(snapshot.balances))
+	for _, w := range r.wallets {
+		address := w.Address.Hex()
+		rows = append(rows, &dbpkg.BalanceSnapshot{
+			RunID:       r.runID,
+			Phase:       phase,
+			Wallet:      address,
+			Balance:     snapshot.balances[address].String(),
+			BlockNumber: snapshot.block,
+			RecordedAt:  snapshot.at,
+		})
+	}
+	if err := r.db.InsertBalances(ctx, rows); err != nil {
+		logger.Warn("%v\n", err)
+	}
+	logger.Debug("Recorded %s balances of %d wallets at block %d\n", phase, len(rows), snapshot.block)
+}
+
+// takeBalanceSnapshot reads the balance of every wallet at the latest
+// block, so all of them are taken at the same point of the chain.
+func takeBalanceSnapshot(ctx context.Context, txSender *txpkg.TransactionSender, wallets []*wallet.Wallet, phase string) (*balanceSnapshot, error) {
+	header, err := txSender.LatestHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &balanceSnapshot{
+		phase:    phase,
+		block:    header.Number.Uint64(),
+		at:       time.Now(),
+		balances: make(map[string]*big.Int, len(wallets)),
+	}
+	for _, w := range wallets {
+		balance, err := txSender.BalanceAt(ctx, w.Address, snapshot.block)
+		if err != nil {
+			return nil, fmt.Errorf("wallet %s: %w", w.Address.Hex(), err)
+		}
+		snapshot.balances[w.Address.Hex()] = balance
+	}
+	return snapshot, nil
+}
+
+// print prints the ETH each wallet and each batch of batches consumed
+// between the snapshots before and after the run, and the trend of the
+// periodic snapshots.
+func (r *balanceRecorder) print(batches []string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	snapshots := r.snapshots
+	r.mu.Unlock()
+	if len(snapshots) < 2 || snapshots[0].phase != dbpkg.BalanceBefore || snapshots[len(snapshots)-1].phase != dbpkg.BalanceAfter {
+		logger.Warn("No balance report: the snapshot before or after the run is missing\n")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var txs []*dbpkg.Transaction
+	spends := make([]report.BatchSpend, 0, len(batches))
+	for _, batch := range batches {
+		batchTxs, err := r.db.GetBatchTransactions(ctx, batch)
+		if err != nil {
+			logger.Warn("Could not load transactions for %s: %v\n", batch, err)
+			continue
+		}
+		txs = append(txs, batchTxs...)
+		spends = append(spends, report.BuildBatchSpend(batch, batchTxs))
+	}
+
+	points := make([]report.BalancePoint, len(snapshots))
+	for i, s := range snapshots {
+		total := new(big.Int)
+		for _, balance := range s.balances {
+			total.Add(total, balance)
+		}
+		points[i] = report.BalancePoint{Time: s.at, Block: s.block, Total: total}
+	}
+
+	stats := report.BuildBalanceStats(snapshots[0].balances, snapshots[len(snapshots)-1].balances, txs)
+	report.PrintBalanceStats(stats, spends, points)
+}
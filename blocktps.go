@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	dbpkg "go-tps/db"
+	"go-tps/logger"
+	"go-tps/report"
+	txpkg "go-tps/tx"
+)
+
+const (
+	// maxBlockScan bounds how many blocks the block-based TPS reads, so a
+	// day-long run on a fast chain does not end in an hour of block fetches.
+	maxBlockScan = 20000
+
+	blockScanWorkers = 8
+)
+
+// printBlockTPS reads every block from the first submission to the last
+// confirmation, counts the run's transactions in each, and reports the TPS
+// the chain achieved next to the rate the run submitted at.
+func printBlockTPS(db dbpkg.Store, txSender *txpkg.TransactionSender, batches []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	ours := make(map[string]bool)
+	var firstSubmit, lastSubmit, lastConfirm time.Time
+	for _, batch := range batches {
+		txs, err := db.GetBatchTransactions(ctx, batch)
+		if err != nil {
+			logger.Warn("Could not load transactions for %s: %v\n", batch, err)
+			continue
+		}
+		for _, t := range txs {
+			if t.TxHash == "" {
+				continue
+			}
+			ours[t.TxHash] = true
+			if firstSubmit.IsZero() || t.SubmittedAt.Before(firstSubmit) {
+				firstSubmit = t.SubmittedAt
+			}
+			if t.SubmittedAt.After(lastSubmit) {
+				lastSubmit = t.SubmittedAt
+			}
+			if t.ConfirmedAt != nil && t.ConfirmedAt.After(lastConfirm) {
+				lastConfirm = *t.ConfirmedAt
+			}
+		}
+	}
+	if lastConfirm.IsZero() {
+		return
+	}
+
+	head, err := txSender.LatestHeader(ctx)
+	if err != nil {
+		logger.Warn("Could not measure block-based TPS: %v\n", err)
+		return
+	}
+	// Block timestamps have whole seconds, so a block stamped in the second
+	// of the first submission may already hold it
+	first, err := firstBlockAt(ctx, txSender, firstSubmit.Truncate(time.Second), head.Number.Uint64())
+	if err != nil {
+		logger.Warn("Could not measure block-based TPS: %v\n", err)
+		return
+	}
+	next, err := firstBlockAt(ctx, txSender, lastConfirm.Add(time.Second), head.Number.Uint64())
+	if err != nil {
+		logger.Warn("Could not measure block-based TPS: %v\n", err)
+		return
+	}
+	if next <= first {
+		return
+	}
+	last := next - 1
+	if span := last - first + 1; span > maxBlockScan {
+		logger.Warn("Run spans %d blocks; skipping block-based TPS (at most %d are read)\n", span, maxBlockScan)
+		return
+	}
+
+	blocks, err := countBlocks(ctx, txSender, first, last, ours)
+	if err != nil {
+		logger.Warn("Could not measure block-based TPS: %v\n", err)
+		return
+	}
+	before := blocks[0].Time
+	if first > 0 {
+		if header, err := txSender.HeaderByNumber(ctx, first-1); err == nil {
+			before = time.Unix(int64(header.Time), 0)
+		}
+	}
+
+	var submissionTPS float64
+	if window := lastSubmit.Sub(firstSubmit).Seconds(); window > 0 {
+		submissionTPS = float64(len(ours)) / window
+	}
+	report.PrintBlockTPS(report.BuildBlockTPS(blocks, before), blocks, submissionTPS)
+}
+
+// firstBlockAt returns the lowest block number up to head whose timestamp is
+// at or after t, or head+1 if there is none, by bisecting on timestamps.
+func firstBlockAt(ctx context.Context, txSender *txpkg.TransactionSender, t time.Time, head uint64) (uint64, error) {
+	lo, hi := uint64(0), head+1
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		header, err := txSender.HeaderByNumber(ctx, mid)
+		if err != nil {
+			return 0, err
+		}
+		if time.Unix(int64(header.Time), 0).Before(t) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo, nil
+}
+
+// countBlocks reads blocks first to last concurrently and counts the
+// transactions of each that are in ours.
+func countBlocks(ctx context.Context, txSender *txpkg.TransactionSender, first, last uint64, ours map[string]bool) ([]report.BlockCount, error) {
+	blocks := make([]report.BlockCount, last-first+1)
+	numbers := make(chan uint64)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	for range blockScanWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range numbers {
+				at, hashes, err := txSender.BlockTxHashes(ctx, n)
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					continue
+				}
+				block := report.BlockCount{Number: n, Time: at, Total: len(hashes)}
+				for _, hash := range hashes {
+					if ours[hash.Hex()] {
+						block.Ours++
+					}
+				}
+				blocks[n-first] = block
+			}
+		}()
+	}
+	for n := first; n <= last; n++ {
+		numbers <- n
+	}
+	close(numbers)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return blocks, nil
+}
+
+// printBlockInclusion prints, per batch, how many blocks its included
+// transactions spread over and how many blocks after the batch's first one
+// they landed, from the blocks their receipts recorded. A burst that the
+// chain took in one block shows 1 block and a delay of 0.
+func printBlockInclusion(db dbpkg.Store, batches []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	const maxBatchRows = 20
+
+	type row struct {
+		batch string
+		stats map[string]interface{}
+	}
+	var rows []row
+	var spanned uint64
+	var maxDelay float64
+	for _, batch := range batches {
+		stats, err := db.GetBatchStats(ctx, batch)
+		if err != nil {
+			logger.Warn("Could not load stats for %s: %v\n", batch, err)
+			continue
+		}
+		if stats["blocks_spanned"].(uint64) == 0 {
+			continue
+		}
+		rows = append(rows, row{batch, stats})
+		spanned += stats["blocks_spanned"].(uint64)
+		maxDelay = max(maxDelay, stats["inclusion_delay_max_blocks"].(float64))
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("BLOCK INCLUSION")
+	fmt.Println(strings.Repeat("=", 60))
+	if !report.Compact() && len(rows) <= maxBatchRows {
+		fmt.Printf("%-28s %7s %7s %6s %6s %6s\n", "Batch", "Blocks", "With tx", "p50", "p95", "Max")
+		for _, r := range rows {
+			fmt.Printf("%-28s %7s %7s %6s %6s %6s\n", r.batch,
+				report.Int(r.stats["blocks_spanned"].(uint64)), report.Int(r.stats["blocks_with_txs"].(int)),
+				report.Float(r.stats["inclusion_delay_p50_blocks"].(float64), 0),
+				report.Float(r.stats["inclusion_delay_p95_blocks"].(float64), 0),
+				report.Float(r.stats["inclusion_delay_max_blocks"].(float64), 0))
+		}
+		fmt.Println(strings.Repeat("-", 60))
+		fmt.Println("p50/p95/Max: blocks after the batch's first block a transaction landed")
+	}
+	fmt.Printf("Total: %s blocks spanned over %d batches, longest inclusion delay %s blocks\n",
+		report.Int(spanned), len(rows), report.Float(maxDelay, 0))
+	fmt.Println(strings.Repeat("=", 60))
+}
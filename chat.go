@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go-tps/config"
+	dbpkg "go-tps/db"
+	"go-tps/logger"
+	"go-tps/notify"
+	"go-tps/report"
+)
+
+// Values of CHAT_NOTIFY.
+const (
+	chatNotifyRun       = "run"
+	chatNotifyIteration = "iteration"
+)
+
+// chatNotifier posts a summary of the run, and with CHAT_NOTIFY=iteration of
+// every loop iteration, to a Slack or Discord webhook.
+type chatNotifier struct {
+	chat       *notify.Chat
+	db         dbpkg.Store
+	reportURL  string  // {batch} is replaced by the batch
+	iterations bool    // also post each loop iteration
+	alertRate  float64 // failure rate (percent) above which a summary is marked failed; 0 = any failure
+	host       string
+
+	wg sync.WaitGroup // iteration posts in flight
+}
+
+// newChatNotifier returns nil without CHAT_WEBHOOK_URL.
+func newChatNotifier(config *config.Config, db dbpkg.Store) (*chatNotifier, error) {
+	if config.ChatWebhookURL == "" {
+		return nil, nil
+	}
+	chat, err := notify.NewChat(config.ChatWebhookURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CHAT_WEBHOOK_URL: %w", err)
+	}
+	when := strings.ToLower(config.ChatNotify)
+	if when != chatNotifyRun && when != chatNotifyIteration {
+		return nil, fmt.Errorf("invalid CHAT_NOTIFY %q: must be %s or %s", config.ChatNotify, chatNotifyRun, chatNotifyIteration)
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return &chatNotifier{
+		chat:       chat,
+		db:         db,
+		reportURL:  config.ChatReportURL,
+		iterations: when == chatNotifyIteration,
+		alertRate:  config.AlertErrorRate,
+		host:       host,
+	}, nil
+}
+
+// Iteration posts the summary of a finished loop iteration in the
+// background, so a slow chat does not hold up the next one. Receipts still
+// outstanding are counted as pending.
+func (n *chatNotifier) Iteration(batch string, iteration int) {
+	if n == nil || !n.iterations {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	txs, err := n.db.GetBatchTransactions(ctx, batch)
+	cancel()
+	if err != nil {
+		logger.Warn("Could not load transactions for %s: %v\n", batch, err)
+		return
+	}
+	s := summarizeBatch(batch, txs)
+	msg := notify.Summary{
+		Title:  fmt.Sprintf("go-tps iteration #%d on %s", iteration, n.host),
+		Fields: append([]notify.Field{{Name: "Batch", Value: batch}}, n.fields(s)...),
+		Link:   n.link(batch),
+		Failed: n.failed(s),
+	}
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		if err := n.chat.Post(msg); err != nil {
+			logger.Warn("Could not post iteration #%d to chat: %v\n", iteration, err)
+		}
+	}()
+}
+
+// Run posts the summary of the whole run, after the iteration summaries
+// still in flight. The link points at the report of the last batch.
+func (n *chatNotifier) Run(summary *runSummary) {
+	if n == nil {
+		return
+	}
+	n.wg.Wait()
+
+	outcome := "finished"
+	if summary.Aborted {
+		outcome = "aborted"
+	}
+	fields := []notify.Field{
+		{Name: "Mode", Value: summary.Mode},
+		{Name: "Duration", Value: summary.GeneratedAt.Sub(summary.StartedAt).Round(time.Second).String()},
+		{Name: "Batches", Value: report.Int(len(summary.Batches))},
+	}
+	fields = append(fields, n.fields(summary.Totals)...)
+	slaFailed := summary.SLA != nil && !summary.SLA.Passed
+	if summary.SLA != nil {
+		sla := "met"
+		if slaFailed {
+			sla = "NOT MET"
+		}
+		fields = append(fields, notify.Field{Name: "SLA", Value: sla})
+	}
+	msg := notify.Summary{
+		Title:  fmt.Sprintf("go-tps run %s on %s", outcome, n.host),
+		Fields: fields,
+		Failed: summary.Aborted || slaFailed || n.failed(summary.Totals),
+	}
+	if len(summary.Batches) > 0 {
+		msg.Link = n.link(summary.Batches[len(summary.Batches)-1].Batch)
+	}
+	if err := n.chat.Post(msg); err != nil {
+		logger.Warn("Could not post the run summary to chat: %v\n", err)
+		return
+	}
+	fmt.Println("✓ Run summary posted to chat")
+}
+
+// fields are the headline numbers of s.
+func (n *chatNotifier) fields(s batchSummary) []notify.Field {
+	success := 0.0
+	if s.Transactions > 0 {
+		success = float64(s.Successful) / float64(s.Transactions) * 100
+	}
+	fields := []notify.Field{
+		{Name: "TPS", Value: report.Float(s.TPS, 2)},
+		{Name: "Success rate", Value: report.Percent(success, 1)},
+		{Name: "p95 latency", Value: report.Seconds(s.ConfirmationLatency.P95, 2)},
+		{Name: "Transactions", Value: fmt.Sprintf("%s sent, %s included", report.Int(s.Submitted), report.Int(s.Included))},
+		{Name: "Failed", Value: fmt.Sprintf("%s rejected, %s reverted", report.Int(s.Rejected), report.Int(s.Reverted))},
+	}
+	if s.Pending > 0 {
+		fields = append(fields, notify.Field{Name: "Pending", Value: report.Int(s.Pending)})
+	}
+	return fields
+}
+
+// failed says whether s failed more than ALERT_ERROR_RATE allows, or at
+// all without one.
+func (n *chatNotifier) failed(s batchSummary) bool {
+	return s.FailureRate > n.alertRate
+}
+
+// link returns the report URL of batch, or "" without CHAT_REPORT_URL.
+func (n *chatNotifier) link(batch string) string {
+	return strings.ReplaceAll(n.reportURL, "{batch}", batch)
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go-tps/config"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// envAnnotation names the environment variable a setting flag overrides.
+const envAnnotation = "env"
+
+// addSettingFlags adds the flags of the most varied settings to fs, each
+// overriding its environment variable, and --set for any other.
+func addSettingFlags(fs *pflag.FlagSet) {
+	env := func(name, variable string) {
+		fs.SetAnnotation(name, envAnnotation, []string{variable})
+	}
+	fs.String("rpc-url", "", "JSON-RPC endpoint (RPC_URL)")
+	env("rpc-url", "RPC_URL")
+	fs.String("ws-url", "", "WebSocket endpoint for receipts (WS_URL)")
+	env("ws-url", "WS_URL")
+	fs.Int("wallets", 0, "wallets to send from (WALLET_COUNT)")
+	env("wallets", "WALLET_COUNT")
+	fs.Int("tx-per-wallet", 0, "transactions per wallet per batch (TX_PER_WALLET)")
+	env("tx-per-wallet", "TX_PER_WALLET")
+	fs.String("to", "", "recipient of the transfers (TO_ADDRESS)")
+	env("to", "TO_ADDRESS")
+	fs.String("value", "", "value sent per transaction, e.g. 0.001eth, 2gwei or a number of wei (VALUE_WEI)")
+	env("value", "VALUE_WEI")
+	fs.String("workload", "", "transfer, swap or meta (WORKLOAD)")
+	env("workload", "WORKLOAD")
+	fs.String("duration", "", "run in loop mode for this long, e.g. 90s or 2h (RUN_DURATION)")
+	env("duration", "RUN_DURATION")
+	fs.Float64("tps", 0, "offered rate in transactions per second (TARGET_TPS)")
+	env("tps", "TARGET_TPS")
+	fs.String("db", "", "SQLite database (DB_PATH)")
+	env("db", "DB_PATH")
+	fs.String("log-level", "", "debug, info, warn or error (LOG_LEVEL)")
+	env("log-level", "LOG_LEVEL")
+	fs.String("scenario", "", "YAML test plan (SCENARIO_FILE)")
+	env("scenario", "SCENARIO_FILE")
+	fs.BoolP("yes", "y", false, "answer yes to every prompt, for CI, cron and containers (AUTOMATED_MODE)")
+	env("yes", "AUTOMATED_MODE")
+	fs.Bool("dry-run", false, "sign one batch and print the plan without sending anything (DRY_RUN)")
+	env("dry-run", "DRY_RUN")
+	fs.StringArray("set", nil, "set any other variable, e.g. --set GAS_LIMIT=30000 (repeatable)")
+}
+
+// applySettingFlags sets the environment variable of every setting flag
+// given on the command line, so they win over the environment, .env and
+// SCENARIO_FILE alike. A named flag wins over --set of the same variable.
+func applySettingFlags(fs *pflag.FlagSet) error {
+	if set, err := fs.GetStringArray("set"); err == nil {
+		for _, pair := range set {
+			name, value, ok := strings.Cut(pair, "=")
+			name = strings.TrimSpace(name)
+			if !ok || name == "" {
+				return fmt.Errorf("invalid --set %q: expected NAME=VALUE", pair)
+			}
+			os.Setenv(name, value)
+		}
+	}
+	fs.Visit(func(f *pflag.Flag) {
+		if variable, ok := f.Annotations[envAnnotation]; ok {
+			os.Setenv(variable[0], f.Value.String())
+		}
+	})
+	return nil
+}
+
+// splitSettingFlags takes the setting flags of fs out of args, for a
+// command that parses the rest itself, and returns them apart. Both -name
+// and --name are taken, as the command's own flag package accepts either;
+// the flags named in keep are the command's own and stay in rest. Nothing
+// after "--" is taken.
+func splitSettingFlags(fs *pflag.FlagSet, args []string, keep []string) (settings, rest []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return settings, append(rest, args[i:]...)
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		var f *pflag.Flag
+		switch {
+		case !strings.HasPrefix(arg, "-") || slices.Contains(keep, name):
+		case len(name) == 1 && !strings.HasPrefix(arg, "--"):
+			f = fs.ShorthandLookup(name)
+		default:
+			f = fs.Lookup(name)
+		}
+		if f == nil {
+			rest = append(rest, arg)
+			continue
+		}
+		settings = append(settings, "--"+strings.TrimLeft(arg, "-"))
+		if !hasValue && f.NoOptDefVal == "" && i+1 < len(args) {
+			i++
+			settings = append(settings, args[i])
+		}
+	}
+	return settings, rest
+}
+
+// newRootCommand returns the go-tps command line. Without a subcommand it
+// runs the load test, as run does. Every command reads the same settings
+// from the environment, .env and SCENARIO_FILE, and takes the setting flags
+// that override them. The commands that work on the database or the
+// wallets also parse their own flags, e.g. -db for DB_PATH (see go-tps
+// COMMAND -h).
+func newRootCommand() *cobra.Command {
+	var (
+		cfg              *config.Config
+		scenario         *config.Scenario
+		scenarioSettings []string
+		// The arguments of a command that parses its own flags, without
+		// the setting flags
+		ownArgs []string
+		// The flags each such command defines with the name of a setting
+		// flag but another meaning
+		ownFlags = map[string][]string{}
+	)
+
+	root := &cobra.Command{
+		Use:               "go-tps",
+		Short:             "Load-test an Ethereum node and measure the transactions per second it includes",
+		Args:              cobra.NoArgs,
+		SilenceUsage:      true,
+		CompletionOptions: cobra.CompletionOptions{DisableDefaultCmd: true},
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.DisableFlagParsing {
+				var settings []string
+				settings, ownArgs = splitSettingFlags(cmd.Flags(), args, ownFlags[cmd.Name()])
+				if err := cmd.Flags().Parse(settings); err != nil {
+					return err
+				}
+			}
+			if err := applySettingFlags(cmd.Flags()); err != nil {
+				return err
+			}
+			// The commands that parse their own flags need the settings to
+			// parse; the load test needs them to make sense as well
+			cfg, scenario, scenarioSettings = loadSettings(!cmd.DisableFlagParsing)
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			runTest(cfg, scenario, scenarioSettings, false)
+		},
+	}
+
+	run := &cobra.Command{
+		Use:   "run",
+		Short: "Send the load test's batches (the default)",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runTest(cfg, scenario, scenarioSettings, false)
+		},
+	}
+	fund := &cobra.Command{
+		Use:   "fund",
+		Short: "Top up the wallets from the funding wallet for one batch, then exit",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runTest(cfg, scenario, scenarioSettings, true)
+		},
+	}
+	root.AddCommand(run, fund)
+
+	// The commands below parse their own flags and exit with their status
+	passthrough := func(use, short string, run func(*config.Config, []string) int, own ...string) *cobra.Command {
+		ownFlags[use] = own
+		return &cobra.Command{
+			Use:                use,
+			Short:              short,
+			DisableFlagParsing: true,
+			Run: func(cmd *cobra.Command, args []string) {
+				os.Exit(run(cfg, ownArgs))
+			},
+		}
+	}
+	root.AddCommand(
+		passthrough("sweep", "Send what the wallets hold back to one address (wallets sweep)", runSweep, "to", "yes"),
+		passthrough("stats", "Show the stats of one batch", runStatsCommand),
+		passthrough("batches", "List the batches in the database", runBatchesCommand),
+		passthrough("failed", "List the most recent failed transactions", runFailedCommand),
+		passthrough("tx", "Show everything stored about one transaction", runTxCommand),
+		passthrough("trend", "Show TPS, latency and failures across batches", runTrendCommand),
+		passthrough("report", "Write a self-contained HTML report of one batch", runReportCommand),
+		passthrough("export", "Export transactions, per-wallet stats and the TPS time series as CSV", runExportCommand),
+		passthrough("import", "Merge the databases of other runs into this one", runImportCommand),
+		passthrough("wallets", "Export, unstick or sweep the wallets", runWalletsCommand, "to", "yes"),
+		passthrough("receipts", "Confirm the pending transactions of earlier runs", runReceiptsCommand),
+		passthrough("db", "Show the database size, prune or vacuum it", runDBCommand, "dry-run", "yes"),
+	)
+	for _, cmd := range root.Commands() {
+		addSettingFlags(cmd.Flags())
+		for _, name := range ownFlags[cmd.Name()] {
+			cmd.Flags().MarkHidden(name)
+		}
+	}
+	addSettingFlags(root.Flags())
+	return root
+}
+
+// pipedAnswerWait is how long a prompt waits for an answer on a stdin that
+// is not a terminal. An answer piped or redirected in is there at once; a
+// stdin attached with nobody writing to it (docker run -i, a Kubernetes
+// container with stdin: true) would otherwise keep the prompt waiting
+// forever.
+const pipedAnswerWait = 2 * time.Second
+
+var (
+	stdinOnce  sync.Once
+	stdinLines chan string // closed when stdin ends or cannot be read
+)
+
+// stdinIsTerminal reports whether stdin is a terminal someone can type at.
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// readAnswer returns the next line of stdin, waiting for it as long as it
+// takes on a terminal and at most pipedAnswerWait otherwise. ok is false
+// when no line came. The lines are read by one goroutine for every prompt,
+// so none piped in is lost between them.
+func readAnswer() (line string, ok bool) {
+	stdinOnce.Do(func() {
+		stdinLines = make(chan string)
+		go func() {
+			scanner := bufio.NewScanner(os.Stdin)
+			for scanner.Scan() {
+				stdinLines <- scanner.Text()
+			}
+			close(stdinLines)
+		}()
+	})
+	if stdinIsTerminal() {
+		line, ok = <-stdinLines
+		return line, ok
+	}
+	select {
+	case line, ok = <-stdinLines:
+		return line, ok
+	case <-time.After(pipedAnswerWait):
+		return "", false
+	}
+}
+
+// confirm asks question on stdin and reports whether the answer is yes. The
+// answer is read from a terminal, or from a pipe or file that has it ready
+// (echo y | go-tps). It fails when stdin ends, cannot be read, or is not a
+// terminal and has no answer within pipedAnswerWait, so an unattended run
+// in CI, cron or a container stops instead of hanging.
+func confirm(question string) (bool, error) {
+	fmt.Print(question + " (y/n): ")
+	answer, ok := readAnswer()
+	if !ok {
+		fmt.Println()
+		return false, fmt.Errorf("nobody can answer on stdin; pass --yes or set AUTO_CONFIRM=true to run unattended")
+	}
+	response := strings.TrimSpace(strings.ToLower(answer))
+	return response == "y" || response == "yes", nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go-tps/config"
+	dbpkg "go-tps/db"
+	"go-tps/logger"
+	"go-tps/report"
+	txpkg "go-tps/tx"
+)
+
+// Orders in which a provider comparison visits its providers.
+const (
+	compareSequence    = "sequence"    // every round on one provider, then the next
+	compareInterleaved = "interleaved" // one batch per provider in turn, round after round
+)
+
+// providerBatches are the batches a provider comparison sent through one
+// RPC endpoint.
+type providerBatches struct {
+	url     string
+	batches []string
+}
+
+// runComparison sends the same batch through each of providers,
+// COMPARE_ROUNDS times each, in sequence or interleaved. Every batch is
+// labelled with the comparison, the provider and the round, e.g.
+// compare143000-p2-r3, so the batches of one comparison stay linked in
+// the database. Each provider also serves its own batch's receipts; since
+// inclusion is timed by block timestamps, a slow receipt poll does not count
+// against it.
+func runComparison(config *config.Config, run *runState, providers []string) ([]string, []providerBatches) {
+	results := make([]providerBatches, len(providers))
+	for i, url := range providers {
+		results[i].url = url
+	}
+	id := "compare" + time.Now().Format("150405")
+	rounds := max(config.CompareRounds, 1)
+
+	var order [][2]int // provider, round
+	if strings.ToLower(config.CompareOrder) == compareInterleaved {
+		for round := 1; round <= rounds; round++ {
+			for p := range providers {
+				order = append(order, [2]int{p, round})
+			}
+		}
+	} else {
+		for p := range providers {
+			for round := 1; round <= rounds; round++ {
+				order = append(order, [2]int{p, round})
+			}
+		}
+	}
+
+	var batches []string
+	for _, step := range order {
+		p, round := step[0], step[1]
+		if run.abort.Aborted() {
+			break
+		}
+		if budgetSpent(run) {
+			fmt.Println("\n💰 Spend budget exhausted. Stopping.")
+			break
+		}
+		fmt.Printf("\n\n[PROVIDER %d/%d, ROUND %d/%d] %s\n", p+1, len(providers), round, rounds, providers[p])
+		fmt.Println(strings.Repeat("-", 60))
+
+		providerConfig := *config
+		providerConfig.RPCURL = providers[p]
+		providerConfig.RPCFallbackURLs = ""
+		providerConfig.SubmitRPCURL = ""
+		txSender, err := newTransactionSender(&providerConfig, nil)
+		if err != nil {
+			logger.Error("Error connecting to %s: %v\n", providers[p], err)
+			os.Exit(1)
+		}
+		run.batchLabel = fmt.Sprintf("%s-p%d-r%d", id, p+1, round)
+		batchNumber, _ := runSingleExecution(config, txSender, run)
+		txSender.Close()
+		run.batchLabel = ""
+
+		batches = append(batches, batchNumber)
+		results[p].batches = append(results[p].batches, batchNumber)
+	}
+	return batches, results
+}
+
+// printComparisonReport prints the providers side by side. Included TPS is
+// the mean over each provider's batches, since interleaved batches of other
+// providers fall between them.
+func printComparisonReport(db dbpkg.Store, results []providerBatches) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 80))
+	fmt.Println("PROVIDER COMPARISON")
+	fmt.Println(strings.Repeat("=", 80))
+	fmt.Printf("%-3s %-24s %6s %9s %10s %9s %9s %8s %6s %7s\n",
+		"#", "Provider", "Txs", "Included", "Incl. TPS", "Send p50", "Send p95", "Incl p95", "Fail%", "Limited")
+	for i, result := range results {
+		var txs []*dbpkg.Transaction
+		var tps float64
+		for _, batch := range result.batches {
+			batchTxs, err := db.GetBatchTransactions(ctx, batch)
+			if err != nil {
+				logger.Warn("Could not load transactions for %s: %v\n", batch, err)
+				continue
+			}
+			tps += report.BuildTrendPoint(batch, batchTxs).TPS
+			txs = append(txs, batchTxs...)
+		}
+		if len(result.batches) > 0 {
+			tps /= float64(len(result.batches))
+		}
+
+		p := report.BuildTrendPoint(result.url, txs)
+		sendP50, sendP95, limited := "-", "-", 0
+		for _, e := range report.BuildEndpointStats(txs) {
+			limited += e.RateLimited
+			if e.Sends-e.Failed > 0 {
+				sendP50, sendP95 = report.Seconds(e.SubmitPercentile(50), 3), report.Seconds(e.SubmitPercentile(95), 3)
+			}
+		}
+		fmt.Printf("%-3d %-24s %6s %9s %10s %9s %9s %8s %6s %7s\n", i+1, txpkg.EndpointName(result.url),
+			report.Int(p.Txs), report.Int(p.Included), report.Float(tps, 2), sendP50, sendP95,
+			report.Seconds(p.P95Latency, 2), report.Percent(p.FailureRate, 1), report.Int(limited))
+	}
+	fmt.Println(strings.Repeat("-", 80))
+	fmt.Println("Send: submission latency; Incl p95: submission to inclusion; Incl. TPS: mean per batch")
+	fmt.Println(strings.Repeat("=", 80))
+}
@@ -3,89 +3,572 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 const (
-	DefaultRPCURL             = "http://localhost:8545"
-	DefaultWSURL              = "http://localhost:8546" // Empty = no WebSocket, will use RPC polling
-	DefaultDBPath             = "./transactions.db"
-	DefaultWalletCount        = 10
-	DefaultTxPerWallet        = 10
-	DefaultValueWei           = "1000000000000000" // 0.001 ETH
-	DefaultToAddress          = "0x0000000000000000000000000000000000000001"
-	DefaultRunDurationMinutes = 0            // 0 = run once, >0 = loop for duration
-	DefaultDBWorkers          = 4            // DB writer workers
-	DefaultReceiptWorkers     = 4            // Receipt confirmation workers
-	DefaultLogLevel           = "DEBUG"      // DEBUG, INFO, WARN, ERROR
-	DefaultAutomatedMode      = false        // true = skip user confirmation
-	DefaultContextTimeout     = 30           // seconds for RPC calls
-	DefaultDBRetentionDays    = 30           // cleanup records older than this
-	DefaultWSReconnectDelay   = 5            // seconds before reconnecting WebSocket
-	DefaultDBBufferSize       = 500          // DB channel buffer size (0 = auto-calculate from WalletCount * TxPerWallet)
-	DefaultReceiptBufferSize  = 1000         // Receipt channel buffer size (0 = auto-calculate)
-	DefaultDBMaxOpenConns     = 15           // max open DB connections
-	DefaultDBMaxIdleConns     = 5            // max idle DB connections
-	DefaultSleepMinutes       = 0            // minutes to sleep before submitting transactions
-	DefaultGasLimit           = 25000        // gas limit for transactions (increased from 21000 to prevent out of gas)
-	DefaultMinGasPrice        = "2000000000" // minimum gas price in wei (2 gwei)
+	DefaultRPCURL              = "http://localhost:8545"
+	DefaultWSURL               = "http://localhost:8546" // Empty = no WebSocket, will use RPC polling
+	DefaultRPCFallbackURLs     = ""                      // comma-separated endpoints to fail over to, in order
+	DefaultRPCHealthInterval   = 10                      // seconds between endpoint health checks with failover (0 = off)
+	DefaultRPCMaxBlockLag      = 3                       // blocks an endpoint may trail the highest before it is excluded
+	DefaultRPCWeights          = ""                      // Empty = fail over in order, url=weight,... = spread requests by weight
+	DefaultSubmitRPCURL        = ""                      // Empty = submit to RPC_URL, URL = send transactions there only
+	DefaultRPCHeaders          = ""                      // comma-separated Name: value headers sent to HTTP and WebSocket endpoints
+	DefaultRPCBasicAuth        = ""                      // user:password for HTTP basic auth on every endpoint
+	DefaultCompareRPCURLs      = ""                      // comma-separated providers to run the same workload against
+	DefaultCompareOrder        = "sequence"              // sequence = all rounds per provider, interleaved = one round of each in turn
+	DefaultCompareRounds       = 1                       // batches per provider in a comparison
+	DefaultDBPath              = "./transactions.db"
+	DefaultWalletCount         = 10
+	DefaultTxPerWallet         = 10
+	DefaultValueWei            = "1000000000000000" // 0.001 ETH
+	DefaultToAddress           = "0x0000000000000000000000000000000000000001"
+	DefaultRunDurationMinutes  = 0            // 0 = run once, >0 = loop for duration
+	DefaultRunDuration         = ""           // Go duration, e.g. 90s or 2h; overrides minutes when set
+	DefaultSoakInterval        = 0            // minutes per soak interval in loop mode (0 = no interim summaries)
+	DefaultDBWorkers           = 4            // DB writer workers
+	DefaultDBBatchSize         = 200          // records each DB writer saves per database transaction (1 = one at a time)
+	DefaultDBFlushInterval     = 100          // milliseconds a record may wait for its DB batch to fill up
+	DefaultReceiptWorkers      = 4            // Receipt confirmation workers
+	DefaultLogLevel            = "DEBUG"      // DEBUG, INFO, WARN, ERROR
+	DefaultAutomatedMode       = false        // true = skip user confirmation
+	DefaultDryRun              = false        // true = sign one batch and print the plan without sending anything
+	DefaultContextTimeout      = 30           // seconds for RPC calls
+	DefaultDBRetentionDays     = 30           // `go-tps db prune` deletes batches older than this
+	DefaultDBDumpPath          = ""           // Empty = none, path = copy the database there at the end of the run
+	DefaultWSReconnectDelay    = 5            // seconds before reconnecting WebSocket
+	DefaultDBBufferSize        = 500          // DB channel buffer size (0 = auto-calculate from WalletCount * TxPerWallet)
+	DefaultDBMaxOpenConns      = 15           // max open DB read connections (writes share one)
+	DefaultDBMaxIdleConns      = 5            // max idle DB read connections
+	DefaultSleepMinutes        = 0            // minutes to sleep before submitting transactions
+	DefaultGasLimit            = 25000        // gas limit for transactions (increased from 21000 to prevent out of gas)
+	DefaultMinGasPrice         = "2000000000" // minimum gas price in wei (2 gwei)
+	DefaultP2PEnode            = ""           // Empty = send via JSON-RPC, enode URL = broadcast over devp2p (experimental)
+	DefaultWorkload            = "transfer"   // transfer, swap
+	DefaultBeaconAPIURL        = ""           // Empty = no slot timing report, URL = consensus client REST API
+	DefaultEngineMetricsURL    = ""           // Prometheus endpoint exposing payload build times (optional)
+	DefaultGasRefreshInterval  = 12           // seconds between base fee refreshes (0 = fetch once per batch)
+	DefaultGasPriceMultiplier  = 1.0          // multiplier applied to the fetched base fee
+	DefaultMaxGasPriceWei      = "0"          // hard cap on max fee per gas in wei (0 = uncapped)
+	DefaultSubmissionTiming    = "immediate"  // immediate, slot (burst just before the next slot), random (burst at a random point in the slot)
+	DefaultSlotDurationSeconds = 12           // slot length for slot/random timing (overridden by BEACON_API_URL)
+	DefaultSlotOffsetMs        = 500          // fire slot-aligned bursts this many ms before the slot boundary
+	DefaultPriorityFeeGwei     = 1.0          // EIP-1559 priority fee (tip) in gwei
+	DefaultFeeBumpStrategy     = "percentage" // fixed, percentage, aggressive
+	DefaultFeeBumpPercent      = 10           // step size for fixed/percentage bumps
+	DefaultStuckTxBlocks       = 0            // blocks before re-sending a stuck tx (0 = off)
+	DefaultStuckTxMaxBumps     = 5            // replacements per stuck transaction
+	DefaultPreBatchHook        = ""           // command or URL run before each batch
+	DefaultPostBatchHook       = ""           // command or URL run after each batch
+	DefaultHookTimeoutSeconds  = 60           // per-hook timeout
+	DefaultGasEstimate         = true         // size gas limits with eth_estimateGas
+	DefaultGasEstimateMargin   = 20           // safety margin on top of estimates (%)
+	DefaultGasLimitOverride    = 0            // fixed gas limit for every tx (0 = off)
+	DefaultBudgetCheck         = true         // stop loop mode when wallets cannot fund another iteration
+	DefaultBlockMetrics        = true         // record each block's base fee and gas usage during the run
+	DefaultBlockTPS            = true         // count the run's transactions per block from chain data after the run
+	DefaultMinIterationSeconds = 1.0          // minimum loop-mode iteration length
+	DefaultLoopPacing          = "interval"   // interval (start-to-start), gap (end-to-start)
+	DefaultMaxSpendWei         = "0"          // cap on worst-case wei committed per run (0 = unlimited)
+	DefaultMaxSpendWalletWei   = "0"          // cap on worst-case wei committed per wallet (0 = unlimited)
+	DefaultFunderKeystore      = ""           // Empty = FUNDER_PRIVATE_KEY or no funding, path = JSON keystore of the funding wallet
+	DefaultKeysFile            = ""           // Empty = derive wallets from the mnemonic, path = one hex private key per line
+	DefaultKeystoreDir         = ""           // Empty = derive wallets from the mnemonic, path = directory of JSON keystore files
+	DefaultSaveMnemonic        = true         // write the mnemonic to mnemonic.txt
+	DefaultMnemonicBits        = 128          // entropy of generated mnemonics: 128 (12 words) to 256 (24 words)
+	DefaultRederiveWallets     = false        // store wallets of previous runs as new, dropping their stored nonces
+	DefaultDerivationStart     = 0            // index of the first wallet in DerivationPath
+	DefaultReceiverCount       = 0            // receivers derived from the mnemonic (0 = send to TO_ADDRESS)
+	DefaultSignerURL           = ""           // Empty = sign with keys held in the process, URL = remote signer (web3signer, clef)
+	DefaultSignerAPI           = "eth"        // eth (eth_signTransaction: web3signer) or clef (account_signTransaction)
+	DefaultFundingMargin       = 20           // percent added on top of each wallet's worst-case batch cost when funding
+	DefaultSkipUnfunded        = false        // leave wallets that cannot pay for their batch out of the run
+	DefaultBalanceSnapshots    = true         // record each wallet's balance before and after the run
+	DefaultBalanceSnapshotMins = 0            // minutes between balance snapshots during the run (0 = only before and after)
+	DefaultTopUpInterval       = 0            // seconds between mid-run balance checks that top wallets up from the funder (0 = off)
+	DefaultTopUpBelowWei       = "0"          // balance below which a wallet is topped up (0 = the most one batch may cost it)
+	DefaultTopUpTargetWei      = "0"          // balance a top-up brings the wallet to (0 = twice the threshold)
+	DefaultRollup              = "none"       // none, optimism, arbitrum
+	DefaultControlAddr         = ""           // Empty = no HTTP control endpoint, host:port = serve POST /abort
+	DefaultAbortGraceSeconds   = 60           // how long an aborted run keeps draining confirmations
+	DefaultStopOnErrorRate     = 0            // percent of failed sends that stops the run (0 = never)
+	DefaultStopOnErrorWindow   = 30           // seconds of sends the error rate is measured over
+	DefaultStopOnErrorMin      = 20           // sends in the window before the error rate is judged
+	DefaultInclusionTarget     = 0            // target inclusion latency in seconds for adaptive fees (0 = off)
+	DefaultFeeControlStep      = 10           // percent the adaptive fee level moves per adjustment
+	DefaultFeeControlInterval  = 12           // seconds between adaptive fee adjustments
+	DefaultFeeControlMinLevel  = 0.5          // lowest adaptive fee level
+	DefaultFeeControlMaxLevel  = 4.0          // highest adaptive fee level
+	DefaultNonceGapRepair      = "ask"        // ask, auto, off
+	DefaultTargetTPS           = 0            // transactions per second across all wallets (0 = unpaced)
+	DefaultTargetTPSBurst      = 1            // sends that may go out at once after a stall
+	DefaultWalletTPS           = 0            // per-wallet send rate cap in tx/s (0 = uncapped)
+	DefaultRampStartTPS        = 0            // ramp profile start rate in tx/s
+	DefaultRampEndTPS          = 0            // ramp profile end rate in tx/s
+	DefaultRampDuration        = 0            // seconds to ramp from start to end rate (0 = no ramp)
+	DefaultSpikeMultiplier     = 0            // spike rate as a multiple of TARGET_TPS (0 = no spikes)
+	DefaultSpikeSeconds        = 10           // length of each spike
+	DefaultSpikeEvery          = 300          // seconds from one spike's start to the next
+	DefaultSpikeRecovery       = 60           // seconds after each spike tagged as recovery
+	DefaultSaturationSearch    = false        // search for the highest sustainable rate
+	DefaultSaturationStartTPS  = 10           // first rate probed, in tx/s
+	DefaultSaturationMaxTPS    = 0            // highest rate probed, in tx/s (0 = no cap)
+	DefaultSaturationProbe     = 60           // seconds each rate is offered
+	DefaultSaturationMaxP95    = 12           // p95 inclusion latency a probe may reach, in seconds
+	DefaultSaturationMaxFail   = 1            // failure rate a probe may reach, in percent
+	DefaultSaturationPrecision = 5            // stop bisecting within this percent
+	DefaultSaturationMaxProbes = 12           // probes before giving up on the search
+	DefaultMaxInflight         = 0            // unmined transactions allowed at once (0 = no cap)
+	DefaultSendDistribution    = "uniform"    // uniform, poisson, burst
+	DefaultSendBurstSize       = 10           // sends per group with the burst distribution
+	DefaultLoadStages          = ""           // staircase profile: tps:seconds stages, comma-separated
+	DefaultNonceSource         = "pending"    // pending, latest
+	DefaultNonceOverrides      = ""           // index=nonce or address=nonce, comma-separated
+	DefaultReportLocale        = "en"         // en, de, fr, ch
+	DefaultReportThousands     = false        // group digits in reports
+	DefaultReportDecimals      = -1           // fixed decimals in reports (-1 = each report's default)
+	DefaultReportDurationUnit  = "s"          // s, ms
+	DefaultReportLayout        = "detailed"   // detailed, compact
+	DefaultHistogramBucket     = 1.0          // width of confirmation latency histogram buckets in seconds
+	DefaultTPSSeriesSeconds    = 1            // bucket width of the stored submission/confirmation time series
+	DefaultSummaryJSON         = ""           // Empty = none, path = write a JSON summary of the run there
+	DefaultTraceEndpoint       = ""           // Empty = no tracing, http(s)://host:4318 = export spans via OTLP/HTTP
+	DefaultTraceHeaders        = ""           // "Name: value, Name: value" sent with every span export
+	DefaultTraceSampleRate     = 1.0          // share of transactions traced, 0-1
+	DefaultInfluxURL           = ""           // Empty = no metrics sink, http(s)://host:8086 = write line protocol there
+	DefaultInfluxToken         = ""           // API token, sent as "Authorization: Token <token>"
+	DefaultInfluxOrg           = ""           // InfluxDB 2 organization
+	DefaultInfluxBucket        = ""           // InfluxDB 2 bucket (set this or INFLUX_DATABASE)
+	DefaultInfluxDatabase      = ""           // InfluxDB 1.x database, also for Telegraf and other /write receivers
+	DefaultWebhookURL          = ""           // Empty = none, http(s) URL = POST run events (completion, alerts) there as JSON
+	DefaultWebhookHeaders      = ""           // "Name: value, Name: value" sent with every webhook call
+	DefaultAlertErrorRate      = 0            // percent of failed transactions in the window that alerts (0 = not watched)
+	DefaultAlertP95Latency     = 0            // p95 confirmation latency in seconds in the window that alerts (0 = not watched)
+	DefaultAlertWindow         = 300          // seconds of outcomes the alert thresholds are measured over
+	DefaultAlertMinSamples     = 20           // outcomes in the window before a threshold is judged
+	DefaultChatWebhookURL      = ""           // Empty = none, Slack or Discord incoming webhook = post run summaries there
+	DefaultChatNotify          = "run"        // run, iteration (also after each loop iteration)
+	DefaultChatReportURL       = ""           // link added to chat summaries; {batch} is replaced by the batch
+	DefaultSLAMinTPS           = 0            // run TPS below this fails the SLA (0 = not asserted)
+	DefaultSLAMaxP95           = 0            // p95 confirmation latency in seconds above this fails the SLA (0 = not asserted)
+	DefaultSLAMaxFailureRate   = -1           // failure rate in percent above this fails the SLA (-1 = not asserted)
+	DefaultProgressInterval    = 0            // seconds between interim stats lines (0 = none)
+	DefaultProgressPersist     = false        // also store the interim stats in progress_stats
+
+	// Lighthouse exposes payload build time via the engine API request histogram
+	DefaultEnginePayloadMetric = `execution_layer_request_times{method="get_payload"}`
+
+	// Standard Ethereum path; {index} is DerivationStart plus the wallet's index
+	DefaultDerivationPath = "m/44'/60'/0'/0/{index}"
+	// The next account of the same tree, which the wallets never reach
+	DefaultReceiverDerivationPath = "m/44'/60'/1'/0/{index}"
 )
 
 type Config struct {
-	RPCURL             string
-	WSURL              string
-	DBPath             string
-	Mnemonic           string
-	WalletCount        int
-	TxPerWallet        int
-	ValueWei           string
-	ToAddress          string
-	RunDurationMinutes int
-	DBWorkers          int // Number of DB writer workers
-	ReceiptWorkers     int // Number of receipt confirmation workers
-	LogLevel           string
-	AutomatedMode      bool   // Skip user confirmation if true
-	ContextTimeout     int    // Timeout for RPC calls in seconds
-	WSReconnectDelay   int    // Seconds before reconnecting WebSocket
-	DBBufferSize       int    // DB channel buffer size (0 = auto-calculate)
-	ReceiptBufferSize  int    // Receipt channel buffer size (0 = auto-calculate)
-	DBMaxOpenConns     int    // Max open SQLite connections
-	DBMaxIdleConns     int    // Max idle SQLite connections
-	SleepMinutes       int    // Minutes to sleep before submitting transactions
-	GasLimit           uint64 // Gas limit for transactions
-	MinGasPrice        string // Minimum gas price in wei
+	RPCURL              string
+	RPCFallbackURLs     string // Comma-separated endpoints requests fail over to, in order, when the active one stops answering
+	RPCHealthInterval   int    // Seconds between health checks of the failover endpoints (0 = off)
+	RPCMaxBlockLag      int    // Blocks an endpoint may trail the highest endpoint before it leaves the rotation
+	RPCWeights          string // Comma-separated url=weight pairs spreading requests over RPCURL and RPCFallbackURLs (empty = failover only)
+	SubmitRPCURL        string // Endpoint for eth_sendRawTransaction only; queries still go to RPCURL (empty = RPCURL)
+	RPCHeaders          string // Comma-separated "Name: value" headers added to every HTTP and WebSocket RPC request
+	RPCBasicAuth        string // user:password sent as HTTP basic auth to every RPC endpoint
+	CompareRPCURLs      string // Comma-separated providers that each get the same workload, compared side by side (empty = off)
+	CompareOrder        string // Order providers are visited in: sequence or interleaved
+	CompareRounds       int    // Batches sent through each provider in a comparison
+	WSURL               string
+	DBPath              string
+	Mnemonic            string
+	MnemonicSource      string // Secrets manager the mnemonic is read from: vault://PATH#FIELD or aws-sm://ID#FIELD
+	MnemonicPassphrase  string // BIP39 passphrase the wallets are derived with (empty = none)
+	MnemonicBits        int    // Entropy of a generated mnemonic: 128 (12 words) to 256 (24 words)
+	SaveMnemonic        bool   // Write the mnemonic to mnemonic.txt
+	MnemonicPassword    string // Encrypts mnemonic.txt (empty = plain text)
+	KeysFile            string // File of hex private keys, one per line, used instead of the mnemonic
+	KeysSource          string // Secrets manager holding keys in the KeysFile format, used instead of the mnemonic
+	KeystoreDir         string // Directory of JSON keystore files, used instead of the mnemonic
+	KeystorePassword    string // Password of the KeystoreDir files (empty = prompt)
+	DerivationPath      string // HD path template of the wallets; {index} is the wallet's index
+	DerivationStart     int    // Index of the first wallet, so several instances can share a mnemonic
+	ReceiverCount       int    // Receivers derived from the mnemonic the transfers are spread across (0 = all to ToAddress)
+	ReceiverPath        string // HD path template of the receivers; {index} is the receiver's index
+	RederiveWallets     bool   // Store wallets already in the wallets table as new, dropping their stored nonces
+	SignerURL           string // Remote signer that holds the wallets' keys, used instead of the mnemonic
+	SignerAPI           string // JSON-RPC API of the remote signer: eth or clef
+	SignerAddresses     string // Comma-separated accounts of the remote signer to use (empty = all it lists)
+	WalletCount         int
+	TxPerWallet         int
+	ValueWei            string
+	ToAddress           string
+	RunDurationMinutes  int
+	RunDuration         string // Loop mode length as a duration, e.g. 90s or 2h (overrides RunDurationMinutes)
+	SoakIntervalMinutes int    // Loop mode: print and store an interim summary every this many minutes (0 = off)
+	DBWorkers           int    // Number of DB writer workers
+	DBBatchSize         int    // Records saved per database transaction
+	DBFlushInterval     int    // Milliseconds a record may wait for its batch to fill up
+	ReceiptWorkers      int    // Number of receipt confirmation workers
+	LogLevel            string
+	AutomatedMode       bool    // Skip user confirmation if AUTOMATED_MODE or AUTO_CONFIRM is true
+	DryRun              bool    // Sign one batch and print the plan, then exit without funding or sending
+	ContextTimeout      int     // Timeout for RPC calls in seconds
+	WSReconnectDelay    int     // Seconds before reconnecting WebSocket
+	DBBufferSize        int     // DB channel buffer size (0 = auto-calculate)
+	DBRetentionDays     int     // Age in days past which `db prune` deletes batches
+	DBDumpPath          string  // File the database is copied to when the run ends (empty = none)
+	DBMaxOpenConns      int     // Max open SQLite read connections
+	DBMaxIdleConns      int     // Max idle SQLite read connections
+	SleepMinutes        int     // Minutes to sleep before submitting transactions
+	GasLimit            uint64  // Gas limit for transactions
+	MinGasPrice         string  // Minimum gas price in wei
+	P2PEnode            string  // Enode URL to broadcast transactions to over devp2p (experimental)
+	Workload            string  // Transaction workload: transfer or swap
+	BeaconAPIURL        string  // Consensus client REST API for slot timing correlation
+	EngineMetricsURL    string  // Prometheus metrics endpoint for payload build times
+	EnginePayloadMetric string  // Histogram selector for payload build time
+	GasRefreshInterval  int     // Seconds between background base fee refreshes (0 = disabled)
+	GasPriceMultiplier  float64 // Multiplier applied to the fetched base fee
+	MaxGasPriceWei      string  // Hard cap on max fee per gas in wei (0 = uncapped)
+	SubmissionTiming    string  // When each batch burst is fired: immediate, slot or random
+	SlotDurationSeconds int     // Slot length in seconds for slot-aligned timing
+	SlotOffsetMs        int     // Milliseconds before the slot boundary to fire the burst
+	PriorityFeeGwei     float64 // EIP-1559 priority fee (tip) in gwei
+	FeeBumpStrategy     string  // How fees rise after underpriced errors: fixed, percentage or aggressive
+	FeeBumpPercent      float64 // Step size in percent for fixed and percentage bumps
+	StuckTxBlocks       int     // Blocks a transaction may stay unmined before it is replaced at a higher fee (0 = disabled)
+	StuckTxMaxBumps     int     // Maximum number of replacements sent for one stuck transaction
+	PreBatchHook        string  // Shell command or http(s) webhook run before each batch
+	PostBatchHook       string  // Shell command or http(s) webhook run after each batch is submitted
+	HookTimeoutSeconds  int     // Timeout for a single hook run in seconds
+	GasEstimate         bool    // Size gas limits with eth_estimateGas (GAS_LIMIT becomes the fallback)
+	GasEstimateMargin   float64 // Safety margin added on top of gas estimates, in percent
+	GasLimitOverride    uint64  // Fixed gas limit for every transaction, bypassing estimation and workload defaults (0 = off)
+	BudgetCheck         bool    // Stop loop mode once aggregate wallet balances cannot cover another iteration
+	BlockMetrics        bool    // Record base fee and gas usage of every block seen during the run
+	BlockTPS            bool    // After the run, read the blocks it spanned and measure TPS from what they included
+	MinIterationSeconds float64 // Loop mode: iteration period (interval pacing) or pause after each iteration (gap pacing)
+	LoopPacing          string  // Loop mode pacing: interval (fixed start-to-start) or gap (fixed end-to-start)
+	MaxSpendWei         string  // Cap on the worst-case wei (value + gas) the run may commit (0 = unlimited)
+	MaxSpendWalletWei   string  // Cap on the worst-case wei each wallet may commit (0 = unlimited)
+	FunderPrivateKey    string  // Hex private key of the wallet that tops up the derived wallets before the run (empty = no funding)
+	FunderKeystore      string  // JSON keystore of the funding wallet, instead of FunderPrivateKey
+	FunderKeystorePass  string  // Password of FunderKeystore
+	FunderKeySource     string  // Secrets manager holding the funding wallet's hex private key, instead of FunderPrivateKey
+	FunderAddress       string  // Account of the remote signer (SignerURL) that funds the wallets, instead of FunderPrivateKey
+	FundingMargin       float64 // Percent added on top of each wallet's worst-case batch cost when funding
+	SkipUnfunded        bool    // Leave wallets that cannot pay for their batch out of the run instead of failing their sends
+	BalanceSnapshots    bool    // Record each wallet's balance in the balances table before and after the run
+	BalanceSnapshotMins int     // Also record the balances every this many minutes during the run (0 = off)
+	TopUpInterval       int     // Seconds between checks that top up draining wallets from the funding wallet during the run (0 = off)
+	TopUpBelowWei       string  // Balance below which a wallet is topped up (0 = the most one batch may cost it)
+	TopUpTargetWei      string  // Balance a top-up brings the wallet to (0 = twice the threshold)
+	Rollup              string  // Rollup whose L1 data fee is added to costs: none, optimism or arbitrum
+	ControlAddr         string  // Address for the HTTP control endpoint (POST /abort); empty = disabled
+	AbortGraceSeconds   int     // Seconds an aborted run keeps draining receipt confirmations
+	StopOnErrorRate     float64 // Stop the run once this percent of sends in the window fail (0 = never)
+	StopOnErrorWindow   int     // Seconds of recent sends the error rate is measured over
+	StopOnErrorMin      int     // Sends the window must hold before the error rate can stop the run
+	InclusionTarget     float64 // Target p95 inclusion latency in seconds that adaptive fees steer towards (0 = disabled)
+	FeeControlStep      float64 // Percent the adaptive fee level rises or falls per adjustment
+	FeeControlInterval  int     // Seconds between adaptive fee adjustments
+	FeeControlMinLevel  float64 // Lowest adaptive fee level (factor on fee cap and tip)
+	FeeControlMaxLevel  float64 // Highest adaptive fee level (factor on fee cap and tip)
+	NonceGapRepair      string  // After the run, fill nonce gaps with self-transfers: ask, auto or off
+	TargetTPS           float64 // Constant submission rate across all wallets in tx/s (0 = as fast as possible)
+	TargetTPSBurst      int     // Sends the rate limiter lets out back to back after falling behind
+	WalletTPS           float64 // Cap on each wallet's own send rate in tx/s, on top of any global rate (0 = uncapped)
+	RampStartTPS        float64 // Ramp profile: offered rate at the first send, in tx/s
+	RampEndTPS          float64 // Ramp profile: offered rate reached after RampDuration, in tx/s
+	RampDuration        int     // Seconds over which the offered rate ramps from start to end (0 = no ramp; overrides TargetTPS)
+	SpikeMultiplier     float64 // Spike profile: multiply TargetTPS by this during each spike (0 = no spikes)
+	SpikeSeconds        int     // Spike profile: length of each spike in seconds
+	SpikeEvery          int     // Spike profile: seconds from one spike's start to the next; the first comes one period in
+	SpikeRecovery       int     // Spike profile: seconds after each spike whose transactions are tagged recovery
+	SaturationSearch    bool    // Search for the highest rate the chain sustains instead of a fixed load
+	SaturationStartTPS  float64 // First rate probed in tx/s; doubled while probes pass
+	SaturationMaxTPS    float64 // Never probe above this rate in tx/s (0 = no cap)
+	SaturationProbe     int     // Seconds each probed rate is offered
+	SaturationMaxP95    float64 // A probe fails once p95 inclusion latency exceeds this many seconds
+	SaturationMaxFail   float64 // A probe fails once more than this percent of its transactions fail
+	SaturationPrecision float64 // Stop bisecting once pass and fail rates are within this percent
+	SaturationMaxProbes int     // Stop the search after this many probes
+	MaxInflight         int     // Closed loop: sends wait while this many submitted transactions are unmined (0 = open loop)
+	SendDistribution    string  // How paced sends are spread around the mean rate: uniform, poisson or burst
+	SendBurstSize       int     // Sends released together with the burst distribution
+	LoadStages          string  // Staircase profile: comma-separated tps:seconds stages (overrides TargetTPS, the ramp and loop mode)
+	NonceSource         string  // Where wallet nonces are read from: pending or latest
+	NonceOverrides      string  // Explicit starting nonces per wallet: index=nonce or address=nonce, comma-separated
+	ReportLocale        string  // Locale whose thousands separator and decimal mark reports use
+	ReportThousands     bool    // Group digits of large numbers in reports
+	ReportDecimals      int     // Fixed decimals for fractional numbers in reports (-1 = each report's default)
+	ReportDurationUnit  string  // Unit of latencies in reports: s or ms
+	ReportLayout        string  // Summary layout: detailed (per-batch rows) or compact (totals only)
+	HistogramBucket     float64 // Width in seconds of the confirmation latency histogram buckets (0 = no histogram)
+	TPSSeriesSeconds    int     // Bucket width in seconds of the per-batch TPS time series (0 = not stored)
+	SummaryJSON         string  // File the run's machine-readable JSON summary is written to (empty = none)
+	TraceEndpoint       string  // OTLP/HTTP collector transaction spans are exported to (empty = no tracing)
+	TraceHeaders        string  // Headers sent with span exports, e.g. a tracing vendor's API key
+	TraceSampleRate     float64 // Share of transactions traced, 0-1
+	InfluxURL           string  // InfluxDB (or other line protocol receiver) metrics are pushed to (empty = none)
+	InfluxToken         string  // InfluxDB API token
+	InfluxOrg           string  // InfluxDB 2 organization
+	InfluxBucket        string  // InfluxDB 2 bucket; selects the /api/v2/write API
+	InfluxDatabase      string  // InfluxDB 1.x database; selects the /write API
+	WebhookURL          string  // URL run events (completion with the summary, threshold breaches) are POSTed to
+	WebhookHeaders      string  // Headers sent with webhook calls, e.g. an Authorization header
+	AlertErrorRate      float64 // Alert when this percent of transactions in the window fail (0 = not watched)
+	AlertP95Latency     float64 // Alert when the p95 confirmation latency in the window exceeds this many seconds (0 = not watched)
+	AlertWindow         int     // Seconds of recent outcomes the alert thresholds are measured over
+	AlertMinSamples     int     // Outcomes the window must hold before a threshold is judged
+	ChatWebhookURL      string  // Slack or Discord incoming webhook run summaries are posted to (empty = none)
+	ChatNotify          string  // When to post: run (at the end) or iteration (also after each loop iteration)
+	ChatReportURL       string  // Report link in chat summaries, with {batch} replaced by the batch
+	SLAMinTPS           float64 // The run fails its SLA below this TPS (0 = not asserted)
+	SLAMaxP95           float64 // The run fails its SLA above this p95 confirmation latency in seconds (0 = not asserted)
+	SLAMaxFailureRate   float64 // The run fails its SLA above this failure rate in percent (-1 = not asserted)
+	ProgressInterval    int     // Seconds between interim stats lines during the run (0 = none)
+	ProgressPersist     bool    // Store the interim stats in the progress_stats table
+
+	errs []error // settings that could not be parsed, see Errors
 }
 
 func LoadConfig() *Config {
+	loadMu.Lock()
+	defer loadMu.Unlock()
+	loadErrs = nil
+
 	// Load from environment variables or use defaults
 	config := &Config{
-		RPCURL:             getEnv("RPC_URL", DefaultRPCURL),
-		WSURL:              getEnv("WS_URL", DefaultWSURL),
-		DBPath:             getEnv("DB_PATH", DefaultDBPath),
-		Mnemonic:           getEnv("MNEMONIC", ""),
-		WalletCount:        getEnvInt("WALLET_COUNT", DefaultWalletCount),
-		TxPerWallet:        getEnvInt("TX_PER_WALLET", DefaultTxPerWallet),
-		ValueWei:           getEnv("VALUE_WEI", DefaultValueWei),
-		ToAddress:          getEnv("TO_ADDRESS", DefaultToAddress),
-		RunDurationMinutes: getEnvInt("RUN_DURATION_MINUTES", DefaultRunDurationMinutes),
-		DBWorkers:          getEnvInt("DB_WORKERS", DefaultDBWorkers),
-		ReceiptWorkers:     getEnvInt("RECEIPT_WORKERS", DefaultReceiptWorkers),
-		LogLevel:           getEnv("LOG_LEVEL", DefaultLogLevel),
-		AutomatedMode:      getEnvBool("AUTOMATED_MODE", DefaultAutomatedMode),
-		ContextTimeout:     getEnvInt("CONTEXT_TIMEOUT", DefaultContextTimeout),
-		WSReconnectDelay:   getEnvInt("WS_RECONNECT_DELAY", DefaultWSReconnectDelay),
-		DBBufferSize:       getEnvInt("DB_BUFFER_SIZE", DefaultDBBufferSize),
-		ReceiptBufferSize:  getEnvInt("RECEIPT_BUFFER_SIZE", DefaultReceiptBufferSize),
-		DBMaxOpenConns:     getEnvInt("DB_MAX_OPEN_CONNS", DefaultDBMaxOpenConns),
-		DBMaxIdleConns:     getEnvInt("DB_MAX_IDLE_CONNS", DefaultDBMaxIdleConns),
-		SleepMinutes:       getEnvInt("SLEEP_MINUTES", DefaultSleepMinutes),
-		GasLimit:           getEnvUint64("GAS_LIMIT", DefaultGasLimit),
-		MinGasPrice:        getEnv("MIN_GAS_PRICE", DefaultMinGasPrice),
+		RPCURL:              getEnv("RPC_URL", DefaultRPCURL),
+		WSURL:               getEnv("WS_URL", DefaultWSURL),
+		RPCFallbackURLs:     getEnv("RPC_FALLBACK_URLS", DefaultRPCFallbackURLs),
+		RPCHealthInterval:   getEnvInt("RPC_HEALTH_INTERVAL_SECONDS", DefaultRPCHealthInterval),
+		RPCMaxBlockLag:      getEnvInt("RPC_MAX_BLOCK_LAG", DefaultRPCMaxBlockLag),
+		RPCWeights:          getEnv("RPC_WEIGHTS", DefaultRPCWeights),
+		SubmitRPCURL:        getEnv("SUBMIT_RPC_URL", DefaultSubmitRPCURL),
+		RPCHeaders:          getEnv("RPC_HEADERS", DefaultRPCHeaders),
+		RPCBasicAuth:        getEnv("RPC_BASIC_AUTH", DefaultRPCBasicAuth),
+		CompareRPCURLs:      getEnv("COMPARE_RPC_URLS", DefaultCompareRPCURLs),
+		CompareOrder:        getEnv("COMPARE_ORDER", DefaultCompareOrder),
+		CompareRounds:       getEnvInt("COMPARE_ROUNDS", DefaultCompareRounds),
+		DBPath:              getEnv("DB_PATH", DefaultDBPath),
+		Mnemonic:            getEnv("MNEMONIC", ""),
+		MnemonicSource:      getEnv("MNEMONIC_SOURCE", ""),
+		MnemonicPassphrase:  getEnv("MNEMONIC_PASSPHRASE", ""),
+		MnemonicBits:        getEnvInt("MNEMONIC_BITS", DefaultMnemonicBits),
+		SaveMnemonic:        getEnvBool("SAVE_MNEMONIC", DefaultSaveMnemonic),
+		MnemonicPassword:    getEnv("MNEMONIC_FILE_PASSWORD", ""),
+		KeysFile:            getEnv("KEYS_FILE", DefaultKeysFile),
+		KeysSource:          getEnv("KEYS_SOURCE", ""),
+		KeystoreDir:         getEnv("KEYSTORE_DIR", DefaultKeystoreDir),
+		KeystorePassword:    getEnv("KEYSTORE_PASSWORD", ""),
+		DerivationPath:      getEnv("DERIVATION_PATH", DefaultDerivationPath),
+		DerivationStart:     getEnvInt("DERIVATION_START_INDEX", DefaultDerivationStart),
+		ReceiverCount:       getEnvInt("RECEIVER_COUNT", DefaultReceiverCount),
+		ReceiverPath:        getEnv("RECEIVER_DERIVATION_PATH", DefaultReceiverDerivationPath),
+		RederiveWallets:     getEnvBool("REDERIVE_WALLETS", DefaultRederiveWallets),
+		SignerURL:           getEnv("SIGNER_URL", DefaultSignerURL),
+		SignerAPI:           getEnv("SIGNER_API", DefaultSignerAPI),
+		SignerAddresses:     getEnv("SIGNER_ADDRESSES", ""),
+		WalletCount:         getEnvInt("WALLET_COUNT", DefaultWalletCount),
+		TxPerWallet:         getEnvInt("TX_PER_WALLET", DefaultTxPerWallet),
+		ValueWei:            getEnv("VALUE_WEI", getEnv("VALUE", DefaultValueWei)),
+		ToAddress:           getEnv("TO_ADDRESS", DefaultToAddress),
+		RunDurationMinutes:  getEnvInt("RUN_DURATION_MINUTES", DefaultRunDurationMinutes),
+		RunDuration:         getEnv("RUN_DURATION", DefaultRunDuration),
+		SoakIntervalMinutes: getEnvInt("SOAK_INTERVAL_MINUTES", DefaultSoakInterval),
+		DBWorkers:           getEnvInt("DB_WORKERS", DefaultDBWorkers),
+		DBBatchSize:         getEnvInt("DB_BATCH_SIZE", DefaultDBBatchSize),
+		DBFlushInterval:     getEnvInt("DB_FLUSH_INTERVAL_MS", DefaultDBFlushInterval),
+		ReceiptWorkers:      getEnvInt("RECEIPT_WORKERS", DefaultReceiptWorkers),
+		LogLevel:            getEnv("LOG_LEVEL", DefaultLogLevel),
+		AutomatedMode:       getEnvBool("AUTOMATED_MODE", DefaultAutomatedMode) || getEnvBool("AUTO_CONFIRM", DefaultAutomatedMode),
+		DryRun:              getEnvBool("DRY_RUN", DefaultDryRun),
+		ContextTimeout:      getEnvInt("CONTEXT_TIMEOUT", DefaultContextTimeout),
+		WSReconnectDelay:    getEnvInt("WS_RECONNECT_DELAY", DefaultWSReconnectDelay),
+		DBBufferSize:        getEnvInt("DB_BUFFER_SIZE", DefaultDBBufferSize),
+		DBRetentionDays:     getEnvInt("DB_RETENTION_DAYS", DefaultDBRetentionDays),
+		DBDumpPath:          getEnv("DB_DUMP_PATH", DefaultDBDumpPath),
+		DBMaxOpenConns:      getEnvInt("DB_MAX_OPEN_CONNS", DefaultDBMaxOpenConns),
+		DBMaxIdleConns:      getEnvInt("DB_MAX_IDLE_CONNS", DefaultDBMaxIdleConns),
+		SleepMinutes:        getEnvInt("SLEEP_MINUTES", DefaultSleepMinutes),
+		GasLimit:            getEnvUint64("GAS_LIMIT", DefaultGasLimit),
+		MinGasPrice:         getEnv("MIN_GAS_PRICE", DefaultMinGasPrice),
+		P2PEnode:            getEnv("P2P_ENODE", DefaultP2PEnode),
+		Workload:            getEnv("WORKLOAD", DefaultWorkload),
+		BeaconAPIURL:        getEnv("BEACON_API_URL", DefaultBeaconAPIURL),
+		EngineMetricsURL:    getEnv("ENGINE_METRICS_URL", DefaultEngineMetricsURL),
+		EnginePayloadMetric: getEnv("ENGINE_PAYLOAD_METRIC", DefaultEnginePayloadMetric),
+		GasRefreshInterval:  getEnvInt("GAS_REFRESH_INTERVAL", DefaultGasRefreshInterval),
+		GasPriceMultiplier:  getEnvFloat("GAS_PRICE_MULTIPLIER", DefaultGasPriceMultiplier),
+		MaxGasPriceWei:      getEnv("MAX_GAS_PRICE_WEI", DefaultMaxGasPriceWei),
+		SubmissionTiming:    getEnv("SUBMISSION_TIMING", DefaultSubmissionTiming),
+		SlotDurationSeconds: getEnvInt("SLOT_DURATION_SECONDS", DefaultSlotDurationSeconds),
+		SlotOffsetMs:        getEnvInt("SLOT_OFFSET_MS", DefaultSlotOffsetMs),
+		PriorityFeeGwei:     getEnvFloat("PRIORITY_FEE_GWEI", DefaultPriorityFeeGwei),
+		FeeBumpStrategy:     getEnv("FEE_BUMP_STRATEGY", DefaultFeeBumpStrategy),
+		FeeBumpPercent:      getEnvFloat("FEE_BUMP_PERCENT", DefaultFeeBumpPercent),
+		StuckTxBlocks:       getEnvInt("STUCK_TX_BLOCKS", DefaultStuckTxBlocks),
+		StuckTxMaxBumps:     getEnvInt("STUCK_TX_MAX_BUMPS", DefaultStuckTxMaxBumps),
+		PreBatchHook:        getEnv("PRE_BATCH_HOOK", DefaultPreBatchHook),
+		PostBatchHook:       getEnv("POST_BATCH_HOOK", DefaultPostBatchHook),
+		HookTimeoutSeconds:  getEnvInt("HOOK_TIMEOUT_SECONDS", DefaultHookTimeoutSeconds),
+		GasEstimate:         getEnvBool("GAS_ESTIMATE", DefaultGasEstimate),
+		GasEstimateMargin:   getEnvFloat("GAS_ESTIMATE_MARGIN_PERCENT", DefaultGasEstimateMargin),
+		GasLimitOverride:    getEnvUint64("GAS_LIMIT_OVERRIDE", DefaultGasLimitOverride),
+		BudgetCheck:         getEnvBool("BUDGET_CHECK", DefaultBudgetCheck),
+		BlockMetrics:        getEnvBool("BLOCK_METRICS", DefaultBlockMetrics),
+		BlockTPS:            getEnvBool("BLOCK_TPS", DefaultBlockTPS),
+		MinIterationSeconds: getEnvFloat("MIN_ITERATION_SECONDS", DefaultMinIterationSeconds),
+		LoopPacing:          getEnv("LOOP_PACING", DefaultLoopPacing),
+		MaxSpendWei:         getEnv("MAX_SPEND_WEI", DefaultMaxSpendWei),
+		MaxSpendWalletWei:   getEnv("MAX_SPEND_PER_WALLET_WEI", DefaultMaxSpendWalletWei),
+		FunderPrivateKey:    getEnv("FUNDER_PRIVATE_KEY", ""),
+		FunderKeystore:      getEnv("FUNDER_KEYSTORE", DefaultFunderKeystore),
+		FunderKeystorePass:  getEnv("FUNDER_KEYSTORE_PASSWORD", ""),
+		FunderKeySource:     getEnv("FUNDER_KEY_SOURCE", ""),
+		FunderAddress:       getEnv("FUNDER_ADDRESS", ""),
+		FundingMargin:       getEnvFloat("FUNDING_MARGIN_PERCENT", DefaultFundingMargin),
+		SkipUnfunded:        getEnvBool("SKIP_UNFUNDED_WALLETS", DefaultSkipUnfunded),
+		BalanceSnapshots:    getEnvBool("BALANCE_SNAPSHOTS", DefaultBalanceSnapshots),
+		BalanceSnapshotMins: getEnvInt("BALANCE_SNAPSHOT_MINUTES", DefaultBalanceSnapshotMins),
+		TopUpInterval:       getEnvInt("TOPUP_INTERVAL_SECONDS", DefaultTopUpInterval),
+		TopUpBelowWei:       getEnv("TOPUP_BELOW_WEI", DefaultTopUpBelowWei),
+		TopUpTargetWei:      getEnv("TOPUP_TARGET_WEI", DefaultTopUpTargetWei),
+		Rollup:              getEnv("ROLLUP", DefaultRollup),
+		ControlAddr:         getEnv("CONTROL_ADDR", DefaultControlAddr),
+		AbortGraceSeconds:   getEnvInt("ABORT_GRACE_SECONDS", DefaultAbortGraceSeconds),
+		StopOnErrorRate:     getEnvFloat("STOP_ON_ERROR_RATE", DefaultStopOnErrorRate),
+		StopOnErrorWindow:   getEnvInt("STOP_ON_ERROR_WINDOW_SECONDS", DefaultStopOnErrorWindow),
+		StopOnErrorMin:      getEnvInt("STOP_ON_ERROR_MIN_SENDS", DefaultStopOnErrorMin),
+		InclusionTarget:     getEnvFloat("INCLUSION_TARGET_SECONDS", DefaultInclusionTarget),
+		FeeControlStep:      getEnvFloat("FEE_CONTROL_STEP_PERCENT", DefaultFeeControlStep),
+		FeeControlInterval:  getEnvInt("FEE_CONTROL_INTERVAL_SECONDS", DefaultFeeControlInterval),
+		FeeControlMinLevel:  getEnvFloat("FEE_CONTROL_MIN_LEVEL", DefaultFeeControlMinLevel),
+		FeeControlMaxLevel:  getEnvFloat("FEE_CONTROL_MAX_LEVEL", DefaultFeeControlMaxLevel),
+		NonceGapRepair:      getEnv("NONCE_GAP_REPAIR", DefaultNonceGapRepair),
+		TargetTPS:           getEnvFloat("TARGET_TPS", DefaultTargetTPS),
+		TargetTPSBurst:      getEnvInt("TARGET_TPS_BURST", DefaultTargetTPSBurst),
+		WalletTPS:           getEnvFloat("WALLET_TPS", DefaultWalletTPS),
+		RampStartTPS:        getEnvFloat("RAMP_START_TPS", DefaultRampStartTPS),
+		RampEndTPS:          getEnvFloat("RAMP_END_TPS", DefaultRampEndTPS),
+		RampDuration:        getEnvInt("RAMP_DURATION_SECONDS", DefaultRampDuration),
+		SpikeMultiplier:     getEnvFloat("SPIKE_MULTIPLIER", DefaultSpikeMultiplier),
+		SpikeSeconds:        getEnvInt("SPIKE_SECONDS", DefaultSpikeSeconds),
+		SpikeEvery:          getEnvInt("SPIKE_EVERY_SECONDS", DefaultSpikeEvery),
+		SpikeRecovery:       getEnvInt("SPIKE_RECOVERY_SECONDS", DefaultSpikeRecovery),
+		SaturationSearch:    getEnvBool("SATURATION_SEARCH", DefaultSaturationSearch),
+		SaturationStartTPS:  getEnvFloat("SATURATION_START_TPS", DefaultSaturationStartTPS),
+		SaturationMaxTPS:    getEnvFloat("SATURATION_MAX_TPS", DefaultSaturationMaxTPS),
+		SaturationProbe:     getEnvInt("SATURATION_PROBE_SECONDS", DefaultSaturationProbe),
+		SaturationMaxP95:    getEnvFloat("SATURATION_MAX_P95_SECONDS", DefaultSaturationMaxP95),
+		SaturationMaxFail:   getEnvFloat("SATURATION_MAX_FAIL_PERCENT", DefaultSaturationMaxFail),
+		SaturationPrecision: getEnvFloat("SATURATION_PRECISION_PERCENT", DefaultSaturationPrecision),
+		SaturationMaxProbes: getEnvInt("SATURATION_MAX_PROBES", DefaultSaturationMaxProbes),
+		MaxInflight:         getEnvInt("MAX_INFLIGHT", DefaultMaxInflight),
+		SendDistribution:    getEnv("SEND_DISTRIBUTION", DefaultSendDistribution),
+		SendBurstSize:       getEnvInt("SEND_BURST_SIZE", DefaultSendBurstSize),
+		LoadStages:          getEnv("LOAD_STAGES", DefaultLoadStages),
+		NonceSource:         getEnv("NONCE_SOURCE", DefaultNonceSource),
+		NonceOverrides:      getEnv("NONCE_OVERRIDES", DefaultNonceOverrides),
+		ReportLocale:        getEnv("REPORT_LOCALE", DefaultReportLocale),
+		ReportThousands:     getEnvBool("REPORT_THOUSANDS_SEPARATOR", DefaultReportThousands),
+		ReportDecimals:      getEnvInt("REPORT_DECIMALS", DefaultReportDecimals),
+		ReportDurationUnit:  getEnv("REPORT_DURATION_UNIT", DefaultReportDurationUnit),
+		ReportLayout:        getEnv("REPORT_LAYOUT", DefaultReportLayout),
+		HistogramBucket:     getEnvFloat("HISTOGRAM_BUCKET_SECONDS", DefaultHistogramBucket),
+		TPSSeriesSeconds:    getEnvInt("TPS_SERIES_SECONDS", DefaultTPSSeriesSeconds),
+		SummaryJSON:         getEnv("SUMMARY_JSON", DefaultSummaryJSON),
+		TraceEndpoint:       getEnv("TRACE_OTLP_ENDPOINT", DefaultTraceEndpoint),
+		TraceHeaders:        getEnv("TRACE_OTLP_HEADERS", DefaultTraceHeaders),
+		TraceSampleRate:     getEnvFloat("TRACE_SAMPLE_RATE", DefaultTraceSampleRate),
+		InfluxURL:           getEnv("INFLUX_URL", DefaultInfluxURL),
+		InfluxToken:         getEnv("INFLUX_TOKEN", DefaultInfluxToken),
+		InfluxOrg:           getEnv("INFLUX_ORG", DefaultInfluxOrg),
+		InfluxBucket:        getEnv("INFLUX_BUCKET", DefaultInfluxBucket),
+		InfluxDatabase:      getEnv("INFLUX_DATABASE", DefaultInfluxDatabase),
+		WebhookURL:          getEnv("WEBHOOK_URL", DefaultWebhookURL),
+		WebhookHeaders:      getEnv("WEBHOOK_HEADERS", DefaultWebhookHeaders),
+		AlertErrorRate:      getEnvFloat("ALERT_ERROR_RATE", DefaultAlertErrorRate),
+		AlertP95Latency:     getEnvFloat("ALERT_P95_LATENCY_SECONDS", DefaultAlertP95Latency),
+		AlertWindow:         getEnvInt("ALERT_WINDOW_SECONDS", DefaultAlertWindow),
+		AlertMinSamples:     getEnvInt("ALERT_MIN_SAMPLES", DefaultAlertMinSamples),
+		ChatWebhookURL:      getEnv("CHAT_WEBHOOK_URL", DefaultChatWebhookURL),
+		ChatNotify:          getEnv("CHAT_NOTIFY", DefaultChatNotify),
+		ChatReportURL:       getEnv("CHAT_REPORT_URL", DefaultChatReportURL),
+		SLAMinTPS:           getEnvFloat("SLA_MIN_TPS", DefaultSLAMinTPS),
+		SLAMaxP95:           getEnvFloat("SLA_MAX_P95_SECONDS", DefaultSLAMaxP95),
+		SLAMaxFailureRate:   getEnvFloat("SLA_MAX_FAILURE_RATE", DefaultSLAMaxFailureRate),
+		ProgressInterval:    getEnvInt("PROGRESS_INTERVAL_SECONDS", DefaultProgressInterval),
+		ProgressPersist:     getEnvBool("PROGRESS_PERSIST", DefaultProgressPersist),
+	}
+	config.errs = loadErrs
+
+	// Amounts may be given with a unit (0.001eth, 2gwei); from here on
+	// they are whole numbers of wei
+	for _, amount := range []struct {
+		name  string
+		value *string
+	}{
+		{"VALUE_WEI", &config.ValueWei},
+		{"MIN_GAS_PRICE", &config.MinGasPrice},
+		{"MAX_GAS_PRICE_WEI", &config.MaxGasPriceWei},
+		{"MAX_SPEND_WEI", &config.MaxSpendWei},
+		{"MAX_SPEND_PER_WALLET_WEI", &config.MaxSpendWalletWei},
+		{"TOPUP_BELOW_WEI", &config.TopUpBelowWei},
+		{"TOPUP_TARGET_WEI", &config.TopUpTargetWei},
+	} {
+		if *amount.value == "" {
+			continue
+		}
+		wei, err := ParseWei(*amount.value)
+		if err != nil {
+			config.errs = append(config.errs, fmt.Errorf("invalid %s: %w", amount.name, err))
+			continue
+		}
+		*amount.value = wei.String()
+	}
+	// VALUE is an alias of VALUE_WEI, e.g. for a .env that sets VALUE_WEI;
+	// which of two different amounts was meant cannot be told
+	if alias, value := os.Getenv("VALUE"), os.Getenv("VALUE_WEI"); alias != "" && value != "" {
+		if wei, err := ParseWei(alias); err != nil || wei.String() != config.ValueWei {
+			config.errs = append(config.errs, fmt.Errorf("VALUE %q and VALUE_WEI %q differ: set only one of them", alias, value))
+		}
+	}
+	if config.RunDuration != "" {
+		if _, err := ParseDuration(config.RunDuration); err != nil {
+			config.errs = append(config.errs, fmt.Errorf("invalid RUN_DURATION: %w", err))
+		}
 	}
 
 	return config
 }
 
+// LoopDuration returns how long loop mode runs: RUN_DURATION if set,
+// otherwise RUN_DURATION_MINUTES. Zero means a single batch.
+func (c *Config) LoopDuration() (time.Duration, error) {
+	if c.RunDuration == "" {
+		return time.Duration(c.RunDurationMinutes) * time.Minute, nil
+	}
+	return ParseDuration(c.RunDuration)
+}
+
+// Errors returns the settings LoadConfig could not parse. Nothing should run
+// with any of them: each was replaced by its default.
+func (c *Config) Errors() []error {
+	return c.errs
+}
+
+// loadErrs collects the settings the getEnv functions could not parse while
+// LoadConfig runs; loadMu keeps two loads from mixing them up.
+var (
+	loadMu   sync.Mutex
+	loadErrs []error
+)
+
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
@@ -100,10 +583,9 @@ func getEnvInt(key string, defaultValue int) int {
 		return defaultValue
 	}
 
-	var intValue int
-	_, err := fmt.Sscanf(value, "%d", &intValue)
+	intValue, err := strconv.Atoi(strings.TrimSpace(value))
 	if err != nil {
-		fmt.Printf("Warning: Invalid integer value for %s: '%s', using default: %d\n", key, value, defaultValue)
+		loadErrs = append(loadErrs, fmt.Errorf("invalid %s: %q is not a whole number", key, value))
 		return defaultValue
 	}
 	return intValue
@@ -115,27 +597,40 @@ func getEnvUint64(key string, defaultValue uint64) uint64 {
 		return defaultValue
 	}
 
-	var uint64Value uint64
-	_, err := fmt.Sscanf(value, "%d", &uint64Value)
+	uint64Value, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
 	if err != nil {
-		fmt.Printf("Warning: Invalid uint64 value for %s: '%s', using default: %d\n", key, value, defaultValue)
+		loadErrs = append(loadErrs, fmt.Errorf("invalid %s: %q is not a whole number of at least 0", key, value))
 		return defaultValue
 	}
 	return uint64Value
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	floatValue, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		loadErrs = append(loadErrs, fmt.Errorf("invalid %s: %q is not a number", key, value))
+		return defaultValue
+	}
+	return floatValue
+}
+
 func getEnvBool(key string, defaultValue bool) bool {
 	value := os.Getenv(key)
 	if value == "" {
 		return defaultValue
 	}
-	switch strings.ToLower(value) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
 	case "true", "1", "yes", "y":
 		return true
 	case "false", "0", "no", "n":
 		return false
 	default:
-		fmt.Printf("Warning: Invalid boolean value for %s: '%s', using default: %v\n", key, value, defaultValue)
+		loadErrs = append(loadErrs, fmt.Errorf("invalid %s: %q is not true or false", key, value))
 		return defaultValue
 	}
 }
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// scenarioKeys maps each scenario file setting, as a dotted path, to the
+// environment variable it stands for.
+var scenarioKeys = map[string]string{
+	"network.rpc_url":                 "RPC_URL",
+	"network.submit_url":              "SUBMIT_RPC_URL",
+	"network.headers":                 "RPC_HEADERS",
+	"network.basic_auth":              "RPC_BASIC_AUTH",
+	"network.fallback_urls":           "RPC_FALLBACK_URLS",
+	"network.weights":                 "RPC_WEIGHTS",
+	"network.health_interval_seconds": "RPC_HEALTH_INTERVAL_SECONDS",
+	"network.max_block_lag":           "RPC_MAX_BLOCK_LAG",
+	"network.ws_url":                  "WS_URL",
+	"network.p2p_enode":               "P2P_ENODE",
+	"network.rollup":                  "ROLLUP",
+	"network.beacon_api":              "BEACON_API_URL",
+
+	"compare.rpc_urls": "COMPARE_RPC_URLS",
+	"compare.order":    "COMPARE_ORDER",
+	"compare.rounds":   "COMPARE_ROUNDS",
+
+	"database.path":                  "DB_PATH",
+	"wallets.count":                  "WALLET_COUNT",
+	"wallets.tx_per_wallet":          "TX_PER_WALLET",
+	"wallets.mnemonic":               "MNEMONIC",
+	"wallets.mnemonic_source":        "MNEMONIC_SOURCE",
+	"wallets.mnemonic_bits":          "MNEMONIC_BITS",
+	"wallets.save_mnemonic":          "SAVE_MNEMONIC",
+	"wallets.derivation_path":        "DERIVATION_PATH",
+	"wallets.derivation_start_index": "DERIVATION_START_INDEX",
+	"wallets.receiver_count":         "RECEIVER_COUNT",
+	"wallets.receiver_path":          "RECEIVER_DERIVATION_PATH",
+	"wallets.keys_file":              "KEYS_FILE",
+	"wallets.keys_source":            "KEYS_SOURCE",
+	"wallets.keystore_dir":           "KEYSTORE_DIR",
+	"wallets.signer_url":             "SIGNER_URL",
+	"wallets.signer_api":             "SIGNER_API",
+	"wallets.signer_addresses":       "SIGNER_ADDRESSES",
+	"wallets.nonce_source":           "NONCE_SOURCE",
+	"wallets.rederive":               "REDERIVE_WALLETS",
+
+	"funding.value_wei":            "VALUE_WEI",
+	"funding.max_spend_wei":        "MAX_SPEND_WEI",
+	"funding.max_spend_wallet_wei": "MAX_SPEND_PER_WALLET_WEI",
+	"funding.budget_check":         "BUDGET_CHECK",
+	"funding.margin_percent":       "FUNDING_MARGIN_PERCENT",
+	"funding.keystore":             "FUNDER_KEYSTORE",
+	"funding.key_source":           "FUNDER_KEY_SOURCE",
+	"funding.address":              "FUNDER_ADDRESS",
+	"funding.skip_unfunded":        "SKIP_UNFUNDED_WALLETS",
+	"funding.balance_snapshots":    "BALANCE_SNAPSHOTS",
+	"funding.snapshot_minutes":     "BALANCE_SNAPSHOT_MINUTES",
+	"funding.topup_interval":       "TOPUP_INTERVAL_SECONDS",
+	"funding.topup_below_wei":      "TOPUP_BELOW_WEI",
+	"funding.topup_target_wei":     "TOPUP_TARGET_WEI",
+
+	"load.target_tps":               "TARGET_TPS",
+	"load.burst":                    "TARGET_TPS_BURST",
+	"load.wallet_tps":               "WALLET_TPS",
+	"load.max_inflight":             "MAX_INFLIGHT",
+	"load.distribution":             "SEND_DISTRIBUTION",
+	"load.burst_size":               "SEND_BURST_SIZE",
+	"load.submission_timing":        "SUBMISSION_TIMING",
+	"load.stages":                   "LOAD_STAGES",
+	"load.ramp.start_tps":           "RAMP_START_TPS",
+	"load.ramp.end_tps":             "RAMP_END_TPS",
+	"load.ramp.duration_seconds":    "RAMP_DURATION_SECONDS",
+	"load.spike.multiplier":         "SPIKE_MULTIPLIER",
+	"load.spike.seconds":            "SPIKE_SECONDS",
+	"load.spike.every_seconds":      "SPIKE_EVERY_SECONDS",
+	"load.spike.recovery_seconds":   "SPIKE_RECOVERY_SECONDS",
+	"load.saturation.enabled":       "SATURATION_SEARCH",
+	"load.saturation.start_tps":     "SATURATION_START_TPS",
+	"load.saturation.max_tps":       "SATURATION_MAX_TPS",
+	"load.saturation.probe_seconds": "SATURATION_PROBE_SECONDS",
+
+	"transactions.workload":          "WORKLOAD",
+	"transactions.to_address":        "TO_ADDRESS",
+	"transactions.gas_limit":         "GAS_LIMIT",
+	"transactions.priority_fee_gwei": "PRIORITY_FEE_GWEI",
+
+	"duration":              "RUN_DURATION",
+	"soak_interval_minutes": "SOAK_INTERVAL_MINUTES",
+	"loop_pacing":           "LOOP_PACING",
+
+	"thresholds.stop_on_error_rate":           "STOP_ON_ERROR_RATE",
+	"thresholds.stop_on_error_window_seconds": "STOP_ON_ERROR_WINDOW_SECONDS",
+	"thresholds.stop_on_error_min_sends":      "STOP_ON_ERROR_MIN_SENDS",
+	"thresholds.max_p95_seconds":              "SATURATION_MAX_P95_SECONDS",
+	"thresholds.max_fail_percent":             "SATURATION_MAX_FAIL_PERCENT",
+	"thresholds.inclusion_target_seconds":     "INCLUSION_TARGET_SECONDS",
+
+	"sla.min_tps":          "SLA_MIN_TPS",
+	"sla.max_p95_seconds":  "SLA_MAX_P95_SECONDS",
+	"sla.max_failure_rate": "SLA_MAX_FAILURE_RATE",
+}
+
+// Scenario is a test plan loaded from a SCENARIO_FILE: the environment
+// settings it stands for, keyed by variable name.
+type Scenario struct {
+	Name        string
+	Description string
+	Path        string
+	Settings    map[string]string
+}
+
+// LoadScenario reads a YAML scenario file. Besides the keys in scenarioKeys,
+// an env section may set any other variable by name. Unknown keys are an
+// error, so a typo does not silently fall back to a default.
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario file: %w", err)
+	}
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario file %s: %w", path, err)
+	}
+
+	s := &Scenario{Path: path, Settings: make(map[string]string)}
+	for key, value := range doc {
+		switch key {
+		case "name":
+			s.Name = fmt.Sprint(value)
+		case "description":
+			s.Description = fmt.Sprint(value)
+		case "env":
+			env, ok := value.(map[interface{}]interface{})
+			if !ok {
+				return nil, fmt.Errorf("scenario %s: env must map variable names to values", path)
+			}
+			for name, v := range env {
+				s.Settings[strings.ToUpper(fmt.Sprint(name))] = scenarioValue(v)
+			}
+		default:
+			if err := s.flatten(key, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+// flatten records value under key, descending into nested sections.
+func (s *Scenario) flatten(key string, value interface{}) error {
+	if section, ok := value.(map[interface{}]interface{}); ok {
+		for k, v := range section {
+			if err := s.flatten(key+"."+fmt.Sprint(k), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	name, ok := scenarioKeys[key]
+	if !ok {
+		return fmt.Errorf("scenario %s: unknown setting %q", s.Path, key)
+	}
+	s.Settings[name] = scenarioValue(value)
+	return nil
+}
+
+// scenarioValue renders a YAML value the way its environment variable is
+// written; lists, such as load stages, become comma-separated.
+func scenarioValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = scenarioValue(item)
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+// Apply exports the scenario's settings to the environment, except those
+// already set there, and returns the names of the ones it set. Call it
+// before the .env file is loaded so the scenario takes precedence over it.
+func (s *Scenario) Apply() []string {
+	var applied []string
+	for name, value := range s.Settings {
+		if _, set := os.LookupEnv(name); set {
+			continue
+		}
+		os.Setenv(name, value)
+		applied = append(applied, name)
+	}
+	sort.Strings(applied)
+	return applied
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// weiUnits are the units ParseWei accepts, in wei.
+var weiUnits = map[string]*big.Int{
+	"wei":    big.NewInt(1),
+	"kwei":   big.NewInt(1e3),
+	"mwei":   big.NewInt(1e6),
+	"gwei":   big.NewInt(1e9),
+	"szabo":  big.NewInt(1e12),
+	"finney": big.NewInt(1e15),
+	"eth":    big.NewInt(1e18),
+	"ether":  big.NewInt(1e18),
+}
+
+// ParseWei parses an amount of ether: a whole number of wei such as
+// "1000000000000000", or a decimal number with a unit such as "0.001eth",
+// "2 gwei" or "1.5ETH". The amount must come to a whole, non-negative number
+// of wei.
+func ParseWei(s string) (*big.Int, error) {
+	s = strings.TrimSpace(s)
+	number := strings.TrimRightFunc(s, func(r rune) bool {
+		return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+	})
+	unit := strings.ToLower(s[len(number):])
+	number = strings.TrimSpace(number)
+	if number == "" {
+		return nil, fmt.Errorf("%q is not an amount, e.g. 1000000000000000, 2gwei or 0.001eth", s)
+	}
+	if unit == "" {
+		unit = "wei"
+	}
+	scale, ok := weiUnits[unit]
+	if !ok {
+		return nil, fmt.Errorf("unknown unit %q in %q (expected wei, gwei or eth)", unit, s)
+	}
+	amount, ok := new(big.Rat).SetString(strings.ReplaceAll(number, "_", ""))
+	if !ok || strings.Contains(number, "/") {
+		return nil, fmt.Errorf("%q is not a number", number)
+	}
+	if amount.Sign() < 0 {
+		return nil, fmt.Errorf("%q is negative", s)
+	}
+	amount.Mul(amount, new(big.Rat).SetInt(scale))
+	if !amount.IsInt() {
+		return nil, fmt.Errorf("%q is not a whole number of wei", s)
+	}
+	return new(big.Int).Set(amount.Num()), nil
+}
+
+// ParseDuration parses a duration such as "90s", "45m", "2h" or "1h30m",
+// and also accepts days ("2d", "1d12h"). A number without a unit is an
+// error rather than a guess at seconds or minutes.
+func ParseDuration(s string) (time.Duration, error) {
+	input := strings.TrimSpace(s)
+	rest := input
+	var days time.Duration
+	if i := strings.IndexByte(rest, 'd'); i > 0 {
+		n, err := strconv.ParseUint(rest[:i], 10, 16)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a duration, e.g. 90s, 45m, 2h or 1d", input)
+		}
+		days = time.Duration(n) * 24 * time.Hour
+		if rest = rest[i+1:]; rest == "" {
+			return days, nil
+		}
+	}
+	d, err := time.ParseDuration(rest)
+	switch {
+	case err != nil && strings.Contains(err.Error(), "missing unit"):
+		return 0, fmt.Errorf("%q has no unit: write e.g. %ss, %sm or %sh", input, input, input, input)
+	case err != nil:
+		return 0, fmt.Errorf("%q is not a duration, e.g. 90s, 45m, 2h or 1d", input)
+	case d < 0:
+		return 0, fmt.Errorf("%q is negative", input)
+	}
+	return days + d, nil
+}
@@ -0,0 +1,114 @@
+package consensus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BeaconClient reads slot timing from a consensus client's REST API.
+type BeaconClient struct {
+	url        string
+	httpClient *http.Client
+}
+
+// SlotClock converts wall-clock times into beacon chain slots.
+type SlotClock struct {
+	GenesisTime    time.Time
+	SecondsPerSlot uint64
+}
+
+func NewBeaconClient(url string) *BeaconClient {
+	return &BeaconClient{
+		url:        strings.TrimRight(url, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (bc *BeaconClient) get(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, bc.url+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := bc.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to query %s: status %s", path, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return nil
+}
+
+// SlotClock fetches genesis time and SECONDS_PER_SLOT from the beacon node.
+func (bc *BeaconClient) SlotClock(ctx context.Context) (*SlotClock, error) {
+	var genesis struct {
+		Data struct {
+			GenesisTime string `json:"genesis_time"`
+		} `json:"data"`
+	}
+	if err := bc.get(ctx, "/eth/v1/beacon/genesis", &genesis); err != nil {
+		return nil, err
+	}
+	genesisTime, err := strconv.ParseInt(genesis.Data.GenesisTime, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid genesis_time %q: %w", genesis.Data.GenesisTime, err)
+	}
+
+	var spec struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := bc.get(ctx, "/eth/v1/config/spec", &spec); err != nil {
+		return nil, err
+	}
+	raw, ok := spec.Data["SECONDS_PER_SLOT"].(string)
+	if !ok {
+		return nil, fmt.Errorf("spec has no SECONDS_PER_SLOT")
+	}
+	secondsPerSlot, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil || secondsPerSlot == 0 {
+		return nil, fmt.Errorf("invalid SECONDS_PER_SLOT %q", raw)
+	}
+
+	return &SlotClock{
+		GenesisTime:    time.Unix(genesisTime, 0),
+		SecondsPerSlot: secondsPerSlot,
+	}, nil
+}
+
+// SlotDuration returns the length of one slot.
+func (sc *SlotClock) SlotDuration() time.Duration {
+	return time.Duration(sc.SecondsPerSlot) * time.Second
+}
+
+// Slot returns the slot that t falls into.
+func (sc *SlotClock) Slot(t time.Time) uint64 {
+	if t.Before(sc.GenesisTime) {
+		return 0
+	}
+	return uint64(t.Sub(sc.GenesisTime) / sc.SlotDuration())
+}
+
+// Offset returns how far into its slot t is.
+func (sc *SlotClock) Offset(t time.Time) time.Duration {
+	if t.Before(sc.GenesisTime) {
+		return 0
+	}
+	return t.Sub(sc.GenesisTime) % sc.SlotDuration()
+}
+
+// NextSlot returns the start of the first slot beginning after t.
+func (sc *SlotClock) NextSlot(t time.Time) time.Time {
+	return sc.GenesisTime.Add(time.Duration(sc.Slot(t)+1) * sc.SlotDuration())
+}
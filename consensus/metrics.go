@@ -0,0 +1,108 @@
+package consensus
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HistogramSample is the cumulative _sum and _count of a Prometheus histogram
+// at one point in time.
+type HistogramSample struct {
+	Sum   float64
+	Count float64
+}
+
+// ScrapeHistogram reads a Prometheus text endpoint and sums the _sum and
+// _count series of the given histogram. The selector is a metric name
+// optionally followed by a label matcher, e.g.
+// `execution_layer_request_times{method="get_payload"}`; series match when
+// their label set contains the matcher verbatim.
+func ScrapeHistogram(ctx context.Context, url, selector string) (*HistogramSample, error) {
+	name, labels := selector, ""
+	if i := strings.Index(selector, "{"); i >= 0 {
+		name = selector[:i]
+		labels = strings.Trim(selector[i:], "{}")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scrape metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to scrape metrics: status %s", resp.Status)
+	}
+
+	sample := &HistogramSample{}
+	found := false
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		series, rest := line, ""
+		if i := strings.LastIndex(line, "}"); i >= 0 {
+			series, rest = line[:i+1], line[i+1:]
+		} else if i := strings.Index(line, " "); i >= 0 {
+			series, rest = line[:i], line[i:]
+		}
+		metric := series
+		if i := strings.Index(series, "{"); i >= 0 {
+			metric = series[:i]
+		}
+
+		var target *float64
+		switch metric {
+		case name + "_sum":
+			target = &sample.Sum
+		case name + "_count":
+			target = &sample.Count
+		default:
+			continue
+		}
+		if labels != "" && !strings.Contains(series, labels) {
+			continue
+		}
+
+		// The value may be followed by an optional timestamp.
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		*target += value
+		found = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read metrics: %w", err)
+	}
+	if !found {
+		return nil, fmt.Errorf("histogram %s not found", selector)
+	}
+
+	return sample, nil
+}
+
+// Mean returns the average observation recorded between two samples.
+func (h *HistogramSample) Mean(since *HistogramSample) (float64, float64) {
+	count := h.Count - since.Count
+	if count <= 0 {
+		return 0, 0
+	}
+	return (h.Sum - since.Sum) / count, count
+}
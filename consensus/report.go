@@ -0,0 +1,104 @@
+package consensus
+
+import (
+	"fmt"
+	"strings"
+
+	"go-tps/db"
+	"go-tps/report"
+)
+
+// SlotBucket aggregates confirmed transactions by how far into a slot they
+// were submitted.
+type SlotBucket struct {
+	OffsetSecond  uint64
+	Count         int
+	TotalLatency  float64 // seconds from submission to inclusion
+	TotalSlots    uint64  // slots waited from submission to inclusion
+	NextSlotCount int     // included in the slot right after submission
+}
+
+func (b *SlotBucket) AvgLatency() float64 {
+	if b.Count == 0 {
+		return 0
+	}
+	return b.TotalLatency / float64(b.Count)
+}
+
+func (b *SlotBucket) AvgSlots() float64 {
+	if b.Count == 0 {
+		return 0
+	}
+	return float64(b.TotalSlots) / float64(b.Count)
+}
+
+// BuildSlotReport buckets successfully confirmed transactions by submission
+// offset within the slot (one bucket per second). Block timestamps are slot
+// start times, so confirmed_at identifies the inclusion slot.
+func BuildSlotReport(clock *SlotClock, txs []*db.Transaction) []*SlotBucket {
+	buckets := make([]*SlotBucket, clock.SecondsPerSlot)
+	for i := range buckets {
+		buckets[i] = &SlotBucket{OffsetSecond: uint64(i)}
+	}
+
+	for _, tx := range txs {
+		if tx.Status != "success" || tx.ConfirmedAt == nil {
+			continue
+		}
+		offset := uint64(clock.Offset(tx.SubmittedAt).Seconds())
+		if offset >= clock.SecondsPerSlot {
+			offset = clock.SecondsPerSlot - 1
+		}
+
+		submittedSlot := clock.Slot(tx.SubmittedAt)
+		includedSlot := clock.Slot(*tx.ConfirmedAt)
+		slots := uint64(0)
+		if includedSlot > submittedSlot {
+			slots = includedSlot - submittedSlot
+		}
+
+		b := buckets[offset]
+		b.Count++
+		b.TotalLatency += tx.ConfirmedAt.Sub(tx.SubmittedAt).Seconds()
+		b.TotalSlots += slots
+		if slots == 1 {
+			b.NextSlotCount++
+		}
+	}
+
+	return buckets
+}
+
+// PrintSlotReport prints the slot timing table. payloadMean and payloadCount
+// describe payload build times scraped from engine metrics; pass a zero
+// count when no metrics were available.
+func PrintSlotReport(clock *SlotClock, buckets []*SlotBucket, payloadMean, payloadCount float64) {
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("SLOT TIMING vs INCLUSION LATENCY")
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("Seconds per slot: %d (genesis %s)\n", clock.SecondsPerSlot, clock.GenesisTime.UTC().Format("2006-01-02 15:04:05"))
+	if payloadCount > 0 {
+		fmt.Printf("Payload build time: %s avg over %s payloads\n", report.Seconds(payloadMean, 3), report.Float(payloadCount, 0))
+	}
+	fmt.Println()
+	fmt.Printf("%-12s %8s %14s %12s %12s\n", "Offset (s)", "Txs", "Avg latency", "Avg slots", "Next slot")
+
+	total := 0
+	for _, b := range buckets {
+		total += b.Count
+		if b.Count == 0 {
+			fmt.Printf("%-12s %8d %14s %12s %12s\n", fmt.Sprintf("%d-%d", b.OffsetSecond, b.OffsetSecond+1), 0, "-", "-", "-")
+			continue
+		}
+		fmt.Printf("%-12s %8s %14s %12s %12s\n",
+			fmt.Sprintf("%d-%d", b.OffsetSecond, b.OffsetSecond+1),
+			report.Int(b.Count), report.Seconds(b.AvgLatency(), 2), report.Float(b.AvgSlots(), 2),
+			report.Percent(float64(b.NextSlotCount)/float64(b.Count)*100, 1))
+	}
+
+	if total == 0 {
+		fmt.Println("No confirmed transactions to correlate.")
+	}
+	fmt.Println(strings.Repeat("=", 60))
+}
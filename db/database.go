@@ -3,7 +3,13 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"go-tps/logger"
@@ -21,83 +27,238 @@ type Transaction struct {
 	Value             string
 	GasPrice          string
 	GasLimit          uint64
+	GasEstimated      uint64 // eth_estimateGas result, 0 if not estimated
 	GasUsed           uint64
 	EffectiveGasPrice string
+	Cost              string // total fee paid in wei (L1 + L2), set from the receipt
+	L1Fee             string // rollup L1 data fee in wei, "" off rollups
+	L2Fee             string // execution fee in wei, gas_used × effective_gas_price off rollups
 	Status            string
 	SubmittedAt       time.Time
 	ConfirmedAt       *time.Time
 	ExecutionTime     float64 // in milliseconds
 	Error             string
+	Phase             string // load profile phase at submission, e.g. spike; empty without one
+	RPCEndpoint       string // host of the RPC endpoint that accepted the submission
+	ErrorCategory     string // ErrorCategory of Error, set when the transaction is stored
+	TraceParent       string // W3C traceparent of the transaction's span, empty when not traced
+	RunID             int64  // Run that sent it, 0 if the run was not recorded
+	BlockNumber       uint64 // block the receipt puts it in; see BlockHash
+	BlockHash         string // empty until included
+	TxIndex           uint   // position in the block
+	SubmitWorker      int    // wallet goroutine that sent it, the N of [WN] in the log; 0 if unknown
+	ReceiptWorker     int    // receipt worker that recorded the outcome, in the process of receipt_claimed_by
+	ReceiptEndpoint   string // host of the endpoint the receipt came from
+	ReceiptSource     string // how the receipt came: poll or ws
 }
 
+// Inclusion is where a receipt puts a transaction on chain.
+type Inclusion struct {
+	BlockNumber uint64
+	BlockHash   string
+	TxIndex     uint
+}
+
+// Resolution is who recorded the outcome of a transaction: the receipt
+// worker, and the endpoint and way the receipt came from if there was one.
+type Resolution struct {
+	Worker   int
+	Endpoint string
+	Source   string
+}
+
+// Run is one invocation of go-tps: what it ran with and against, so a batch
+// can be traced back to its settings long after the run.
+type Run struct {
+	ID            int64
+	StartedAt     time.Time
+	EndedAt       *time.Time // nil while running, or if the run never finished
+	Mode          string     // single, loop, staged, ...; set when the run ends
+	Aborted       bool
+	Config        string // effective configuration as JSON, secrets redacted
+	Version       string // go-tps build, e.g. the VCS revision
+	ChainID       string
+	ClientVersion string // web3_clientVersion of the RPC node
+}
+
+// BatchHook is the recorded outcome of a pre- or post-batch hook. Status is
+// the exit code of a command or the HTTP status of a webhook.
+type BatchHook struct {
+	ID          int64
+	BatchNumber string
+	Phase       string
+	Kind        string
+	Command     string
+	Status      int
+	Output      string
+	Error       string
+	StartedAt   time.Time
+	Duration    float64 // in milliseconds
+}
+
+// StoredWallet is a row of the wallets table.
+type StoredWallet struct {
+	Address        string
+	DerivationPath string
+	CreatedAt      time.Time
+	NextNonce      *uint64 // nil until a run stored one
+}
+
+// BlockMetric is one block header observed while a run was active.
+type BlockMetric struct {
+	BlockNumber uint64
+	BlockHash   string
+	Timestamp   time.Time
+	BaseFee     string // wei, empty before London
+	GasUsed     uint64
+	GasLimit    uint64
+	ObservedAt  time.Time
+}
+
+// SoakInterval is the interim summary of one soak-test interval, taken when
+// the interval ended. Latencies are in seconds, over the transactions
+// included by then.
+type SoakInterval struct {
+	Label     string
+	StartedAt time.Time
+	EndedAt   time.Time
+	Txs       int
+	Included  int
+	Failed    int
+	Pending   int
+	TPS       float64 // included transactions per second of the interval
+	P50       float64
+	P95       float64
+	P99       float64
+}
+
+// ProgressStat is one interim snapshot of a run, taken every
+// PROGRESS_INTERVAL_SECONDS while it goes on.
+type ProgressStat struct {
+	RecordedAt    time.Time
+	BatchNumber   string  // batch of the most recently stored transaction
+	WindowSeconds float64 // the rates and P95 are over the window before RecordedAt
+	SubmitTPS     float64
+	ConfirmTPS    float64
+	Inflight      int // submitted and not yet resolved
+	Submitted     int // totals since the run started
+	Confirmed     int
+	Failed        int
+	P95           float64 // confirmation latency in seconds, 0 without confirmations in the window
+}
+
+// Balance snapshot phases.
+const (
+	BalanceBefore   = "before"
+	BalanceAfter    = "after"
+	BalancePeriodic = "periodic" // every BALANCE_SNAPSHOT_MINUTES during the run
+)
+
+// BalanceSnapshot is one wallet's balance at a block, recorded before,
+// during or after a run.
+type BalanceSnapshot struct {
+	RunID       int64 // 0 if the run was not recorded
+	Phase       string
+	Wallet      string
+	Balance     string // wei
+	BlockNumber uint64
+	RecordedAt  time.Time
+}
+
+// LatencyBucket is one bucket of a batch's confirmation latency histogram:
+// how many of its transactions were included between Start and End seconds
+// after submission.
+type LatencyBucket struct {
+	Start float64
+	End   float64
+	Count int
+}
+
+// SeriesPoint counts the submissions and confirmations of one time bucket,
+// which starts at Time.
+type SeriesPoint struct {
+	Time      time.Time
+	Submitted int
+	Confirmed int
+}
+
+// Database is the SQLite store. Every write goes through writer, a single
+// connection, so concurrent writers (DB writers, receipt workers, hooks)
+// queue for it instead of failing with "database is locked"; reads use the
+// db pool and, in WAL mode, run alongside the writes.
 type Database struct {
-	db *sql.DB
+	db     *sql.DB // reads
+	writer *sql.DB // writes, one connection
 }
 
+// busyTimeout is how long a connection waits for another one, e.g. of a
+// second go-tps process on the same file, to release its lock.
+const busyTimeout = 10 * time.Second
+
+// MemoryPath as the path keeps the database in memory, without disk I/O,
+// until the process exits; Dump saves it.
+const MemoryPath = ":memory:"
+
 func NewDatabase(dbPath string, maxOpenConns, maxIdleConns int) (*Database, error) {
-	dsn := fmt.Sprintf("file:%s?_journal_mode=WAL&_busy_timeout=5000&_synchronous=NORMAL&_cache_size=-64000", dbPath)
-	db, err := sql.Open("sqlite3", dsn)
+	if dbPath == MemoryPath {
+		return newMemoryDatabase()
+	}
+	dsn := fmt.Sprintf("file:%s?_journal_mode=WAL&_busy_timeout=%d&_synchronous=NORMAL&_cache_size=-64000", dbPath, busyTimeout.Milliseconds())
+
+	// Transactions on the writer take the write lock up front (BEGIN
+	// IMMEDIATE): a deferred one that upgrades later gets SQLITE_BUSY at
+	// once, without waiting out the busy timeout.
+	writer, err := sql.Open("sqlite3", dsn+"&_txlock=immediate")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	writer.SetMaxOpenConns(1)
+	writer.SetMaxIdleConns(1)
+	writer.SetConnMaxLifetime(0)
 
-	db.SetMaxOpenConns(maxOpenConns)
-	db.SetMaxIdleConns(maxIdleConns)
-	db.SetConnMaxLifetime(0)
-
-	if err := createTables(db); err != nil {
-		db.Close()
+	if err := migrate(writer); err != nil {
+		writer.Close()
 		return nil, err
 	}
 
-	if err := optimizeDatabase(db); err != nil {
+	if err := optimizeDatabase(writer); err != nil {
+		writer.Close()
 		return nil, fmt.Errorf("failed to optimize database: %w", err)
 	}
 
-	return &Database{db: db}, nil
-}
-
-func createTables(db *sql.DB) error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS transactions (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		batch_number TEXT NOT NULL,
-		wallet_address TEXT NOT NULL,
-		tx_hash TEXT,
-		nonce INTEGER NOT NULL,
-		to_address TEXT NOT NULL,
-		value TEXT NOT NULL,
-		gas_price TEXT NOT NULL,
-		gas_limit INTEGER NOT NULL,
-		gas_used INTEGER,
-		effective_gas_price TEXT,
-		status TEXT NOT NULL,
-		submitted_at TIMESTAMP NOT NULL,
-		confirmed_at TIMESTAMP,
-		execution_time REAL,
-		error TEXT
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_batch_number ON transactions(batch_number);
-	CREATE INDEX IF NOT EXISTS idx_wallet_address ON transactions(wallet_address);
-	CREATE INDEX IF NOT EXISTS idx_tx_hash ON transactions(tx_hash);
-	CREATE INDEX IF NOT EXISTS idx_status ON transactions(status);
-	CREATE INDEX IF NOT EXISTS idx_submitted_at ON transactions(submitted_at);
-
-	CREATE TABLE IF NOT EXISTS wallets (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		address TEXT NOT NULL UNIQUE,
-		derivation_path TEXT NOT NULL,
-		created_at TIMESTAMP NOT NULL
-	);
-	`
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		writer.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
+	db.SetConnMaxLifetime(0)
+
+	return &Database{db: db, writer: writer}, nil
+}
 
-	_, err := db.Exec(schema)
+// newMemoryDatabase opens an in-memory database. Each SQLite connection
+// to :memory: has a database of its own, so reads and writes share one
+// connection that is never closed.
+func newMemoryDatabase() (*Database, error) {
+	db, err := sql.Open("sqlite3", "file::memory:?_txlock=immediate")
 	if err != nil {
-		return fmt.Errorf("failed to create tables: %w", err)
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(0)
 
-	return nil
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err := db.Exec("PRAGMA temp_store=MEMORY"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to optimize database: %w", err)
+	}
+	return &Database{db: db, writer: db}, nil
 }
 
 func optimizeDatabase(db *sql.DB) error {
@@ -120,18 +281,46 @@ func optimizeDatabase(db *sql.DB) error {
 	return nil
 }
 
-func (d *Database) InsertTransaction(ctx context.Context, tx *Transaction) (int64, error) {
-	query := `
-		INSERT INTO transactions (
-			batch_number, wallet_address, tx_hash, nonce, to_address, value,
-			gas_price, gas_limit, gas_used, effective_gas_price, status, submitted_at, confirmed_at,
-			execution_time, error
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
+const insertTransactionQuery = `
+	INSERT INTO transactions (
+		batch_number, wallet_address, tx_hash, nonce, to_address, value,
+		gas_price, gas_limit, gas_estimated, gas_used, effective_gas_price, cost, l1_fee, l2_fee, status, submitted_at,
+		confirmed_at, execution_time, error, phase, rpc_endpoint, error_category, trace_parent, run_id, submit_worker,
+		block_number, block_hash, tx_index
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (tx_hash) WHERE tx_hash != '' DO UPDATE SET
+		status = excluded.status, confirmed_at = excluded.confirmed_at, gas_used = excluded.gas_used,
+		effective_gas_price = excluded.effective_gas_price, cost = excluded.cost, l1_fee = excluded.l1_fee,
+		l2_fee = excluded.l2_fee, error = excluded.error, error_category = excluded.error_category,
+		block_number = excluded.block_number, block_hash = excluded.block_hash, tx_index = excluded.tx_index
+	WHERE excluded.status != 'pending' OR transactions.status = 'pending'
+	RETURNING id
+`
 
-	logger.Debug("[DB] INSERT tx_hash=%s status=%s nonce=%d wallet=%s\n", tx.TxHash, tx.Status, tx.Nonce, tx.WalletAddress)
+// queryer is a *sql.DB or *sql.Tx.
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// scanInsertedID returns the id row of insertTransactionQuery returned for
+// tx. A transaction whose hash is already stored is not stored twice: a
+// retry or backfill refreshes the outcome of its row (status, error, fees
+// and inclusion), except that a pending one never overwrites a resolved
+// outcome, in which case the row is left as it is.
+func scanInsertedID(ctx context.Context, row *sql.Row, q queryer, tx *Transaction) (int64, error) {
+	var id int64
+	err := row.Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = q.QueryRowContext(ctx, `SELECT id FROM transactions WHERE tx_hash = ?`, tx.TxHash).Scan(&id)
+	}
+	return id, err
+}
 
-	result, err := d.db.ExecContext(ctx, query,
+// insertTransactionArgs returns the values of insertTransactionQuery for tx,
+// setting its error category first.
+func insertTransactionArgs(tx *Transaction) []any {
+	tx.ErrorCategory = ErrorCategory(tx.Error)
+	return []any{
 		tx.BatchNumber,
 		tx.WalletAddress,
 		tx.TxHash,
@@ -140,51 +329,188 @@ func (d *Database) InsertTransaction(ctx context.Context, tx *Transaction) (int6
 		tx.Value,
 		tx.GasPrice,
 		tx.GasLimit,
+		tx.GasEstimated,
 		tx.GasUsed,
 		tx.EffectiveGasPrice,
+		tx.Cost,
+		tx.L1Fee,
+		tx.L2Fee,
 		tx.Status,
 		tx.SubmittedAt,
 		tx.ConfirmedAt,
 		tx.ExecutionTime,
 		tx.Error,
-	)
+		tx.Phase,
+		tx.RPCEndpoint,
+		tx.ErrorCategory,
+		tx.TraceParent,
+		sql.NullInt64{Int64: tx.RunID, Valid: tx.RunID != 0},
+		sql.NullInt64{Int64: int64(tx.SubmitWorker), Valid: tx.SubmitWorker != 0},
+		sql.NullInt64{Int64: int64(tx.BlockNumber), Valid: tx.BlockHash != ""},
+		sql.NullString{String: tx.BlockHash, Valid: tx.BlockHash != ""},
+		sql.NullInt64{Int64: int64(tx.TxIndex), Valid: tx.BlockHash != ""},
+	}
+}
+
+func (d *Database) InsertTransaction(ctx context.Context, tx *Transaction) (int64, error) {
+	logger.Debug("[DB] INSERT tx_hash=%s status=%s nonce=%d wallet=%s\n", tx.TxHash, tx.Status, tx.Nonce, tx.WalletAddress)
 
+	row := d.writer.QueryRowContext(ctx, insertTransactionQuery, insertTransactionArgs(tx)...)
+	id, err := scanInsertedID(ctx, row, d.writer, tx)
 	if err != nil {
 		logger.Error("[DB] INSERT FAILED tx_hash=%s error=%v\n", tx.TxHash, err)
 		return 0, fmt.Errorf("failed to insert transaction: %w", err)
 	}
 
-	id, err := result.LastInsertId()
+	tx.ID = id
 	logger.Debug("[DB] INSERT OK tx_hash=%s id=%d\n", tx.TxHash, id)
-	return id, err
+	return id, nil
+}
+
+// InsertTransactions saves txs in one database transaction, setting the ID
+// of each. Committing once per batch instead of once per record is what lets
+// the writers keep up at high send rates. Either all of txs are saved or,
+// on error, none.
+func (d *Database) InsertTransactions(ctx context.Context, txs []*Transaction) error {
+	if len(txs) == 0 {
+		return nil
+	}
+	logger.Debug("[DB] INSERT %d transactions\n", len(txs))
+
+	dbTx, err := d.writer.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin insert transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	stmt, err := dbTx.PrepareContext(ctx, insertTransactionQuery)
+	if err != nil {
+		return fmt.Errorf("failed to prepare transaction insert: %w", err)
+	}
+	defer stmt.Close()
+
+	ids := make([]int64, len(txs))
+	for i, tx := range txs {
+		ids[i], err = scanInsertedID(ctx, stmt.QueryRowContext(ctx, insertTransactionArgs(tx)...), dbTx, tx)
+		if err != nil {
+			logger.Error("[DB] INSERT FAILED tx_hash=%s error=%v\n", tx.TxHash, err)
+			return fmt.Errorf("failed to insert transaction: %w", err)
+		}
+	}
+	if err := dbTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transactions: %w", err)
+	}
+	for i, tx := range txs {
+		tx.ID = ids[i]
+	}
+	logger.Debug("[DB] INSERT OK %d transactions\n", len(txs))
+	return nil
 }
 
-func (d *Database) UpdateTransactionStatus(ctx context.Context, txHash, status string, confirmedAt *time.Time, gasUsed uint64, effectiveGasPrice string, errMsg string) error {
-	logger.Debug("[DB] UPDATE tx_hash=%s status=%s gas_used=%d err=%q\n", txHash, status, gasUsed, errMsg)
+// UpdateTransactionStatus stores the outcome of a transaction. inclusion is
+// nil for transactions without a receipt, and by for outcomes not recorded
+// by a receipt worker. A hash that is not stored, e.g. because it was
+// replaced or not saved yet, fails with ErrTransactionNotFound.
+func (d *Database) UpdateTransactionStatus(ctx context.Context, txHash, status string, confirmedAt *time.Time, gasUsed uint64, effectiveGasPrice, l1Fee, l2Fee, cost string, errMsg string, inclusion *Inclusion, by *Resolution) error {
+	logger.Debug("[DB] UPDATE tx_hash=%s status=%s gas_used=%d cost=%s (l1 %s) err=%q\n", txHash, status, gasUsed, cost, l1Fee, errMsg)
+	if txHash == "" {
+		return fmt.Errorf("failed to update transaction: no hash")
+	}
 
 	query := `
 		UPDATE transactions
-		SET status = ?, confirmed_at = ?, gas_used = ?, effective_gas_price = ?, error = ?
+		SET status = ?, confirmed_at = ?, gas_used = ?, effective_gas_price = ?, l1_fee = ?, l2_fee = ?, cost = ?, error = ?, error_category = ?,
+		    block_number = ?, block_hash = ?, tx_index = ?, receipt_worker = ?, receipt_endpoint = ?, receipt_source = ?
 		WHERE tx_hash = ?
 	`
 
-	_, err := d.db.ExecContext(ctx, query, status, confirmedAt, gasUsed, effectiveGasPrice, errMsg, txHash)
+	var blockNumber, txIndex sql.NullInt64
+	var blockHash sql.NullString
+	if inclusion != nil {
+		blockNumber = sql.NullInt64{Int64: int64(inclusion.BlockNumber), Valid: true}
+		blockHash = sql.NullString{String: inclusion.BlockHash, Valid: true}
+		txIndex = sql.NullInt64{Int64: int64(inclusion.TxIndex), Valid: true}
+	}
+	var worker sql.NullInt64
+	var endpoint, source sql.NullString
+	if by != nil {
+		worker = sql.NullInt64{Int64: int64(by.Worker), Valid: true}
+		endpoint = sql.NullString{String: by.Endpoint, Valid: by.Endpoint != ""}
+		source = sql.NullString{String: by.Source, Valid: by.Source != ""}
+	}
+	result, err := d.writer.ExecContext(ctx, query, status, confirmedAt, gasUsed, effectiveGasPrice, l1Fee, l2Fee, cost, errMsg, ErrorCategory(errMsg),
+		blockNumber, blockHash, txIndex, worker, endpoint, source, txHash)
 	if err != nil {
 		logger.Error("[DB] UPDATE FAILED tx_hash=%s error=%v\n", txHash, err)
 		return fmt.Errorf("failed to update transaction: %w", err)
 	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("failed to update transaction %s: %w", txHash, ErrTransactionNotFound)
+	}
 
 	logger.Debug("[DB] UPDATE OK tx_hash=%s\n", txHash)
 	return nil
 }
 
+// ErrTransactionNotFound is returned by UpdateTransactionStatus and
+// ReplaceTransactionHash when no record has the hash, e.g. because it has
+// not been saved yet.
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+// ReplaceTransactionHash points a transaction record at the replacement sent
+// for it (same nonce, higher fee) and records the new max fee.
+func (d *Database) ReplaceTransactionHash(ctx context.Context, oldHash, newHash, gasPrice string) error {
+	logger.Debug("[DB] REPLACE tx_hash=%s -> %s gas_price=%s\n", oldHash, newHash, gasPrice)
+
+	query := `
+		UPDATE transactions
+		SET tx_hash = ?, gas_price = ?
+		WHERE tx_hash = ?
+	`
+
+	result, err := d.writer.ExecContext(ctx, query, newHash, gasPrice, oldHash)
+	if err != nil {
+		return fmt.Errorf("failed to replace transaction hash: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("failed to replace transaction hash %s: %w", oldHash, ErrTransactionNotFound)
+	}
+
+	return nil
+}
+
+// GetPendingTransactionHash returns the hash of the most recent pending
+// transaction recorded for wallet at nonce, or "" if there is none.
+func (d *Database) GetPendingTransactionHash(ctx context.Context, wallet string, nonce uint64) (string, error) {
+	query := `
+		SELECT tx_hash
+		FROM transactions
+		WHERE wallet_address = ? AND nonce = ? AND status = 'pending' AND tx_hash IS NOT NULL AND tx_hash != ''
+		ORDER BY id DESC
+		LIMIT 1
+	`
+
+	var txHash string
+	err := d.db.QueryRowContext(ctx, query, wallet, nonce).Scan(&txHash)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query pending transaction: %w", err)
+	}
+	return txHash, nil
+}
+
+// InsertWallet records a wallet; wallets already recorded by an earlier run
+// are left as they are.
 func (d *Database) InsertWallet(ctx context.Context, address, derivationPath string) error {
 	query := `
 		INSERT INTO wallets (address, derivation_path, created_at)
 		VALUES (?, ?, ?)
+		ON CONFLICT(address) DO NOTHING
 	`
 
-	_, err := d.db.ExecContext(ctx, query, address, derivationPath, time.Now())
+	_, err := d.writer.ExecContext(ctx, query, address, derivationPath, time.Now())
 	if err != nil {
 		return fmt.Errorf("failed to insert wallet: %w", err)
 	}
@@ -192,19 +518,343 @@ func (d *Database) InsertWallet(ctx context.Context, address, derivationPath str
 	return nil
 }
 
+// GetWallets returns every stored wallet, keyed by address.
+func (d *Database) GetWallets(ctx context.Context) (map[string]*StoredWallet, error) {
+	rows, err := d.db.QueryContext(ctx, `SELECT address, derivation_path, created_at, next_nonce FROM wallets`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wallets: %w", err)
+	}
+	defer rows.Close()
+
+	wallets := make(map[string]*StoredWallet)
+	for rows.Next() {
+		w := &StoredWallet{}
+		var nonce sql.NullInt64
+		if err := rows.Scan(&w.Address, &w.DerivationPath, &w.CreatedAt, &nonce); err != nil {
+			return nil, fmt.Errorf("failed to scan wallet: %w", err)
+		}
+		if nonce.Valid {
+			next := uint64(nonce.Int64)
+			w.NextNonce = &next
+		}
+		wallets[w.Address] = w
+	}
+	return wallets, rows.Err()
+}
+
+// ResetWallet stores the wallet as new: with derivationPath, created now
+// and without a stored nonce.
+func (d *Database) ResetWallet(ctx context.Context, address, derivationPath string) error {
+	query := `
+		INSERT INTO wallets (address, derivation_path, created_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(address) DO UPDATE SET
+			derivation_path = excluded.derivation_path, created_at = excluded.created_at,
+			next_nonce = NULL, nonce_updated_at = NULL
+	`
+	if _, err := d.writer.ExecContext(ctx, query, address, derivationPath, time.Now()); err != nil {
+		return fmt.Errorf("failed to reset wallet: %w", err)
+	}
+	return nil
+}
+
+// SetWalletNonce stores nonce as the next nonce the wallet will use.
+func (d *Database) SetWalletNonce(ctx context.Context, address string, nonce uint64) error {
+	query := `UPDATE wallets SET next_nonce = ?, nonce_updated_at = ? WHERE address = ?`
+	if _, err := d.writer.ExecContext(ctx, query, nonce, time.Now(), address); err != nil {
+		return fmt.Errorf("failed to store wallet nonce: %w", err)
+	}
+	return nil
+}
+
+// AdvanceWalletNonce raises the wallet's stored next nonce to next, leaving
+// it alone if it is already higher (records are written out of order).
+func (d *Database) AdvanceWalletNonce(ctx context.Context, address string, next uint64) error {
+	query := `
+		UPDATE wallets SET next_nonce = MAX(COALESCE(next_nonce, 0), ?), nonce_updated_at = ?
+		WHERE address = ?
+	`
+	if _, err := d.writer.ExecContext(ctx, query, next, time.Now(), address); err != nil {
+		return fmt.Errorf("failed to advance wallet nonce: %w", err)
+	}
+	return nil
+}
+
+// AdvanceWalletNonces is AdvanceWalletNonce for several wallets, keyed by
+// address, in one database transaction.
+func (d *Database) AdvanceWalletNonces(ctx context.Context, next map[string]uint64) error {
+	if len(next) == 0 {
+		return nil
+	}
+	dbTx, err := d.writer.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin nonce transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	stmt, err := dbTx.PrepareContext(ctx, `
+		UPDATE wallets SET next_nonce = MAX(COALESCE(next_nonce, 0), ?), nonce_updated_at = ?
+		WHERE address = ?
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare nonce update: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for address, nonce := range next {
+		if _, err := stmt.ExecContext(ctx, nonce, now, address); err != nil {
+			return fmt.Errorf("failed to advance wallet nonce: %w", err)
+		}
+	}
+	if err := dbTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit wallet nonces: %w", err)
+	}
+	return nil
+}
+
+// GetWalletNonces returns the stored next nonce of every wallet that has one,
+// keyed by address.
+func (d *Database) GetWalletNonces(ctx context.Context) (map[string]uint64, error) {
+	rows, err := d.db.QueryContext(ctx, `SELECT address, next_nonce FROM wallets WHERE next_nonce IS NOT NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wallet nonces: %w", err)
+	}
+	defer rows.Close()
+
+	nonces := make(map[string]uint64)
+	for rows.Next() {
+		var address string
+		var nonce uint64
+		if err := rows.Scan(&address, &nonce); err != nil {
+			return nil, fmt.Errorf("failed to scan wallet nonce: %w", err)
+		}
+		nonces[address] = nonce
+	}
+	return nonces, rows.Err()
+}
+
+// InsertRun records the start of a run and returns its ID.
+func (d *Database) InsertRun(ctx context.Context, run *Run) (int64, error) {
+	query := `
+		INSERT INTO runs (started_at, config, version, chain_id, client_version)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	result, err := d.writer.ExecContext(ctx, query, run.StartedAt, run.Config, run.Version, run.ChainID, run.ClientVersion)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert run: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// FinishRun records how run id ended.
+func (d *Database) FinishRun(ctx context.Context, id int64, endedAt time.Time, mode string, aborted bool) error {
+	query := `UPDATE runs SET ended_at = ?, mode = ?, aborted = ? WHERE id = ?`
+	if _, err := d.writer.ExecContext(ctx, query, endedAt, mode, aborted, id); err != nil {
+		return fmt.Errorf("failed to finish run: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) InsertBatchHook(ctx context.Context, hook *BatchHook) error {
+	query := `
+		INSERT INTO batch_hooks (
+			batch_number, phase, kind, command, status, output, error, started_at, duration
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := d.writer.ExecContext(ctx, query,
+		hook.BatchNumber, hook.Phase, hook.Kind, hook.Command, hook.Status,
+		hook.Output, hook.Error, hook.StartedAt, hook.Duration,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert batch hook: %w", err)
+	}
+
+	return nil
+}
+
+// InsertBlockMetric records a block header. A block seen twice (e.g. from
+// both the subscription and a gap-filling poll) is stored once.
+func (d *Database) InsertBlockMetric(ctx context.Context, block *BlockMetric) error {
+	query := `
+		INSERT OR IGNORE INTO block_metrics (
+			block_number, block_hash, timestamp, base_fee, gas_used, gas_limit, observed_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := d.writer.ExecContext(ctx, query,
+		block.BlockNumber, block.BlockHash, block.Timestamp, block.BaseFee,
+		block.GasUsed, block.GasLimit, block.ObservedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert block metric: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Database) InsertSoakInterval(ctx context.Context, s *SoakInterval) error {
+	query := `
+		INSERT INTO soak_intervals (
+			label, started_at, ended_at, txs, included, failed, pending, tps, p50_latency, p95_latency, p99_latency
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := d.writer.ExecContext(ctx, query,
+		s.Label, s.StartedAt, s.EndedAt, s.Txs, s.Included, s.Failed, s.Pending,
+		s.TPS, s.P50, s.P95, s.P99,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert soak interval: %w", err)
+	}
+
+	return nil
+}
+
+// InsertBalances stores one snapshot of the wallets' balances.
+func (d *Database) InsertBalances(ctx context.Context, snapshots []*BalanceSnapshot) error {
+	tx, err := d.writer.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin balances transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, b := range snapshots {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO balances (run_id, phase, wallet_address, balance, block_number, recorded_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			sql.NullInt64{Int64: b.RunID, Valid: b.RunID != 0}, b.Phase, b.Wallet, b.Balance, b.BlockNumber, b.RecordedAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert balance: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit balances: %w", err)
+	}
+	return nil
+}
+
+// InsertProgressStat stores an interim snapshot of the run.
+func (d *Database) InsertProgressStat(ctx context.Context, s *ProgressStat) error {
+	query := `
+		INSERT INTO progress_stats (
+			recorded_at, batch_number, window_seconds, submit_tps, confirm_tps, inflight, submitted, confirmed, failed, p95_latency
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := d.writer.ExecContext(ctx, query,
+		s.RecordedAt, s.BatchNumber, s.WindowSeconds, s.SubmitTPS, s.ConfirmTPS,
+		s.Inflight, s.Submitted, s.Confirmed, s.Failed, s.P95,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert progress stat: %w", err)
+	}
+
+	return nil
+}
+
+// ReplaceLatencyHistogram stores a batch's confirmation latency histogram,
+// replacing any stored before, so the histogram reflects the receipts
+// confirmed by the last time it was built.
+func (d *Database) ReplaceLatencyHistogram(ctx context.Context, batchNumber string, buckets []LatencyBucket) error {
+	tx, err := d.writer.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin histogram transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM latency_histograms WHERE batch_number = ?`, batchNumber); err != nil {
+		return fmt.Errorf("failed to clear latency histogram: %w", err)
+	}
+	for _, b := range buckets {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO latency_histograms (batch_number, bucket_start, bucket_end, count) VALUES (?, ?, ?, ?)`,
+			batchNumber, b.Start, b.End, b.Count)
+		if err != nil {
+			return fmt.Errorf("failed to insert latency bucket: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit latency histogram: %w", err)
+	}
+	return nil
+}
+
+// ReplaceTPSSeries stores a batch's submissions and confirmations per time
+// bucket of width seconds, replacing any series stored before.
+func (d *Database) ReplaceTPSSeries(ctx context.Context, batchNumber string, width int, points []SeriesPoint) error {
+	tx, err := d.writer.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin series transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM tps_series WHERE batch_number = ?`, batchNumber); err != nil {
+		return fmt.Errorf("failed to clear TPS series: %w", err)
+	}
+	for _, p := range points {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO tps_series (batch_number, bucket_start, bucket_seconds, submitted, confirmed) VALUES (?, ?, ?, ?, ?)`,
+			batchNumber, p.Time, width, p.Submitted, p.Confirmed)
+		if err != nil {
+			return fmt.Errorf("failed to insert TPS series point: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit TPS series: %w", err)
+	}
+	return nil
+}
+
+// GetTPSSeries returns the stored series of the given batches, summed per
+// bucket start, in time order.
+func (d *Database) GetTPSSeries(ctx context.Context, batchNumbers []string) ([]SeriesPoint, error) {
+	if len(batchNumbers) == 0 {
+		return nil, nil
+	}
+	args := make([]interface{}, len(batchNumbers))
+	for i, batch := range batchNumbers {
+		args[i] = batch
+	}
+	query := `
+		SELECT bucket_start, SUM(submitted), SUM(confirmed)
+		FROM tps_series
+		WHERE batch_number IN (?` + strings.Repeat(", ?", len(batchNumbers)-1) + `)
+		GROUP BY bucket_start
+		ORDER BY bucket_start
+	`
+
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query TPS series: %w", err)
+	}
+	defer rows.Close()
+
+	var points []SeriesPoint
+	for rows.Next() {
+		var p SeriesPoint
+		if err := rows.Scan(&p.Time, &p.Submitted, &p.Confirmed); err != nil {
+			return nil, fmt.Errorf("failed to scan TPS series: %w", err)
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
 func (d *Database) Close() error {
-	if d.db != nil {
+	if d.db == nil {
+		return nil
+	}
+	if d.writer == d.db {
 		return d.db.Close()
 	}
-	return nil
+	return errors.Join(d.db.Close(), d.writer.Close())
 }
 
 // GetPendingTransactionsBatch fetches pending transactions in batches
 func (d *Database) GetPendingTransactionsBatch(limit, offset int) ([]*Transaction, error) {
 	query := `
-		SELECT id, batch_number, wallet_address, tx_hash, nonce, to_address, 
-		       value, gas_price, gas_limit, gas_used, effective_gas_price, 
-		       status, submitted_at, confirmed_at, execution_time, error
+		SELECT ` + transactionColumns + `
 		FROM transactions 
 		WHERE status = 'pending' AND tx_hash IS NOT NULL AND tx_hash != ''
 		ORDER BY submitted_at ASC
@@ -217,22 +867,7 @@ func (d *Database) GetPendingTransactionsBatch(limit, offset int) ([]*Transactio
 	}
 	defer rows.Close()
 
-	var transactions []*Transaction
-	for rows.Next() {
-		tx := &Transaction{}
-		err := rows.Scan(
-			&tx.ID, &tx.BatchNumber, &tx.WalletAddress, &tx.TxHash, &tx.Nonce,
-			&tx.ToAddress, &tx.Value, &tx.GasPrice, &tx.GasLimit, &tx.GasUsed,
-			&tx.EffectiveGasPrice, &tx.Status, &tx.SubmittedAt, &tx.ConfirmedAt,
-			&tx.ExecutionTime, &tx.Error,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan transaction: %w", err)
-		}
-		transactions = append(transactions, tx)
-	}
-
-	return transactions, nil
+	return scanTransactions(rows)
 }
 
 // GetPendingTransactionCount returns the total count of pending transactions
@@ -252,9 +887,7 @@ func (d *Database) GetPendingTransactionCount(ctx context.Context) (int, error)
 // This is more efficient than OFFSET/LIMIT for large datasets and avoids missing records
 func (d *Database) GetPendingTransactionsBatchCursor(ctx context.Context, lastID int64, limit int) ([]*Transaction, error) {
 	query := `
-		SELECT id, batch_number, wallet_address, tx_hash, nonce, to_address, 
-		       value, gas_price, gas_limit, gas_used, effective_gas_price, 
-		       status, submitted_at, confirmed_at, execution_time, error
+		SELECT ` + transactionColumns + `
 		FROM transactions 
 		WHERE status IN ('pending', 'failed') AND tx_hash IS NOT NULL AND tx_hash != ''
 		AND id > ?
@@ -268,14 +901,339 @@ func (d *Database) GetPendingTransactionsBatchCursor(ctx context.Context, lastID
 	}
 	defer rows.Close()
 
+	return scanTransactions(rows)
+}
+
+// ClaimReceiptJob claims the oldest submitted transaction that still needs a
+// receipt and is not claimed by anyone else, leasing it to owner until lease
+// has passed. A claim whose lease ran out (its process died) can be claimed
+// again. It returns nil if nothing is claimable, along with how many receipt
+// attempts the transaction has had.
+func (d *Database) ClaimReceiptJob(ctx context.Context, owner string, lease time.Duration) (*Transaction, int, error) {
+	now := time.Now()
+	query := `
+		UPDATE transactions
+		SET receipt_claimed_by = ?, receipt_claimed_until = ?
+		WHERE id = (
+			SELECT id FROM transactions
+			WHERE status = 'pending' AND tx_hash IS NOT NULL AND tx_hash != ''
+			AND (receipt_claimed_until IS NULL OR receipt_claimed_until < ?)
+			ORDER BY submitted_at ASC, nonce ASC
+			LIMIT 1
+		)
+		RETURNING ` + transactionColumns + `, receipt_attempts
+	`
+
+	tx := &Transaction{}
+	var attempts int
+	err := d.writer.QueryRowContext(ctx, query, owner, now.Add(lease).Unix(), now.Unix()).Scan(
+		&tx.ID, &tx.BatchNumber, &tx.WalletAddress, &tx.TxHash, &tx.Nonce,
+		&tx.ToAddress, &tx.Value, &tx.GasPrice, &tx.GasLimit, &tx.GasEstimated, &tx.GasUsed,
+		&tx.EffectiveGasPrice, &tx.Cost, &tx.L1Fee, &tx.L2Fee, &tx.Status, &tx.SubmittedAt, &tx.ConfirmedAt,
+		&tx.ExecutionTime, &tx.Error, &tx.Phase, &tx.RPCEndpoint, &tx.ErrorCategory,
+		&tx.TraceParent, &tx.RunID, &tx.BlockNumber, &tx.BlockHash, &tx.TxIndex,
+		&tx.SubmitWorker, &tx.ReceiptWorker, &tx.ReceiptEndpoint, &tx.ReceiptSource, &attempts,
+	)
+	if err == sql.ErrNoRows {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to claim receipt job: %w", err)
+	}
+	logger.Debug("[DB] CLAIM tx_hash=%s owner=%s attempts=%d\n", tx.TxHash, owner, attempts)
+	return tx, attempts, nil
+}
+
+// RetryReceiptJob counts a failed receipt attempt for the transaction and
+// keeps it claimed until retryAt, after which any worker may claim it again.
+func (d *Database) RetryReceiptJob(ctx context.Context, id int64, retryAt time.Time) error {
+	query := `
+		UPDATE transactions
+		SET receipt_attempts = receipt_attempts + 1, receipt_claimed_until = ?
+		WHERE id = ?
+	`
+	if _, err := d.writer.ExecContext(ctx, query, retryAt.Unix(), id); err != nil {
+		return fmt.Errorf("failed to schedule receipt retry: %w", err)
+	}
+	return nil
+}
+
+// CountReceiptClaims returns how many pending transactions owner still holds
+// a live claim on, in flight or waiting for a retry.
+func (d *Database) CountReceiptClaims(ctx context.Context, owner string) (int, error) {
+	query := `
+		SELECT COUNT(*) FROM transactions
+		WHERE status = 'pending' AND receipt_claimed_by = ? AND receipt_claimed_until >= ?
+	`
+	var count int
+	if err := d.db.QueryRowContext(ctx, query, owner, time.Now().Unix()).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count receipt claims: %w", err)
+	}
+	return count, nil
+}
+
+// GetBatchTransactions returns every transaction recorded for a batch, oldest first
+func (d *Database) GetBatchTransactions(ctx context.Context, batchNumber string) ([]*Transaction, error) {
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		WHERE batch_number = ?
+		ORDER BY submitted_at ASC
+	`
+
+	rows, err := d.db.QueryContext(ctx, query, batchNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query batch transactions: %w", err)
+	}
+	defer rows.Close()
+
+	return scanTransactions(rows)
+}
+
+// GetTransactionByHash returns the latest transaction recorded with
+// txHash, or nil if there is none.
+func (d *Database) GetTransactionByHash(ctx context.Context, txHash string) (*Transaction, error) {
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		WHERE tx_hash = ?
+		ORDER BY id DESC
+		LIMIT 1
+	`
+
+	rows, err := d.db.QueryContext(ctx, query, txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transaction: %w", err)
+	}
+	defer rows.Close()
+
+	txs, err := scanTransactions(rows)
+	if err != nil || len(txs) == 0 {
+		return nil, err
+	}
+	return txs[0], nil
+}
+
+// GetFailedTransactions returns the failed transactions of a batch, or of
+// every batch if batchNumber is empty, newest first and at most limit of
+// them (0 = all).
+func (d *Database) GetFailedTransactions(ctx context.Context, batchNumber string, limit int) ([]*Transaction, error) {
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		WHERE status = 'failed' AND (? = '' OR batch_number = ?)
+		ORDER BY submitted_at DESC
+		LIMIT ?
+	`
+	if limit <= 0 {
+		limit = -1 // no limit in SQLite
+	}
+
+	rows, err := d.db.QueryContext(ctx, query, batchNumber, batchNumber, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query failed transactions: %w", err)
+	}
+	defer rows.Close()
+
+	return scanTransactions(rows)
+}
+
+// ListBatches returns every batch number in the database, oldest first.
+func (d *Database) ListBatches(ctx context.Context) ([]string, error) {
+	query := `
+		SELECT batch_number
+		FROM transactions
+		GROUP BY batch_number
+		ORDER BY MIN(submitted_at) ASC
+	`
+
+	rows, err := d.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list batches: %w", err)
+	}
+	defer rows.Close()
+
+	var batches []string
+	for rows.Next() {
+		var batch string
+		if err := rows.Scan(&batch); err != nil {
+			return nil, fmt.Errorf("failed to scan batch: %w", err)
+		}
+		batches = append(batches, batch)
+	}
+	return batches, rows.Err()
+}
+
+// GetHighestSubmittedNonces returns, per wallet address, the highest nonce
+// of the batch's transactions that the node accepted (those with a hash).
+func (d *Database) GetHighestSubmittedNonces(ctx context.Context, batchNumber string) (map[string]uint64, error) {
+	query := `
+		SELECT wallet_address, MAX(nonce)
+		FROM transactions
+		WHERE batch_number = ? AND tx_hash IS NOT NULL AND tx_hash != ''
+		GROUP BY wallet_address
+	`
+
+	rows, err := d.db.QueryContext(ctx, query, batchNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query submitted nonces: %w", err)
+	}
+	defer rows.Close()
+
+	nonces := make(map[string]uint64)
+	for rows.Next() {
+		var address string
+		var nonce uint64
+		if err := rows.Scan(&address, &nonce); err != nil {
+			return nil, fmt.Errorf("failed to scan submitted nonce: %w", err)
+		}
+		nonces[address] = nonce
+	}
+	return nonces, rows.Err()
+}
+
+// LatencyPercentiles are the percentiles GetBatchStats reports latencies at.
+var LatencyPercentiles = []float64{50, 90, 95, 99}
+
+// GetBatchStats summarises a batch: transaction counts by status, gas used,
+// the fees paid by confirmed transactions, and percentiles of submission
+// latency (submission_p50_ms, …) and confirmation latency, from submission to
+// the block (confirmation_p50_seconds, …). Costs are summed as big integers
+// because wei totals overflow SQLite's 64-bit integers.
+//
+// Of the included transactions it reports the blocks the batch landed in,
+// from first_block to last_block (blocks_spanned, of which blocks_with_txs
+// hold any), and each transaction's inclusion delay: how many blocks after
+// first_block it landed (inclusion_delay_p50_blocks, …,
+// inclusion_delay_max_blocks). Without included transactions these are 0.
+func (d *Database) GetBatchStats(ctx context.Context, batchNumber string) (map[string]interface{}, error) {
+	query := `
+		SELECT status, COALESCE(gas_used, 0), COALESCE(cost, ''),
+		       COALESCE(tx_hash, ''), execution_time, submitted_at, confirmed_at,
+		       COALESCE(block_number, 0), COALESCE(block_hash, '')
+		FROM transactions
+		WHERE batch_number = ?
+	`
+
+	rows, err := d.db.QueryContext(ctx, query, batchNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query batch stats: %w", err)
+	}
+	defer rows.Close()
+
+	var total, success, failed, pending, cancelled int
+	var gasUsed uint64
+	totalCost := new(big.Int)
+	var submission, confirmation []float64
+	var included []uint64 // block numbers
+	blocks := make(map[uint64]bool)
+	for rows.Next() {
+		var status, cost, hash, blockHash string
+		var used, blockNumber uint64
+		var executionTime float64
+		var submittedAt time.Time
+		var confirmedAt *time.Time
+		if err := rows.Scan(&status, &used, &cost, &hash, &executionTime, &submittedAt, &confirmedAt, &blockNumber, &blockHash); err != nil {
+			return nil, fmt.Errorf("failed to scan batch stats: %w", err)
+		}
+		if blockHash != "" {
+			included = append(included, blockNumber)
+			blocks[blockNumber] = true
+		}
+		if hash != "" {
+			submission = append(submission, executionTime)
+		}
+		if confirmedAt != nil {
+			confirmation = append(confirmation, confirmedAt.Sub(submittedAt).Seconds())
+		}
+		total++
+		switch status {
+		case "success":
+			success++
+		case "failed":
+			failed++
+		case "pending":
+			pending++
+		case "cancelled":
+			cancelled++
+		}
+		gasUsed += used
+		if c, ok := new(big.Int).SetString(cost, 10); ok {
+			totalCost.Add(totalCost, c)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batch stats: %w", err)
+	}
+
+	ethSpent, _ := new(big.Float).Quo(new(big.Float).SetInt(totalCost), big.NewFloat(1e18)).Float64()
+	stats := map[string]interface{}{
+		"batch_number":       batchNumber,
+		"total_transactions": total,
+		"successful":         success,
+		"failed":             failed,
+		"pending":            pending,
+		"cancelled":          cancelled,
+		"total_gas_used":     gasUsed,
+		"total_cost_wei":     totalCost.String(),
+		"total_eth_spent":    ethSpent,
+	}
+	sort.Float64s(submission)
+	sort.Float64s(confirmation)
+	for _, p := range LatencyPercentiles {
+		stats[fmt.Sprintf("submission_p%g_ms", p)] = Percentile(submission, p)
+		stats[fmt.Sprintf("confirmation_p%g_seconds", p)] = Percentile(confirmation, p)
+	}
+
+	var firstBlock, lastBlock, spanned uint64
+	delays := make([]float64, len(included))
+	if len(included) > 0 {
+		firstBlock, lastBlock = slices.Min(included), slices.Max(included)
+		spanned = lastBlock - firstBlock + 1
+		for i, n := range included {
+			delays[i] = float64(n - firstBlock)
+		}
+		sort.Float64s(delays)
+	}
+	stats["first_block"] = firstBlock
+	stats["last_block"] = lastBlock
+	stats["blocks_spanned"] = spanned
+	stats["blocks_with_txs"] = len(blocks)
+	for _, p := range LatencyPercentiles {
+		stats[fmt.Sprintf("inclusion_delay_p%g_blocks", p)] = Percentile(delays, p)
+	}
+	stats["inclusion_delay_max_blocks"] = Percentile(delays, 100)
+	return stats, nil
+}
+
+// Percentile returns the p-th percentile (0-100) of sorted values by nearest
+// rank, or 0 if there are none. report.Percentile sorts the values first;
+// it lives here because report imports db.
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+const transactionColumns = `id, batch_number, wallet_address, tx_hash, nonce, to_address,
+		       value, gas_price, gas_limit, gas_estimated, gas_used, effective_gas_price,
+		       COALESCE(cost, ''), COALESCE(l1_fee, ''), COALESCE(l2_fee, ''), status, submitted_at, confirmed_at, execution_time, error, phase, rpc_endpoint, error_category, trace_parent, COALESCE(run_id, 0),
+		       COALESCE(block_number, 0), COALESCE(block_hash, ''), COALESCE(tx_index, 0),
+		       COALESCE(submit_worker, 0), COALESCE(receipt_worker, 0), COALESCE(receipt_endpoint, ''), COALESCE(receipt_source, '')`
+
+func scanTransactions(rows *sql.Rows) ([]*Transaction, error) {
 	var transactions []*Transaction
 	for rows.Next() {
 		tx := &Transaction{}
 		err := rows.Scan(
 			&tx.ID, &tx.BatchNumber, &tx.WalletAddress, &tx.TxHash, &tx.Nonce,
-			&tx.ToAddress, &tx.Value, &tx.GasPrice, &tx.GasLimit, &tx.GasUsed,
-			&tx.EffectiveGasPrice, &tx.Status, &tx.SubmittedAt, &tx.ConfirmedAt,
-			&tx.ExecutionTime, &tx.Error,
+			&tx.ToAddress, &tx.Value, &tx.GasPrice, &tx.GasLimit, &tx.GasEstimated, &tx.GasUsed,
+			&tx.EffectiveGasPrice, &tx.Cost, &tx.L1Fee, &tx.L2Fee, &tx.Status, &tx.SubmittedAt, &tx.ConfirmedAt,
+			&tx.ExecutionTime, &tx.Error, &tx.Phase, &tx.RPCEndpoint, &tx.ErrorCategory,
+			&tx.TraceParent, &tx.RunID, &tx.BlockNumber, &tx.BlockHash, &tx.TxIndex,
+			&tx.SubmitWorker, &tx.ReceiptWorker, &tx.ReceiptEndpoint, &tx.ReceiptSource,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
@@ -283,5 +1241,5 @@ func (d *Database) GetPendingTransactionsBatchCursor(ctx context.Context, lastID
 		transactions = append(transactions, tx)
 	}
 
-	return transactions, nil
+	return transactions, rows.Err()
 }
@@ -0,0 +1,137 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"go-tps/logger"
+)
+
+// Error categories stored in transactions.error_category, so failures can
+// be counted by kind instead of by free-text message.
+const (
+	ErrorInsufficientFunds = "insufficient_funds"
+	ErrorNonce             = "nonce"       // nonce too low/high, already known, replacement of a pending nonce
+	ErrorUnderpriced       = "underpriced" // fees below the pool's or the block's minimum
+	ErrorGas               = "gas"         // intrinsic gas too low, gas limit exceeded
+	ErrorRateLimited       = "rate_limited"
+	ErrorTimeout           = "timeout"
+	ErrorConnection        = "connection"
+	ErrorRevert            = "revert"
+	ErrorCancelled         = "cancelled"
+	ErrorOther             = "other"
+)
+
+// ErrorCategories lists every category, in the order reports show them.
+var ErrorCategories = []string{
+	ErrorInsufficientFunds, ErrorNonce, ErrorUnderpriced, ErrorGas, ErrorRateLimited,
+	ErrorTimeout, ErrorConnection, ErrorRevert, ErrorCancelled, ErrorOther,
+}
+
+// errorPatterns maps lower-case message fragments to their category. They
+// are tried in order, so the more specific wording comes first: a
+// "replacement transaction underpriced" is a nonce conflict, not a fee
+// problem. Geth's wording is covered along with Nethermind's and Besu's.
+var errorPatterns = []struct {
+	fragment string
+	category string
+}{
+	{"insufficient funds", ErrorInsufficientFunds},
+	{"insufficient balance", ErrorInsufficientFunds},
+	{"insufficientfunds", ErrorInsufficientFunds},
+	{"upfront cost exceeds", ErrorInsufficientFunds},
+
+	{"replacement transaction underpriced", ErrorNonce},
+	{"replacementnotallowed", ErrorNonce},
+	{"nonce too low", ErrorNonce},
+	{"nonce too high", ErrorNonce},
+	{"oldnonce", ErrorNonce},
+	{"invalid nonce", ErrorNonce},
+	{"nonce gap", ErrorNonce},
+	{"already known", ErrorNonce},
+	{"alreadyknown", ErrorNonce},
+	{"known transaction", ErrorNonce},
+
+	{"underpriced", ErrorUnderpriced},
+	{"feetoolow", ErrorUnderpriced},
+	{"fee too low", ErrorUnderpriced},
+	{"less than block base fee", ErrorUnderpriced},
+	{"max priority fee per gas higher than max fee per gas", ErrorUnderpriced},
+
+	{"intrinsic gas too low", ErrorGas},
+	{"exceeds block gas limit", ErrorGas},
+	{"gas limit reached", ErrorGas},
+	{"out of gas", ErrorGas},
+
+	{"too many requests", ErrorRateLimited},
+	{"rate limit", ErrorRateLimited},
+	{"limit exceeded", ErrorRateLimited},
+
+	{"timeout", ErrorTimeout},
+	{"timed out", ErrorTimeout},
+	{"deadline exceeded", ErrorTimeout},
+
+	{"connection refused", ErrorConnection},
+	{"connection reset", ErrorConnection},
+	{"broken pipe", ErrorConnection},
+	{"no such host", ErrorConnection},
+	{"network is unreachable", ErrorConnection},
+	{"eof", ErrorConnection},
+	{"dial ", ErrorConnection},
+	{"tls:", ErrorConnection},
+	{"502 bad gateway", ErrorConnection},
+	{"503 service unavailable", ErrorConnection},
+
+	{"revert", ErrorRevert},
+
+	{"replaced by cancel", ErrorCancelled},
+}
+
+// ErrorCategory normalises a send or receipt error message into one of the
+// error categories, or returns "" for no error.
+func ErrorCategory(msg string) string {
+	if msg == "" {
+		return ""
+	}
+	msg = strings.ToLower(msg)
+	for _, p := range errorPatterns {
+		if strings.Contains(msg, p.fragment) {
+			return p.category
+		}
+	}
+	return ErrorOther
+}
+
+// backfillErrorCategories classifies the errors of transactions stored
+// before error_category existed.
+func backfillErrorCategories(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT DISTINCT error FROM transactions WHERE error != '' AND error_category = ''`)
+	if err != nil {
+		return fmt.Errorf("failed to query unclassified errors: %w", err)
+	}
+	var messages []string
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan unclassified error: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query unclassified errors: %w", err)
+	}
+
+	for _, msg := range messages {
+		if _, err := tx.Exec(`UPDATE transactions SET error_category = ? WHERE error = ? AND error_category = ''`,
+			ErrorCategory(msg), msg); err != nil {
+			return fmt.Errorf("failed to classify errors: %w", err)
+		}
+	}
+	if len(messages) > 0 {
+		logger.Info("[DB] Classified %d distinct stored errors\n", len(messages))
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BatchSize is how much of the database one batch takes up.
+type BatchSize struct {
+	BatchNumber    string
+	Transactions   int
+	FirstSubmitted time.Time
+	LastSubmitted  time.Time
+	Bytes          int64 // estimated, see ListBatchSizes
+}
+
+// Pruned counts the rows Prune deleted.
+type Pruned struct {
+	Transactions int64
+	Other        int64 // hooks, histograms, series, stats, block metrics, soak intervals, balances and runs
+}
+
+// rowBytes approximates the stored size of a transactions row: its text
+// columns plus a fixed allowance for the numbers and the record header.
+const rowBytes = `LENGTH(batch_number) + LENGTH(wallet_address) + LENGTH(COALESCE(tx_hash, '')) + LENGTH(to_address) +
+	LENGTH(value) + LENGTH(gas_price) + LENGTH(COALESCE(effective_gas_price, '')) + LENGTH(COALESCE(cost, '')) +
+	LENGTH(COALESCE(l1_fee, '')) + LENGTH(COALESCE(l2_fee, '')) + LENGTH(status) + LENGTH(submitted_at) +
+	LENGTH(COALESCE(confirmed_at, '')) + LENGTH(COALESCE(error, '')) + LENGTH(phase) + LENGTH(rpc_endpoint) +
+	LENGTH(error_category) + LENGTH(trace_parent) + LENGTH(COALESCE(block_hash, '')) +
+	LENGTH(COALESCE(receipt_claimed_by, '')) + LENGTH(COALESCE(receipt_endpoint, '')) + LENGTH(COALESCE(receipt_source, '')) + 60`
+
+// Size returns the bytes the database file uses and the bytes of free pages
+// in it, which only a VACUUM gives back to the file system.
+func (d *Database) Size(ctx context.Context) (used, free int64, err error) {
+	var pages, freePages, pageSize int64
+	if err := d.db.QueryRowContext(ctx, `PRAGMA page_count`).Scan(&pages); err != nil {
+		return 0, 0, fmt.Errorf("failed to read page count: %w", err)
+	}
+	if err := d.db.QueryRowContext(ctx, `PRAGMA freelist_count`).Scan(&freePages); err != nil {
+		return 0, 0, fmt.Errorf("failed to read free pages: %w", err)
+	}
+	if err := d.db.QueryRowContext(ctx, `PRAGMA page_size`).Scan(&pageSize); err != nil {
+		return 0, 0, fmt.Errorf("failed to read page size: %w", err)
+	}
+	return (pages - freePages) * pageSize, freePages * pageSize, nil
+}
+
+// ListBatchSizes returns every batch, oldest first, with its transaction
+// count, submission span and size. SQLite does not report the space of
+// individual rows, so a batch's size is its share of the used space by the
+// row data of its transactions; everything else the batch stored is spread
+// in proportion.
+func (d *Database) ListBatchSizes(ctx context.Context) ([]BatchSize, error) {
+	used, _, err := d.Size(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT batch_number, COUNT(*),
+		       CAST(strftime('%s', MIN(submitted_at)) AS INTEGER), CAST(strftime('%s', MAX(submitted_at)) AS INTEGER),
+		       SUM(` + rowBytes + `)
+		FROM transactions
+		GROUP BY batch_number
+		ORDER BY MIN(submitted_at) ASC
+	`
+	rows, err := d.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query batch sizes: %w", err)
+	}
+	defer rows.Close()
+
+	var sizes []BatchSize
+	var payloads []int64
+	var total int64
+	for rows.Next() {
+		var s BatchSize
+		var first, last, payload int64
+		if err := rows.Scan(&s.BatchNumber, &s.Transactions, &first, &last, &payload); err != nil {
+			return nil, fmt.Errorf("failed to scan batch size: %w", err)
+		}
+		s.FirstSubmitted, s.LastSubmitted = time.Unix(first, 0), time.Unix(last, 0)
+		sizes = append(sizes, s)
+		payloads = append(payloads, payload)
+		total += payload
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batch sizes: %w", err)
+	}
+	if total > 0 {
+		for i := range sizes {
+			sizes[i].Bytes = int64(float64(used) * float64(payloads[i]) / float64(total))
+		}
+	}
+	return sizes, nil
+}
+
+// Prune deletes batches with everything stored for them, and the block
+// metrics, soak intervals, balance snapshots and runs (once none of their transactions are
+// left) from before before. Free pages stay in the file until Vacuum.
+func (d *Database) Prune(ctx context.Context, batches []string, before time.Time) (*Pruned, error) {
+	tx, err := d.writer.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin prune transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	pruned := &Pruned{}
+	exec := func(counter *int64, query string, args ...any) error {
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to prune: %w", err)
+		}
+		n, _ := result.RowsAffected()
+		*counter += n
+		return nil
+	}
+
+	// In chunks, as SQLite limits the parameters of one statement
+	const chunk = 500
+	for len(batches) > 0 {
+		n := min(len(batches), chunk)
+		in := "(" + strings.TrimSuffix(strings.Repeat("?,", n), ",") + ")"
+		args := make([]any, n)
+		for i, b := range batches[:n] {
+			args[i] = b
+		}
+		batches = batches[n:]
+
+		if err := exec(&pruned.Transactions, `DELETE FROM transactions WHERE batch_number IN `+in, args...); err != nil {
+			return nil, err
+		}
+		for _, table := range []string{"batch_hooks", "latency_histograms", "tps_series", "progress_stats"} {
+			if err := exec(&pruned.Other, `DELETE FROM `+table+` WHERE batch_number IN `+in, args...); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	cutoff := before.Unix()
+	if err := exec(&pruned.Other, `DELETE FROM block_metrics WHERE strftime('%s', timestamp) < ?`, cutoff); err != nil {
+		return nil, err
+	}
+	if err := exec(&pruned.Other, `DELETE FROM soak_intervals WHERE strftime('%s', ended_at) < ?`, cutoff); err != nil {
+		return nil, err
+	}
+	if err := exec(&pruned.Other, `DELETE FROM balances WHERE strftime('%s', recorded_at) < ?`, cutoff); err != nil {
+		return nil, err
+	}
+	err = exec(&pruned.Other, `
+		DELETE FROM runs
+		WHERE strftime('%s', started_at) < ?
+		AND NOT EXISTS (SELECT 1 FROM transactions WHERE transactions.run_id = runs.id)
+	`, cutoff)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit prune: %w", err)
+	}
+	return pruned, nil
+}
+
+// Vacuum rebuilds the database file without its free pages and truncates
+// the write-ahead log. It needs as much free disk as the database takes up.
+func (d *Database) Vacuum(ctx context.Context) error {
+	if _, err := d.writer.ExecContext(ctx, `VACUUM`); err != nil {
+		return fmt.Errorf("failed to vacuum: %w", err)
+	}
+	if _, err := d.writer.ExecContext(ctx, `PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+		return fmt.Errorf("failed to checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Dump writes a compacted copy of the database to a new file at path, e.g.
+// to keep an in-memory database. The file must not exist yet.
+func (d *Database) Dump(ctx context.Context, path string) error {
+	if _, err := d.writer.ExecContext(ctx, `VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("failed to dump database to %s: %w", path, err)
+	}
+	return nil
+}
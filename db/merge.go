@@ -0,0 +1,178 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// Imported counts what Import copied and skipped.
+type Imported struct {
+	Transactions int64
+	Duplicates   int64 // transactions already in the database
+	Wallets      int64
+	Runs         int64
+	Other        int64 // hooks, histograms, series, stats, block metrics, soak intervals and balances
+}
+
+// transactionCopyColumns are the transactions columns Import copies, all
+// but the id, run_id and the receipt claim.
+const transactionCopyColumns = `batch_number, wallet_address, tx_hash, nonce, to_address, value, gas_price, gas_limit,
+	gas_estimated, gas_used, effective_gas_price, cost, l1_fee, l2_fee, status, submitted_at, confirmed_at,
+	execution_time, error, receipt_attempts, phase, rpc_endpoint, error_category, trace_parent,
+	block_number, block_hash, tx_index, submit_worker, receipt_worker, receipt_endpoint, receipt_source`
+
+// Import merges the database at path, which must have the same schema
+// version and is only read, into d. Batches keep their labels. A transaction already in d,
+// by hash or, for sends that never got one, by batch, wallet, nonce and
+// submission time, is skipped, so importing the same file twice adds
+// nothing. The batch hooks, latency histograms, TPS series and progress
+// stats of a batch are only copied if d has none yet; runs, wallets, block
+// metrics, soak intervals and balance snapshots are copied unless already
+// present. Receipt claims are not copied, so pending transactions can be
+// confirmed from d.
+func (d *Database) Import(ctx context.Context, path string) (*Imported, error) {
+	// ATTACH belongs to a connection, and the transaction must run on it
+	conn, err := d.writer.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a connection: %w", err)
+	}
+	defer conn.Close()
+
+	// Read-only, so a source that is not what it should be is left as it is
+	if _, err := conn.ExecContext(ctx, `ATTACH DATABASE ? AS src`, fmt.Sprintf("file:%s?mode=ro", path)); err != nil {
+		return nil, fmt.Errorf("failed to attach %s: %w", path, err)
+	}
+	defer conn.ExecContext(context.Background(), `DETACH DATABASE src`)
+
+	var version int
+	if err := conn.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM src.schema_version`).Scan(&version); err != nil {
+		return nil, fmt.Errorf("failed to read schema version of %s: %w", path, err)
+	}
+	if latest := migrations[len(migrations)-1].version; version != latest {
+		return nil, fmt.Errorf("%s has schema version %d, not %d: open it once with this go-tps, e.g. `go-tps stats -db %s`, to upgrade it", path, version, latest, path)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin import transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	imported := &Imported{}
+	exec := func(counter *int64, query string) error {
+		result, err := tx.ExecContext(ctx, query)
+		if err != nil {
+			return fmt.Errorf("failed to import: %w", err)
+		}
+		n, _ := result.RowsAffected()
+		*counter += n
+		return nil
+	}
+
+	// A run is the same run if it started at the same time with the same
+	// config on the same chain
+	err = exec(&imported.Runs, `
+		INSERT INTO main.runs (started_at, ended_at, mode, aborted, config, version, chain_id, client_version)
+		SELECT started_at, ended_at, mode, aborted, config, version, chain_id, client_version
+		FROM src.runs s
+		WHERE NOT EXISTS (
+			SELECT 1 FROM main.runs r
+			WHERE r.started_at = s.started_at AND r.config = s.config AND r.chain_id = s.chain_id
+		)
+		ORDER BY s.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM src.transactions`).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count transactions of %s: %w", path, err)
+	}
+	err = exec(&imported.Transactions, `
+		INSERT INTO main.transactions (`+transactionCopyColumns+`, run_id)
+		SELECT `+transactionCopyColumns+`,
+		       (SELECT r.id FROM main.runs r JOIN src.runs sr
+		        ON r.started_at = sr.started_at AND r.config = sr.config AND r.chain_id = sr.chain_id
+		        WHERE sr.id = s.run_id)
+		FROM src.transactions s
+		WHERE CASE WHEN COALESCE(s.tx_hash, '') != ''
+			THEN NOT EXISTS (SELECT 1 FROM main.transactions t WHERE t.tx_hash = s.tx_hash)
+			ELSE NOT EXISTS (
+				SELECT 1 FROM main.transactions t
+				WHERE t.batch_number = s.batch_number AND t.wallet_address = s.wallet_address
+				AND t.nonce = s.nonce AND t.submitted_at = s.submitted_at
+			)
+		END
+		ORDER BY s.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	imported.Duplicates = total - imported.Transactions
+
+	err = exec(&imported.Wallets, `
+		INSERT OR IGNORE INTO main.wallets (address, derivation_path, created_at, next_nonce, nonce_updated_at)
+		SELECT address, derivation_path, created_at, next_nonce, nonce_updated_at FROM src.wallets ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	batchTables := []struct{ table, columns string }{
+		{"batch_hooks", "batch_number, phase, kind, command, status, output, error, started_at, duration"},
+		{"latency_histograms", "batch_number, bucket_start, bucket_end, count"},
+		{"tps_series", "batch_number, bucket_start, bucket_seconds, submitted, confirmed"},
+		{"progress_stats", "batch_number, recorded_at, window_seconds, submit_tps, confirm_tps, inflight, submitted, confirmed, failed, p95_latency"},
+	}
+	for _, t := range batchTables {
+		err := exec(&imported.Other, `
+			INSERT INTO main.`+t.table+` (`+t.columns+`)
+			SELECT `+t.columns+` FROM src.`+t.table+` s
+			WHERE s.batch_number NOT IN (SELECT batch_number FROM main.`+t.table+`)
+			ORDER BY s.id
+		`)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = exec(&imported.Other, `
+		INSERT OR IGNORE INTO main.block_metrics (block_number, block_hash, timestamp, base_fee, gas_used, gas_limit, observed_at)
+		SELECT block_number, block_hash, timestamp, base_fee, gas_used, gas_limit, observed_at FROM src.block_metrics ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	err = exec(&imported.Other, `
+		INSERT INTO main.soak_intervals (label, started_at, ended_at, txs, included, failed, pending, tps, p50_latency, p95_latency, p99_latency)
+		SELECT label, started_at, ended_at, txs, included, failed, pending, tps, p50_latency, p95_latency, p99_latency
+		FROM src.soak_intervals s
+		WHERE NOT EXISTS (SELECT 1 FROM main.soak_intervals i WHERE i.label = s.label AND i.started_at = s.started_at)
+		ORDER BY s.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	err = exec(&imported.Other, `
+		INSERT INTO main.balances (run_id, phase, wallet_address, balance, block_number, recorded_at)
+		SELECT (SELECT r.id FROM main.runs r JOIN src.runs sr
+		        ON r.started_at = sr.started_at AND r.config = sr.config AND r.chain_id = sr.chain_id
+		        WHERE sr.id = s.run_id),
+		       phase, wallet_address, balance, block_number, recorded_at
+		FROM src.balances s
+		WHERE NOT EXISTS (
+			SELECT 1 FROM main.balances b
+			WHERE b.wallet_address = s.wallet_address AND b.phase = s.phase AND b.recorded_at = s.recorded_at
+		)
+		ORDER BY s.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit import: %w", err)
+	}
+	return imported, nil
+}
@@ -0,0 +1,388 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-tps/logger"
+)
+
+// migration is one versioned step of the schema. Steps are applied in
+// order when a database is opened, each in its own transaction together
+// with its schema_version row, so an older transactions.db is upgraded in
+// place. A released step never changes: a new table or column is a new
+// step at the end of migrations.
+type migration struct {
+	version     int
+	description string
+	up          func(tx *sql.Tx) error
+}
+
+var migrations = []migration{
+	{1, "baseline schema", baselineSchema},
+	{2, "runs table", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			CREATE TABLE runs (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				started_at TIMESTAMP NOT NULL,
+				ended_at TIMESTAMP,
+				mode TEXT NOT NULL DEFAULT '',
+				aborted INTEGER NOT NULL DEFAULT 0,
+				config TEXT NOT NULL,
+				version TEXT NOT NULL,
+				chain_id TEXT NOT NULL,
+				client_version TEXT NOT NULL
+			);
+			ALTER TABLE transactions ADD COLUMN run_id INTEGER REFERENCES runs(id);
+			CREATE INDEX idx_run_id ON transactions(run_id);
+		`)
+		return err
+	}},
+	{3, "block of each transaction", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			ALTER TABLE transactions ADD COLUMN block_number INTEGER;
+			ALTER TABLE transactions ADD COLUMN block_hash TEXT;
+			ALTER TABLE transactions ADD COLUMN tx_index INTEGER;
+		`)
+		return err
+	}},
+	{4, "unique transaction hashes", func(tx *sql.Tx) error {
+		// Resends and "already known" retries could store a hash more than
+		// once. Which copy is right is for the user to decide, not the upgrade.
+		if err := checkDuplicateHashes(tx); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`CREATE UNIQUE INDEX idx_tx_hash_unique ON transactions(tx_hash) WHERE tx_hash != ''`)
+		return err
+	}},
+	{5, "workers and endpoints of each transaction", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			ALTER TABLE transactions ADD COLUMN submit_worker INTEGER;
+			ALTER TABLE transactions ADD COLUMN receipt_worker INTEGER;
+			ALTER TABLE transactions ADD COLUMN receipt_endpoint TEXT;
+			ALTER TABLE transactions ADD COLUMN receipt_source TEXT;
+		`)
+		return err
+	}},
+	{6, "wallet balance snapshots", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			CREATE TABLE balances (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				run_id INTEGER REFERENCES runs(id),
+				phase TEXT NOT NULL,
+				wallet_address TEXT NOT NULL,
+				balance TEXT NOT NULL,
+				block_number INTEGER NOT NULL,
+				recorded_at TIMESTAMP NOT NULL
+			);
+			CREATE INDEX idx_balances_run ON balances(run_id);
+			CREATE INDEX idx_balances_wallet ON balances(wallet_address);
+		`)
+		return err
+	}},
+}
+
+// checkDuplicateHashes returns an error naming the transaction hashes stored
+// more than once, if any.
+func checkDuplicateHashes(tx *sql.Tx) error {
+	rows, err := tx.Query(`
+		SELECT tx_hash, COUNT(*) FROM transactions
+		WHERE tx_hash != '' GROUP BY tx_hash HAVING COUNT(*) > 1
+		ORDER BY MIN(id)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to look for duplicate transaction hashes: %w", err)
+	}
+	defer rows.Close()
+
+	var duplicates []string
+	for rows.Next() {
+		var hash string
+		var count int
+		if err := rows.Scan(&hash, &count); err != nil {
+			return fmt.Errorf("failed to scan duplicate transaction hash: %w", err)
+		}
+		duplicates = append(duplicates, fmt.Sprintf("%s (%d rows)", hash, count))
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to look for duplicate transaction hashes: %w", err)
+	}
+	if len(duplicates) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d transaction hashes are stored more than once, which the upgrade leaves to you to resolve (see Duplicate Hashes in the README):\n  %s",
+		len(duplicates), strings.Join(duplicates, "\n  "))
+}
+
+// migrate brings db up to the latest schema version. A database written by
+// a newer go-tps is refused rather than written with an older schema.
+func migrate(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			description TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	latest := migrations[len(migrations)-1].version
+	if current > latest {
+		return fmt.Errorf("database schema version %d is newer than this go-tps supports (%d); upgrade go-tps", current, latest)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(db, m); err != nil {
+			return err
+		}
+		if current > 0 {
+			logger.Info("[DB] Upgraded schema to version %d: %s\n", m.version, m.description)
+		}
+	}
+	return nil
+}
+
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %d: %w", m.version, err)
+	}
+	defer tx.Rollback()
+
+	if err := m.up(tx); err != nil {
+		return fmt.Errorf("failed to apply migration %d (%s): %w", m.version, m.description, err)
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_version (version, description, applied_at) VALUES (?, ?, ?)`,
+		m.version, m.description, time.Now()); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", m.version, err)
+	}
+	return nil
+}
+
+// baselineSchema creates the schema as it was when versions were
+// introduced, and upgrades databases from before then, whatever columns
+// they already have.
+func baselineSchema(tx *sql.Tx) error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS transactions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		batch_number TEXT NOT NULL,
+		wallet_address TEXT NOT NULL,
+		tx_hash TEXT,
+		nonce INTEGER NOT NULL,
+		to_address TEXT NOT NULL,
+		value TEXT NOT NULL,
+		gas_price TEXT NOT NULL,
+		gas_limit INTEGER NOT NULL,
+		gas_estimated INTEGER NOT NULL DEFAULT 0,
+		gas_used INTEGER,
+		effective_gas_price TEXT,
+		cost TEXT,
+		l1_fee TEXT,
+		l2_fee TEXT,
+		status TEXT NOT NULL,
+		submitted_at TIMESTAMP NOT NULL,
+		confirmed_at TIMESTAMP,
+		execution_time REAL,
+		error TEXT,
+		receipt_claimed_by TEXT,
+		receipt_claimed_until INTEGER,
+		receipt_attempts INTEGER NOT NULL DEFAULT 0,
+		phase TEXT NOT NULL DEFAULT '',
+		rpc_endpoint TEXT NOT NULL DEFAULT '',
+		error_category TEXT NOT NULL DEFAULT '',
+		trace_parent TEXT NOT NULL DEFAULT ''
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_batch_number ON transactions(batch_number);
+	CREATE INDEX IF NOT EXISTS idx_wallet_address ON transactions(wallet_address);
+	CREATE INDEX IF NOT EXISTS idx_tx_hash ON transactions(tx_hash);
+	CREATE INDEX IF NOT EXISTS idx_status ON transactions(status);
+	CREATE INDEX IF NOT EXISTS idx_submitted_at ON transactions(submitted_at);
+
+	CREATE TABLE IF NOT EXISTS wallets (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		address TEXT NOT NULL UNIQUE,
+		derivation_path TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		next_nonce INTEGER,
+		nonce_updated_at TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS batch_hooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		batch_number TEXT NOT NULL,
+		phase TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		command TEXT NOT NULL,
+		status INTEGER NOT NULL,
+		output TEXT,
+		error TEXT,
+		started_at TIMESTAMP NOT NULL,
+		duration REAL
+	);
+	CREATE INDEX IF NOT EXISTS idx_batch_hooks_batch ON batch_hooks(batch_number);
+
+	CREATE TABLE IF NOT EXISTS block_metrics (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		block_number INTEGER NOT NULL,
+		block_hash TEXT NOT NULL UNIQUE,
+		timestamp TIMESTAMP NOT NULL,
+		base_fee TEXT,
+		gas_used INTEGER NOT NULL,
+		gas_limit INTEGER NOT NULL,
+		observed_at TIMESTAMP NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_block_metrics_number ON block_metrics(block_number);
+	CREATE INDEX IF NOT EXISTS idx_block_metrics_timestamp ON block_metrics(timestamp);
+
+	CREATE TABLE IF NOT EXISTS soak_intervals (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		label TEXT NOT NULL,
+		started_at TIMESTAMP NOT NULL,
+		ended_at TIMESTAMP NOT NULL,
+		txs INTEGER NOT NULL,
+		included INTEGER NOT NULL,
+		failed INTEGER NOT NULL,
+		pending INTEGER NOT NULL,
+		tps REAL,
+		p50_latency REAL,
+		p95_latency REAL,
+		p99_latency REAL
+	);
+
+	CREATE TABLE IF NOT EXISTS latency_histograms (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		batch_number TEXT NOT NULL,
+		bucket_start REAL NOT NULL,
+		bucket_end REAL NOT NULL,
+		count INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_latency_histograms_batch ON latency_histograms(batch_number);
+
+	CREATE TABLE IF NOT EXISTS tps_series (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		batch_number TEXT NOT NULL,
+		bucket_start TIMESTAMP NOT NULL,
+		bucket_seconds INTEGER NOT NULL,
+		submitted INTEGER NOT NULL,
+		confirmed INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_tps_series_batch ON tps_series(batch_number);
+
+	CREATE TABLE IF NOT EXISTS progress_stats (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		recorded_at TIMESTAMP NOT NULL,
+		batch_number TEXT NOT NULL,
+		window_seconds REAL NOT NULL,
+		submit_tps REAL NOT NULL,
+		confirm_tps REAL NOT NULL,
+		inflight INTEGER NOT NULL,
+		submitted INTEGER NOT NULL,
+		confirmed INTEGER NOT NULL,
+		failed INTEGER NOT NULL,
+		p95_latency REAL NOT NULL
+	);
+	`
+
+	_, err := tx.Exec(schema)
+	if err != nil {
+		return fmt.Errorf("failed to create tables: %w", err)
+	}
+
+	// Columns added before schema versions were recorded; CREATE TABLE IF
+	// NOT EXISTS leaves databases from those versions without them.
+	if err := ensureColumn(tx, "transactions", "gas_estimated", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(tx, "transactions", "cost", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(tx, "transactions", "l1_fee", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(tx, "transactions", "l2_fee", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(tx, "wallets", "next_nonce", "INTEGER"); err != nil {
+		return err
+	}
+	if err := ensureColumn(tx, "wallets", "nonce_updated_at", "TIMESTAMP"); err != nil {
+		return err
+	}
+	if err := ensureColumn(tx, "transactions", "receipt_claimed_by", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(tx, "transactions", "receipt_claimed_until", "INTEGER"); err != nil {
+		return err
+	}
+	if err := ensureColumn(tx, "transactions", "receipt_attempts", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(tx, "transactions", "phase", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := ensureColumn(tx, "transactions", "rpc_endpoint", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := ensureColumn(tx, "transactions", "error_category", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := ensureColumn(tx, "transactions", "trace_parent", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := backfillErrorCategories(tx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ensureColumn adds a column to an existing table if it is missing.
+func ensureColumn(tx *sql.Tx, table, column, definition string) error {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name, typ  string
+			notNull    int
+			defaultVal sql.NullString
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &defaultVal, &pk); err != nil {
+			return fmt.Errorf("failed to inspect table %s: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	rows.Close()
+
+	if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+	logger.Info("[DB] Added column %s.%s\n", table, column)
+	return nil
+}
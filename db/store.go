@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// Store is everything a run keeps: transactions, wallets and the per-batch
+// records reports are built from. The main loop, the workers, the hooks and
+// the reports depend only on Store; Database, on SQLite, is the default
+// implementation, and another backend only has to implement Store and be
+// returned by Open.
+type Store interface {
+	// Transactions and their receipts
+	InsertTransaction(ctx context.Context, tx *Transaction) (int64, error)
+	InsertTransactions(ctx context.Context, txs []*Transaction) error
+	UpdateTransactionStatus(ctx context.Context, txHash, status string, confirmedAt *time.Time, gasUsed uint64, effectiveGasPrice, l1Fee, l2Fee, cost string, errMsg string, inclusion *Inclusion, by *Resolution) error
+	ReplaceTransactionHash(ctx context.Context, oldHash, newHash, gasPrice string) error
+	GetPendingTransactionHash(ctx context.Context, wallet string, nonce uint64) (string, error)
+	GetPendingTransactionsBatch(limit, offset int) ([]*Transaction, error)
+	GetPendingTransactionCount(ctx context.Context) (int, error)
+	GetPendingTransactionsBatchCursor(ctx context.Context, lastID int64, limit int) ([]*Transaction, error)
+	ClaimReceiptJob(ctx context.Context, owner string, lease time.Duration) (*Transaction, int, error)
+	RetryReceiptJob(ctx context.Context, id int64, retryAt time.Time) error
+	CountReceiptClaims(ctx context.Context, owner string) (int, error)
+	GetBatchTransactions(ctx context.Context, batchNumber string) ([]*Transaction, error)
+	GetTransactionByHash(ctx context.Context, txHash string) (*Transaction, error)
+	GetFailedTransactions(ctx context.Context, batchNumber string, limit int) ([]*Transaction, error)
+	ListBatches(ctx context.Context) ([]string, error)
+	GetHighestSubmittedNonces(ctx context.Context, batchNumber string) (map[string]uint64, error)
+	GetBatchStats(ctx context.Context, batchNumber string) (map[string]interface{}, error)
+
+	// Wallets
+	InsertWallet(ctx context.Context, address, derivationPath string) error
+	GetWallets(ctx context.Context) (map[string]*StoredWallet, error)
+	ResetWallet(ctx context.Context, address, derivationPath string) error
+	SetWalletNonce(ctx context.Context, address string, nonce uint64) error
+	AdvanceWalletNonce(ctx context.Context, address string, next uint64) error
+	AdvanceWalletNonces(ctx context.Context, next map[string]uint64) error
+	GetWalletNonces(ctx context.Context) (map[string]uint64, error)
+
+	// Per-batch and per-run records
+	InsertRun(ctx context.Context, run *Run) (int64, error)
+	FinishRun(ctx context.Context, id int64, endedAt time.Time, mode string, aborted bool) error
+	InsertBatchHook(ctx context.Context, hook *BatchHook) error
+	InsertBlockMetric(ctx context.Context, block *BlockMetric) error
+	InsertSoakInterval(ctx context.Context, s *SoakInterval) error
+	InsertProgressStat(ctx context.Context, s *ProgressStat) error
+	InsertBalances(ctx context.Context, snapshots []*BalanceSnapshot) error
+	ReplaceLatencyHistogram(ctx context.Context, batchNumber string, buckets []LatencyBucket) error
+	ReplaceTPSSeries(ctx context.Context, batchNumber string, width int, points []SeriesPoint) error
+	GetTPSSeries(ctx context.Context, batchNumbers []string) ([]SeriesPoint, error)
+
+	// Maintenance
+	Size(ctx context.Context) (used, free int64, err error)
+	ListBatchSizes(ctx context.Context) ([]BatchSize, error)
+	Prune(ctx context.Context, batches []string, before time.Time) (*Pruned, error)
+	Vacuum(ctx context.Context) error
+	Dump(ctx context.Context, path string) error
+	Import(ctx context.Context, path string) (*Imported, error)
+
+	Close() error
+}
+
+var _ Store = (*Database)(nil)
+
+// Open opens the store at path: the SQLite database, created if missing.
+func Open(path string, maxOpenConns, maxIdleConns int) (Store, error) {
+	d, err := NewDatabase(path, maxOpenConns, maxIdleConns)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
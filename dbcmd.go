@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go-tps/config"
+	dbpkg "go-tps/db"
+	"go-tps/logger"
+	"go-tps/report"
+)
+
+// runDBCommand implements `go-tps db`: the size of each batch, pruning old
+// batches and vacuuming, for databases that grow on long-running machines.
+func runDBCommand(config *config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Println("Usage: go-tps db size [-db PATH]")
+		fmt.Println("       go-tps db prune [-db PATH] [-days N] [-dry-run] [-vacuum] [-yes]")
+		fmt.Println("       go-tps db vacuum [-db PATH]")
+		return 2
+	}
+	switch args[0] {
+	case "size":
+		return runDBSize(config, args[1:])
+	case "prune":
+		return runDBPrune(config, args[1:])
+	case "vacuum":
+		return runDBVacuum(config, args[1:])
+	default:
+		fmt.Printf("Unknown db command %q (available: size, prune, vacuum)\n", args[0])
+		return 2
+	}
+}
+
+// openDB opens the database at path for a db command.
+func openDB(config *config.Config, path string) (dbpkg.Store, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, fmt.Errorf("no database at %s", path)
+	}
+	return dbpkg.Open(path, config.DBMaxOpenConns, config.DBMaxIdleConns)
+}
+
+func runDBSize(config *config.Config, args []string) int {
+	fs := flag.NewFlagSet("db size", flag.ContinueOnError)
+	dbPath := fs.String("db", config.DBPath, "SQLite database to inspect")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	db, err := openDB(config, *dbPath)
+	if err != nil {
+		logger.Error("Error opening database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	sizes, err := db.ListBatchSizes(ctx)
+	if err != nil {
+		logger.Error("%v\n", err)
+		return 1
+	}
+	used, free, err := db.Size(ctx)
+	if err != nil {
+		logger.Error("%v\n", err)
+		return 1
+	}
+
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("DATABASE SIZE")
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("%-28s %8s %11s %10s\n", "Batch", "Txs", "Last sent", "≈ Size")
+	now := time.Now()
+	for _, s := range sizes {
+		fmt.Printf("%-28s %8s %11s %10s\n", s.BatchNumber, report.Int(s.Transactions),
+			age(now.Sub(s.LastSubmitted)), formatBytes(s.Bytes))
+	}
+	fmt.Println(strings.Repeat("-", 60))
+	fmt.Printf("%d batches, %s used, %s free (reclaimed by `go-tps db vacuum`)\n", len(sizes), formatBytes(used), formatBytes(free))
+	if info, err := os.Stat(*dbPath + "-wal"); err == nil && info.Size() > 0 {
+		fmt.Printf("Write-ahead log: %s\n", formatBytes(info.Size()))
+	}
+	fmt.Println(strings.Repeat("=", 60))
+	return 0
+}
+
+func runDBPrune(config *config.Config, args []string) int {
+	fs := flag.NewFlagSet("db prune", flag.ContinueOnError)
+	dbPath := fs.String("db", config.DBPath, "SQLite database to prune")
+	days := fs.Int("days", config.DBRetentionDays, "delete batches whose last transaction is older than this many days")
+	dryRun := fs.Bool("dry-run", false, "list the batches that would be deleted without deleting them")
+	vacuum := fs.Bool("vacuum", false, "vacuum the database afterwards to shrink the file")
+	yes := fs.Bool("yes", config.AutomatedMode, "delete without asking")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *days <= 0 {
+		fmt.Println("Nothing to prune: -days (DB_RETENTION_DAYS) must be positive")
+		return 2
+	}
+	db, err := openDB(config, *dbPath)
+	if err != nil {
+		logger.Error("Error opening database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	sizes, err := db.ListBatchSizes(ctx)
+	if err != nil {
+		logger.Error("%v\n", err)
+		return 1
+	}
+	cutoff := time.Now().AddDate(0, 0, -*days)
+	var batches []string
+	var txs int
+	var bytes int64
+	for _, s := range sizes {
+		if s.LastSubmitted.Before(cutoff) {
+			batches = append(batches, s.BatchNumber)
+			txs += s.Transactions
+			bytes += s.Bytes
+		}
+	}
+	if len(batches) == 0 {
+		fmt.Printf("✓ No batches older than %d days\n", *days)
+	} else {
+		fmt.Printf("%d of %d batches are older than %d days (%s transactions, ≈ %s)\n",
+			len(batches), len(sizes), *days, report.Int(txs), formatBytes(bytes))
+	}
+	if *dryRun {
+		for _, b := range batches {
+			fmt.Printf("  %s\n", b)
+		}
+		return 0
+	}
+
+	if !*yes {
+		fmt.Println()
+		proceed, err := confirm(fmt.Sprintf("Delete them, with the block metrics, soak intervals and runs from before %s?", cutoff.Format("2006-01-02")))
+		if err != nil {
+			logger.Error("%v\n", err)
+			return 1
+		}
+		if !proceed {
+			fmt.Println("\nPrune cancelled.")
+			return 1
+		}
+	}
+
+	pruned, err := db.Prune(ctx, batches, cutoff)
+	if err != nil {
+		logger.Error("%v\n", err)
+		return 1
+	}
+	fmt.Printf("✓ Deleted %s transactions and %s other rows\n", report.Int(pruned.Transactions), report.Int(pruned.Other))
+	if *vacuum {
+		return vacuumDB(ctx, db)
+	}
+	fmt.Println("Run `go-tps db vacuum` to give the space back to the file system")
+	return 0
+}
+
+func runDBVacuum(config *config.Config, args []string) int {
+	fs := flag.NewFlagSet("db vacuum", flag.ContinueOnError)
+	dbPath := fs.String("db", config.DBPath, "SQLite database to vacuum")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	db, err := openDB(config, *dbPath)
+	if err != nil {
+		logger.Error("Error opening database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	return vacuumDB(ctx, db)
+}
+
+// vacuumDB vacuums db and prints how much it shrank.
+func vacuumDB(ctx context.Context, db dbpkg.Store) int {
+	used, free, err := db.Size(ctx)
+	if err != nil {
+		logger.Error("%v\n", err)
+		return 1
+	}
+	fmt.Printf("Vacuuming (%s used, %s free)...\n", formatBytes(used), formatBytes(free))
+	if err := db.Vacuum(ctx); err != nil {
+		logger.Error("%v\n", err)
+		return 1
+	}
+	after, _, err := db.Size(ctx)
+	if err != nil {
+		logger.Error("%v\n", err)
+		return 1
+	}
+	fmt.Printf("✓ Database is now %s (was %s)\n", formatBytes(after), formatBytes(used+free))
+	return 0
+}
+
+// dumpDatabase copies the run's database to path (DB_DUMP_PATH).
+func dumpDatabase(db dbpkg.Store, path string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	if err := db.Dump(ctx, path); err != nil {
+		logger.Error("%v\n", err)
+		return
+	}
+	fmt.Printf("✓ Database saved to %s\n", path)
+}
+
+// formatBytes renders n bytes in the largest binary unit under it.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return report.Int(n) + " B"
+	}
+	v, suffix := float64(n), ""
+	for _, s := range []string{"KiB", "MiB", "GiB", "TiB"} {
+		v /= unit
+		suffix = s
+		if v < unit {
+			break
+		}
+	}
+	return report.Float(v, 1) + " " + suffix
+}
+
+// age renders d in whole days, or hours under a day.
+func age(d time.Duration) string {
+	if d < 24*time.Hour {
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"go-tps/config"
+	"go-tps/logger"
+	txpkg "go-tps/tx"
+	"go-tps/wallet"
+	"go-tps/workload"
+)
+
+// dryRunWalletRows is how many wallets the dry run lists one by one.
+const dryRunWalletRows = 20
+
+// errDryRun ends the spans of the requests a dry run signed.
+var errDryRun = errors.New("dry run: not sent")
+
+// dryRunWallet is one wallet's batch as the dry run signed it.
+type dryRunWallet struct {
+	index   int // 0-based wallet index
+	wallet  *wallet.Wallet
+	reqs    []*txpkg.TxRequest
+	maxCost *big.Int // value plus gas limit × max fee plus L1 fees
+}
+
+// dryRun signs one batch of every wallet's transactions the way the run
+// would, at the current fees and the wallets' next nonces, and prints what
+// they transfer and may cost. Nothing is sent: funding, workload setup and
+// the batches are all left out.
+func dryRun(config *config.Config, txSender *txpkg.TransactionSender, load workload.Workload, wallets []*wallet.Wallet, funding *fundingPlan) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.ContextTimeout)*time.Second)
+	defer cancel()
+
+	baseFee, tip, err := batchFees(ctx, config, txSender, nil)
+	if err != nil {
+		return fmt.Errorf("failed to read the fees: %w", err)
+	}
+	maxFee := txSender.MaxFeePerGas(baseFee, tip)
+	fee := new(big.Int).Add(baseFee, tip) // per gas at the current base fee
+	if fee.Cmp(maxFee) > 0 {
+		fee.Set(maxFee)
+	}
+
+	var estimator *txpkg.GasEstimator
+	if config.GasEstimate || config.GasLimitOverride > 0 {
+		estimator = txpkg.NewGasEstimator(txSender, config.GasEstimateMargin, config.GasLimitOverride)
+	}
+
+	var planned []dryRunWallet
+	txs, gas := 0, uint64(0)
+	value, expected, worst, l1 := new(big.Int), new(big.Int), new(big.Int), new(big.Int)
+	for idx, w := range wallets {
+		calls := load.Calls(idx, config.TxPerWallet)
+		if len(calls) == 0 {
+			continue
+		}
+		if estimator != nil {
+			estimator.Apply(ctx, w.Address, calls)
+		}
+		reqs, _, err := txSender.PrepareBatchTransactions(ctx, calls, baseFee, tip, config.GasLimit, w.Signer(), w.Nonce)
+		if err != nil {
+			return fmt.Errorf("wallet %d: %w", idx+1, err)
+		}
+		if err := txSender.EstimateL1Fees(ctx, reqs); err != nil {
+			logger.Warn("[Wallet %d/%d] Could not estimate L1 data fees: %v\n", idx+1, len(wallets), err)
+		}
+
+		p := dryRunWallet{index: idx, wallet: w, reqs: reqs, maxCost: new(big.Int)}
+		for _, req := range reqs {
+			req.Finish(errDryRun)
+			txs++
+			gas += req.GasLimit
+			if req.Value != nil {
+				value.Add(value, req.Value)
+				expected.Add(expected, req.Value)
+			}
+			expected.Add(expected, new(big.Int).Mul(fee, new(big.Int).SetUint64(req.GasLimit)))
+			if req.L1Fee != nil {
+				l1.Add(l1, req.L1Fee)
+				if !req.L1FeeInGas {
+					expected.Add(expected, req.L1Fee)
+				}
+			}
+			p.maxCost.Add(p.maxCost, req.MaxCost())
+		}
+		worst.Add(worst, p.maxCost)
+		planned = append(planned, p)
+	}
+
+	toEth := func(wei *big.Int) *big.Float {
+		return new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e18))
+	}
+
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("DRY RUN (nothing was sent)")
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("Workload:          %s\n", load.Name())
+	fmt.Printf("Transactions:      %d from %d wallets (TX_PER_WALLET %d)\n", txs, len(planned), config.TxPerWallet)
+	if d, err := config.LoopDuration(); err == nil && d > 0 {
+		fmt.Printf("Loop mode:         batches like this one for %s\n", d)
+	}
+	fmt.Printf("Base fee:          %s wei (GAS_PRICE_MULTIPLIER %.2fx)\n", baseFee.String(), config.GasPriceMultiplier)
+	fmt.Printf("Priority fee:      %s wei\n", tip.String())
+	fmt.Printf("Max fee per gas:   %s wei\n", maxFee.String())
+	switch {
+	case config.GasLimitOverride > 0:
+		fmt.Printf("Gas limit:         %d per transaction (override)\n", config.GasLimitOverride)
+	case config.GasEstimate:
+		fmt.Printf("Gas limit:         estimated +%g%% (fallback %d)\n", config.GasEstimateMargin, config.GasLimit)
+	default:
+		fmt.Printf("Gas limit:         %d per transaction, unless the workload sets its own\n", config.GasLimit)
+	}
+	fmt.Printf("Gas:               %d in total\n", gas)
+	fmt.Printf("Value:             %s wei (%.6f ETH)\n", value.String(), toEth(value))
+	if l1.Sign() > 0 {
+		fmt.Printf("L1 data fees:      %s wei (%.6f ETH)\n", l1.String(), toEth(l1))
+	}
+	fmt.Printf("Cost at base fee:  %s wei (%.6f ETH)\n", expected.String(), toEth(expected))
+	fmt.Printf("Cost at most:      %s wei (%.6f ETH)\n", worst.String(), toEth(worst))
+
+	if len(planned) > 0 {
+		fmt.Println(strings.Repeat("-", 60))
+		for i, p := range planned {
+			if i == dryRunWalletRows {
+				fmt.Printf("... and %d more wallets\n", len(planned)-dryRunWalletRows)
+				break
+			}
+			first, last := p.reqs[0], p.reqs[len(p.reqs)-1]
+			fmt.Printf("[%d] %s  nonces %d-%d  up to %s wei\n", p.index+1, p.wallet.Address.Hex(), first.Nonce, last.Nonce, p.maxCost.String())
+			fmt.Printf("    first %s\n", first.Hash().Hex())
+		}
+	}
+	if funding != nil && len(funding.transfers) > 0 {
+		fmt.Println(strings.Repeat("-", 60))
+		fmt.Printf("The funding wallet would first send %d transfers of %s wei in total (see above)\n", len(funding.transfers), funding.total.String())
+	}
+	if load.Name() != "transfer" {
+		fmt.Printf("⚠️  The %s workload deploys its contracts in its setup, which a dry run skips;\n", load.Name())
+		fmt.Println("   its transactions are signed as they would be without them")
+	}
+	fmt.Println(strings.Repeat("=", 60))
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-tps/report"
+)
+
+// errorStop stops a run once too many sends fail: it keeps the outcomes of
+// the last window of sends and aborts the run when their failure rate goes
+// above the threshold, so a dead node is not hammered for the rest of the
+// configured duration.
+type errorStop struct {
+	threshold  float64 // percent
+	window     time.Duration
+	minSamples int
+	abort      *abortController
+
+	mu       sync.Mutex
+	start    time.Time
+	samples  []sendOutcome
+	sent     int
+	failed   int
+	tripped  bool
+	trip     errorStopTrip
+	lastErrs map[string]int // error messages within the tripping window
+}
+
+// maxErrorStopMessages bounds how many distinct errors the report lists.
+const maxErrorStopMessages = 5
+
+type sendOutcome struct {
+	at  time.Time
+	err string // empty = sent
+}
+
+// errorStopTrip records where the run degraded.
+type errorStopTrip struct {
+	at        time.Time
+	rate      float64 // failure rate over the window, percent
+	samples   int
+	firstFail time.Time // earliest failure within the window
+	sent      int       // sends before the trip, successful or not
+	failed    int
+}
+
+func newErrorStop(threshold float64, window time.Duration, minSamples int, abort *abortController) *errorStop {
+	if threshold <= 0 {
+		return nil
+	}
+	return &errorStop{
+		threshold:  threshold,
+		window:     window,
+		minSamples: max(minSamples, 1),
+		abort:      abort,
+		start:      time.Now(),
+	}
+}
+
+// Observe records one send's outcome and stops the run if the failure rate
+// over the window is now above the threshold.
+func (s *errorStop) Observe(sendErr error) {
+	now := time.Now()
+	outcome := sendOutcome{at: now}
+	if sendErr != nil {
+		outcome.err = sendErr.Error()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tripped {
+		return
+	}
+
+	s.sent++
+	if sendErr != nil {
+		s.failed++
+	}
+	s.samples = append(s.samples, outcome)
+	cutoff := now.Add(-s.window)
+	drop := 0
+	for drop < len(s.samples) && s.samples[drop].at.Before(cutoff) {
+		drop++
+	}
+	s.samples = s.samples[drop:]
+
+	if len(s.samples) < s.minSamples {
+		return
+	}
+	failed := 0
+	var firstFail time.Time
+	for _, sample := range s.samples {
+		if sample.err != "" {
+			if failed == 0 {
+				firstFail = sample.at
+			}
+			failed++
+		}
+	}
+	rate := float64(failed) / float64(len(s.samples)) * 100
+	if rate <= s.threshold {
+		return
+	}
+
+	s.tripped = true
+	s.trip = errorStopTrip{at: now, rate: rate, samples: len(s.samples), firstFail: firstFail, sent: s.sent, failed: s.failed}
+	s.lastErrs = make(map[string]int)
+	for _, sample := range s.samples {
+		if sample.err != "" {
+			s.lastErrs[sample.err]++
+		}
+	}
+	s.abort.Trigger(fmt.Sprintf("%.1f%% of the last %d sends failed, above STOP_ON_ERROR_RATE %g%%", rate, len(s.samples), s.threshold))
+}
+
+// Print reports where the run degraded, if it was stopped.
+func (s *errorStop) Print() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.tripped {
+		return
+	}
+
+	t := s.trip
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("STOPPED ON ERROR RATE")
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("Stopped at:          %s (%s into the run)\n", t.at.Format("15:04:05"), t.at.Sub(s.start).Round(time.Second))
+	fmt.Printf("Window failure rate: %s of %s sends (threshold %s over %s)\n",
+		report.Percent(t.rate, 1), report.Int(t.samples), report.Percent(s.threshold, 1), s.window)
+	fmt.Printf("Degradation started: %s (%s into the run, first failure in the window)\n",
+		t.firstFail.Format("15:04:05"), t.firstFail.Sub(s.start).Round(time.Second))
+	fmt.Printf("Sends before stop:   %s, %s failed\n", report.Int(t.sent), report.Int(t.failed))
+	msgs := make([]string, 0, len(s.lastErrs))
+	for msg := range s.lastErrs {
+		msgs = append(msgs, msg)
+	}
+	sort.Slice(msgs, func(i, j int) bool { return s.lastErrs[msgs[i]] > s.lastErrs[msgs[j]] })
+	fmt.Println("Most common errors in the window:")
+	for i, msg := range msgs {
+		if i == maxErrorStopMessages {
+			fmt.Printf("  … and %d other errors\n", len(msgs)-i)
+			break
+		}
+		fmt.Printf("  %5s × %s\n", report.Int(s.lastErrs[msg]), msg)
+	}
+	fmt.Println(strings.Repeat("=", 60))
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"go-tps/config"
+	dbpkg "go-tps/db"
+	"go-tps/logger"
+	"go-tps/report"
+)
+
+// runExportCommand implements `go-tps export`: transactions, per-wallet
+// stats and the TPS time series as CSV files, for spreadsheets.
+func runExportCommand(config *config.Config, args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	dbPath := fs.String("db", config.DBPath, "SQLite database to read")
+	batch := fs.String("batch", "", "batch to export (default: every batch)")
+	dir := fs.String("dir", "export", "directory to write the CSV files to")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	db, err := dbpkg.Open(*dbPath, config.DBMaxOpenConns, config.DBMaxIdleConns)
+	if err != nil {
+		logger.Error("Error opening database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	batches := []string{*batch}
+	if *batch == "" {
+		batches, err = db.ListBatches(ctx)
+		if err != nil {
+			logger.Error("Error listing batches: %v\n", err)
+			return 1
+		}
+		if len(batches) == 0 {
+			fmt.Println("No batches in database.")
+			return 1
+		}
+	}
+
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		logger.Error("Error creating %s: %v\n", *dir, err)
+		return 1
+	}
+	txFile, err := newCSVFile(filepath.Join(*dir, "transactions.csv"),
+		"batch_number", "wallet_address", "tx_hash", "nonce", "to_address", "value", "gas_price", "gas_limit",
+		"gas_estimated", "gas_used", "effective_gas_price", "cost", "l1_fee", "l2_fee", "status", "submitted_at",
+		"confirmed_at", "execution_time_ms", "confirmation_seconds", "error", "error_category", "phase", "rpc_endpoint",
+		"submit_worker", "receipt_worker", "receipt_endpoint", "receipt_source")
+	if err != nil {
+		logger.Error("%v\n", err)
+		return 1
+	}
+	defer txFile.file.Close()
+	walletFile, err := newCSVFile(filepath.Join(*dir, "wallets.csv"),
+		"batch_number", "wallet_address", "transactions", "submitted", "confirmed", "successful", "failed",
+		"pending", "avg_confirmation_seconds", "gas_used", "cost")
+	if err != nil {
+		logger.Error("%v\n", err)
+		return 1
+	}
+	defer walletFile.file.Close()
+	seriesFile, err := newCSVFile(filepath.Join(*dir, "tps_series.csv"),
+		"batch_number", "bucket_start", "submitted", "confirmed")
+	if err != nil {
+		logger.Error("%v\n", err)
+		return 1
+	}
+	defer seriesFile.file.Close()
+
+	count := 0
+	for _, b := range batches {
+		txs, err := db.GetBatchTransactions(ctx, b)
+		if err != nil {
+			logger.Error("Error loading %s: %v\n", b, err)
+			return 1
+		}
+		count += len(txs)
+		for _, t := range txs {
+			confirmedAt, confirmation := "", ""
+			if t.ConfirmedAt != nil {
+				confirmedAt = t.ConfirmedAt.UTC().Format(time.RFC3339Nano)
+				confirmation = strconv.FormatFloat(t.ConfirmedAt.Sub(t.SubmittedAt).Seconds(), 'f', 3, 64)
+			}
+			txFile.Write([]string{t.BatchNumber, t.WalletAddress, t.TxHash, u64(t.Nonce), t.ToAddress, t.Value,
+				t.GasPrice, u64(t.GasLimit), u64(t.GasEstimated), u64(t.GasUsed), t.EffectiveGasPrice, t.Cost,
+				t.L1Fee, t.L2Fee, t.Status, t.SubmittedAt.UTC().Format(time.RFC3339Nano), confirmedAt,
+				strconv.FormatFloat(t.ExecutionTime, 'f', 3, 64), confirmation, t.Error, t.ErrorCategory, t.Phase, t.RPCEndpoint,
+				strconv.Itoa(t.SubmitWorker), strconv.Itoa(t.ReceiptWorker), t.ReceiptEndpoint, t.ReceiptSource})
+		}
+		for _, w := range report.BuildWalletStats(txs) {
+			walletFile.Write([]string{b, w.Wallet, strconv.Itoa(w.Txs), strconv.Itoa(w.Submitted),
+				strconv.Itoa(w.Confirmed), strconv.Itoa(w.Successful), strconv.Itoa(w.Failed), strconv.Itoa(w.Pending),
+				strconv.FormatFloat(w.AvgLatency(), 'f', 3, 64), u64(w.GasUsed), w.Cost.String()})
+		}
+		points, err := db.GetTPSSeries(ctx, []string{b})
+		if err != nil {
+			logger.Error("Error loading the TPS series of %s: %v\n", b, err)
+			return 1
+		}
+		for _, p := range points {
+			seriesFile.Write([]string{b, p.Time.UTC().Format(time.RFC3339), strconv.Itoa(p.Submitted), strconv.Itoa(p.Confirmed)})
+		}
+	}
+
+	failed := false
+	for _, f := range []*csvFile{txFile, walletFile, seriesFile} {
+		if err := f.close(); err != nil {
+			logger.Error("%v\n", err)
+			failed = true
+		}
+	}
+	if failed {
+		return 1
+	}
+	fmt.Printf("✓ Exported %d transactions from %d batches to %s (transactions.csv, wallets.csv, tps_series.csv)\n",
+		count, len(batches), *dir)
+	return 0
+}
+
+// csvFile is a CSV writer over the file it writes to.
+type csvFile struct {
+	*csv.Writer
+	file *os.File
+}
+
+// newCSVFile creates path and writes the header row.
+func newCSVFile(path string, header ...string) (*csvFile, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("error creating %s: %w", path, err)
+	}
+	f := &csvFile{Writer: csv.NewWriter(file), file: file}
+	f.Write(header)
+	return f, nil
+}
+
+// close flushes the writer and closes the file, returning the first error
+// of either. Closing the file again after close is harmless.
+func (f *csvFile) close() error {
+	f.Flush()
+	if err := f.Error(); err != nil {
+		return fmt.Errorf("error writing %s: %w", f.file.Name(), err)
+	}
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("error writing %s: %w", f.file.Name(), err)
+	}
+	return nil
+}
+
+func u64(n uint64) string {
+	return strconv.FormatUint(n, 10)
+}
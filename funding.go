@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"go-tps/config"
+	"go-tps/logger"
+	txpkg "go-tps/tx"
+	"go-tps/wallet"
+	"go-tps/workload"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// fundingReceiptTimeout is how long the funding transfers may take to be
+// included.
+const fundingReceiptTimeout = 5 * time.Minute
+
+// fundingPlan is what the funding wallet sends before the run: every wallet
+// whose pending balance is short of one batch gets the difference.
+type fundingPlan struct {
+	funder    *wallet.Wallet
+	balance   *big.Int // of the funder
+	baseFee   *big.Int
+	tip       *big.Int
+	gasLimit  uint64
+	transfers []fundingTransfer
+	total     *big.Int // value of the transfers
+	fees      *big.Int // worst-case gas of the transfers
+}
+
+// fundingTransfer tops up one wallet.
+type fundingTransfer struct {
+	index  int // 0-based wallet index
+	to     common.Address
+	amount *big.Int
+}
+
+// fundingCheck is each wallet's pending balance against the most one batch
+// may cost it at the current fees.
+type fundingCheck struct {
+	baseFee  *big.Int
+	tip      *big.Int
+	maxFee   *big.Int // per gas
+	balances []*big.Int
+	costs    []*big.Int
+}
+
+// checkFunding reads the pending balance of every wallet and works out the
+// most one batch of load could cost it: for each of its TX_PER_WALLET
+// transactions the value plus gas limit times the max fee per gas the
+// wallets sign with at the current base fee, plus the L1 data fee on
+// rollups. A node refuses a transaction the sender cannot cover this way.
+func checkFunding(config *config.Config, txSender *txpkg.TransactionSender, load workload.Workload, wallets []*wallet.Wallet) (*fundingCheck, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.ContextTimeout)*time.Second)
+	defer cancel()
+
+	baseFee, tip, err := batchFees(ctx, config, txSender, nil)
+	if err != nil {
+		return nil, err
+	}
+	check := &fundingCheck{
+		baseFee:  baseFee,
+		tip:      tip,
+		maxFee:   txSender.MaxFeePerGas(baseFee, tip),
+		balances: make([]*big.Int, len(wallets)),
+	}
+	check.costs, err = batchCosts(ctx, config, txSender, load, wallets, check.maxFee)
+	if err != nil {
+		return nil, err
+	}
+	for i, w := range wallets {
+		check.balances[i], err = txSender.GetPendingBalance(ctx, w.Address)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return check, nil
+}
+
+// shortfall returns how much wallet i is short of its batch, or nil if it
+// can pay for it.
+func (c *fundingCheck) shortfall(i int) *big.Int {
+	if c.balances[i].Cmp(c.costs[i]) >= 0 {
+		return nil
+	}
+	return new(big.Int).Sub(c.costs[i], c.balances[i])
+}
+
+// skippedWallet is a wallet SKIP_UNFUNDED_WALLETS left out of the run.
+type skippedWallet struct {
+	Index   int    `json:"index"` // 1-based, as in the wallet list
+	Address string `json:"address"`
+	Balance string `json:"balance_wei"`
+	Needed  string `json:"needed_wei"` // most its batch could have cost
+}
+
+// skipUnfunded returns the wallets of check that can pay for their batch,
+// and the ones left out.
+func skipUnfunded(wallets []*wallet.Wallet, check *fundingCheck) ([]*wallet.Wallet, []skippedWallet) {
+	kept := make([]*wallet.Wallet, 0, len(wallets))
+	var skipped []skippedWallet
+	for i, w := range wallets {
+		if check.shortfall(i) == nil {
+			kept = append(kept, w)
+			continue
+		}
+		skipped = append(skipped, skippedWallet{
+			Index:   i + 1,
+			Address: w.Address.Hex(),
+			Balance: check.balances[i].String(),
+			Needed:  check.costs[i].String(),
+		})
+	}
+	return kept, skipped
+}
+
+// loadFunder returns the funding wallet from FUNDER_PRIVATE_KEY,
+// FUNDER_KEY_SOURCE, FUNDER_KEYSTORE or FUNDER_ADDRESS, or nil if none is
+// set.
+func loadFunder(config *config.Config) (*wallet.Wallet, error) {
+	sources := 0
+	for _, s := range []string{config.FunderPrivateKey, config.FunderKeySource, config.FunderKeystore, config.FunderAddress} {
+		if s != "" {
+			sources++
+		}
+	}
+	switch {
+	case sources > 1:
+		return nil, fmt.Errorf("set only one of FUNDER_PRIVATE_KEY, FUNDER_KEY_SOURCE, FUNDER_KEYSTORE and FUNDER_ADDRESS")
+	case config.FunderKeySource != "":
+		key, err := fetchSecret(config, config.FunderKeySource)
+		if err != nil {
+			return nil, err
+		}
+		funder, err := wallet.FromPrivateKey(key)
+		if err != nil {
+			// The error never includes the key itself
+			return nil, fmt.Errorf("invalid private key in FUNDER_KEY_SOURCE")
+		}
+		return funder, nil
+	case config.FunderAddress != "":
+		if !common.IsHexAddress(config.FunderAddress) {
+			return nil, fmt.Errorf("invalid FUNDER_ADDRESS %q", config.FunderAddress)
+		}
+		signer, err := dialSigner(config)
+		if err != nil {
+			return nil, fmt.Errorf("FUNDER_ADDRESS needs a remote signer: %w", err)
+		}
+		return wallet.FromSigner(signer.Account(common.HexToAddress(config.FunderAddress)), signer.Name()), nil
+	case config.FunderPrivateKey != "":
+		funder, err := wallet.FromPrivateKey(config.FunderPrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid FUNDER_PRIVATE_KEY: %w", err)
+		}
+		return funder, nil
+	case config.FunderKeystore != "":
+		return wallet.FromKeystore(config.FunderKeystore, config.FunderKeystorePass)
+	}
+	return nil, nil
+}
+
+// planFunding works out what each wallet of check is short of: the most one
+// batch could cost it, plus FUNDING_MARGIN_PERCENT, minus its pending
+// balance. It fails if the funder cannot cover the transfers and their gas.
+func planFunding(config *config.Config, txSender *txpkg.TransactionSender, funder *wallet.Wallet, wallets []*wallet.Wallet, check *fundingCheck) (*fundingPlan, error) {
+	for i, w := range wallets {
+		if w.Address == funder.Address {
+			return nil, fmt.Errorf("the funding wallet %s is wallet %d of the run", funder.Address.Hex(), i+1)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.ContextTimeout)*time.Second)
+	defer cancel()
+
+	margin := big.NewFloat(1 + config.FundingMargin/100)
+	plan := &fundingPlan{
+		funder:   funder,
+		baseFee:  check.baseFee,
+		tip:      check.tip,
+		gasLimit: config.GasLimit,
+		total:    new(big.Int),
+		fees:     new(big.Int),
+	}
+	for i, w := range wallets {
+		need, _ := new(big.Float).Mul(new(big.Float).SetInt(check.costs[i]), margin).Int(nil)
+		if check.balances[i].Cmp(need) >= 0 {
+			continue
+		}
+		amount := need.Sub(need, check.balances[i])
+		plan.transfers = append(plan.transfers, fundingTransfer{index: i, to: w.Address, amount: amount})
+		plan.total.Add(plan.total, amount)
+	}
+	plan.fees.Mul(check.maxFee, new(big.Int).SetUint64(plan.gasLimit*uint64(len(plan.transfers))))
+
+	var err error
+	plan.balance, err = txSender.GetPendingBalance(ctx, funder.Address)
+	if err != nil {
+		return nil, err
+	}
+	if needed := new(big.Int).Add(plan.total, plan.fees); plan.balance.Cmp(needed) < 0 {
+		return nil, fmt.Errorf("funding wallet %s holds %s wei, but topping up %d wallets needs up to %s wei",
+			funder.Address.Hex(), plan.balance.String(), len(plan.transfers), needed.String())
+	}
+	return plan, nil
+}
+
+// print shows the transfers the plan will send.
+func (p *fundingPlan) print() {
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("WALLET FUNDING")
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("Funding wallet: %s (%s wei)\n", p.funder.Address.Hex(), p.balance.String())
+	if len(p.transfers) == 0 {
+		fmt.Println("✓ Every wallet holds enough for the batch")
+		fmt.Println(strings.Repeat("=", 60))
+		return
+	}
+	for _, t := range p.transfers {
+		fmt.Printf("[%d] %s  +%s wei\n", t.index+1, t.to.Hex(), t.amount.String())
+	}
+	eth := new(big.Float).Quo(new(big.Float).SetInt(p.total), big.NewFloat(1e18))
+	fmt.Printf("%d wallets to top up with %s wei (%.6f ETH), plus up to %s wei gas\n",
+		len(p.transfers), p.total.String(), eth, p.fees.String())
+	fmt.Println(strings.Repeat("=", 60))
+}
+
+// fundWallets sends the plan's transfers from the funding wallet and waits
+// until all of them are included.
+func fundWallets(txSender *txpkg.TransactionSender, plan *fundingPlan) error {
+	if len(plan.transfers) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), fundingReceiptTimeout)
+	defer cancel()
+
+	nonce, err := txSender.GetNonce(ctx, plan.funder.Address)
+	if err != nil {
+		return fmt.Errorf("failed to get nonce of the funding wallet: %w", err)
+	}
+	logger.Info("Funding %d wallets from %s...\n", len(plan.transfers), plan.funder.Address.Hex())
+	hashes := make([]common.Hash, 0, len(plan.transfers))
+	for _, t := range plan.transfers {
+		req := &txpkg.TxRequest{
+			ToAddress: t.to,
+			Value:     t.amount,
+			Nonce:     nonce,
+			GasLimit:  plan.gasLimit,
+			BaseFee:   plan.baseFee,
+			Tip:       plan.tip,
+		}
+		hash, err := txSender.SendRequest(ctx, req, plan.funder.Signer())
+		if err != nil {
+			return fmt.Errorf("failed to fund wallet %d: %w", t.index+1, err)
+		}
+		nonce++
+		hashes = append(hashes, hash)
+	}
+
+	for i, hash := range hashes {
+		receipt, err := txSender.WaitForReceipt(ctx, hash, fundingReceiptTimeout)
+		if err != nil {
+			return fmt.Errorf("funding of wallet %d (%s): %w", plan.transfers[i].index+1, hash.Hex(), err)
+		}
+		if receipt.Status != 1 {
+			return fmt.Errorf("funding of wallet %d (%s) reverted", plan.transfers[i].index+1, hash.Hex())
+		}
+	}
+	logger.Info("✓ Funded %d wallets with %s wei\n", len(plan.transfers), plan.total.String())
+	return nil
+}
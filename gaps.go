@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-tps/config"
+	dbpkg "go-tps/db"
+	"go-tps/logger"
+	txpkg "go-tps/tx"
+	"go-tps/wallet"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Nonce gap repair modes.
+const (
+	gapRepairAsk  = "ask"  // report gaps and ask before filling them
+	gapRepairAuto = "auto" // fill gaps without asking
+	gapRepairOff  = "off"  // do not scan
+)
+
+// repairNonceGaps looks for wallets whose submitted transactions are stuck
+// behind a missing nonce and, depending on NONCE_GAP_REPAIR, fills each hole
+// with a zero-value self-transfer so the wallet is usable again next run.
+func repairNonceGaps(config *config.Config, txSender *txpkg.TransactionSender, db dbpkg.Store, run *runState, batches []string) {
+	mode := strings.ToLower(config.NonceGapRepair)
+	if mode == gapRepairOff {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	highest := make(map[string]uint64)
+	for _, batch := range batches {
+		nonces, err := db.GetHighestSubmittedNonces(ctx, batch)
+		if err != nil {
+			logger.Warn("Could not load submitted nonces for %s: %v\n", batch, err)
+			return
+		}
+		for addr, nonce := range nonces {
+			if nonce > highest[addr] {
+				highest[addr] = nonce
+			}
+		}
+	}
+
+	var gaps []*txpkg.NonceGap
+	for _, w := range run.wallets {
+		nonce, ok := highest[w.Address.Hex()]
+		if !ok {
+			continue
+		}
+		gap, err := txSender.FindNonceGap(ctx, w.Address, nonce)
+		if err != nil {
+			logger.Warn("Could not check %s for nonce gaps: %v\n", w.Address.Hex(), err)
+			continue
+		}
+		if gap != nil {
+			gaps = append(gaps, gap)
+		}
+	}
+	if len(gaps) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("NONCE GAPS")
+	fmt.Println(strings.Repeat("=", 60))
+	for _, gap := range gaps {
+		fmt.Printf("%s: next mined nonce %d, nonce %d missing, nonces up to %d stuck behind it\n",
+			gap.Address.Hex(), gap.Mined, gap.Pending, gap.Highest)
+	}
+
+	if mode != gapRepairAuto {
+		if config.AutomatedMode {
+			fmt.Println("Set NONCE_GAP_REPAIR=auto to fill gaps with self-transfers in automated mode.")
+			fmt.Println(strings.Repeat("=", 60))
+			return
+		}
+		fill, err := confirm("Fill the gaps with zero-value self-transfers?")
+		if err != nil {
+			logger.Warn("%v\n", err)
+		}
+		if !fill {
+			fmt.Println("Gaps left in place; these wallets will stall until they are filled.")
+			fmt.Println(strings.Repeat("=", 60))
+			return
+		}
+	}
+
+	feeHistory, err := txSender.FeeHistory(ctx)
+	if err != nil {
+		logger.Error("Could not fetch base fee to fill nonce gaps: %v\n", err)
+		return
+	}
+	baseFee := feeHistory.BaseFee[len(feeHistory.BaseFee)-1]
+	tip, err := gweiToWei(config.PriorityFeeGwei)
+	if err != nil {
+		logger.Error("Invalid PRIORITY_FEE_GWEI: %v\n", err)
+		return
+	}
+
+	wallets := make(map[common.Address]*wallet.Wallet, len(run.wallets))
+	for _, w := range run.wallets {
+		wallets[w.Address] = w
+	}
+	for _, gap := range gaps {
+		filled, err := txSender.FillNonceGap(ctx, gap, wallets[gap.Address].Signer(), baseFee, tip)
+		if err != nil {
+			logger.Error("%s: %v\n", gap.Address.Hex(), err)
+		}
+		fmt.Printf("%s: sent %d self-transfers\n", gap.Address.Hex(), filled)
+	}
+	fmt.Println(strings.Repeat("=", 60))
+}
@@ -0,0 +1,160 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"go-tps/db"
+	"go-tps/logger"
+)
+
+// Phases a hook can run in.
+const (
+	PreBatch  = "pre"
+	PostBatch = "post"
+)
+
+// maxOutput caps how much hook output is kept in the database.
+const maxOutput = 4096
+
+// Runner executes the configured pre- and post-batch hooks and records each
+// outcome in the batch_hooks table. A hook is either a shell command, run
+// with `sh -c`, or an http(s) URL that receives a JSON POST.
+type Runner struct {
+	pre     string
+	post    string
+	timeout time.Duration
+	db      db.Store
+}
+
+func NewRunner(pre, post string, timeout time.Duration, database db.Store) *Runner {
+	return &Runner{pre: pre, post: post, timeout: timeout, db: database}
+}
+
+// Run executes the hook for phase, if one is configured. vars are passed to
+// commands as GO_TPS_<KEY> environment variables and to webhooks as JSON
+// fields. It returns false only if a configured hook failed.
+func (r *Runner) Run(phase, batchNumber string, vars map[string]string) bool {
+	if r == nil {
+		return true
+	}
+	hook := r.pre
+	if phase == PostBatch {
+		hook = r.post
+	}
+	if hook == "" {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	record := &db.BatchHook{
+		BatchNumber: batchNumber,
+		Phase:       phase,
+		Command:     hook,
+		StartedAt:   time.Now(),
+	}
+
+	fields := map[string]string{"batch_number": batchNumber, "phase": phase}
+	for k, v := range vars {
+		fields[k] = v
+	}
+
+	var err error
+	if strings.HasPrefix(hook, "http://") || strings.HasPrefix(hook, "https://") {
+		record.Kind = "webhook"
+		record.Status, record.Output, err = runWebhook(ctx, hook, fields)
+	} else {
+		record.Kind = "command"
+		record.Status, record.Output, err = runCommand(ctx, hook, fields)
+	}
+	record.Duration = time.Since(record.StartedAt).Seconds() * 1000
+	if err != nil {
+		record.Error = err.Error()
+		logger.Warn("[Hooks] %s-batch %s failed for %s: %v\n", phase, record.Kind, batchNumber, err)
+	} else {
+		logger.Info("[Hooks] %s-batch %s completed for %s (status %d, %.0fms)\n", phase, record.Kind, batchNumber, record.Status, record.Duration)
+	}
+
+	saveCtx, saveCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer saveCancel()
+	if saveErr := r.db.InsertBatchHook(saveCtx, record); saveErr != nil {
+		logger.Warn("[Hooks] Could not record hook result: %v\n", saveErr)
+	}
+
+	return err == nil
+}
+
+// runCommand returns the command's exit code (-1 if it could not be run or
+// timed out) and its combined output.
+func runCommand(ctx context.Context, command string, fields map[string]string) (int, string, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = os.Environ()
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		cmd.Env = append(cmd.Env, "GO_TPS_"+strings.ToUpper(k)+"="+fields[k])
+	}
+
+	out, err := cmd.CombinedOutput()
+	output := truncate(string(out))
+	if err == nil {
+		return 0, output, nil
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		return exitErr.ExitCode(), output, fmt.Errorf("command exited with status %d", exitErr.ExitCode())
+	}
+	if ctx.Err() != nil {
+		return -1, output, fmt.Errorf("command timed out: %w", ctx.Err())
+	}
+	return -1, output, fmt.Errorf("failed to run command: %w", err)
+}
+
+// runWebhook returns the HTTP status code and response body.
+func runWebhook(ctx context.Context, url string, fields map[string]string) (int, string, error) {
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return -1, "", fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return -1, "", fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return -1, "", fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	out, _ := io.ReadAll(io.LimitReader(resp.Body, maxOutput))
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, string(out), fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return resp.StatusCode, string(out), nil
+}
+
+func truncate(s string) string {
+	if len(s) > maxOutput {
+		return s[:maxOutput] + "...(truncated)"
+	}
+	return s
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"go-tps/config"
+	dbpkg "go-tps/db"
+	"go-tps/logger"
+	"go-tps/report"
+)
+
+// runReportCommand implements `go-tps report`: a self-contained HTML report
+// of one batch, to share instead of the database.
+func runReportCommand(config *config.Config, args []string) int {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	dbPath := fs.String("db", config.DBPath, "SQLite database to read")
+	batch := fs.String("batch", "", "batch to report on (default: the most recent)")
+	outPath := fs.String("o", "", "HTML file to write (default: report-<batch>.html)")
+	bucket := fs.Float64("bucket", config.HistogramBucket, "latency histogram bucket width in seconds")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	db, err := dbpkg.Open(*dbPath, config.DBMaxOpenConns, config.DBMaxIdleConns)
+	if err != nil {
+		logger.Error("Error opening database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if *batch == "" {
+		batches, err := db.ListBatches(ctx)
+		if err != nil {
+			logger.Error("Error listing batches: %v\n", err)
+			return 1
+		}
+		if len(batches) == 0 {
+			fmt.Println("No batches in database.")
+			return 1
+		}
+		*batch = batches[len(batches)-1]
+	}
+
+	txs, err := db.GetBatchTransactions(ctx, *batch)
+	if err != nil {
+		logger.Error("Error loading %s: %v\n", *batch, err)
+		return 1
+	}
+	if len(txs) == 0 {
+		fmt.Printf("No transactions in batch %s.\n", *batch)
+		return 1
+	}
+	if *bucket <= 0 {
+		*bucket = 1
+	}
+
+	if *outPath == "" {
+		*outPath = fmt.Sprintf("report-%s.html", *batch)
+	}
+	file, err := os.Create(*outPath)
+	if err != nil {
+		logger.Error("Error creating %s: %v\n", *outPath, err)
+		return 1
+	}
+	defer file.Close()
+	if err := report.WriteBatchHTML(file, report.BuildBatchReport(*batch, txs, *bucket)); err != nil {
+		logger.Error("Error writing %s: %v\n", *outPath, err)
+		return 1
+	}
+	fmt.Printf("✓ Report of %s (%d transactions) written to %s\n", *batch, len(txs), *outPath)
+	return 0
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"go-tps/config"
+	dbpkg "go-tps/db"
+	"go-tps/logger"
+	"go-tps/report"
+)
+
+// runImportCommand implements `go-tps import`: merges the databases of
+// load generators on other machines into one, for consolidated reports.
+func runImportCommand(config *config.Config, args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	dbPath := fs.String("db", config.DBPath, "SQLite database to import into (created if missing)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Println("Usage: go-tps import [-db PATH] SOURCE.db...")
+		return 2
+	}
+
+	// Checked before anything is opened, as opening creates the database
+	target, _ := os.Stat(*dbPath)
+	for _, source := range fs.Args() {
+		info, err := os.Stat(source)
+		if err != nil {
+			logger.Error("Error reading %s: %v\n", source, err)
+			return 1
+		}
+		if target != nil && os.SameFile(info, target) {
+			logger.Error("%s is the database being imported into\n", source)
+			return 1
+		}
+	}
+
+	db, err := dbpkg.Open(*dbPath, config.DBMaxOpenConns, config.DBMaxIdleConns)
+	if err != nil {
+		logger.Error("Error opening database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	for _, source := range fs.Args() {
+		imported, err := db.Import(ctx, source)
+		if err != nil {
+			logger.Error("%v\n", err)
+			return 1
+		}
+		fmt.Printf("✓ %s: %s transactions (%s already present), %s wallets, %s runs, %s other rows\n",
+			source, report.Int(imported.Transactions), report.Int(imported.Duplicates),
+			report.Int(imported.Wallets), report.Int(imported.Runs), report.Int(imported.Other))
+	}
+	return 0
+}
@@ -1,22 +1,36 @@
 package main
 
 import (
-	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"math/big"
+	"math/rand"
 	"os"
+	"runtime/debug"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go-tps/config"
+	"go-tps/consensus"
 	dbpkg "go-tps/db"
+	"go-tps/hooks"
 	"go-tps/logger"
+	"go-tps/metrics"
+	"go-tps/rate"
+	"go-tps/report"
+	"go-tps/tracing"
 	txpkg "go-tps/tx"
 	"go-tps/wallet"
 	"go-tps/worker"
+	"go-tps/workload"
 
+	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/joho/godotenv"
 )
@@ -33,6 +47,37 @@ func main() {
 		fmt.Println("✓ Log files initialised in logs/")
 	}
 
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(2)
+	}
+}
+
+// loadSettings applies the SCENARIO_FILE test plan and .env, and loads the
+// configuration every command runs with. It also returns the scenario and
+// the settings it applied, or nil if there is none. It exits listing every
+// setting that does not parse, and with validate every problem
+// validateConfig finds, before anything connects.
+func loadSettings(validate bool) (*config.Config, *config.Scenario, []string) {
+	// Apply the SCENARIO_FILE test plan first, so its settings win over
+	// .env but not over variables set in the environment
+	scenarioFile := os.Getenv("SCENARIO_FILE")
+	if scenarioFile == "" {
+		if env, err := godotenv.Read(); err == nil {
+			scenarioFile = env["SCENARIO_FILE"]
+		}
+	}
+	var scenario *config.Scenario
+	var scenarioSettings []string
+	if scenarioFile != "" {
+		var err error
+		scenario, err = config.LoadScenario(scenarioFile)
+		if err != nil {
+			logger.Error("Invalid SCENARIO_FILE: %v\n", err)
+			os.Exit(1)
+		}
+		scenarioSettings = scenario.Apply()
+	}
+
 	// Load .env file if it exists (optional)
 	if err := godotenv.Load(); err != nil {
 		logger.Debug("No .env file found, using environment variables or defaults\n")
@@ -40,27 +85,117 @@ func main() {
 
 	// Load configuration
 	config := config.LoadConfig()
+	problems := config.Errors()
+	if validate {
+		problems = validateConfig(config)
+	}
+	if len(problems) > 0 {
+		logger.Error("%d problem(s) with the configuration:\n", len(problems))
+		for _, err := range problems {
+			logger.Error("  - %v\n", err)
+		}
+		os.Exit(1)
+	}
 	logger.SetLevel(config.LogLevel)
+	reportFormat, err := report.NewFormat(config.ReportLocale, config.ReportThousands, config.ReportDecimals, config.ReportDurationUnit, config.ReportLayout)
+	if err != nil {
+		logger.Error("Invalid report settings: %v\n", err)
+		os.Exit(1)
+	}
+	report.SetFormat(reportFormat)
+	rpcHeaders, err := txpkg.ParseHeaders(config.RPCHeaders, config.RPCBasicAuth)
+	if err != nil {
+		logger.Error("Invalid RPC_HEADERS or RPC_BASIC_AUTH: %v\n", err)
+		os.Exit(1)
+	}
+	txpkg.SetHeaders(rpcHeaders)
+	return config, scenario, scenarioSettings
+}
+
+// runTest runs the load test, or with fundOnly stops once the funding
+// wallet has topped up the wallets.
+func runTest(config *config.Config, scenario *config.Scenario, scenarioSettings []string, fundOnly bool) {
+	if scenario != nil {
+		name := scenario.Name
+		if name == "" {
+			name = scenario.Path
+		}
+		logger.Info("📋 Scenario: %s\n", name)
+		if scenario.Description != "" {
+			logger.Info("   %s\n", scenario.Description)
+		}
+		if overridden := len(scenario.Settings) - len(scenarioSettings); overridden > 0 {
+			logger.Warn("%d scenario setting(s) overridden by the environment\n", overridden)
+		}
+		logger.Debug("Scenario settings applied: %s\n", strings.Join(scenarioSettings, ", "))
+	}
+
+	// Export transaction spans if a collector is configured
+	stopTracing, err := startTracing(config)
+	if err != nil {
+		logger.Error("%v\n", err)
+		os.Exit(1)
+	}
+
+	// Push transaction metrics if a time-series database is configured
+	stopMetrics, err := startMetrics(config)
+	if err != nil {
+		logger.Error("%v\n", err)
+		os.Exit(1)
+	}
 
 	// Initialize database
 	logger.Info("Initializing database...\n")
-	db, err := dbpkg.NewDatabase(config.DBPath, config.DBMaxOpenConns, config.DBMaxIdleConns)
+	db, err := dbpkg.Open(config.DBPath, config.DBMaxOpenConns, config.DBMaxIdleConns)
 	if err != nil {
 		logger.Error("Error initializing database: %v\n", err)
 		os.Exit(1)
 	}
 	defer db.Close()
 	logger.Info("✓ Database initialized\n")
+	if config.DBPath == dbpkg.MemoryPath && config.DBDumpPath == "" {
+		logger.Warn("Database is in memory and lost at exit; set DB_DUMP_PATH to keep it\n")
+	}
 
 	// Connect to RPC
 	logger.Info("Connecting to RPC: %s\n", config.RPCURL)
-	txSender, err := txpkg.NewTransactionSender(config.RPCURL)
+	txSender, err := newTransactionSender(config, nil)
 	if err != nil {
 		logger.Error("Error connecting to RPC: %v\n", err)
 		os.Exit(1)
 	}
 	defer txSender.Close()
 	logger.Info("✓ Connected to RPC\n")
+	if config.SubmitRPCURL != "" {
+		logger.Info("📤 Submitting transactions to %s; queries go to %s\n", config.SubmitRPCURL, config.RPCURL)
+	}
+	failover := txSender.Failover()
+	switch {
+	case failover != nil && failover.Weights() != nil:
+		logger.Info("⚖️  Spreading RPC requests by weight: %s\n", config.RPCWeights)
+	case failover != nil:
+		logger.Info("🔀 RPC failover enabled; active endpoint %s\n", failover.Active())
+	case config.RPCWeights != "":
+		logger.Warn("RPC_WEIGHTS needs RPC_FALLBACK_URLS; sending everything to %s\n", config.RPCURL)
+	}
+	if failover != nil && config.RPCHealthInterval > 0 {
+		failover.StartHealthChecks(time.Duration(config.RPCHealthInterval)*time.Second, uint64(max(config.RPCMaxBlockLag, 0)))
+		logger.Info("🩺 Health-checking RPC endpoints every %ds\n", config.RPCHealthInterval)
+	}
+
+	// Connect to the node's p2p port if configured (experimental devp2p sender)
+	var broadcaster *txpkg.P2PBroadcaster
+	if config.P2PEnode != "" {
+		logger.Info("Connecting to devp2p peer: %s\n", config.P2PEnode)
+		broadcaster, err = txpkg.NewP2PBroadcaster(config.P2PEnode)
+		if err != nil {
+			logger.Error("Error connecting to devp2p peer: %v\n", err)
+			os.Exit(1)
+		}
+		defer broadcaster.Close()
+		txSender.SetBroadcaster(broadcaster)
+		logger.Warn("⚠️  EXPERIMENTAL: transactions will be broadcast over devp2p, bypassing JSON-RPC\n")
+	}
 
 	// Connect to WebSocket if URL is provided (for faster receipt confirmations)
 	var wsManager *worker.WebSocketManager
@@ -79,34 +214,69 @@ func main() {
 		logger.Debug("No WebSocket URL provided, will use RPC polling for receipts\n")
 	}
 
-	// Get or generate mnemonic
-	var mnemonic string
-	if config.Mnemonic != "" {
-		logger.Info("\nUsing provided mnemonic...\n")
-		mnemonic = config.Mnemonic
-	} else {
-		logger.Info("\nGenerating new mnemonic...\n")
-		var err error
-		mnemonic, err = wallet.GenerateMnemonic()
+	var wallets []*wallet.Wallet
+	var receivers []common.Address
+	mnemonicSaved := false
+	if walletsImported(config) {
+		wallets, err = importWallets(config, txSender)
 		if err != nil {
-			logger.Error("Error generating mnemonic: %v\n", err)
+			logger.Error("Error loading wallets: %v\n", err)
 			os.Exit(1)
 		}
-	}
+	} else {
+		// Get or generate mnemonic
+		if err := fetchMnemonic(config); err != nil {
+			logger.Error("Error fetching mnemonic: %v\n", err)
+			os.Exit(1)
+		}
+		var mnemonic string
+		if config.Mnemonic != "" {
+			logger.Info("\nUsing provided mnemonic...\n")
+			mnemonic = config.Mnemonic
+		} else {
+			logger.Info("\nGenerating new %d-word mnemonic...\n", config.MnemonicBits/32*3)
+			var err error
+			mnemonic, err = wallet.GenerateMnemonic(config.MnemonicBits)
+			if err != nil {
+				logger.Error("Error generating mnemonic: %v\n", err)
+				os.Exit(1)
+			}
+		}
 
-	// Generate wallets from single mnemonic
-	logger.Info("Deriving %d wallets from mnemonic...\n", config.WalletCount)
+		// Generate wallets from single mnemonic
+		logger.Info("Deriving %d wallets from mnemonic at %s, index %d to %d...\n", config.WalletCount,
+			config.DerivationPath, config.DerivationStart, config.DerivationStart+config.WalletCount-1)
 
-	wallets, err := wallet.DeriveWalletsFromMnemonic(mnemonic, config.WalletCount, txSender)
-	if err != nil {
-		logger.Error("Error deriving wallets: %v\n", err)
-		os.Exit(1)
-	}
+		if strings.EqualFold(config.NonceSource, nonceSourceLocal) {
+			// Nonces come from the wallets table below
+			wallets, err = wallet.DeriveWallets(mnemonic, derivation(config), config.WalletCount)
+		} else {
+			wallets, err = wallet.DeriveWalletsFromMnemonic(mnemonic, derivation(config), config.WalletCount, txSender)
+		}
+		if err != nil {
+			logger.Error("Error deriving wallets: %v\n", err)
+			os.Exit(1)
+		}
+		if config.ReceiverCount > 0 {
+			logger.Info("Deriving %d receivers from mnemonic at %s...\n", config.ReceiverCount, config.ReceiverPath)
+			receivers, err = deriveReceivers(config, mnemonic, wallets)
+			if err != nil {
+				logger.Error("Error deriving receivers: %v\n", err)
+				os.Exit(1)
+			}
+		}
 
-	// Save mnemonic to file
-	err = SaveMnemonicToFile("mnemonic.txt", mnemonic)
-	if err != nil {
-		logger.Warn("Could not save mnemonic: %v\n", err)
+		// Save mnemonic to file; one from a secrets manager stays there
+		if config.SaveMnemonic && config.MnemonicSource == "" {
+			err = SaveMnemonicToFile(mnemonicFileName, mnemonic, config.MnemonicPassword)
+			if err != nil {
+				logger.Warn("Could not save mnemonic: %v\n", err)
+			} else {
+				mnemonicSaved = true
+			}
+		} else if config.Mnemonic == "" {
+			logger.Warn("⚠️  SAVE_MNEMONIC=false: the generated mnemonic is not stored anywhere; funds left in these wallets cannot be recovered\n")
+		}
 	}
 
 	logger.Info("✓ Generated %d wallets\n", len(wallets))
@@ -115,29 +285,88 @@ func main() {
 	setupCtx, setupCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer setupCancel()
 
-	// Save wallets to database
-	logger.Info("\nSaving wallets to database...\n")
-	for _, w := range wallets {
-		err := db.InsertWallet(setupCtx, w.Address.Hex(), w.DerivationPath)
+	if err := saveWallets(config, db, wallets); err != nil {
+		logger.Error("Error saving wallets: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := reconcileStoredNonces(config, db, txSender, wallets); err != nil {
+		logger.Error("Error loading stored nonces: %v\n", err)
+		os.Exit(1)
+	}
+	if config.NonceOverrides != "" {
+		overridden, err := wallet.ApplyNonceOverrides(wallets, config.NonceOverrides)
+		if err != nil {
+			logger.Error("Invalid NONCE_OVERRIDES: %v\n", err)
+			os.Exit(1)
+		}
+		logger.Warn("⚠️  Starting nonce overridden for %d wallets (NONCE_OVERRIDES)\n", overridden)
+	}
+
+	// The workload's calls size the funding; it is set up once the run is
+	// confirmed
+	value := new(big.Int)
+	value.SetString(config.ValueWei, 10)
+	load, err := workload.New(config.Workload, common.HexToAddress(config.ToAddress), value)
+	if err != nil {
+		logger.Error("Error creating workload: %v\n", err)
+		os.Exit(1)
+	}
+	if len(receivers) > 0 {
+		if t, ok := load.(*workload.Transfer); ok {
+			t.Receivers = receivers
+		} else {
+			logger.Warn("RECEIVER_COUNT only applies to the transfer workload; the %s workload ignores it\n", load.Name())
+		}
+	}
+
+	// Check every wallet can pay for its transactions at the current fees
+	check, err := checkFunding(config, txSender, load, wallets)
+	if err != nil {
+		logger.Warn("Could not check wallet funding: %v\n", err)
+	}
+
+	// Top up the wallets from the funding wallet once the run is confirmed
+	var funding *fundingPlan
+	funder, err := loadFunder(config)
+	if err != nil {
+		logger.Error("Error loading funding wallet: %v\n", err)
+		os.Exit(1)
+	}
+	if fundOnly && funder == nil {
+		logger.Error("go-tps fund needs a funding wallet: FUNDER_PRIVATE_KEY, FUNDER_KEY_SOURCE, FUNDER_KEYSTORE or FUNDER_ADDRESS\n")
+		os.Exit(1)
+	}
+	if check == nil && (funder != nil || config.SkipUnfunded) {
+		logger.Error("Cannot fund or skip wallets without knowing their balances\n")
+		os.Exit(1)
+	}
+	if funder != nil {
+		funding, err = planFunding(config, txSender, funder, wallets, check)
 		if err != nil {
-			logger.Warn("Could not save wallet %s: %v\n", w.Address.Hex(), err)
+			logger.Error("Error planning wallet funding: %v\n", err)
+			os.Exit(1)
 		}
 	}
-	logger.Info("✓ Wallets saved to database\n")
+	topUps, err := newTopUpMonitor(config, txSender, funder, load, wallets)
+	if err != nil {
+		logger.Error("%v\n", err)
+		os.Exit(1)
+	}
 
 	// Display wallet addresses and balances
 	fmt.Println("\n" + strings.Repeat("=", 60))
 	fmt.Println("WALLET ADDRESSES AND BALANCES")
 	fmt.Println(strings.Repeat("=", 60))
 
-	allFunded := true
-
+	short, shortTotal := 0, new(big.Int)
 	for i, w := range wallets {
-		balance, err := txSender.GetBalance(setupCtx, w.Address)
-		if err != nil {
+		var balance *big.Int
+		if check != nil {
+			balance = check.balances[i]
+		} else if balance, err = txSender.GetPendingBalance(setupCtx, w.Address); err != nil {
 			logger.Debug("[%d] %s\n", i+1, w.Address.Hex())
 			logger.Error("Error fetching balance: %v\n", err)
-			allFunded = false
 			continue
 		}
 
@@ -147,24 +376,67 @@ func main() {
 
 		fmt.Printf("[%d] %s\n", i+1, w.Address.Hex())
 		fmt.Printf("    Balance: %s wei (%.6f ETH)\n", balance.String(), ethValue)
-		// Check if balance is zero
-		if balance.Cmp(big.NewInt(0)) == 0 {
-			logger.Warn("    ⚠️  WARNING: Wallet has ZERO balance!\n")
+		if check == nil {
+			continue
+		}
+		if missing := check.shortfall(i); missing != nil {
+			logger.Warn("    ⚠️  Short by %s wei: %d transactions may cost up to %s wei\n", missing.String(), config.TxPerWallet, check.costs[i].String())
+			short++
+			shortTotal.Add(shortTotal, missing)
 		}
 	}
 
-	if !allFunded {
-		fmt.Println("⚠️  WARNING: Some wallets have zero balance or errors!")
+	var skipped []skippedWallet
+	switch {
+	case short > 0 && funding != nil:
+		fmt.Printf("%d of %d wallets are short by %s wei in total; the funding wallet tops them up\n", short, len(wallets), shortTotal.String())
+	case short > 0 && config.SkipUnfunded:
+		wallets, skipped = skipUnfunded(wallets, check)
+		fmt.Printf("⚠️  Skipping %d of %d wallets that cannot pay for all their transactions (SKIP_UNFUNDED_WALLETS);\n", len(skipped), len(skipped)+len(wallets))
+		fmt.Printf("   the run sends %d transactions from the other %d\n", len(wallets)*config.TxPerWallet, len(wallets))
+		if len(wallets) == 0 {
+			logger.Error("No wallet can pay for its transactions\n")
+			os.Exit(1)
+		}
+		config.WalletCount = len(wallets)
+	case short > 0:
+		fmt.Printf("⚠️  WARNING: %d of %d wallets cannot pay for all their transactions (%s wei short in total);\n", short, len(wallets), shortTotal.String())
+		fmt.Println("   their sends will fail with insufficient funds part-way through the batch")
+	case check != nil:
+		fmt.Printf("✓ Every wallet can pay for its %d transactions (up to %s wei per gas)\n", config.TxPerWallet, check.maxFee.String())
 	}
 	fmt.Println()
+	if funding != nil {
+		funding.print()
+		fmt.Println()
+	}
 
-	if !config.AutomatedMode {
-		fmt.Print("Do you want to proceed with sending transactions? (y/n): ")
-		scanner := bufio.NewScanner(os.Stdin)
-		scanner.Scan()
-		response := strings.TrimSpace(strings.ToLower(scanner.Text()))
+	if config.DryRun {
+		if fundOnly {
+			fmt.Println("Dry run: the funding transfers were not sent")
+		} else if err := dryRun(config, txSender, load, wallets, funding); err != nil {
+			logger.Error("Dry run failed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if fundOnly && len(funding.transfers) == 0 {
+		return
+	}
 
-		if response != "y" && response != "yes" {
+	if !config.AutomatedMode {
+		question := "Do you want to proceed with sending transactions?"
+		if fundOnly {
+			question = fmt.Sprintf("Do you want to fund %d wallets?", len(funding.transfers))
+		} else if funding != nil && len(funding.transfers) > 0 {
+			question = fmt.Sprintf("Do you want to fund %d wallets and proceed with sending transactions?", len(funding.transfers))
+		}
+		proceed, err := confirm(question)
+		if err != nil {
+			logger.Error("%v\n", err)
+			os.Exit(1)
+		}
+		if !proceed {
 			fmt.Println("\nOperation cancelled by user.")
 			fmt.Println("Please fund the wallets and try again.")
 			os.Exit(0)
@@ -174,6 +446,43 @@ func main() {
 		fmt.Println("\n✓ Automated mode enabled. Proceeding with transactions...")
 	}
 
+	if funding != nil {
+		if err := fundWallets(txSender, funding); err != nil {
+			logger.Error("Error funding wallets: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if fundOnly {
+		return
+	}
+
+	// Set up the workload (deploys contracts for non-transfer scenarios)
+	workloadCtx, workloadCancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	err = load.Setup(workloadCtx, txSender, wallets)
+	workloadCancel()
+	if err != nil {
+		logger.Error("Error setting up %s workload: %v\n", load.Name(), err)
+		os.Exit(1)
+	}
+
+	// From here on nonces come from the nonce manager, seeded with each
+	// wallet's nonce after workload setup
+	nonces := txpkg.NewNonceManager(txSender)
+	for _, w := range wallets {
+		nonces.Set(w.Address, w.Nonce)
+	}
+	storeWalletNonces(db, wallets)
+	nonces.OnResync(func(addr common.Address, nonce uint64) {
+		// The chain's nonce may be lower than the stored one after a
+		// dropped transaction; storing it as is keeps NONCE_SOURCE=local
+		// from starting the next run past a gap
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := db.SetWalletNonce(ctx, addr.Hex(), nonce); err != nil {
+			logger.Warn("Could not store nonce of %s: %v\n", addr.Hex(), err)
+		}
+	})
+
 	var receiptWG sync.WaitGroup // WaitGroup for receipt confirmations
 
 	// Create worker pools ONCE (reused across all iterations in loop mode)
@@ -194,33 +503,309 @@ func main() {
 	}
 	dbWriteChan := make(chan worker.DBWriteJob, dbBufferSize)
 
-	// Calculate Receipt buffer size
-	receiptBufferSize := config.ReceiptBufferSize
-	if receiptBufferSize == 0 {
-		// Auto-calculate - typically needs to handle pending transactions from DB
-		receiptBufferSize = 10000 // Default for batched processing
-		logger.Debug("Auto-calculated receipt buffer size: %d\n", receiptBufferSize)
-	} else {
-		logger.Debug("Using configured receipt buffer size: %d\n", receiptBufferSize)
+	dbWriteWG := sync.WaitGroup{}
+
+	// Baseline engine metrics so payload build times can be attributed to this run
+	var payloadBaseline *consensus.HistogramSample
+	if config.EngineMetricsURL != "" {
+		scrapeCtx, scrapeCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		payloadBaseline, err = consensus.ScrapeHistogram(scrapeCtx, config.EngineMetricsURL, config.EnginePayloadMetric)
+		scrapeCancel()
+		if err != nil {
+			logger.Warn("Could not scrape engine metrics: %v\n", err)
+		}
 	}
 
-	dbWriteWG := sync.WaitGroup{}
+	// Sample every block's base fee for the whole run, confirmations included
+	var blockRecorder *worker.BlockRecorder
+	if config.BlockMetrics {
+		blockRecorder = worker.NewBlockRecorder(wsManager, txSender, db, time.Second)
+		blockRecorder.Start()
+		logger.Info("📦 Recording block base fees to block_metrics\n")
+	}
 
-	worker.StartDBWriterPool(config.DBWorkers, dbWriteChan, db, &dbWriteWG)
+	worker.StartDBWriterPool(config.DBWorkers, config.DBBatchSize, time.Duration(config.DBFlushInterval)*time.Millisecond, dbWriteChan, db, &dbWriteWG)
 	logger.Info("📋 Started %d DB writer workers\n\n", config.DBWorkers)
 
-	// Check if we should run in loop mode
-	if config.RunDurationMinutes > 0 {
-		fmt.Printf("Running in LOOP MODE for %d minutes\n", config.RunDurationMinutes)
+	// Keep the base fee fresh in the background for the whole run
+	var gasRefresher *txpkg.GasPriceRefresher
+	if config.GasRefreshInterval > 0 {
+		gasRefresher = txpkg.NewGasPriceRefresher(txSender, time.Duration(config.GasRefreshInterval)*time.Second)
+		gasRefresher.Start()
+		logger.Info("⛽ Refreshing base fee every %ds\n", config.GasRefreshInterval)
+	}
+
+	// Replace transactions that stay unmined too long (same nonce, higher fee)
+	var stuckMonitor *txpkg.StuckMonitor
+	if config.StuckTxBlocks > 0 {
+		stuckMonitor = txpkg.NewStuckMonitor(txSender, uint64(config.StuckTxBlocks), config.StuckTxMaxBumps,
+			strings.ToLower(config.FeeBumpStrategy), config.FeeBumpPercent,
+			func(oldHash, newHash common.Hash, req *txpkg.TxRequest) {
+				// After the record of oldHash, which may still be queued
+				dbWriteChan <- worker.DBWriteJob{Replace: &worker.HashReplacement{
+					OldHash:  oldHash.Hex(),
+					NewHash:  newHash.Hex(),
+					GasPrice: req.GasFeeCap().String(),
+				}}
+			})
+		stuckMonitor.Start(2 * time.Second)
+		logger.Info("🔁 Escalating transactions stuck for %d blocks (max %d bumps)\n", config.StuckTxBlocks, config.StuckTxMaxBumps)
+	}
+
+	// Closed loop: hold sends while too many transactions are unmined
+	inflight := txpkg.NewInflightLimit(txSender, config.MaxInflight)
+	if inflight != nil {
+		inflight.Start(time.Second)
+		logger.Info("🔄 Closed loop: at most %d transactions in flight\n", config.MaxInflight)
+	}
+
+	// Steer fees towards the inclusion-latency target over long runs
+	var feeControl *txpkg.FeeController
+	if config.InclusionTarget > 0 {
+		feeControl = txpkg.NewFeeController(txSender, time.Duration(config.InclusionTarget*float64(time.Second)),
+			config.FeeControlStep, config.FeeControlMinLevel, config.FeeControlMaxLevel)
+		feeControl.Start(time.Second, time.Duration(config.FeeControlInterval)*time.Second)
+		logger.Info("🎯 Adapting fees to a %gs inclusion target (level %.2f–%.2f, ±%g%% every %ds)\n",
+			config.InclusionTarget, config.FeeControlMinLevel, config.FeeControlMaxLevel, config.FeeControlStep, config.FeeControlInterval)
+	}
+
+	hookRunner := hooks.NewRunner(config.PreBatchHook, config.PostBatchHook, time.Duration(config.HookTimeoutSeconds)*time.Second, db)
+	if config.PreBatchHook != "" || config.PostBatchHook != "" {
+		logger.Info("🪝 Batch hooks configured (pre: %q, post: %q)\n", config.PreBatchHook, config.PostBatchHook)
+	}
+
+	var gasEstimator *txpkg.GasEstimator
+	if config.GasEstimate || config.GasLimitOverride > 0 {
+		gasEstimator = txpkg.NewGasEstimator(txSender, config.GasEstimateMargin, config.GasLimitOverride)
+	}
+
+	// Cap worst-case spend so a run cannot drain faucet-funded wallets
+	maxSpend, ok := new(big.Int).SetString(config.MaxSpendWei, 10)
+	if !ok {
+		logger.Error("Invalid MAX_SPEND_WEI %q\n", config.MaxSpendWei)
+		os.Exit(1)
+	}
+	maxSpendWallet, ok := new(big.Int).SetString(config.MaxSpendWalletWei, 10)
+	if !ok {
+		logger.Error("Invalid MAX_SPEND_PER_WALLET_WEI %q\n", config.MaxSpendWalletWei)
+		os.Exit(1)
+	}
+	budget := txpkg.NewSpendBudget(maxSpend, maxSpendWallet)
+	if budget != nil {
+		logger.Info("💰 Spend budget: %s wei per run, %s wei per wallet (0 = unlimited)\n", maxSpend.String(), maxSpendWallet.String())
+	}
+
+	// Ctrl-C (or POST /abort) stops sending but still accounts for the batch
+	abort := newAbortController(config.ControlAddr)
+	defer abort.Close()
+
+	// Abort the same way once too many sends fail, e.g. the node died
+	errStop := newErrorStop(config.StopOnErrorRate, time.Duration(config.StopOnErrorWindow)*time.Second, config.StopOnErrorMin, abort)
+	if errStop != nil {
+		logger.Info("🧯 Stopping if more than %g%% of sends fail over %ds\n", config.StopOnErrorRate, config.StopOnErrorWindow)
+	}
+
+	// Call the webhook at the end of the run and whenever an alert
+	// threshold is crossed, and post the summary to chat
+	webhook, err := newWebhook(config)
+	if err != nil {
+		logger.Error("%v\n", err)
+		os.Exit(1)
+	}
+	chat, err := newChatNotifier(config, db)
+	if err != nil {
+		logger.Error("%v\n", err)
+		os.Exit(1)
+	}
+	alerts := newAlertWatcher(webhook, config.AlertErrorRate, config.AlertP95Latency, time.Duration(config.AlertWindow)*time.Second, config.AlertMinSamples)
+	if alerts != nil {
+		worker.AddObserver(alerts)
+		defer worker.RemoveObserver(alerts)
+		logger.Info("🚨 Alerting on %s over %ds\n", alertThresholds(config), config.AlertWindow)
+	}
+
+	// Print a stats line every PROGRESS_INTERVAL_SECONDS while the run goes on
+	var progressDB dbpkg.Store
+	if config.ProgressPersist {
+		progressDB = db
+	}
+	progress := newProgressReporter(time.Duration(config.ProgressInterval)*time.Second, progressDB)
+	if progress != nil {
+		worker.AddObserver(progress)
+		defer worker.RemoveObserver(progress)
+	}
+
+	// Loop for RUN_DURATION (or RUN_DURATION_MINUTES) instead of one batch
+	loopDuration, err := config.LoopDuration()
+	if err != nil {
+		logger.Error("Invalid RUN_DURATION: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Send the same workload through several providers in turn
+	var providers []string
+	if config.CompareRPCURLs != "" {
+		providers = splitList(config.CompareRPCURLs)
+		if loopDuration > 0 {
+			logger.Warn("COMPARE_RPC_URLS is set; ignoring RUN_DURATION_MINUTES\n")
+			loopDuration = 0
+		}
+		if broadcaster != nil {
+			logger.Warn("COMPARE_RPC_URLS is set; submitting through each provider instead of P2P_ENODE\n")
+		}
+		logger.Info("⚖️  Comparing %d providers, %d round(s) each, %s\n", len(providers), max(config.CompareRounds, 1), strings.ToLower(config.CompareOrder))
+	}
+
+	// Pace submissions at a constant rate across all wallets, along a ramp
+	// to find the rate at which inclusion latency degrades, with periodic
+	// spikes, or in stages
+	var limiter *rate.Limiter
+	var rateLag *rate.LagMonitor
+	var ramp *rate.Ramp
+	var spike *rate.Spike
+	var stages []rate.Stage
+	if config.SaturationSearch {
+		if config.TargetTPS > 0 || loopDuration > 0 {
+			logger.Warn("SATURATION_SEARCH is set; ignoring TARGET_TPS and RUN_DURATION_MINUTES\n")
+		}
+		limiter = rate.NewLimiter(config.SaturationStartTPS, config.TargetTPSBurst)
+		logger.Info("🔍 Searching for the saturation point from %g tx/s\n", config.SaturationStartTPS)
+	} else if config.LoadStages != "" {
+		stages, err = rate.ParseStages(config.LoadStages)
+		if err != nil {
+			logger.Error("Invalid LOAD_STAGES: %v\n", err)
+			os.Exit(1)
+		}
+		if config.TargetTPS > 0 || loopDuration > 0 {
+			logger.Warn("LOAD_STAGES is set; ignoring TARGET_TPS and RUN_DURATION_MINUTES\n")
+		}
+		limiter = rate.NewLimiter(stages[0].TPS, config.TargetTPSBurst)
+		logger.Info("🚦 Staircase load in %d stages: %s\n", len(stages), config.LoadStages)
+	} else if config.SpikeMultiplier > 0 {
+		spike = &rate.Spike{
+			Base:       config.TargetTPS,
+			Multiplier: config.SpikeMultiplier,
+			Length:     time.Duration(config.SpikeSeconds) * time.Second,
+			Every:      time.Duration(config.SpikeEvery) * time.Second,
+			Recovery:   time.Duration(max(config.SpikeRecovery, 0)) * time.Second,
+		}
+		limiter = rate.NewSpikeLimiter(spike, config.TargetTPSBurst)
+		if limiter == nil {
+			logger.Error("SPIKE_MULTIPLIER needs a positive TARGET_TPS and SPIKE_SECONDS shorter than SPIKE_EVERY_SECONDS\n")
+			os.Exit(1)
+		}
+		logger.Info("🚦 Pacing submissions at %g tx/s with %gx spikes for %ds every %ds\n",
+			config.TargetTPS, config.SpikeMultiplier, config.SpikeSeconds, config.SpikeEvery)
+	} else if config.RampDuration > 0 {
+		ramp = &rate.Ramp{Start: config.RampStartTPS, End: config.RampEndTPS, Duration: time.Duration(config.RampDuration) * time.Second}
+		limiter = rate.NewRampLimiter(ramp, config.TargetTPSBurst)
+		if limiter == nil {
+			logger.Error("RAMP_START_TPS and RAMP_END_TPS must be positive when RAMP_DURATION_SECONDS is set\n")
+			os.Exit(1)
+		}
+		if config.TargetTPS > 0 {
+			logger.Warn("Both TARGET_TPS and a ramp are set; following the ramp\n")
+		}
+		logger.Info("🚦 Ramping submissions from %g to %g tx/s over %ds\n", config.RampStartTPS, config.RampEndTPS, config.RampDuration)
+	} else if limiter = rate.NewLimiter(config.TargetTPS, config.TargetTPSBurst); limiter != nil {
+		rateLag = rate.NewLagMonitor(config.TargetTPS)
+		logger.Info("🚦 Pacing submissions at %g tx/s (burst %d)\n", config.TargetTPS, max(config.TargetTPSBurst, 1))
+	}
+
+	if limiter != nil {
+		if err := limiter.SetDistribution(config.SendDistribution, config.SendBurstSize); err != nil {
+			logger.Error("Invalid SEND_DISTRIBUTION: %v\n", err)
+			os.Exit(1)
+		}
+		if dist := strings.ToLower(config.SendDistribution); dist != rate.DistributionUniform {
+			logger.Info("🎲 Send distribution: %s\n", dist)
+		}
+	} else if !strings.EqualFold(config.SendDistribution, rate.DistributionUniform) {
+		logger.Warn("SEND_DISTRIBUTION only applies with TARGET_TPS, a ramp or LOAD_STAGES; sending as fast as possible\n")
+	}
+
+	// Cap each wallet's own rate, independently of the global pace
+	var walletLimiters []*rate.Limiter
+	if config.WalletTPS > 0 {
+		walletLimiters = make([]*rate.Limiter, len(wallets))
+		for i := range walletLimiters {
+			walletLimiters[i] = rate.NewLimiter(config.WalletTPS, 1)
+		}
+		logger.Info("🚦 Pacing each wallet at up to %g tx/s\n", config.WalletTPS)
+	}
+
+	runStart := time.Now()
+	runID := startRun(config, db, txSender, runStart)
+	balances := newBalanceRecorder(config, txSender, db, runID, wallets)
+	topUps.Start()
+
+	run := &runState{
+		gasRefresher: gasRefresher,
+		gasEstimator: gasEstimator,
+		feeControl:   feeControl,
+		nonces:       nonces,
+		budget:       budget,
+		abort:        abort,
+		errStop:      errStop,
+		stuckMonitor: stuckMonitor,
+		inflight:     inflight,
+		hooks:        hookRunner,
+		load:         load,
+		wallets:      wallets,
+		dbWriteChan:  dbWriteChan,
+		dbWriteWG:    &dbWriteWG,
+		limiter:      limiter,
+		walletLimits: walletLimiters,
+		rateLag:      rateLag,
+		spike:        spike,
+		chat:         chat,
+		topUps:       topUps,
+		runID:        runID,
+	}
+
+	var batches []string
+	var stageResults []stageBatches
+	var probes []*saturationProbe
+	var search *rate.SaturationSearch
+	var soakIntervals []*soakInterval
+	var providerResults []providerBatches
+	var mode string
+
+	// Check if we should compare providers, search, or run in staged or loop mode
+	if len(providers) > 0 {
+		mode = "compare"
+		fmt.Printf("Running a PROVIDER COMPARISON (%d providers)\n", len(providers))
+		fmt.Println()
+		batches, providerResults = runComparison(config, run, providers)
+	} else if config.SaturationSearch {
+		mode = "saturation"
+		fmt.Println("Running a SATURATION SEARCH")
+		fmt.Println()
+		batches, probes, search = runSaturationSearch(config, broadcaster, run, db, wsManager)
+	} else if len(stages) > 0 {
+		mode = "staged"
+		fmt.Printf("Running in STAGED MODE (%d stages)\n", len(stages))
+		fmt.Println()
+		batches, stageResults = runInStagedMode(config, broadcaster, run, stages)
+	} else if loopDuration > 0 && config.SoakIntervalMinutes > 0 {
+		mode = "soak"
+		fmt.Printf("Running a SOAK TEST for %s with %d-minute intervals\n", loopDuration, config.SoakIntervalMinutes)
 		fmt.Println()
-		runInLoopMode(config, wallets, dbWriteChan, &dbWriteWG)
+		batches, soakIntervals = runSoak(config, broadcaster, run, db, wsManager, loopDuration)
+	} else if loopDuration > 0 {
+		mode = "loop"
+		fmt.Printf("Running in LOOP MODE for %s\n", loopDuration)
+		fmt.Println()
+		batches = runInLoopMode(config, broadcaster, run, loopDuration)
 	} else {
+		mode = "single"
 		fmt.Println("Running in SINGLE MODE")
 		fmt.Println()
 
 		executionStart := time.Now()
 
-		runSingleExecution(config, txSender, wallets, dbWriteChan, &dbWriteWG)
+		batchNumber, _ := runSingleExecution(config, txSender, run)
+		batches = append(batches, batchNumber)
 
 		// Calculate elapsed time and ensure minimum 1 second
 		executionElapsed := time.Since(executionStart)
@@ -235,152 +820,693 @@ func main() {
 		}
 	}
 
+	if gasRefresher != nil {
+		gasRefresher.Stop()
+	}
+	if budget != nil {
+		fmt.Printf("💰 Spend budget: %s wei committed (worst case) of %s wei per run\n", budget.Spent().String(), maxSpend.String())
+	}
+	if stuckMonitor != nil {
+		replaced, abandoned := stuckMonitor.Stop()
+		fmt.Printf("🔁 Stuck transactions: %d replacements sent, %d could not be escalated further\n", replaced, abandoned)
+	}
+	if inflight != nil {
+		peak, waited := inflight.Stop()
+		fmt.Printf("🔄 Closed loop: peak %d of %d transactions in flight, senders waited %s for confirmations\n",
+			peak, config.MaxInflight, waited.Round(time.Millisecond))
+	}
+	if n := nonces.Resyncs(); n > 0 {
+		fmt.Printf("🔢 Nonces resynced from the node %d times\n", n)
+	}
+	if feeControl != nil {
+		level, raised, lowered := feeControl.Stop()
+		fmt.Printf("🎯 Adaptive fees: final level %.2f (raised %d times, lowered %d times)\n", level, raised, lowered)
+	}
+	if topUps != nil {
+		sent, failed, total := topUps.Stop()
+		fmt.Printf("💧 Mid-run top-ups: %d sent with %s wei, %d failed\n", sent, total.String(), failed)
+	}
+
 	// Close channels to signal workers to exit
 	fmt.Println("\nClosing worker channels...")
 	close(dbWriteChan)
 	dbWriteWG.Wait() // Wait for DB writers to finish
 	fmt.Println("✓ All database writes completed")
 
-	receiptJobChan := make(chan worker.ReceiptJob, receiptBufferSize)
-
-	// Start worker pools
-	worker.StartReceiptWorkerPool(config.ReceiptWorkers, receiptJobChan, &receiptWG, wsManager, db, txSender)
-	logger.Info("📋 Started %d receipt confirmation workers\n", config.ReceiptWorkers)
-
-	// Queue pending transactions for receipt processing
-	if err := worker.QueuePendingTransactionsForReceipt(db, receiptJobChan); err != nil {
-		logger.Error("Error queuing pending transactions: %v\n", err)
+	// Receipt workers claim pending transactions straight from the database
+	countCtx, countCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	if pending, err := db.GetPendingTransactionCount(countCtx); err == nil {
+		fmt.Printf("\nConfirming %d pending transactions...\n", pending)
 	}
+	countCancel()
+	worker.StartReceiptWorkerPool(config.ReceiptWorkers, &receiptWG, wsManager, db, txSender)
+	logger.Info("📋 Started %d receipt confirmation workers\n", config.ReceiptWorkers)
 
-	close(receiptJobChan)
 	fmt.Println("Waiting for receipt confirmations to finish...")
-	receiptWG.Wait() // Wait for all receipt confirmations to finish
-	fmt.Println("✓ All receipt confirmations completed")
+	receiptsDone := make(chan struct{})
+	go func() {
+		receiptWG.Wait() // Wait for all receipt confirmations to finish
+		close(receiptsDone)
+	}()
+	select {
+	case <-receiptsDone:
+		fmt.Println("✓ All receipt confirmations completed")
+	case <-abort.Done():
+		// An aborted run only drains confirmations for the grace period
+		grace := time.Duration(config.AbortGraceSeconds) * time.Second
+		fmt.Printf("Draining receipt confirmations for up to %s...\n", grace)
+		select {
+		case <-receiptsDone:
+			fmt.Println("✓ All receipt confirmations completed")
+		case <-time.After(grace):
+			fmt.Println("⚠️  Grace period expired; unconfirmed transactions are left pending")
+		}
+	}
 
-	// Final summary
-	fmt.Println()
-	fmt.Println(strings.Repeat("=", 60))
-	fmt.Println("✓ All executions completed")
-	fmt.Printf("✓ Mnemonic saved to: mnemonic.txt\n")
-	fmt.Printf("✓ Database: %s\n", config.DBPath)
-	fmt.Println(strings.Repeat("=", 60))
-}
+	progress.Stop()
+	balances.Stop()
 
-func runInLoopMode(config *config.Config, wallets []*wallet.Wallet, dbWriteChan chan worker.DBWriteJob, dbWriteWG *sync.WaitGroup) {
-	duration := time.Duration(config.RunDurationMinutes) * time.Minute
-	startTime := time.Now()
-	endTime := startTime.Add(duration)
-	iteration := 0
+	if blockRecorder != nil {
+		fmt.Printf("📦 Recorded %d blocks to block_metrics\n", blockRecorder.Stop())
+	}
 
-	fmt.Printf("Loop started at: %s\n", startTime.Format("15:04:05"))
-	fmt.Printf("Will run until: %s\n", endTime.Format("15:04:05"))
-	fmt.Println(strings.Repeat("=", 60))
+	if rateLag != nil {
+		lagReport := rateLag.Report()
+		rate.PrintLagReport(lagReport)
+		if lagReport.GeneratorLimited() {
+			logger.Warn("Generator-limited: achieved %.2f tx/s of TARGET_TPS %.2f (scheduler lag grew %.3fs/s)\n",
+				lagReport.AchievedRate, lagReport.RequestedRate, lagReport.LagGrowth)
+		}
+	}
 
-	for time.Now().Before(endTime) {
-		iteration++
-		remainingTime := time.Until(endTime)
-		fmt.Printf("\n\n[ITERATION #%d] Time remaining: %.1f minutes\n", iteration, remainingTime.Minutes())
-		fmt.Println(strings.Repeat("-", 60))
+	if abort.Aborted() {
+		printPartialReport(db, batches)
+	}
 
-		// Record start time for this iteration
-		iterationStart := time.Now()
+	if errStop != nil {
+		errStop.Print()
+	}
 
-		txSender, err := txpkg.NewTransactionSender(config.RPCURL)
-		if err != nil {
-			logger.Error("Error connecting to RPC: %v\n", err)
-			os.Exit(1)
+	if failover != nil {
+		failover.StopHealthChecks()
+		if config.RPCHealthInterval > 0 {
+			printEndpointHealth(failover.Health())
 		}
-		runSingleExecution(config, txSender, wallets, dbWriteChan, dbWriteWG)
-		txSender.Close()
-		// Calculate elapsed time and ensure minimum 1 second per iteration
-		iterationElapsed := time.Since(iterationStart)
-		minDuration := 990 * time.Millisecond
-
-		if iterationElapsed < minDuration {
-			remainingSleep := minDuration - iterationElapsed
-			fmt.Printf("\n⏱  Iteration completed in %.3f seconds. Waiting %.3f seconds to maintain 1-second minimum...\n",
-				iterationElapsed.Seconds(), remainingSleep.Seconds())
-			time.Sleep(remainingSleep)
-		} else {
-			fmt.Printf("\n⏱  Iteration completed in %.3f seconds\n", iterationElapsed.Seconds())
+		if failover.Failovers() > 0 {
+			logger.Warn("RPC failed over %d time(s) during the run; ended on %s\n", failover.Failovers(), failover.Active())
 		}
 	}
 
-	totalDuration := time.Since(startTime)
+	repairNonceGaps(config, txSender, db, run, batches)
+
+	printCostSummary(db, batches)
+
+	printLatencySummary(db, batches)
+
+	printBlockInclusion(db, batches)
+
+	if config.HistogramBucket > 0 {
+		printLatencyHistogram(db, batches, config.HistogramBucket)
+	}
+
+	if config.BlockTPS {
+		printBlockTPS(db, txSender, batches)
+	}
+
+	if config.TPSSeriesSeconds > 0 {
+		storeTPSSeries(db, batches, time.Duration(config.TPSSeriesSeconds)*time.Second)
+	}
+
+	var weights map[string]int
+	if failover != nil && config.SubmitRPCURL == "" {
+		weights = failover.Weights()
+	}
+	printEndpointStats(db, batches, weights)
+
+	printWalletStats(db, batches)
+
+	balances.print(batches)
+
+	printErrorCategories(db, batches)
+
+	if timing := strings.ToLower(config.SubmissionTiming); timing != "" && timing != "immediate" {
+		printInclusionSummary(db, batches)
+	}
+
+	if ramp != nil {
+		printRampReport(db, batches, ramp)
+	}
+
+	if spike != nil {
+		printSpikeReport(db, batches, spike)
+	}
+
+	if config.WalletTPS > 0 {
+		printWalletRates(db, batches, config.WalletTPS)
+	}
+
+	if len(stageResults) > 0 {
+		printStageReport(db, stageResults)
+	}
+
+	if mode == "loop" && len(batches) > 1 {
+		printIterationReport(db, batches)
+	}
+
+	if len(providerResults) > 0 {
+		printComparisonReport(db, providerResults)
+	}
+
+	if search != nil {
+		printSaturationReport(config, probes, search)
+	}
+
+	if len(soakIntervals) > 0 {
+		printSoakReport(db, soakIntervals)
+	}
+
+	if config.BeaconAPIURL != "" {
+		printSlotTimingReport(config, db, batches, payloadBaseline)
+	}
+
+	alerts.Stop()
+	slaFailed := false
+	if config.SummaryJSON != "" || webhook != nil || chat != nil || slaConfigured(config) {
+		summary := buildRunSummary(config, db, batches, mode, runStart, abort.Aborted())
+		summary.Skipped = skipped
+		if slaConfigured(config) {
+			summary.SLA = evaluateSLA(config, summary.Totals, summary.Aborted)
+			printSLAReport(summary.SLA)
+			slaFailed = !summary.SLA.Passed
+		}
+		if config.SummaryJSON != "" {
+			writeSummaryJSON(config.SummaryJSON, summary)
+		}
+		if webhook != nil {
+			notifyRunCompleted(webhook, summary)
+		}
+		chat.Run(summary)
+	}
+
+	stopTracing()
+	stopMetrics()
+
+	finishRun(db, runID, mode, abort.Aborted())
+	if config.DBDumpPath != "" {
+		dumpDatabase(db, config.DBDumpPath)
+	}
+
+	// Final summary
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("✓ All executions completed")
+	if len(skipped) > 0 {
+		fmt.Printf("⚠️  %d unfunded wallets were skipped:\n", len(skipped))
+		for _, w := range skipped {
+			fmt.Printf("   [%d] %s (%s wei, needed up to %s wei)\n", w.Index, w.Address, w.Balance, w.Needed)
+		}
+	}
+	if mnemonicSaved {
+		fmt.Printf("✓ Mnemonic saved to: %s\n", mnemonicFileName)
+	}
+	if config.DBPath != dbpkg.MemoryPath {
+		fmt.Printf("✓ Database: %s\n", config.DBPath)
+	}
+	fmt.Println(strings.Repeat("=", 60))
+
+	if slaFailed {
+		db.Close()
+		os.Exit(exitSLAFailed)
+	}
+}
+
+// startTracing sets up span export when TRACE_OTLP_ENDPOINT is set. The
+// returned function flushes the spans still buffered.
+func startTracing(config *config.Config) (func(), error) {
+	if config.TraceEndpoint == "" {
+		return func() {}, nil
+	}
+	headers, err := txpkg.ParseHeaders(config.TraceHeaders, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRACE_OTLP_HEADERS: %w", err)
+	}
+	shutdown, err := tracing.Setup(config.TraceEndpoint, headers, config.TraceSampleRate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tracing settings: %w", err)
+	}
+	logger.Info("✓ Tracing %g%% of transactions to %s\n", config.TraceSampleRate*100, config.TraceEndpoint)
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			logger.Warn("Could not flush trace spans: %v\n", err)
+		}
+	}, nil
+}
+
+// startMetrics starts pushing transaction metrics to INFLUX_URL, if set, by
+// observing the worker pools. The returned function writes the points still
+// buffered.
+func startMetrics(config *config.Config) (func(), error) {
+	if config.InfluxURL == "" {
+		return func() {}, nil
+	}
+	sink, err := metrics.NewSink(metrics.Target{
+		URL:      config.InfluxURL,
+		Token:    config.InfluxToken,
+		Org:      config.InfluxOrg,
+		Bucket:   config.InfluxBucket,
+		Database: config.InfluxDatabase,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid InfluxDB settings: %w", err)
+	}
+	worker.AddObserver(sink)
+	logger.Info("✓ Pushing transaction metrics to %s\n", config.InfluxURL)
+	return func() {
+		worker.RemoveObserver(sink)
+		sink.Close()
+	}, nil
+}
+
+// runState holds the long-lived components shared by every batch of a run.
+type runState struct {
+	gasRefresher *txpkg.GasPriceRefresher
+	gasEstimator *txpkg.GasEstimator
+	feeControl   *txpkg.FeeController
+	nonces       *txpkg.NonceManager
+	budget       *txpkg.SpendBudget
+	abort        *abortController
+	errStop      *errorStop // nil = never stop on failed sends
+	stuckMonitor *txpkg.StuckMonitor
+	inflight     *txpkg.InflightLimit // nil = open loop
+	hooks        *hooks.Runner
+	load         workload.Workload
+	wallets      []*wallet.Wallet
+	dbWriteChan  chan worker.DBWriteJob
+	dbWriteWG    *sync.WaitGroup
+	limiter      *rate.Limiter    // nil = send as fast as possible
+	walletLimits []*rate.Limiter  // per wallet index; nil = no per-wallet cap
+	rateLag      *rate.LagMonitor // scheduled vs actual sends under limiter
+	spike        *rate.Spike      // tags transactions with their spike phase; nil = no spike profile
+	chat         *chatNotifier    // nil = no chat summaries
+	topUps       *topUpMonitor    // nil = no mid-run top-ups
+	runID        int64            // runs table ID the transactions belong to; 0 = not recorded
+	batchLabel   string           // appended to batch numbers, e.g. the load stage
+	streamUntil  time.Time        // streaming: wallets keep sending until then (zero = one chunk each)
+}
+
+// mnemonicFileName is where a run saves its mnemonic (SAVE_MNEMONIC).
+const mnemonicFileName = "mnemonic.txt"
+
+// maxNonceResyncs bounds how often one wallet's batch is renumbered after
+// nonce errors before the failing transaction is given up on.
+const maxNonceResyncs = 3
+
+// maxReprices bounds how often one transaction is re-signed at a higher price
+// after underpriced errors before it is given up on.
+const maxReprices = 3
+
+// Loop mode pacing strategies.
+const (
+	loopPacingInterval = "interval" // fixed start-to-start period
+	loopPacingGap      = "gap"      // fixed pause from one iteration's end to the next start
+	loopPacingStream   = "stream"   // one batch, every wallet sends until the end
+)
+
+func runInLoopMode(config *config.Config, broadcaster *txpkg.P2PBroadcaster, run *runState, duration time.Duration) []string {
+	if strings.EqualFold(config.LoopPacing, loopPacingStream) {
+		return runStreaming(config, broadcaster, run, duration)
+	}
+
+	wallets := run.wallets
+	startTime := time.Now()
+	endTime := startTime.Add(duration)
+	iteration := 0
+	var batches []string
+	label := run.batchLabel // e.g. the soak interval
+
+	// With interval pacing iteration n is scheduled at start + n×interval and
+	// the full batch is meant to go out within that interval. Gap pacing
+	// instead pauses for the interval after each iteration ends.
+	pacing := strings.ToLower(config.LoopPacing)
+	if pacing != loopPacingInterval && pacing != loopPacingGap {
+		logger.Warn("Unknown LOOP_PACING %q, using %s\n", config.LoopPacing, loopPacingInterval)
+		pacing = loopPacingInterval
+	}
+	iterationInterval := time.Duration(config.MinIterationSeconds * float64(time.Second))
+	if iterationInterval <= 0 {
+		logger.Warn("MIN_ITERATION_SECONDS must be positive, using 1s\n")
+		iterationInterval = time.Second
+	}
+	requestedRate := float64(len(wallets)*config.TxPerWallet) / iterationInterval.Seconds()
+	lagMonitor := rate.NewLagMonitor(requestedRate)
+
+	fmt.Printf("Loop started at: %s\n", startTime.Format("15:04:05"))
+	fmt.Printf("Will run until: %s\n", endTime.Format("15:04:05"))
+	fmt.Printf("Pacing: %s, %.3fs\n", pacing, iterationInterval.Seconds())
+	fmt.Println(strings.Repeat("=", 60))
+
+	outOfFunds := false
+	for time.Now().Before(endTime) {
+		if run.abort.Aborted() {
+			break
+		}
+		if budgetSpent(run) {
+			fmt.Println("\n💰 Spend budget exhausted. Stopping.")
+			break
+		}
+
+		txSender, err := newTransactionSender(config, broadcaster)
+		if err != nil {
+			logger.Error("Error connecting to RPC: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Stop before the wallets start failing with insufficient funds,
+		// unless the top-ups can refill them
+		run.topUps.Check()
+		if config.BudgetCheck {
+			balance, cost, err := checkBudget(config, txSender, run)
+			if err != nil {
+				logger.Warn("Could not check wallet balances, continuing: %v\n", err)
+			} else if balance.Cmp(cost) < 0 {
+				fmt.Printf("\n💸 Wallets hold %s wei in total, but one more iteration may cost up to %s wei. Stopping.\n",
+					balance.String(), cost.String())
+				txSender.Close()
+				outOfFunds = true
+				break
+			}
+		}
+
+		iteration++
+		remainingTime := time.Until(endTime)
+		fmt.Printf("\n\n[ITERATION #%d] Time remaining: %.1f minutes\n", iteration, remainingTime.Minutes())
+		fmt.Println(strings.Repeat("-", 60))
+
+		// Record start time for this iteration
+		iterationStart := time.Now()
+
+		// Label the batch so iterations starting within the same second
+		// still get their own
+		run.batchLabel = strings.TrimPrefix(fmt.Sprintf("%s-iter%d", label, iteration), "-")
+		batchNumber, submitted := runSingleExecution(config, txSender, run)
+		run.batchLabel = label
+		batches = append(batches, batchNumber)
+		txSender.Close()
+		run.chat.Iteration(batchNumber, iteration)
+		iterationElapsed := time.Since(iterationStart)
+
+		if pacing == loopPacingGap {
+			fmt.Printf("\n⏱  Iteration completed in %.3f seconds. Pausing %.3f seconds...\n",
+				iterationElapsed.Seconds(), iterationInterval.Seconds())
+			run.abort.Sleep(iterationInterval)
+			continue
+		}
+
+		// Interval pacing: start the next iteration on schedule, or at once
+		// if this one overran (the lag monitor reports sustained overruns).
+		nextStart := startTime.Add(time.Duration(iteration) * iterationInterval)
+		lagMonitor.Observe(nextStart, time.Now(), submitted)
+		if wait := time.Until(nextStart); wait > 0 {
+			fmt.Printf("\n⏱  Iteration completed in %.3f seconds. Waiting %.3f seconds for the next %.3fs interval...\n",
+				iterationElapsed.Seconds(), wait.Seconds(), iterationInterval.Seconds())
+			run.abort.Sleep(wait)
+		} else {
+			fmt.Printf("\n⏱  Iteration completed in %.3f seconds\n", iterationElapsed.Seconds())
+		}
+	}
+
+	totalDuration := time.Since(startTime)
 	fmt.Println()
 	fmt.Println(strings.Repeat("=", 60))
 	fmt.Printf("=== LOOP MODE COMPLETED ===")
 	fmt.Println()
 	fmt.Printf("Total iterations: %d\n", iteration)
+	if outOfFunds {
+		fmt.Printf("Stopped early: wallet funds ran out after %d iterations within budget\n", iteration)
+	}
+	if run.abort.Aborted() {
+		fmt.Printf("Stopped early: aborted during iteration %d\n", iteration)
+	}
 	fmt.Printf("Total duration: %.2f minutes\n", totalDuration.Minutes())
 	fmt.Println(strings.Repeat("=", 60))
+
+	// Gap pacing has no schedule to fall behind, and TARGET_TPS replaces the
+	// iteration schedule with its own
+	if pacing == loopPacingInterval && run.limiter == nil {
+		lagReport := lagMonitor.Report()
+		rate.PrintLagReport(lagReport)
+		if lagReport.GeneratorLimited() {
+			logger.Warn("Generator-limited: achieved %.2f tx/s of %.2f tx/s requested (scheduler lag grew %.3fs/s)\n",
+				lagReport.AchievedRate, lagReport.RequestedRate, lagReport.LagGrowth)
+		}
+	}
+
+	return batches
+}
+
+// printIterationReport compares loop mode's iterations, one batch each,
+// against the first ones to show degradation over the run.
+func printIterationReport(db dbpkg.Store, batches []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	points := make([]report.TrendPoint, 0, len(batches))
+	for _, batch := range batches {
+		txs, err := db.GetBatchTransactions(ctx, batch)
+		if err != nil {
+			logger.Warn("Could not load transactions for %s: %v\n", batch, err)
+			continue
+		}
+		points = append(points, report.BuildTrendPoint(batch, txs))
+	}
+	report.PrintIterations(points)
+}
+
+// runStreaming sends a single batch for the whole duration: every wallet
+// keeps preparing and sending TX_PER_WALLET transactions at a time until the
+// end, so no wallet idles while the next iteration's batch is set up.
+func runStreaming(config *config.Config, broadcaster *txpkg.P2PBroadcaster, run *runState, duration time.Duration) []string {
+	txSender, err := newTransactionSender(config, broadcaster)
+	if err != nil {
+		logger.Error("Error connecting to RPC: %v\n", err)
+		os.Exit(1)
+	}
+	defer txSender.Close()
+
+	startTime := time.Now()
+	run.streamUntil = startTime.Add(duration)
+	defer func() { run.streamUntil = time.Time{} }()
+
+	fmt.Printf("Streaming started at: %s\n", startTime.Format("15:04:05"))
+	fmt.Printf("Will run until: %s\n", run.streamUntil.Format("15:04:05"))
+	fmt.Println(strings.Repeat("=", 60))
+
+	batchNumber, submitted := runSingleExecution(config, txSender, run)
+
+	totalDuration := time.Since(startTime)
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("=== STREAMING COMPLETED ===")
+	fmt.Println()
+	fmt.Printf("Transactions submitted: %d (%.2f tx/s)\n", submitted, float64(submitted)/totalDuration.Seconds())
+	if run.abort.Aborted() {
+		fmt.Println("Stopped early: aborted")
+	}
+	fmt.Printf("Total duration: %s\n", totalDuration.Round(time.Second))
+	fmt.Println(strings.Repeat("=", 60))
+
+	return []string{batchNumber}
+}
+
+// budgetSpent reports whether the spend budget leaves no wallet able to send.
+func budgetSpent(run *runState) bool {
+	if run.budget == nil {
+		return false
+	}
+	if run.budget.Exhausted() {
+		return true
+	}
+	for _, w := range run.wallets {
+		if !run.budget.WalletExhausted(w.Address) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkBudget returns the wallets' combined pending balance and the most one
+// more iteration could cost them: value plus gas limit times the max fee per
+// gas, which is what the node checks before accepting each transaction, plus
+// the L1 data fee on rollups.
+func checkBudget(config *config.Config, txSender *txpkg.TransactionSender, run *runState) (balance, cost *big.Int, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.ContextTimeout)*time.Second)
+	defer cancel()
+
+	baseFee, tip, err := batchFees(ctx, config, txSender, run.gasRefresher)
+	if err != nil {
+		return nil, nil, err
+	}
+	costs, err := batchCosts(ctx, config, txSender, run.load, run.wallets, txSender.MaxFeePerGas(baseFee, tip))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	balance, cost = new(big.Int), new(big.Int)
+	for idx, w := range run.wallets {
+		walletBalance, err := txSender.GetPendingBalance(ctx, w.Address)
+		if err != nil {
+			return nil, nil, err
+		}
+		balance.Add(balance, walletBalance)
+		cost.Add(cost, costs[idx])
+	}
+	return balance, cost, nil
+}
+
+// batchFees returns the base fee the next batch is priced from, as the
+// wallets will see it (GAS_PRICE_MULTIPLIER and MIN_GAS_PRICE applied), and
+// the tip. gasRefresher may be nil.
+func batchFees(ctx context.Context, config *config.Config, txSender *txpkg.TransactionSender, gasRefresher *txpkg.GasPriceRefresher) (baseFee, tip *big.Int, err error) {
+	baseFee = new(big.Int)
+	if gasRefresher != nil && gasRefresher.BaseFee() != nil {
+		baseFee.Set(gasRefresher.BaseFee())
+	} else {
+		feeHistory, err := txSender.FeeHistory(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		baseFee.Set(feeHistory.BaseFee[len(feeHistory.BaseFee)-1])
+	}
+	if config.GasPriceMultiplier != 1.0 {
+		baseFee, _ = new(big.Float).Mul(new(big.Float).SetInt(baseFee), big.NewFloat(config.GasPriceMultiplier)).Int(nil)
+	}
+	if minGasPrice, ok := new(big.Int).SetString(config.MinGasPrice, 10); ok && baseFee.Cmp(minGasPrice) < 0 {
+		baseFee = minGasPrice
+	}
+	tip, err = gweiToWei(config.PriorityFeeGwei)
+	if err != nil {
+		return nil, nil, err
+	}
+	return baseFee, tip, nil
+}
+
+// batchCosts returns the most one batch of load could cost each wallet at
+// maxFee per gas: value plus gas limit times maxFee for every transaction,
+// plus the L1 data fee on rollups that charge it on top of the gas.
+func batchCosts(ctx context.Context, config *config.Config, txSender *txpkg.TransactionSender, load workload.Workload, wallets []*wallet.Wallet, maxFee *big.Int) ([]*big.Int, error) {
+	l1Fees := make(map[string]*big.Int)
+	costs := make([]*big.Int, len(wallets))
+	for idx := range wallets {
+		cost := new(big.Int)
+		for _, call := range load.Calls(idx, config.TxPerWallet) {
+			gasLimit := config.GasLimit
+			if config.GasLimitOverride > 0 {
+				gasLimit = config.GasLimitOverride
+			} else if call.GasLimit != 0 {
+				gasLimit = call.GasLimit
+			}
+			cost.Add(cost, new(big.Int).Mul(maxFee, new(big.Int).SetUint64(gasLimit)))
+			if call.Value != nil {
+				cost.Add(cost, call.Value)
+			}
+
+			// OP stack rollups charge an L1 data fee on top of execution
+			// gas; on Arbitrum the gas limit already covers it
+			if txSender.L1FeeInGas() {
+				continue
+			}
+			key := call.To.Hex() + ":" + common.Bytes2Hex(call.Data)
+			l1Fee, ok := l1Fees[key]
+			if !ok {
+				var err error
+				l1Fee, err = txSender.EstimateL1Fee(ctx, call.To, call.Value, call.Data)
+				if err != nil {
+					return nil, err
+				}
+				l1Fees[key] = l1Fee
+			}
+			cost.Add(cost, l1Fee)
+		}
+		costs[idx] = cost
+	}
+	return costs, nil
 }
 
-func runSingleExecution(config *config.Config, txSender *txpkg.TransactionSender, wallets []*wallet.Wallet, dbWriteChan chan worker.DBWriteJob, dbWriteWG *sync.WaitGroup) {
+// runSingleExecution submits one batch and returns its batch number and the
+// number of transactions accepted by the node.
+func runSingleExecution(config *config.Config, txSender *txpkg.TransactionSender, run *runState) (string, int) {
+	gasRefresher, stuckMonitor, load, wallets, dbWriteChan := run.gasRefresher, run.stuckMonitor, run.load, run.wallets, run.dbWriteChan
+
 	// Lock submission mutex to pause all workers during transaction submission
 	logger.Debug("🔒 Submission phase started - workers paused\n")
 
 	// Generate unique batch number for this execution
 	batchNumber := fmt.Sprintf("batch-%s", time.Now().Format("20060102-150405"))
+	timing := strings.ToLower(config.SubmissionTiming)
+	if timing != "" && timing != "immediate" {
+		batchNumber += "-" + timing
+	}
+	if run.batchLabel != "" {
+		batchNumber += "-" + run.batchLabel
+	}
 	fmt.Printf("Batch Number: %s\n\n", batchNumber)
 
+	run.hooks.Run(hooks.PreBatch, batchNumber, map[string]string{
+		"workload": load.Name(),
+		"wallets":  strconv.Itoa(len(wallets)),
+		"txs":      strconv.Itoa(len(wallets) * config.TxPerWallet),
+	})
+
 	// Parse configuration values
 	value := new(big.Int)
 	value.SetString(config.ValueWei, 10)
 	toAddress := common.HexToAddress(config.ToAddress)
 
 	logger.Info("\nTransaction Configuration:\n")
+	logger.Info("  - Workload: %s\n", load.Name())
 	logger.Info("  - Number of wallets: %d\n", len(wallets))
 	logger.Info("  - Transactions per wallet: %d\n", config.TxPerWallet)
 	logger.Info("  - Total transactions: %d\n", len(wallets)*config.TxPerWallet)
-	logger.Info("  - Target address: %s\n", toAddress.Hex())
+	if t, ok := load.(*workload.Transfer); ok && len(t.Receivers) > 0 {
+		logger.Info("  - Target addresses: %d receivers at %s\n", len(t.Receivers), config.ReceiverPath)
+	} else {
+		logger.Info("  - Target address: %s\n", toAddress.Hex())
+	}
 	logger.Info("  - Value per tx: %s wei\n", value.String())
+	logger.Info("  - Gas price multiplier: %.2fx\n", config.GasPriceMultiplier)
+	logger.Info("  - Priority fee: %g gwei (bump strategy: %s)\n", config.PriorityFeeGwei, config.FeeBumpStrategy)
+	if config.MaxGasPriceWei != "0" && config.MaxGasPriceWei != "" {
+		logger.Info("  - Max gas price: %s wei\n", config.MaxGasPriceWei)
+	}
+	switch {
+	case config.GasLimitOverride > 0:
+		logger.Info("  - Gas limit: %d (override)\n", config.GasLimitOverride)
+	case config.GasEstimate:
+		logger.Info("  - Gas limit: estimated +%g%% (fallback %d)\n", config.GasEstimateMargin, config.GasLimit)
+	default:
+		logger.Info("  - Gas limit: %d\n", config.GasLimit)
+	}
 	logger.Info("\n")
 
-	// Gas price adjustment mechanism for underpriced errors
-	var gasPriceMultiplier float64 = 1.0
-	var gasPriceMutex sync.RWMutex
-
-	// Function to get current adjusted gas price
-	getAdjustedGasPrice := func(baseGasPrice *big.Int) *big.Int {
-		gasPriceMutex.RLock()
-		multiplier := gasPriceMultiplier
-		gasPriceMutex.RUnlock()
-
-		if multiplier == 1.0 {
-			return baseGasPrice
-		}
-
-		// Apply multiplier: basePrice * multiplier
-		multiplierBig := big.NewFloat(multiplier)
-		basePriceFloat := new(big.Float).SetInt(baseGasPrice)
-		adjustedFloat := new(big.Float).Mul(basePriceFloat, multiplierBig)
-
-		adjustedPrice, _ := adjustedFloat.Int(nil)
-
-		// if adjusted price is lower than 2 gwei then assign 2 gwei to avoid underpriced errors
-		minGasPrice := big.NewInt(2000000000) // 2 gwei
-		if adjustedPrice.Cmp(minGasPrice) < 0 {
-			return minGasPrice
-		}
-
-		return adjustedPrice
+	// Fee adjustment mechanism for underpriced errors
+	priorityFee, err := gweiToWei(config.PriorityFeeGwei)
+	if err != nil {
+		logger.Error("Invalid PRIORITY_FEE_GWEI: %v\n", err)
+		return batchNumber, 0
 	}
-
-	// Function to increase gas price due to underpriced errors
-	increaseGasPrice := func() {
-		gasPriceMutex.Lock()
-		defer gasPriceMutex.Unlock()
-
-		oldMultiplier := gasPriceMultiplier
-		gasPriceMultiplier *= 1.10 // Increase by 10%
-
-		logger.Warn("Gas price multiplier increased from %.2f to %.2f due to underpriced errors\n",
-			oldMultiplier, gasPriceMultiplier)
+	feeBumper, err := txpkg.NewFeeBumper(strings.ToLower(config.FeeBumpStrategy), config.FeeBumpPercent, config.GasPriceMultiplier, priorityFee)
+	if err != nil {
+		logger.Error("Invalid fee bump settings: %v\n", err)
+		return batchNumber, 0
 	}
 
 	// Use mutex for thread-safe counter updates
 	var wgSubmit sync.WaitGroup // Wait for transaction submissions only
+	var submitted atomic.Int64
+	var panicked atomic.Int64
+	var repriced atomic.Int64
 	// Receipt confirmations happen in background, we don't wait for them
 
 	fmt.Println("Starting transaction submission...")
@@ -389,14 +1515,39 @@ func runSingleExecution(config *config.Config, txSender *txpkg.TransactionSender
 	ctx, wCancel := context.WithTimeout(context.Background(), time.Duration(config.ContextTimeout)*time.Second)
 	defer wCancel()
 
-	feeHistory, feeErr := txSender.FeeHistory(ctx)
-
 	var currentBaseFee *big.Int
-	if feeErr != nil {
-		logger.Warn("Error fetching fee history: %v\n", feeErr)
+	if gasRefresher != nil && gasRefresher.BaseFee() != nil {
+		currentBaseFee = gasRefresher.BaseFee()
+		logger.Debug("Current base fee from gas refresher: %s wei\n", currentBaseFee.String())
 	} else {
-		currentBaseFee = feeHistory.BaseFee[len(feeHistory.BaseFee)-1]
-		logger.Debug("Current base fee from fee history: %s wei\n", currentBaseFee.String())
+		feeHistory, feeErr := txSender.FeeHistory(ctx)
+		if feeErr != nil {
+			logger.Warn("Error fetching fee history: %v\n", feeErr)
+		} else {
+			currentBaseFee = feeHistory.BaseFee[len(feeHistory.BaseFee)-1]
+			logger.Debug("Current base fee from fee history: %s wei\n", currentBaseFee.String())
+		}
+	}
+
+	minGasPrice := new(big.Int)
+	minGasPrice.SetString(config.MinGasPrice, 10)
+
+	// Function to apply the multiplier, adaptive fee level and configured
+	// minimum to a base fee
+	priceFor := func(baseGasPrice *big.Int) *big.Int {
+		adjustedGasPrice := run.feeControl.Scale(feeBumper.GasPrice(baseGasPrice))
+		if adjustedGasPrice.Cmp(minGasPrice) < 0 {
+			return minGasPrice
+		}
+		return adjustedGasPrice
+	}
+	tipFor := func() *big.Int {
+		return run.feeControl.Scale(feeBumper.Tip())
+	}
+
+	burstAt := burstTime(ctx, config, txSender)
+	if !burstAt.IsZero() {
+		fmt.Printf("Burst scheduled at %s (%s timing)\n", burstAt.Format("15:04:05.000"), timing)
 	}
 
 	// Process all wallets in parallel
@@ -405,185 +1556,442 @@ func runSingleExecution(config *config.Config, txSender *txpkg.TransactionSender
 		go func(idx int, w *wallet.Wallet) {
 			defer wgSubmit.Done()
 
+			// A panic must not take down the whole run: record the transactions
+			// this wallet had not yet accounted for as failed and carry on.
+			var txRequests []*txpkg.TxRequest
+			recorded := 0
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				panicked.Add(1)
+				logger.Error("[Wallet %d/%d] PANIC in submission goroutine: %v\n%s\n", idx+1, len(wallets), r, debug.Stack())
+				for _, req := range txRequests[recorded:] {
+					dbWriteChan <- worker.DBWriteJob{Tx: &dbpkg.Transaction{
+						BatchNumber:   batchNumber,
+						WalletAddress: w.Address.Hex(),
+						Nonce:         req.Nonce,
+						ToAddress:     req.ToAddress.Hex(),
+						Value:         req.Value.String(),
+						GasPrice:      req.GasFeeCap().String(),
+						GasLimit:      req.GasLimit,
+						GasEstimated:  req.GasEstimated,
+						SubmittedAt:   time.Now(),
+						Status:        "failed",
+						Error:         fmt.Sprintf("panic: %v", r),
+						RunID:         run.runID,
+						SubmitWorker:  idx + 1,
+					}}
+					req.Finish(fmt.Errorf("panic: %v", r))
+				}
+				// The node may or may not have seen the in-flight transaction
+				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				defer cancel()
+				if _, err := run.nonces.Resync(ctx, w.Address); err != nil {
+					logger.Error("[Wallet %d/%d] Failed to resync nonce: %v\n", idx+1, len(wallets), err)
+				}
+			}()
+
+			if run.budget != nil && run.budget.WalletExhausted(w.Address) {
+				logger.Debug("[Wallet %d/%d] Spend budget exhausted, not sending\n", idx+1, len(wallets))
+				return
+			}
+
 			logger.Info("[Wallet %d/%d] Starting goroutine for %s\n",
 				idx+1, len(wallets), w.Address.Hex())
 
 			logger.Debug("\n[Wallet %d/%d] (%s)\n",
 				idx+1, len(wallets), w.Address.Hex())
 
-			// Each wallet gets its own context so a slow wallet cannot
-			// consume the shared timeout and stall all other goroutines.
-			wCtx, wCancel := context.WithTimeout(context.Background(), time.Duration(config.ContextTimeout)*time.Second)
-			defer wCancel()
-
-			// Prepare batch transactions with precalculated nonces
-			logger.Debug("[Wallet %d/%d] Preparing batch transactions...\n", idx+1, len(wallets))
-
-			// Use adjusted gas price based on current multiplier
-			var baseGasPrice *big.Int
-			if currentBaseFee != nil {
-				baseGasPrice = currentBaseFee
-			} else {
-				// Fallback gas price if fee history is unavailable (20 gwei)
-				baseGasPrice = big.NewInt(20000000000)
-				logger.Debug("[Wallet %d/%d] Using fallback gas price: %s wei\n", idx+1, len(wallets), baseGasPrice.String())
-			}
-			adjustedGasPrice := getAdjustedGasPrice(baseGasPrice)
-
-			// Enforce minimum gas price from config
-			minGasPrice := new(big.Int)
-			minGasPrice.SetString(config.MinGasPrice, 10)
-			if adjustedGasPrice.Cmp(minGasPrice) < 0 {
-				logger.Debug("[Wallet %d/%d] Gas price %s wei below minimum %s wei, using minimum\n",
-					idx+1, len(wallets), adjustedGasPrice.String(), minGasPrice.String())
-				adjustedGasPrice = minGasPrice
-			}
-
-			if adjustedGasPrice.Cmp(baseGasPrice) != 0 {
-				logger.Debug("[Wallet %d/%d] Using adjusted gas price: %s wei (base: %s wei)\n",
-					idx+1, len(wallets), adjustedGasPrice.String(), baseGasPrice.String())
-			}
-
-			w.Lock()
-			txRequests, newNonce, err := txSender.PrepareBatchTransactions(
-				wCtx,
-				toAddress,
-				value,
-				config.TxPerWallet,
-				adjustedGasPrice,
-				config.GasLimit,
-				w.PrivateKey,
-				w.Nonce,
-			)
-			w.Unlock()
-
-			if err != nil {
-				logger.Error("[Wallet %d/%d] Error preparing transactions: %v\n", idx+1, len(wallets), err)
-				return
-			}
-			logger.Debug("[Wallet %d/%d] Successfully prepared %d transactions\n", idx+1, len(wallets), len(txRequests))
-
-			// Update wallet nonce
-			w.Lock()
-			w.Nonce = newNonce
-			w.Unlock()
-
-			// Sleep until next minute boundary if configured
-			if config.SleepMinutes > 0 {
-				now := time.Now()
-				// Calculate next minute boundary
-				nextMinute := now.Truncate(time.Minute).Add(time.Minute)
-				waitDuration := time.Until(nextMinute)
-
-				fmt.Printf("Current time: %s\n", now.Format("15:04:05"))
-				fmt.Printf("[Wallet %d/%d] Waiting %.1f seconds until next minute (%s)...\n",
-					idx+1, len(wallets), waitDuration.Seconds(), nextMinute.Format("15:04:05"))
-				time.Sleep(waitDuration)
-				fmt.Println("Sleep completed. Starting transaction submission...")
-			}
-
-			// Send all transactions for this wallet
-			for txIdx, req := range txRequests {
-				// Per-transaction context so one hung RPC call doesn't block
-				// the wallet goroutine longer than ContextTimeout seconds.
-				txCtx, txCancel := context.WithTimeout(context.Background(), time.Duration(config.ContextTimeout)*time.Second)
-				result, err := txSender.CreateAndSendTransaction(txCtx, req)
-				txCancel()
-
-				// Guard against nil result (returned when CreateTransaction or
-				// SignTransaction fails before any RPC call is made).
-				var submittedAt time.Time
-				var execTime float64
-				if result != nil {
-					submittedAt = result.SubmittedAt
-					execTime = result.ExecutionTime
+			// sendChunk prepares and sends the wallet's next TX_PER_WALLET
+			// transactions. It reports whether the wallet may send more.
+			sendChunk := func(first bool) bool {
+				txRequests, recorded = nil, 0
+				exhausted, sendFailed := false, false
+
+				// Each wallet gets its own context so a slow wallet cannot
+				// consume the shared timeout and stall all other goroutines.
+				wCtx, wCancel := context.WithTimeout(context.Background(), time.Duration(config.ContextTimeout)*time.Second)
+				defer wCancel()
+
+				// Prepare batch transactions with precalculated nonces
+				logger.Debug("[Wallet %d/%d] Preparing batch transactions...\n", idx+1, len(wallets))
+
+				// Use adjusted gas price based on current multiplier
+				var baseGasPrice *big.Int
+				if !first && gasRefresher != nil && gasRefresher.BaseFee() != nil {
+					// Later streaming chunks follow the refreshed base fee
+					baseGasPrice = gasRefresher.BaseFee()
+				} else if currentBaseFee != nil {
+					baseGasPrice = currentBaseFee
 				} else {
-					submittedAt = time.Now()
+					// Fallback gas price if fee history is unavailable (20 gwei)
+					baseGasPrice = big.NewInt(20000000000)
+					logger.Debug("[Wallet %d/%d] Using fallback gas price: %s wei\n", idx+1, len(wallets), baseGasPrice.String())
 				}
+				// Apply multiplier and enforce minimum gas price from config
+				adjustedGasPrice := priceFor(baseGasPrice)
 
-				// Create database transaction record
-				dbTx := &dbpkg.Transaction{
-					BatchNumber:   batchNumber,
-					WalletAddress: w.Address.Hex(),
-					Nonce:         req.Nonce,
-					ToAddress:     toAddress.Hex(),
-					Value:         value.String(),
-					GasLimit:      req.GasLimit,
-					SubmittedAt:   submittedAt,
-					ExecutionTime: execTime,
+				if adjustedGasPrice.Cmp(baseGasPrice) != 0 {
+					logger.Debug("[Wallet %d/%d] Using adjusted gas price: %s wei (base: %s wei)\n",
+						idx+1, len(wallets), adjustedGasPrice.String(), baseGasPrice.String())
 				}
 
-				if err != nil {
-					dbTx.Status = "failed"
-					dbTx.Error = err.Error()
+				calls := load.Calls(idx, config.TxPerWallet)
+				if len(calls) == 0 {
+					logger.Debug("[Wallet %d/%d] Nothing to send for the %s workload\n", idx+1, len(wallets), load.Name())
+					return false
+				}
+				if run.gasEstimator != nil {
+					run.gasEstimator.Apply(wCtx, w.Address, calls)
+				}
 
-					// Capture error details before reassigning err variable
-					originalErrorMsg := err.Error()
+				startNonce := run.nonces.Allocate(w.Address, len(calls))
+				var err error
+				txRequests, _, err = txSender.PrepareBatchTransactions(
+					wCtx,
+					calls,
+					adjustedGasPrice,
+					tipFor(),
+					config.GasLimit,
+					w.Signer(),
+					startNonce,
+				)
 
-					// Update wallet nonce
-					ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-					recoveredNonce, getNonceErr := txSender.GetNonce(ctx, w.Address)
-					cancel() // Call cancel immediately instead of deferring
-					if getNonceErr != nil {
-						logger.Error("  [W%d] Failed to update nonce for wallet %s: %v\n", idx+1, w.Address.Hex(), getNonceErr)
-					} else {
-						w.Lock()
-						w.Nonce = recoveredNonce
-						w.Unlock()
-						logger.Debug("  [W%d] Wallet nonce recovered: %d\n", idx+1, recoveredNonce)
+				if err != nil {
+					run.nonces.Release(w.Address, startNonce)
+					logger.Error("[Wallet %d/%d] Error preparing transactions: %v\n", idx+1, len(wallets), err)
+					return false
+				}
+				if err := txSender.EstimateL1Fees(wCtx, txRequests); err != nil {
+					logger.Warn("[Wallet %d/%d] Could not estimate L1 data fees: %v\n", idx+1, len(wallets), err)
+				}
+				logger.Debug("[Wallet %d/%d] Successfully prepared %d transactions\n", idx+1, len(wallets), len(txRequests))
+
+				// recordUnsent stores planned transactions that will never be sent
+				// and hands their nonces back to the wallet.
+				recordUnsent := func(reqs []*txpkg.TxRequest, status, reason string) {
+					for _, unsent := range reqs {
+						dbWriteChan <- worker.DBWriteJob{Tx: &dbpkg.Transaction{
+							BatchNumber:   batchNumber,
+							WalletAddress: w.Address.Hex(),
+							Nonce:         unsent.Nonce,
+							ToAddress:     unsent.ToAddress.Hex(),
+							Value:         unsent.Value.String(),
+							GasPrice:      unsent.GasFeeCap().String(),
+							GasLimit:      unsent.GasLimit,
+							GasEstimated:  unsent.GasEstimated,
+							SubmittedAt:   time.Now(),
+							Status:        status,
+							Error:         reason,
+							RunID:         run.runID,
+							SubmitWorker:  idx + 1,
+						}}
+						unsent.Finish(errors.New(reason))
+						recorded++
 					}
+					run.nonces.Release(w.Address, reqs[0].Nonce)
+				}
 
-					// Check for specific error types that indicate gas price issues
-					isUnderpriced := strings.Contains(originalErrorMsg, "replacement transaction underpriced") ||
-						strings.Contains(originalErrorMsg, "transaction underpriced") ||
-						strings.Contains(originalErrorMsg, "insufficient funds for gas")
-
-					if isUnderpriced {
-						logger.Warn("  [W%d] Gas price issue for wallet %s (error: %s)\n", idx+1, w.Address.Hex(), originalErrorMsg)
-						increaseGasPrice()
+				// resync handles a nonce error on txRequests[from]: the wallet's
+				// nonce is reloaded from the node and the transactions not yet sent
+				// are renumbered from there. It reports whether a retry can help.
+				resyncs := 0
+				resync := func(from int, sendErr error) bool {
+					kind := txpkg.NonceError(sendErr)
+					if kind == nil || resyncs >= maxNonceResyncs {
+						return false
 					}
+					resyncs++
+					ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+					defer cancel()
+					nonce, err := run.nonces.Resync(ctx, w.Address)
+					if err != nil {
+						logger.Error("  [W%d] Failed to resync nonce: %v\n", idx+1, err)
+						return false
+					}
+					if nonce == txRequests[from].Nonce {
+						return false
+					}
+					if err := txSender.Renumber(txRequests[from:], nonce, w.Signer()); err != nil {
+						logger.Error("  [W%d] Failed to renumber transactions: %v\n", idx+1, err)
+						return false
+					}
+					run.nonces.Allocate(w.Address, len(txRequests)-from)
+					logger.Warn("  [W%d] %v at tx %d; resynced nonce to %d and renumbered %d transactions\n",
+						idx+1, kind, from+1, nonce, len(txRequests)-from)
+					return true
+				}
 
-					// For nonce errors, log the expected vs actual nonce for debugging
-					if strings.Contains(originalErrorMsg, "nonce too low") {
-						logger.Warn("  [W%d] Nonce conflict for wallet %s: %s (tx nonce: %d)\n",
-							idx+1, w.Address.Hex(), originalErrorMsg, req.Nonce)
+				// reprice handles an underpriced error on req by re-signing it with
+				// higher fees: enough to replace a transaction already pending at
+				// its nonce, or to clear the pool's price floor. The spend budget
+				// is charged the rise in its worst-case cost.
+				reprices := 0
+				reprice := func(req *txpkg.TxRequest, sendErr error) bool {
+					kind := txpkg.SendError(sendErr)
+					if (kind != txpkg.ErrReplacementUnderpriced && kind != txpkg.ErrUnderpriced) || reprices >= maxReprices {
+						return false
 					}
+					reprices++
+					if kind == txpkg.ErrUnderpriced {
+						// Price the rest of the batch higher as well
+						feeBumper.Bump()
+					}
+					raised, err := txSender.Reprice(req, config.FeeBumpPercent, w.Signer())
+					if err != nil {
+						logger.Error("  [W%d] Failed to re-price tx (nonce %d): %v\n", idx+1, req.Nonce, err)
+						return false
+					}
+					if !raised {
+						logger.Warn("  [W%d] %v at nonce %d and MAX_GAS_PRICE_WEI leaves no room to bid higher\n", idx+1, kind, req.Nonce)
+						return false
+					}
+					if run.budget != nil && !run.budget.Reprice(w.Address, req) {
+						logger.Warn("  [W%d] %v at nonce %d and the spend budget leaves no room to bid higher\n", idx+1, kind, req.Nonce)
+						return false
+					}
+					repriced.Add(1)
+					logger.Warn("  [W%d] %v at nonce %d; re-sending at max fee %s wei\n", idx+1, kind, req.Nonce, req.GasFeeCap().String())
+					return true
+				}
 
-					// Print failure reason
-					logger.Error("  [W%d] Tx %d FAILED (nonce %d): %v\n", idx+1, txIdx+1, req.Nonce, err)
+				// Sleep until next minute boundary if configured
+				if first && config.SleepMinutes > 0 {
+					now := time.Now()
+					// Calculate next minute boundary
+					nextMinute := now.Truncate(time.Minute).Add(time.Minute)
+					waitDuration := time.Until(nextMinute)
+
+					fmt.Printf("Current time: %s\n", now.Format("15:04:05"))
+					fmt.Printf("[Wallet %d/%d] Waiting %.1f seconds until next minute (%s)...\n",
+						idx+1, len(wallets), waitDuration.Seconds(), nextMinute.Format("15:04:05"))
+					run.abort.Sleep(waitDuration)
+					fmt.Println("Sleep completed. Starting transaction submission...")
+				}
+
+				// Hold the burst until the slot-aligned (or random) fire time
+				if wait := time.Until(burstAt); first && !burstAt.IsZero() && wait > 0 {
+					run.abort.Sleep(wait)
+				}
+
+				// Send all transactions for this wallet
+				for txIdx, req := range txRequests {
+					// Hold off while the wallet is being topped up
+					run.topUps.Wait(w.Address, run.abort.Done())
+
+					// Closed loop: wait until a confirmation frees a slot
+					acquired := run.inflight != nil && run.inflight.Acquire(run.abort.Done())
 
-					// Queue DB write. Use a select so the goroutine can exit
-					// if the process is shutting down instead of blocking forever.
-					select {
-					case dbWriteChan <- worker.DBWriteJob{Tx: dbTx}:
-					case <-wCtx.Done():
-						logger.Warn("  [W%d] Context expired while queuing DB write for nonce %d; dropping record\n", idx+1, req.Nonce)
-						return
+					// Wait for this wallet's own slot, then for the shared one
+					if run.walletLimits != nil {
+						run.walletLimits[idx].Wait(run.abort.Done())
 					}
-					break // Stop sending further transactions for this wallet on error
-				} else {
-					dbTx.TxHash = result.TxHash
-					dbTx.Status = "pending"
-
-					logger.Debug("  [W%d] Tx %d sent (nonce %d): %s\n", idx+1, txIdx+1, req.Nonce, result.TxHash[:16]+"...")
-					// Queue DB write. Use a select so the goroutine can exit
-					// if the process is shutting down instead of blocking forever.
-					select {
-					case dbWriteChan <- worker.DBWriteJob{Tx: dbTx}:
-					case <-wCtx.Done():
-						logger.Warn("  [W%d] Context expired while queuing DB write for nonce %d; dropping record\n", idx+1, req.Nonce)
-						return
+					var scheduled time.Time
+					if run.limiter != nil {
+						scheduled, _ = run.limiter.Wait(run.abort.Done())
+					}
+
+					// Once aborted, nothing more goes out; sends already under way
+					// finish and the rest of the batch is recorded as cancelled.
+					if run.abort.Aborted() {
+						if acquired {
+							run.inflight.Release()
+						}
+						logger.Warn("  [W%d] Aborted, cancelling %d unsent transactions\n", idx+1, len(txRequests)-txIdx)
+						recordUnsent(txRequests[txIdx:], "cancelled", "run aborted before send")
+						break
 					}
+
+					// Per-transaction context so one hung RPC call doesn't block
+					// the wallet goroutine longer than ContextTimeout seconds.
+					txCtx, txCancel := context.WithTimeout(context.Background(), time.Duration(config.ContextTimeout)*time.Second)
+
+					// Re-sign at the refreshed price if the base fee or the adaptive
+					// fee level rose since the batch was prepared, so later
+					// transactions don't go underpriced.
+					latest := currentBaseFee
+					if gasRefresher != nil && gasRefresher.BaseFee() != nil {
+						latest = gasRefresher.BaseFee()
+					}
+					if latest != nil && (gasRefresher != nil || run.feeControl != nil) {
+						if price := priceFor(latest); price.Cmp(req.BaseFee) > 0 {
+							if err := txSender.Resign(req, price, tipFor(), w.Signer()); err != nil {
+								logger.Warn("  [W%d] Could not re-sign tx (nonce %d) at refreshed price: %v\n", idx+1, req.Nonce, err)
+							} else {
+								logger.Debug("  [W%d] Re-signed tx (nonce %d) at refreshed price %s wei\n", idx+1, req.Nonce, price.String())
+							}
+						}
+					}
+
+					// Stop this wallet once the next transaction would break the
+					// spend budget; the rest of its batch is recorded as skipped.
+					if run.budget != nil && !run.budget.Reserve(w.Address, req) {
+						txCancel()
+						if acquired {
+							run.inflight.Release()
+						}
+						logger.Warn("  [W%d] Spend budget reached, skipping %d remaining transactions\n", idx+1, len(txRequests)-txIdx)
+						recordUnsent(txRequests[txIdx:], "skipped_budget", "spend budget exhausted")
+						exhausted = true
+						break
+					}
+
+					reprices = 0
+					result, err := txSender.CreateAndSendTransaction(txCtx, req)
+					for err != nil && (resync(txIdx, err) || reprice(req, err)) {
+						result, err = txSender.CreateAndSendTransaction(txCtx, req)
+					}
+					txCancel()
+					req.Finish(err)
+					if run.rateLag != nil && err == nil {
+						run.rateLag.Observe(scheduled, result.SubmittedAt, 1)
+					}
+					if run.errStop != nil {
+						run.errStop.Observe(err)
+					}
+
+					// Guard against nil result (returned when CreateTransaction or
+					// SignTransaction fails before any RPC call is made).
+					var submittedAt time.Time
+					var execTime float64
+					var endpoint string
+					if result != nil {
+						submittedAt = result.SubmittedAt
+						execTime = result.ExecutionTime
+						endpoint = result.Endpoint
+					} else {
+						submittedAt = time.Now()
+					}
+
+					// Create database transaction record
+					dbTx := &dbpkg.Transaction{
+						BatchNumber:   batchNumber,
+						WalletAddress: w.Address.Hex(),
+						Nonce:         req.Nonce,
+						ToAddress:     req.ToAddress.Hex(),
+						Value:         req.Value.String(),
+						GasPrice:      req.GasFeeCap().String(),
+						GasLimit:      req.GasLimit,
+						GasEstimated:  req.GasEstimated,
+						SubmittedAt:   submittedAt,
+						ExecutionTime: execTime,
+						RPCEndpoint:   endpoint,
+						TraceParent:   req.TraceParent(),
+						RunID:         run.runID,
+						SubmitWorker:  idx + 1,
+					}
+					if run.spike != nil {
+						dbTx.Phase = run.spike.PhaseAt(submittedAt)
+					}
+
+					if err != nil {
+						dbTx.Status = "failed"
+						dbTx.Error = err.Error()
+						if acquired {
+							run.inflight.Release()
+						}
+						if run.budget != nil {
+							run.budget.Release(w.Address, req)
+						}
+
+						// Capture error details before reassigning err variable
+						originalErrorMsg := err.Error()
+
+						// Update wallet nonce
+						ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+						recoveredNonce, getNonceErr := run.nonces.Resync(ctx, w.Address)
+						cancel() // Call cancel immediately instead of deferring
+						if getNonceErr != nil {
+							logger.Error("  [W%d] Failed to update nonce for wallet %s: %v\n", idx+1, w.Address.Hex(), getNonceErr)
+						} else {
+							logger.Debug("  [W%d] Wallet nonce recovered: %d\n", idx+1, recoveredNonce)
+						}
+
+						// Check for specific error types that indicate gas price issues
+						isUnderpriced := strings.Contains(originalErrorMsg, "replacement transaction underpriced") ||
+							strings.Contains(originalErrorMsg, "transaction underpriced") ||
+							strings.Contains(originalErrorMsg, "insufficient funds for gas")
+
+						if isUnderpriced {
+							logger.Warn("  [W%d] Gas price issue for wallet %s (error: %s)\n", idx+1, w.Address.Hex(), originalErrorMsg)
+							feeBumper.Bump()
+						}
+						if dbpkg.ErrorCategory(originalErrorMsg) == dbpkg.ErrorInsufficientFunds {
+							run.topUps.Poke()
+						}
+
+						// For nonce errors, log the expected vs actual nonce for debugging
+						if strings.Contains(originalErrorMsg, "nonce too low") {
+							logger.Warn("  [W%d] Nonce conflict for wallet %s: %s (tx nonce: %d)\n",
+								idx+1, w.Address.Hex(), originalErrorMsg, req.Nonce)
+						}
+
+						// Print failure reason
+						logger.Error("  [W%d] Tx %d FAILED (nonce %d): %v\n", idx+1, txIdx+1, req.Nonce, err)
+
+						// Queue DB write. Use a select so the goroutine can exit
+						// if the process is shutting down instead of blocking forever.
+						select {
+						case dbWriteChan <- worker.DBWriteJob{Tx: dbTx}:
+							recorded++
+						case <-wCtx.Done():
+							logger.Warn("  [W%d] Context expired while queuing DB write for nonce %d; dropping record\n", idx+1, req.Nonce)
+							return false
+						}
+						sendFailed = true
+						break // Stop sending further transactions for this wallet on error
+					} else {
+						dbTx.TxHash = result.TxHash
+						dbTx.Status = "pending"
+						submitted.Add(1)
+						if stuckMonitor != nil {
+							stuckMonitor.Track(w.Address, req, w.Signer(), common.HexToHash(result.TxHash))
+						}
+						if acquired {
+							run.inflight.Sent(w.Address, req.Nonce)
+						}
+						if run.feeControl != nil {
+							run.feeControl.Track(w.Address, req.Nonce, dbTx.SubmittedAt)
+						}
+
+						logger.Debug("  [W%d] Tx %d sent (nonce %d): %s\n", idx+1, txIdx+1, req.Nonce, result.TxHash[:16]+"...")
+						// Queue DB write. Use a select so the goroutine can exit
+						// if the process is shutting down instead of blocking forever.
+						select {
+						case dbWriteChan <- worker.DBWriteJob{Tx: dbTx}:
+							recorded++
+						case <-wCtx.Done():
+							logger.Warn("  [W%d] Context expired while queuing DB write for nonce %d; dropping record\n", idx+1, req.Nonce)
+							return false
+						}
+					}
+
 				}
 
+				logger.Info("  [W%d] ✓ Sent %d transactions (nonce %d to %d)\n",
+					idx+1,
+					len(txRequests),
+					txRequests[0].Nonce,
+					txRequests[len(txRequests)-1].Nonce,
+				)
+
+				if sendFailed && time.Now().Before(run.streamUntil) {
+					// Back off before the next chunk so a persistent error
+					// cannot spin the wallet
+					run.abort.Sleep(time.Second)
+				}
+				return !exhausted
 			}
 
-			logger.Info("  [W%d] ✓ Sent %d transactions (nonce %d to %d)\n",
-				idx+1,
-				len(txRequests),
-				txRequests[0].Nonce,
-				txRequests[len(txRequests)-1].Nonce,
-			)
+			// Streaming keeps the wallet's nonce pipeline full: the next chunk
+			// is prepared as soon as the last one went out, until the end of
+			// the run, instead of the wallet waiting for a new batch.
+			more := sendChunk(true)
+			for more && time.Now().Before(run.streamUntil) && !run.abort.Aborted() {
+				more = sendChunk(false)
+			}
 		}(walletIdx, w)
 	}
 
@@ -591,27 +1999,761 @@ func runSingleExecution(config *config.Config, txSender *txpkg.TransactionSender
 	fmt.Println("\nWaiting for all transactions to be submitted...")
 	wgSubmit.Wait()
 	fmt.Println("✓ All transactions submitted")
+	if n := panicked.Load(); n > 0 {
+		fmt.Printf("⚠️  %d wallet goroutine(s) panicked; their unsent transactions were recorded as failed (see logs/error.log)\n", n)
+	}
+	if n := repriced.Load(); n > 0 {
+		fmt.Printf("💸 %d sends retried at a higher price after underpriced errors\n", n)
+	}
 
 	logger.Debug("🔓 Submission phase completed - workers resumed\n")
 
 	fmt.Println("✓ Database writes queued (processing in background)")
 	fmt.Println("✓ Receipt confirmations queued (processing in background)")
 
+	run.hooks.Run(hooks.PostBatch, batchNumber, map[string]string{
+		"workload":  load.Name(),
+		"wallets":   strconv.Itoa(len(wallets)),
+		"txs":       strconv.Itoa(len(wallets) * config.TxPerWallet),
+		"submitted": strconv.FormatInt(submitted.Load(), 10),
+	})
+
 	// Return immediately after transactions are submitted; analysis and summaries
 	// can be performed later using the provided tooling (e.g. analyze.sh).
+	return batchNumber, int(submitted.Load())
+}
+
+// burstTime returns when a batch's transactions should be fired under the
+// configured SUBMISSION_TIMING, or the zero time to send immediately. Slot
+// phase comes from the beacon API when configured, otherwise from the latest
+// block timestamp (block times are slot starts post-merge).
+func burstTime(ctx context.Context, config *config.Config, txSender *txpkg.TransactionSender) time.Time {
+	timing := strings.ToLower(config.SubmissionTiming)
+	if timing == "" || timing == "immediate" {
+		return time.Time{}
+	}
+
+	var clock *consensus.SlotClock
+	if config.BeaconAPIURL != "" {
+		var err error
+		clock, err = consensus.NewBeaconClient(config.BeaconAPIURL).SlotClock(ctx)
+		if err != nil {
+			logger.Warn("Could not read slot timing from beacon API, sending immediately: %v\n", err)
+			return time.Time{}
+		}
+	} else {
+		if config.SlotDurationSeconds <= 0 {
+			logger.Warn("SLOT_DURATION_SECONDS must be positive, sending immediately\n")
+			return time.Time{}
+		}
+		header, err := txSender.LatestHeader(ctx)
+		if err != nil {
+			logger.Warn("Could not read latest block for slot timing, sending immediately: %v\n", err)
+			return time.Time{}
+		}
+		clock = &consensus.SlotClock{
+			GenesisTime:    time.Unix(int64(header.Time), 0),
+			SecondsPerSlot: uint64(config.SlotDurationSeconds),
+		}
+	}
+
+	now := time.Now()
+	switch timing {
+	case "slot":
+		// Leave at least a second to finish preparing and signing the batch.
+		at := clock.NextSlot(now).Add(-time.Duration(config.SlotOffsetMs) * time.Millisecond)
+		for at.Before(now.Add(time.Second)) {
+			at = at.Add(clock.SlotDuration())
+		}
+		return at
+	case "random":
+		return now.Add(time.Duration(rand.Int63n(int64(clock.SlotDuration()))))
+	default:
+		logger.Warn("Unknown SUBMISSION_TIMING %q, sending immediately\n", config.SubmissionTiming)
+		return time.Time{}
+	}
+}
+
+// printInclusionSummary prints inclusion latency per batch so slot-aligned
+// and randomly timed bursts can be compared side by side. The compact layout
+// prints one row over all batches.
+func printInclusionSummary(db dbpkg.Store, batches []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("INCLUSION LATENCY BY BATCH")
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("%-32s %6s %8s %8s %8s\n", "Batch", "Txs", "Avg", "Min", "Max")
+
+	var all inclusionStats
+	for _, batch := range batches {
+		txs, err := db.GetBatchTransactions(ctx, batch)
+		if err != nil {
+			logger.Warn("Could not load transactions for %s: %v\n", batch, err)
+			continue
+		}
+
+		var stats inclusionStats
+		for _, tx := range txs {
+			if tx.Status != "success" || tx.ConfirmedAt == nil {
+				continue
+			}
+			latency := tx.ConfirmedAt.Sub(tx.SubmittedAt).Seconds()
+			stats.add(latency)
+			all.add(latency)
+		}
+		if !report.Compact() {
+			stats.print(batch)
+		}
+	}
+	if report.Compact() {
+		all.print(fmt.Sprintf("%d batches", len(batches)))
+	}
+	fmt.Println(strings.Repeat("=", 60))
+}
+
+// inclusionStats accumulates inclusion latencies in seconds.
+type inclusionStats struct {
+	count           int
+	total, min, max float64
+}
+
+func (s *inclusionStats) add(latency float64) {
+	if s.count == 0 || latency < s.min {
+		s.min = latency
+	}
+	if latency > s.max {
+		s.max = latency
+	}
+	s.total += latency
+	s.count++
+}
+
+func (s *inclusionStats) print(label string) {
+	if s.count == 0 {
+		fmt.Printf("%-32s %6d %8s %8s %8s\n", label, 0, "-", "-", "-")
+		return
+	}
+	fmt.Printf("%-32s %6s %8s %8s %8s\n", label, report.Int(s.count),
+		report.Seconds(s.total/float64(s.count), 2), report.Seconds(s.min, 2), report.Seconds(s.max, 2))
+}
+
+// printPartialReport accounts for every planned transaction of an aborted
+// run, so the numbers that follow are read as covering a partial run.
+func printPartialReport(db dbpkg.Store, batches []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var total, success, failed, pending, cancelled int
+	for _, batch := range batches {
+		stats, err := db.GetBatchStats(ctx, batch)
+		if err != nil {
+			logger.Warn("Could not load stats for %s: %v\n", batch, err)
+			continue
+		}
+		total += stats["total_transactions"].(int)
+		success += stats["successful"].(int)
+		failed += stats["failed"].(int)
+		pending += stats["pending"].(int)
+		cancelled += stats["cancelled"].(int)
+	}
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("⚠️  PARTIAL RUN — ABORTED")
+	fmt.Println(strings.Repeat("=", 60))
+	if report.Compact() {
+		fmt.Printf("%s planned in %d batches: %s confirmed, %s failed, %s pending, %s cancelled\n",
+			report.Int(total), len(batches), report.Int(success), report.Int(failed), report.Int(pending), report.Int(cancelled))
+		fmt.Println(strings.Repeat("=", 60))
+		return
+	}
+	fmt.Printf("Batches:                   %d (last one incomplete)\n", len(batches))
+	fmt.Printf("Planned transactions:      %s\n", report.Int(total))
+	fmt.Printf("  Sent and confirmed:      %s\n", report.Int(success))
+	fmt.Printf("  Failed:                  %s\n", report.Int(failed))
+	fmt.Printf("  Sent, still pending:     %s\n", report.Int(pending))
+	fmt.Printf("  Cancelled (never sent):  %s\n", report.Int(cancelled))
+	if other := total - success - failed - pending - cancelled; other > 0 {
+		fmt.Printf("  Other (e.g. budget):     %s\n", report.Int(other))
+	}
+	fmt.Println(strings.Repeat("=", 60))
+}
+
+// printCostSummary prints the fees paid by the run's confirmed transactions,
+// per batch when there are only a few and in total.
+func printCostSummary(db dbpkg.Store, batches []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	const maxBatchRows = 20
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("GAS COST BY BATCH")
+	fmt.Println(strings.Repeat("=", 60))
+	perBatch := len(batches) <= maxBatchRows && !report.Compact()
+	if perBatch {
+		fmt.Printf("%-32s %6s %12s %14s\n", "Batch", "Txs", "Gas used", "ETH spent")
+	}
+
+	var txs, confirmed int
+	var gasUsed uint64
+	totalCost := new(big.Int)
+	for _, batch := range batches {
+		stats, err := db.GetBatchStats(ctx, batch)
+		if err != nil {
+			logger.Warn("Could not load stats for %s: %v\n", batch, err)
+			continue
+		}
+		txs += stats["total_transactions"].(int)
+		confirmed += stats["successful"].(int) + stats["failed"].(int)
+		gasUsed += stats["total_gas_used"].(uint64)
+		if c, ok := new(big.Int).SetString(stats["total_cost_wei"].(string), 10); ok {
+			totalCost.Add(totalCost, c)
+		}
+		if perBatch {
+			fmt.Printf("%-32s %6s %12s %14s\n", batch, report.Int(stats["total_transactions"].(int)),
+				report.Int(stats["total_gas_used"].(uint64)), report.Float(stats["total_eth_spent"].(float64), 6))
+		}
+	}
+
+	ethSpent, _ := new(big.Float).Quo(new(big.Float).SetInt(totalCost), big.NewFloat(1e18)).Float64()
+	fmt.Printf("Total: %s txs, %s gas used, %s ETH spent", report.Int(txs), report.Int(gasUsed), report.Float(ethSpent, 6))
+	if confirmed > 0 {
+		perTx, _ := new(big.Float).Quo(new(big.Float).SetInt(totalCost), big.NewFloat(float64(confirmed))).Float64()
+		fmt.Printf(" (%s wei per included tx)", report.Float(perTx, 0))
+	}
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 60))
+}
+
+// printLatencySummary prints percentiles of submission and confirmation
+// latency over the run, and per batch when there are only a few. Averages
+// hide the tail that matters when comparing clients.
+func printLatencySummary(db dbpkg.Store, batches []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	const maxBatchRows = 20
+
+	var submission, confirmation []float64
+	for _, batch := range batches {
+		txs, err := db.GetBatchTransactions(ctx, batch)
+		if err != nil {
+			logger.Warn("Could not load transactions for %s: %v\n", batch, err)
+			continue
+		}
+		for _, t := range txs {
+			if t.TxHash != "" {
+				submission = append(submission, t.ExecutionTime/1000)
+			}
+			if t.ConfirmedAt != nil {
+				confirmation = append(confirmation, t.ConfirmedAt.Sub(t.SubmittedAt).Seconds())
+			}
+		}
+	}
+	if len(submission) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("LATENCY PERCENTILES")
+	fmt.Println(strings.Repeat("=", 60))
+	if report.Compact() {
+		fmt.Printf("Submission p50/p90/p95/p99: %s\n", latencyPercentiles(submission, 3))
+		fmt.Printf("Confirmation p50/p90/p95/p99: %s\n", latencyPercentiles(confirmation, 2))
+		fmt.Println(strings.Repeat("=", 60))
+		return
+	}
+	fmt.Printf("%-14s %8s %9s %9s %9s %9s\n", "Latency", "Txs", "p50", "p90", "p95", "p99")
+	for _, row := range []struct {
+		label    string
+		values   []float64
+		decimals int
+	}{
+		{"Submission", submission, 3},
+		{"Confirmation", confirmation, 2},
+	} {
+		fmt.Printf("%-14s %8s", row.label, report.Int(len(row.values)))
+		for _, p := range dbpkg.LatencyPercentiles {
+			if len(row.values) == 0 {
+				fmt.Printf(" %9s", "-")
+				continue
+			}
+			fmt.Printf(" %9s", report.Seconds(report.Percentile(row.values, p), row.decimals))
+		}
+		fmt.Println()
+	}
+
+	if len(batches) > 1 && len(batches) <= maxBatchRows {
+		fmt.Println()
+		fmt.Printf("%-32s %9s %9s %9s %9s\n", "Batch", "Send p50", "Send p99", "Conf p50", "Conf p99")
+		for _, batch := range batches {
+			stats, err := db.GetBatchStats(ctx, batch)
+			if err != nil {
+				logger.Warn("Could not load stats for %s: %v\n", batch, err)
+				continue
+			}
+			fmt.Printf("%-32s %9s %9s %9s %9s\n", batch,
+				report.Seconds(stats["submission_p50_ms"].(float64)/1000, 3), report.Seconds(stats["submission_p99_ms"].(float64)/1000, 3),
+				report.Seconds(stats["confirmation_p50_seconds"].(float64), 2), report.Seconds(stats["confirmation_p99_seconds"].(float64), 2))
+		}
+	}
+	fmt.Println(strings.Repeat("=", 60))
+}
+
+// printLatencyHistogram stores each batch's confirmation latency histogram
+// and prints the run's, whose modes show how many block intervals
+// transactions waited.
+func printLatencyHistogram(db dbpkg.Store, batches []string, width float64) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var all []float64
+	for _, batch := range batches {
+		txs, err := db.GetBatchTransactions(ctx, batch)
+		if err != nil {
+			logger.Warn("Could not load transactions for %s: %v\n", batch, err)
+			continue
+		}
+		var latencies []float64
+		for _, t := range txs {
+			if t.ConfirmedAt != nil {
+				latencies = append(latencies, t.ConfirmedAt.Sub(t.SubmittedAt).Seconds())
+			}
+		}
+		all = append(all, latencies...)
+
+		histogram := report.BuildHistogram(latencies, width)
+		buckets := make([]dbpkg.LatencyBucket, len(histogram))
+		for i, b := range histogram {
+			buckets[i] = dbpkg.LatencyBucket{Start: b.Start, End: b.End, Count: b.Count}
+		}
+		if err := db.ReplaceLatencyHistogram(ctx, batch, buckets); err != nil {
+			logger.Warn("Could not store latency histogram for %s: %v\n", batch, err)
+		}
+	}
+
+	report.PrintHistogram("CONFIRMATION LATENCY HISTOGRAM", report.BuildHistogram(all, width))
+}
+
+// storeTPSSeries stores each batch's submissions and confirmations per
+// bucket of width and prints the peaks and stalls of the run's series.
+func storeTPSSeries(db dbpkg.Store, batches []string, width time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var all []*dbpkg.Transaction
+	for _, batch := range batches {
+		txs, err := db.GetBatchTransactions(ctx, batch)
+		if err != nil {
+			logger.Warn("Could not load transactions for %s: %v\n", batch, err)
+			continue
+		}
+		all = append(all, txs...)
+		series := report.BuildTPSSeries(txs, width)
+		if err := db.ReplaceTPSSeries(ctx, batch, int(width/time.Second), series); err != nil {
+			logger.Warn("Could not store TPS series for %s: %v\n", batch, err)
+		}
+	}
+
+	if series := report.BuildTPSSeries(all, width); len(series) > 0 {
+		report.PrintSeriesStats(report.BuildSeriesStats(series, width), width)
+	}
+}
+
+// latencyPercentiles joins the run's latency percentiles, in seconds, with
+// slashes for the compact layout.
+func latencyPercentiles(values []float64, decimals int) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	parts := make([]string, len(dbpkg.LatencyPercentiles))
+	for i, p := range dbpkg.LatencyPercentiles {
+		parts[i] = report.Seconds(report.Percentile(values, p), decimals)
+	}
+	return strings.Join(parts, " / ")
 }
 
-func SaveMnemonicToFile(filename string, mnemonic string) error {
-	file, err := os.Create(filename)
+// rampBuckets is how many offered-rate ranges the ramp report splits into.
+const rampBuckets = 10
+
+// printRampReport buckets the run's transactions by the rate the ramp
+// offered when each was submitted.
+func printRampReport(db dbpkg.Store, batches []string, ramp *rate.Ramp) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var txs []*dbpkg.Transaction
+	for _, batch := range batches {
+		batchTxs, err := db.GetBatchTransactions(ctx, batch)
+		if err != nil {
+			logger.Warn("Could not load transactions for %s: %v\n", batch, err)
+			continue
+		}
+		txs = append(txs, batchTxs...)
+	}
+	rate.PrintRampReport(ramp, rate.BuildRampReport(ramp, txs, rampBuckets))
+}
+
+// printSpikeReport compares latency across the spike profile's phases and
+// how quickly it recovered after each spike.
+func printSpikeReport(db dbpkg.Store, batches []string, spike *rate.Spike) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var txs []*dbpkg.Transaction
+	for _, batch := range batches {
+		batchTxs, err := db.GetBatchTransactions(ctx, batch)
+		if err != nil {
+			logger.Warn("Could not load transactions for %s: %v\n", batch, err)
+			continue
+		}
+		txs = append(txs, batchTxs...)
+	}
+	rate.PrintSpikeReport(spike, rate.BuildSpikeReport(spike, txs))
+}
+
+// printEndpointStats breaks the run's submissions down by the RPC endpoint
+// that took them, against the endpoint weights if requests were weighted,
+// and its receipts by the endpoint they came from.
+func printEndpointStats(db dbpkg.Store, batches []string, weights map[string]int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var txs []*dbpkg.Transaction
+	for _, batch := range batches {
+		batchTxs, err := db.GetBatchTransactions(ctx, batch)
+		if err != nil {
+			logger.Warn("Could not load transactions for %s: %v\n", batch, err)
+			continue
+		}
+		txs = append(txs, batchTxs...)
+	}
+	if stats := report.BuildEndpointStats(txs); len(stats) > 0 {
+		report.PrintEndpointStats(stats, report.BuildReceiptStats(txs), weights)
+	}
+}
+
+// printWalletStats breaks the run down by sending wallet and flags the
+// wallets that fell behind the rest.
+func printWalletStats(db dbpkg.Store, batches []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var txs []*dbpkg.Transaction
+	for _, batch := range batches {
+		batchTxs, err := db.GetBatchTransactions(ctx, batch)
+		if err != nil {
+			logger.Warn("Could not load transactions for %s: %v\n", batch, err)
+			continue
+		}
+		txs = append(txs, batchTxs...)
+	}
+	stats := report.BuildWalletStats(txs)
+	if len(stats) < 2 {
+		return
+	}
+	report.FlagWallets(stats)
+	report.PrintWalletStats(stats)
+}
+
+// printErrorCategories counts the run's failures by kind of error.
+func printErrorCategories(db dbpkg.Store, batches []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var txs []*dbpkg.Transaction
+	for _, batch := range batches {
+		batchTxs, err := db.GetBatchTransactions(ctx, batch)
+		if err != nil {
+			logger.Warn("Could not load transactions for %s: %v\n", batch, err)
+			continue
+		}
+		txs = append(txs, batchTxs...)
+	}
+	if counts := report.BuildErrorCategories(txs); len(counts) > 0 {
+		report.PrintErrorCategories(counts)
+	}
+}
+
+// printEndpointHealth prints the health checks of every failover endpoint.
+func printEndpointHealth(health []txpkg.EndpointHealth) {
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("RPC ENDPOINT HEALTH")
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("%-24s %6s %6s %8s %8s %7s %9s %9s\n", "Endpoint", "Checks", "Failed", "Avg", "Max", "MaxLag", "Excluded", "Status")
+	for _, h := range health {
+		status := "healthy"
+		if !h.Healthy {
+			status = "unhealthy"
+		}
+		fmt.Printf("%-24s %6s %6s %8s %8s %7s %9s %9s\n", h.Host, report.Int(h.Checks), report.Int(h.Failed),
+			report.Seconds(h.AvgLatency().Seconds(), 3), report.Seconds(h.MaxLatency.Seconds(), 3),
+			report.Int(h.MaxLag), fmt.Sprintf("%d×/%s", h.Exclusions, h.Excluded.Round(time.Second)), status)
+		if !h.Healthy && h.LastError != "" {
+			fmt.Printf("  last failure: %s\n", h.LastError)
+		}
+	}
+	fmt.Println(strings.Repeat("=", 60))
+}
+
+// printWalletRates prints each wallet's achieved send rate against the
+// WALLET_TPS cap: sends over the time from its first to its last send. The
+// compact layout prints the spread over all wallets only.
+func printWalletRates(db dbpkg.Store, batches []string, capTPS float64) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	type walletSends struct {
+		sent        int
+		first, last time.Time
+	}
+	byWallet := make(map[string]*walletSends)
+	var order []string
+	for _, batch := range batches {
+		txs, err := db.GetBatchTransactions(ctx, batch)
+		if err != nil {
+			logger.Warn("Could not load transactions for %s: %v\n", batch, err)
+			continue
+		}
+		for _, tx := range txs {
+			if tx.TxHash == "" {
+				continue
+			}
+			ws, ok := byWallet[tx.WalletAddress]
+			if !ok {
+				ws = &walletSends{first: tx.SubmittedAt, last: tx.SubmittedAt}
+				byWallet[tx.WalletAddress] = ws
+				order = append(order, tx.WalletAddress)
+			}
+			ws.sent++
+			if tx.SubmittedAt.Before(ws.first) {
+				ws.first = tx.SubmittedAt
+			}
+			if tx.SubmittedAt.After(ws.last) {
+				ws.last = tx.SubmittedAt
+			}
+		}
+	}
+	if len(order) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("PER-WALLET SEND RATE")
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("Cap: %s tx/s per wallet\n", report.Float(capTPS, 2))
+	if !report.Compact() {
+		fmt.Printf("%-44s %6s %10s\n", "Wallet", "Sent", "tx/s")
+	}
+	low, high, sum := math.Inf(1), 0.0, 0.0
+	for _, addr := range order {
+		ws := byWallet[addr]
+		// n sends spaced 1/rate apart span n-1 intervals
+		achieved := 0.0
+		if span := ws.last.Sub(ws.first).Seconds(); ws.sent > 1 && span > 0 {
+			achieved = float64(ws.sent-1) / span
+		}
+		low, high, sum = math.Min(low, achieved), math.Max(high, achieved), sum+achieved
+		if !report.Compact() {
+			fmt.Printf("%-44s %6s %10s\n", addr, report.Int(ws.sent), report.Float(achieved, 2))
+		}
+	}
+	fmt.Printf("Achieved: min %s, avg %s, max %s tx/s over %d wallets\n",
+		report.Float(low, 2), report.Float(sum/float64(len(order)), 2), report.Float(high, 2), len(order))
+	fmt.Println(strings.Repeat("=", 60))
+}
+
+// printSlotTimingReport correlates confirmed transactions from this run with
+// beacon chain slot boundaries and, when available, engine payload build times.
+func printSlotTimingReport(config *config.Config, db dbpkg.Store, batches []string, payloadBaseline *consensus.HistogramSample) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	clock, err := consensus.NewBeaconClient(config.BeaconAPIURL).SlotClock(ctx)
+	if err != nil {
+		logger.Warn("Could not read slot timing from beacon API: %v\n", err)
+		return
+	}
+
+	var txs []*dbpkg.Transaction
+	for _, batch := range batches {
+		batchTxs, err := db.GetBatchTransactions(ctx, batch)
+		if err != nil {
+			logger.Warn("Could not load transactions for %s: %v\n", batch, err)
+			continue
+		}
+		txs = append(txs, batchTxs...)
+	}
+
+	var payloadMean, payloadCount float64
+	if payloadBaseline != nil {
+		sample, err := consensus.ScrapeHistogram(ctx, config.EngineMetricsURL, config.EnginePayloadMetric)
+		if err != nil {
+			logger.Warn("Could not scrape engine metrics: %v\n", err)
+		} else {
+			payloadMean, payloadCount = sample.Mean(payloadBaseline)
+		}
+	}
+
+	consensus.PrintSlotReport(clock, consensus.BuildSlotReport(clock, txs), payloadMean, payloadCount)
+}
+
+// newTransactionSender connects to the RPC endpoint, failing over to
+// RPC_FALLBACK_URLS when set, and applies the sender-level settings from
+// config.
+func newTransactionSender(config *config.Config, broadcaster *txpkg.P2PBroadcaster) (*txpkg.TransactionSender, error) {
+	var txSender *txpkg.TransactionSender
+	var err error
+	if strings.TrimSpace(config.RPCFallbackURLs) != "" {
+		rpcFailoverOnce.Do(func() {
+			endpoints := append([]string{config.RPCURL}, splitList(config.RPCFallbackURLs)...)
+			rpcFailover, rpcFailoverErr = txpkg.NewFailover(endpoints)
+			if rpcFailoverErr == nil && strings.TrimSpace(config.RPCWeights) != "" {
+				var weights []int
+				if weights, rpcFailoverErr = txpkg.ParseWeights(config.RPCWeights, endpoints); rpcFailoverErr == nil {
+					rpcFailoverErr = rpcFailover.SetWeights(weights)
+				}
+			}
+		})
+		if rpcFailoverErr != nil {
+			return nil, rpcFailoverErr
+		}
+		txSender, err = txpkg.NewFailoverTransactionSender(rpcFailover)
+	} else {
+		txSender, err = txpkg.NewTransactionSender(config.RPCURL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if broadcaster != nil {
+		txSender.SetBroadcaster(broadcaster)
+	}
+	if config.SubmitRPCURL != "" {
+		if err := txSender.SetSubmitEndpoint(config.SubmitRPCURL); err != nil {
+			txSender.Close()
+			return nil, err
+		}
+	}
+	if config.MaxGasPriceWei != "" {
+		maxGasPrice, ok := new(big.Int).SetString(config.MaxGasPriceWei, 10)
+		if !ok {
+			txSender.Close()
+			return nil, fmt.Errorf("invalid MAX_GAS_PRICE_WEI %q", config.MaxGasPriceWei)
+		}
+		txSender.SetMaxGasPrice(maxGasPrice)
+	}
+	if err := txSender.SetRollup(config.Rollup); err != nil {
+		txSender.Close()
+		return nil, err
+	}
+	if err := txSender.SetNonceSource(chainNonceSource(config)); err != nil {
+		txSender.Close()
+		return nil, err
+	}
+	return txSender, nil
+}
+
+// rpcFailover is shared by every sender of the run, so the active endpoint
+// and the failover count carry over between loop iterations.
+var (
+	rpcFailoverOnce sync.Once
+	rpcFailover     *txpkg.Failover
+	rpcFailoverErr  error
+)
+
+// splitList splits a comma-separated setting, dropping blank entries.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// gweiToWei converts a (possibly fractional) gwei amount to wei.
+func gweiToWei(gwei float64) (*big.Int, error) {
+	if gwei < 0 {
+		return nil, fmt.Errorf("negative amount %g gwei", gwei)
+	}
+	wei, _ := new(big.Float).Mul(big.NewFloat(gwei), big.NewFloat(1e9)).Int(nil)
+	return wei, nil
+}
+
+// SaveMnemonicToFile writes mnemonic to filename, readable only by the
+// user. With a password the phrase is encrypted as in a geth keystore file
+// (scrypt and AES-128-CTR) and stored as JSON on the last line.
+func SaveMnemonicToFile(filename, mnemonic, password string) error {
+	header := "=== MNEMONIC PHRASE ===\n"
+	phrase := mnemonic
+	if password != "" {
+		encrypted, err := keystore.EncryptDataV3([]byte(mnemonic), []byte(password), keystore.StandardScryptN, keystore.StandardScryptP)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt mnemonic: %w", err)
+		}
+		data, err := json.Marshal(encrypted)
+		if err != nil {
+			return err
+		}
+		header = "=== MNEMONIC PHRASE (ENCRYPTED, see MNEMONIC_FILE_PASSWORD) ===\n"
+		phrase = string(data)
+	}
+
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
+	// The mode of OpenFile only applies to a new file; an existing one
+	// keeps its permissions unless changed
+	if err := file.Chmod(0o600); err != nil {
+		return err
+	}
 
-	file.WriteString("=== MNEMONIC PHRASE ===\n")
+	file.WriteString(header)
 	file.WriteString("KEEP THIS SAFE AND PRIVATE!\n")
 	file.WriteString("Generated: " + time.Now().Format(time.RFC3339) + "\n\n")
-	file.WriteString(mnemonic + "\n")
+	file.WriteString(phrase + "\n")
 
 	return nil
 }
+
+// LoadMnemonicFromFile reads back a mnemonic written by SaveMnemonicToFile:
+// the last non-empty line of the file, decrypted with password if it was
+// encrypted.
+func LoadMnemonicFromFile(filename, password string) (string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	mnemonic := strings.TrimSpace(lines[len(lines)-1])
+	if mnemonic == "" || strings.HasPrefix(mnemonic, "===") {
+		return "", fmt.Errorf("no mnemonic found in %s", filename)
+	}
+	if !strings.HasPrefix(mnemonic, "{") {
+		return mnemonic, nil
+	}
+
+	if password == "" {
+		return "", fmt.Errorf("%s is encrypted: set MNEMONIC_FILE_PASSWORD", filename)
+	}
+	var encrypted keystore.CryptoJSON
+	if err := json.Unmarshal([]byte(mnemonic), &encrypted); err != nil {
+		return "", fmt.Errorf("invalid encrypted mnemonic in %s: %w", filename, err)
+	}
+	plain, err := keystore.DecryptDataV3(encrypted, password)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: %w", filename, err)
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,321 @@
+// Package metrics mirrors the run to a time-series database while it is
+// going: a point per stored and per resolved transaction, and a point per
+// second with the counts of that second, written in InfluxDB line protocol.
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-tps/db"
+	"go-tps/logger"
+)
+
+// Measurements the sink writes.
+const (
+	TxMeasurement   = "go_tps_tx"   // one point per transaction event (submit, receipt)
+	RateMeasurement = "go_tps_rate" // one point per second of the run
+)
+
+// maxBuffered caps the lines held while the database is slow or down; older
+// lines are dropped beyond it.
+const maxBuffered = 100000
+
+// Target says where the line protocol is written. With Bucket set the
+// InfluxDB 2 API (/api/v2/write) is used, otherwise the 1.x API (/write),
+// which InfluxDB 3, Telegraf's influxdb_listener and most other line
+// protocol receivers accept too.
+type Target struct {
+	URL      string // e.g. http://localhost:8086
+	Token    string // sent as "Authorization: Token <token>" if set
+	Org      string
+	Bucket   string
+	Database string
+}
+
+// writeURL returns the write endpoint of t.
+func (t Target) writeURL() (string, error) {
+	u, err := url.Parse(t.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid InfluxDB URL %q: %w", t.URL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("invalid InfluxDB URL %q: want http(s)://host:port", t.URL)
+	}
+	q := u.Query()
+	base := strings.TrimSuffix(u.Path, "/")
+	switch {
+	case t.Bucket != "":
+		u.Path = base + "/api/v2/write"
+		q.Set("bucket", t.Bucket)
+		if t.Org != "" {
+			q.Set("org", t.Org)
+		}
+	case t.Database != "":
+		u.Path = base + "/write"
+		q.Set("db", t.Database)
+	default:
+		return "", fmt.Errorf("set an InfluxDB bucket (2.x) or database (1.x)")
+	}
+	q.Set("precision", "ns")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Sink buffers points and writes them once a second. It is safe for
+// concurrent use by the DB writer and receipt workers.
+type Sink struct {
+	url    string
+	token  string
+	client *http.Client
+
+	mu      sync.Mutex
+	lines   []string
+	counts  rateCounts
+	dropped int
+	failing bool // the last write failed; its error was already logged
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// rateCounts are the events of one second.
+type rateCounts struct {
+	submitted, sendFailed       int
+	confirmed, reverted, failed int
+}
+
+// NewSink checks target and starts writing to it every second until Close.
+func NewSink(target Target) (*Sink, error) {
+	writeURL, err := target.writeURL()
+	if err != nil {
+		return nil, err
+	}
+	s := &Sink{
+		url:    writeURL,
+		token:  target.Token,
+		client: &http.Client{Timeout: 10 * time.Second},
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+func (s *Sink) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.flush(now)
+		case <-s.stop:
+			s.flush(time.Now())
+			return
+		}
+	}
+}
+
+// Close writes what is still buffered and stops the sink.
+func (s *Sink) Close() {
+	close(s.stop)
+	<-s.done
+	if s.dropped > 0 {
+		logger.Warn("[Metrics] %d points could not be written to InfluxDB\n", s.dropped)
+	}
+}
+
+// Stored records a transaction as the DB writer saved it: submitted, or
+// failed or skipped before it was sent.
+func (s *Sink) Stored(tx *db.Transaction) {
+	var b lineBuilder
+	b.measurement(TxMeasurement)
+	b.tag("batch", tx.BatchNumber)
+	b.tag("endpoint", tx.RPCEndpoint)
+	b.tag("error_category", tx.ErrorCategory)
+	b.tag("event", "submit")
+	b.tag("phase", tx.Phase)
+	b.tag("status", tx.Status)
+	b.intField("nonce", int64(tx.Nonce))
+	b.floatField("submit_ms", tx.ExecutionTime)
+	b.stringField("tx_hash", tx.TxHash)
+	b.stringField("wallet", tx.WalletAddress)
+	b.timestamp(tx.SubmittedAt)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tx.TxHash != "" {
+		s.counts.submitted++
+	} else {
+		s.counts.sendFailed++
+	}
+	s.add(b.String())
+}
+
+// Resolved records the final status of a submitted transaction, read from
+// its receipt or given up on.
+func (s *Sink) Resolved(tx *db.Transaction) {
+	at := time.Now()
+	var b lineBuilder
+	b.measurement(TxMeasurement)
+	b.tag("batch", tx.BatchNumber)
+	b.tag("endpoint", tx.RPCEndpoint)
+	b.tag("error_category", tx.ErrorCategory)
+	b.tag("event", "receipt")
+	b.tag("phase", tx.Phase)
+	b.tag("status", tx.Status)
+	if tx.ConfirmedAt != nil {
+		at = *tx.ConfirmedAt
+		b.floatField("confirm_seconds", tx.ConfirmedAt.Sub(tx.SubmittedAt).Seconds())
+		b.intField("gas_used", int64(tx.GasUsed))
+	}
+	b.intField("nonce", int64(tx.Nonce))
+	b.stringField("tx_hash", tx.TxHash)
+	b.timestamp(at)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case tx.Status == "success":
+		s.counts.confirmed++
+	case tx.ConfirmedAt != nil:
+		s.counts.reverted++
+	default:
+		s.counts.failed++
+	}
+	s.add(b.String())
+}
+
+// add buffers a line; s.mu must be held.
+func (s *Sink) add(line string) {
+	if len(s.lines) >= maxBuffered {
+		s.lines = s.lines[1:]
+		s.dropped++
+	}
+	s.lines = append(s.lines, line)
+}
+
+// flush adds the rate point of the second ending at now and writes every
+// buffered line.
+func (s *Sink) flush(now time.Time) {
+	var b lineBuilder
+	b.measurement(RateMeasurement)
+	s.mu.Lock()
+	b.intField("confirmed", int64(s.counts.confirmed))
+	b.intField("failed", int64(s.counts.failed))
+	b.intField("reverted", int64(s.counts.reverted))
+	b.intField("send_failed", int64(s.counts.sendFailed))
+	b.intField("submitted", int64(s.counts.submitted))
+	b.timestamp(now.Truncate(time.Second))
+	s.counts = rateCounts{}
+	s.add(b.String())
+	lines := s.lines
+	s.lines = nil
+	s.mu.Unlock()
+
+	err := s.write(lines)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		if !s.failing {
+			logger.Warn("[Metrics] %v (dropping points until InfluxDB accepts them again)\n", err)
+		}
+		s.failing = true
+		s.dropped += len(lines)
+		return
+	}
+	if s.failing {
+		logger.Info("[Metrics] Writing to InfluxDB again\n")
+	}
+	s.failing = false
+}
+
+// write posts lines in one request.
+func (s *Sink) write(lines []string) error {
+	body := strings.Join(lines, "\n") + "\n"
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Token "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to write metrics: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// lineBuilder builds one line of line protocol. Tags must be added in key
+// order, before the fields; empty tags are left out, as the protocol
+// requires.
+type lineBuilder struct {
+	strings.Builder
+	fields int
+}
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	tagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	stringEscaper      = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+)
+
+func (b *lineBuilder) measurement(name string) {
+	b.WriteString(measurementEscaper.Replace(name))
+}
+
+func (b *lineBuilder) tag(key, value string) {
+	if value == "" {
+		return
+	}
+	b.WriteString("," + key + "=" + tagEscaper.Replace(value))
+}
+
+func (b *lineBuilder) field(key, value string) {
+	if b.fields == 0 {
+		b.WriteByte(' ')
+	} else {
+		b.WriteByte(',')
+	}
+	b.fields++
+	b.WriteString(key + "=" + value)
+}
+
+func (b *lineBuilder) intField(key string, v int64) {
+	b.field(key, strconv.FormatInt(v, 10)+"i")
+}
+
+func (b *lineBuilder) floatField(key string, v float64) {
+	b.field(key, strconv.FormatFloat(v, 'f', -1, 64))
+}
+
+func (b *lineBuilder) stringField(key, v string) {
+	if v == "" {
+		return
+	}
+	b.field(key, `"`+stringEscaper.Replace(v)+`"`)
+}
+
+func (b *lineBuilder) timestamp(t time.Time) {
+	b.WriteString(" " + strconv.FormatInt(t.UnixNano(), 10))
+}
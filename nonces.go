@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-tps/config"
+	dbpkg "go-tps/db"
+	"go-tps/logger"
+	txpkg "go-tps/tx"
+	"go-tps/wallet"
+)
+
+// nonceSourceLocal starts wallets from the next nonce the previous run
+// stored in the wallets table instead of asking the node first. Resyncs
+// after nonce errors still read the pending nonce.
+const nonceSourceLocal = "local"
+
+// chainNonceSource is the NONCE_SOURCE the transaction sender reads from.
+func chainNonceSource(config *config.Config) string {
+	if strings.EqualFold(config.NonceSource, nonceSourceLocal) {
+		return txpkg.NonceSourcePending
+	}
+	return config.NonceSource
+}
+
+// saveWallets records the run's wallets in the wallets table. A wallet a
+// previous run stored is reused as it is, with its creation time and stored
+// next nonce, which NONCE_SOURCE=local starts from and the cross-check of
+// reconcileStoredNonces compares; REDERIVE_WALLETS stores it as new instead,
+// dropping its stored nonce.
+func saveWallets(config *config.Config, db dbpkg.Store, wallets []*wallet.Wallet) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	stored, err := db.GetWallets(ctx)
+	if err != nil {
+		return err
+	}
+	logger.Info("\nSaving wallets to database...\n")
+	added, reused, reset := 0, 0, 0
+	var firstUsed time.Time
+	for i, w := range wallets {
+		address := w.Address.Hex()
+		s, ok := stored[address]
+		switch {
+		case !ok:
+			err = db.InsertWallet(ctx, address, w.DerivationPath)
+			added++
+		case config.RederiveWallets:
+			err = db.ResetWallet(ctx, address, w.DerivationPath)
+			reset++
+		default:
+			reused++
+			if firstUsed.IsZero() || s.CreatedAt.Before(firstUsed) {
+				firstUsed = s.CreatedAt
+			}
+			if s.DerivationPath != w.DerivationPath {
+				logger.Debug("  Wallet %d %s was stored as %s, now %s\n", i, address, s.DerivationPath, w.DerivationPath)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if reused > 0 {
+		logger.Info("✓ Reusing %d wallets stored by previous runs (first used %s)\n", reused, firstUsed.Format("2006-01-02 15:04"))
+	}
+	if reset > 0 {
+		logger.Info("✓ Stored %d wallets of previous runs as new (REDERIVE_WALLETS)\n", reset)
+	}
+	if added > 0 {
+		logger.Info("✓ Saved %d new wallets\n", added)
+	}
+	return nil
+}
+
+// reconcileStoredNonces cross-checks each wallet's stored next nonce against
+// the chain and flags mismatches, e.g. transactions that were dropped or a
+// wallet used elsewhere. With NONCE_SOURCE=local the stored nonce is the
+// starting point, and the chain is only needed for wallets without one;
+// otherwise wallets keep the nonce read from the chain.
+func reconcileStoredNonces(config *config.Config, db dbpkg.Store, txSender *txpkg.TransactionSender, wallets []*wallet.Wallet) error {
+	local := strings.EqualFold(config.NonceSource, nonceSourceLocal)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	stored, err := db.GetWalletNonces(ctx)
+	if err != nil {
+		if local {
+			return err
+		}
+		logger.Warn("Could not load stored nonces, skipping cross-check: %v\n", err)
+		return nil
+	}
+
+	mismatched, unchecked := 0, 0
+	for i, w := range wallets {
+		storedNonce, ok := stored[w.Address.Hex()]
+		chainNonce, chainErr := w.Nonce, error(nil)
+		if local {
+			chainNonce, chainErr = txSender.NextNonce(ctx, w.Address)
+			switch {
+			case ok:
+				w.Nonce = storedNonce
+			case chainErr != nil:
+				return fmt.Errorf("wallet %d has no stored nonce and the chain could not be asked: %w", i, chainErr)
+			default:
+				w.Nonce = chainNonce
+			}
+		}
+
+		switch {
+		case !ok:
+		case chainErr != nil:
+			unchecked++
+			logger.Debug("  Could not cross-check wallet %d against the chain: %v\n", i, chainErr)
+		case storedNonce != chainNonce:
+			mismatched++
+			logger.Warn("  ⚠️  Wallet %d %s: stored next nonce %d, chain reports %d\n", i, w.Address.Hex(), storedNonce, chainNonce)
+		}
+	}
+
+	if unchecked > 0 {
+		logger.Warn("⚠️  %d wallets start from their stored nonce without a chain cross-check\n", unchecked)
+	}
+	if mismatched > 0 {
+		if local {
+			logger.Warn("⚠️  %d wallets' stored nonces differ from the chain; starting from the stored nonces (NONCE_SOURCE=local)\n", mismatched)
+		} else {
+			logger.Warn("⚠️  %d wallets' stored nonces differ from the chain; starting from the chain nonces\n", mismatched)
+		}
+	}
+	return nil
+}
+
+// storeWalletNonces records each wallet's next nonce, e.g. after workload
+// setup sent transactions the DB writer never sees.
+func storeWalletNonces(db dbpkg.Store, wallets []*wallet.Wallet) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for _, w := range wallets {
+		if err := db.SetWalletNonce(ctx, w.Address.Hex(), w.Nonce); err != nil {
+			logger.Warn("Could not store nonce of %s: %v\n", w.Address.Hex(), err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Summary is a run or iteration summary formatted for a chat channel.
+type Summary struct {
+	Title  string
+	Fields []Field // shown in order, side by side where the chat allows
+	Link   string  // e.g. the HTML report; empty = none
+	Failed bool    // something went wrong; Discord shows the embed in red
+}
+
+// Field is one labelled value of a Summary, e.g. TPS.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Embed colours on Discord.
+const (
+	discordGreen = 0x2eb67d
+	discordRed   = 0xe01e5a
+)
+
+// Chat posts summaries to a Slack or Discord incoming webhook. Discord is
+// recognised by its URL; any other URL gets Slack's format, which
+// Mattermost and Rocket.Chat understand too.
+type Chat struct {
+	hook    *Webhook
+	discord bool
+}
+
+// NewChat returns a Chat posting to the incoming webhook at rawURL.
+func NewChat(rawURL string) (*Chat, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" && u.Scheme != "http" || u.Host == "" {
+		return nil, fmt.Errorf("invalid chat webhook URL: want an http(s) URL")
+	}
+	host := strings.ToLower(u.Hostname())
+	discord := host == "discord.com" || host == "discordapp.com" || strings.HasSuffix(host, ".discord.com")
+	return &Chat{hook: NewWebhook(rawURL, nil), discord: discord}, nil
+}
+
+// Post sends s, retrying like Webhook.Send.
+func (c *Chat) Post(s Summary) error {
+	var payload any
+	if c.discord {
+		payload = discordMessage(s)
+	} else {
+		payload = slackMessage(s)
+	}
+	if err := c.hook.postJSON(payload); err != nil {
+		return fmt.Errorf("failed to post to chat: %w", err)
+	}
+	return nil
+}
+
+// slackMessage lays s out in Block Kit: a header, the fields in two
+// columns and the link. text is the notification and fallback.
+func slackMessage(s Summary) map[string]any {
+	text := func(kind, t string) map[string]any { return map[string]any{"type": kind, "text": t} }
+	var fields []map[string]any
+	var plain []string
+	for _, f := range s.Fields {
+		fields = append(fields, text("mrkdwn", fmt.Sprintf("*%s*\n%s", f.Name, f.Value)))
+		plain = append(plain, f.Name+": "+f.Value)
+	}
+	blocks := []map[string]any{{"type": "header", "text": text("plain_text", s.Title)}}
+	// A section holds at most 10 fields
+	for len(fields) > 0 {
+		n := min(len(fields), 10)
+		blocks = append(blocks, map[string]any{"type": "section", "fields": fields[:n]})
+		fields = fields[n:]
+	}
+	if s.Link != "" {
+		blocks = append(blocks, map[string]any{"type": "section", "text": text("mrkdwn", fmt.Sprintf("<%s|Full report>", s.Link))})
+	}
+	return map[string]any{
+		"text":   s.Title + ": " + strings.Join(plain, ", "),
+		"blocks": blocks,
+	}
+}
+
+// discordMessage lays s out as one embed with inline fields.
+func discordMessage(s Summary) map[string]any {
+	color := discordGreen
+	if s.Failed {
+		color = discordRed
+	}
+	var fields []map[string]any
+	for _, f := range s.Fields {
+		fields = append(fields, map[string]any{"name": f.Name, "value": f.Value, "inline": true})
+	}
+	embed := map[string]any{
+		"title":     s.Title,
+		"color":     color,
+		"fields":    fields,
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	}
+	if s.Link != "" {
+		embed["url"] = s.Link
+		embed["description"] = fmt.Sprintf("[Full report](%s)", s.Link)
+	}
+	return map[string]any{"embeds": []any{embed}}
+}
@@ -0,0 +1,122 @@
+// Package notify posts run events, such as a finished run or a breached
+// alert threshold, as JSON to a webhook, and run summaries to Slack or
+// Discord.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Events a webhook receives.
+const (
+	RunCompleted       = "run_completed"
+	ThresholdBreached  = "threshold_breached"
+	ThresholdRecovered = "threshold_recovered"
+)
+
+// Event is the JSON body of every webhook call.
+type Event struct {
+	Event   string    `json:"event"`
+	Time    time.Time `json:"time"`
+	Host    string    `json:"host"`
+	Message string    `json:"message"` // one line for people, e.g. to forward to chat
+	Alert   *Alert    `json:"alert,omitempty"`
+	Summary any       `json:"summary,omitempty"` // the JSON run summary, with run_completed
+}
+
+// Alert is the threshold a threshold_breached or threshold_recovered event
+// is about.
+type Alert struct {
+	Metric        string  `json:"metric"` // error_rate (percent) or p95_latency (seconds)
+	Value         float64 `json:"value"`
+	Threshold     float64 `json:"threshold"`
+	WindowSeconds int     `json:"window_seconds"`
+	Samples       int     `json:"samples"`
+}
+
+// Delays before the retries of a failed call.
+var retryDelays = []time.Duration{2 * time.Second, 10 * time.Second}
+
+// Webhook posts events to one URL.
+type Webhook struct {
+	url     string
+	headers http.Header
+	client  *http.Client
+	host    string
+}
+
+// NewWebhook returns a Webhook posting to url with headers on every call,
+// e.g. an Authorization header.
+func NewWebhook(url string, headers http.Header) *Webhook {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return &Webhook{url: url, headers: headers, client: &http.Client{Timeout: 10 * time.Second}, host: host}
+}
+
+// Send posts e, filling in its time and host, and retries twice if the
+// call fails or the webhook answers with a 5xx or 429 status.
+func (w *Webhook) Send(e Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Host = w.host
+	if err := w.postJSON(e); err != nil {
+		return fmt.Errorf("failed to send %s event: %w", e.Event, err)
+	}
+	return nil
+}
+
+// postJSON posts v as JSON, with the retries of Send.
+func (w *Webhook) postJSON(v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == len(retryDelays) {
+			return err
+		}
+		time.Sleep(retryDelays[attempt])
+	}
+}
+
+// post makes one call and says whether a failure is worth retrying.
+func (w *Webhook) post(body []byte) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for name, values := range w.headers {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("webhook returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return false, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	dbpkg "go-tps/db"
+	"go-tps/logger"
+	"go-tps/report"
+)
+
+// progressReporter prints a one-line snapshot of the run every interval,
+// from the transactions the workers store and resolve, so long runs can be
+// followed before the end-of-run reports. With a database set the
+// snapshots are also stored in progress_stats.
+type progressReporter struct {
+	interval time.Duration
+	db       dbpkg.Store // nil = not stored
+
+	mu        sync.Mutex
+	batch     string // of the last stored transaction
+	submitted int    // since the start of the run
+	confirmed int
+	failed    int // failed sends and failed or reverted receipts
+	resolved  int // receipts of any outcome
+	window    progressWindow
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// progressWindow counts the events since the last snapshot.
+type progressWindow struct {
+	submitted int
+	confirmed int
+	latencies []float64
+}
+
+// newProgressReporter starts reporting, or returns nil if interval is not
+// positive.
+func newProgressReporter(interval time.Duration, db dbpkg.Store) *progressReporter {
+	if interval <= 0 {
+		return nil
+	}
+	p := &progressReporter{
+		interval: interval,
+		db:       db,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// Stored counts a submitted or failed send.
+func (p *progressReporter) Stored(tx *dbpkg.Transaction) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.batch = tx.BatchNumber
+	switch {
+	case tx.TxHash != "":
+		p.submitted++
+		p.window.submitted++
+	case tx.Status == "failed":
+		p.failed++
+	}
+}
+
+// Resolved counts a receipt outcome and its confirmation latency.
+func (p *progressReporter) Resolved(tx *dbpkg.Transaction) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.resolved++
+	if tx.Status == "success" {
+		p.confirmed++
+		p.window.confirmed++
+	} else {
+		p.failed++
+	}
+	if tx.ConfirmedAt != nil {
+		p.window.latencies = append(p.window.latencies, tx.ConfirmedAt.Sub(tx.SubmittedAt).Seconds())
+	}
+}
+
+func (p *progressReporter) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			p.report(now)
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// Stop stops reporting.
+func (p *progressReporter) Stop() {
+	if p == nil {
+		return
+	}
+	close(p.stop)
+	<-p.done
+}
+
+// report prints, and stores, the snapshot at now and starts a new window.
+func (p *progressReporter) report(now time.Time) {
+	p.mu.Lock()
+	seconds := p.interval.Seconds()
+	stat := &dbpkg.ProgressStat{
+		RecordedAt:    now,
+		BatchNumber:   p.batch,
+		WindowSeconds: seconds,
+		SubmitTPS:     float64(p.window.submitted) / seconds,
+		ConfirmTPS:    float64(p.window.confirmed) / seconds,
+		Inflight:      p.submitted - p.resolved,
+		Submitted:     p.submitted,
+		Confirmed:     p.confirmed,
+		Failed:        p.failed,
+		P95:           report.Percentile(p.window.latencies, 95),
+	}
+	latencies := len(p.window.latencies)
+	p.window = progressWindow{}
+	p.mu.Unlock()
+
+	if stat.Submitted == 0 && stat.Failed == 0 {
+		return // nothing sent yet
+	}
+	p95 := "-"
+	if latencies > 0 {
+		p95 = report.Seconds(stat.P95, 2)
+	}
+	fmt.Printf("[%s] %s tx/s sent, %s tx/s confirmed | in flight %s | confirmed %s | failed %s | p95 %s (last %s)\n",
+		now.Format("15:04:05"), report.Float(stat.SubmitTPS, 1), report.Float(stat.ConfirmTPS, 1),
+		report.Int(stat.Inflight), report.Int(stat.Confirmed), report.Int(stat.Failed), p95, p.interval)
+
+	if p.db == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := p.db.InsertProgressStat(ctx, stat); err != nil {
+		logger.Warn("%v\n", err)
+	}
+}
@@ -301,6 +301,30 @@ SELECT
     MAX(gas_limit) as max_gas_limit
 FROM transactions;
 
+-- Gas fees paid per batch (cost is gas_used * effective_gas_price in wei)
+SELECT
+    batch_number,
+    COUNT(*) as tx_count,
+    SUM(gas_used) as total_gas_used,
+    ROUND(SUM(CAST(cost AS REAL)) / 1e18, 6) as eth_spent,
+    ROUND(AVG(CAST(cost AS REAL)), 0) as avg_cost_wei
+FROM transactions
+WHERE cost IS NOT NULL AND cost != ''
+GROUP BY batch_number
+ORDER BY batch_number DESC;
+
+-- Estimated vs actual gas used per recipient
+SELECT
+    to_address,
+    COUNT(*) as tx_count,
+    ROUND(AVG(gas_estimated), 0) as avg_gas_estimated,
+    ROUND(AVG(gas_limit), 0) as avg_gas_limit,
+    ROUND(AVG(gas_used), 0) as avg_gas_used,
+    MAX(gas_used) as max_gas_used
+FROM transactions
+WHERE gas_estimated > 0 AND gas_used IS NOT NULL
+GROUP BY to_address;
+
 -- Total value transferred (in wei)
 SELECT 
     COUNT(*) as tx_count,
@@ -397,3 +421,13 @@ SELECT
     'Unique Wallets:',
     CAST(COUNT(DISTINCT wallet_address) as TEXT)
 FROM transactions;
+
+-- Base fee and block fullness over time
+SELECT
+    block_number,
+    timestamp,
+    ROUND(CAST(base_fee AS REAL) / 1e9, 3) as base_fee_gwei,
+    ROUND(100.0 * gas_used / gas_limit, 1) as gas_used_pct
+FROM block_metrics
+ORDER BY block_number DESC
+LIMIT 100;
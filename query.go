@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go-tps/config"
+	dbpkg "go-tps/db"
+	"go-tps/logger"
+	"go-tps/report"
+)
+
+// runBatchesCommand implements `go-tps batches`: every batch in the
+// database with its transaction counts.
+func runBatchesCommand(config *config.Config, args []string) int {
+	fs := flag.NewFlagSet("batches", flag.ContinueOnError)
+	dbPath := fs.String("db", config.DBPath, "SQLite database to read")
+	last := fs.Int("last", 0, "only list the most recent N batches (0 = all)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	db, err := openDB(config, *dbPath)
+	if err != nil {
+		logger.Error("Error opening database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	sizes, err := db.ListBatchSizes(ctx)
+	if err != nil {
+		logger.Error("Error listing batches: %v\n", err)
+		return 1
+	}
+	if len(sizes) == 0 {
+		fmt.Println("No batches in database.")
+		return 0
+	}
+	if *last > 0 && len(sizes) > *last {
+		sizes = sizes[len(sizes)-*last:]
+	}
+
+	fmt.Printf("%-28s %-16s %8s %8s %8s %8s\n", "Batch", "Started", "Txs", "Success", "Failed", "Pending")
+	for _, s := range sizes {
+		stats, err := db.GetBatchStats(ctx, s.BatchNumber)
+		if err != nil {
+			logger.Error("Error loading %s: %v\n", s.BatchNumber, err)
+			return 1
+		}
+		fmt.Printf("%-28s %-16s %8s %8s %8s %8s\n", s.BatchNumber, s.FirstSubmitted.Format("2006-01-02 15:04"),
+			report.Int(s.Transactions), report.Int(stats["successful"].(int)),
+			report.Int(stats["failed"].(int)), report.Int(stats["pending"].(int)))
+	}
+	return 0
+}
+
+// runStatsCommand implements `go-tps stats [BATCH]`: the stats of one
+// batch, the latest by default.
+func runStatsCommand(config *config.Config, args []string) int {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	dbPath := fs.String("db", config.DBPath, "SQLite database to read")
+	asJSON := fs.Bool("json", false, "print the stats as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	db, err := openDB(config, *dbPath)
+	if err != nil {
+		logger.Error("Error opening database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	batch := fs.Arg(0)
+	if batch == "" {
+		batches, err := db.ListBatches(ctx)
+		if err != nil {
+			logger.Error("Error listing batches: %v\n", err)
+			return 1
+		}
+		if len(batches) == 0 {
+			fmt.Println("No batches in database.")
+			return 1
+		}
+		batch = batches[len(batches)-1]
+	}
+	stats, err := db.GetBatchStats(ctx, batch)
+	if err != nil {
+		logger.Error("%v\n", err)
+		return 1
+	}
+	if stats["total_transactions"].(int) == 0 {
+		fmt.Printf("No batch %q in database.\n", batch)
+		return 1
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(stats); err != nil {
+			logger.Error("%v\n", err)
+			return 1
+		}
+		return 0
+	}
+	printBatchStats(stats)
+	return 0
+}
+
+// printBatchStats prints what GetBatchStats returned.
+func printBatchStats(stats map[string]interface{}) {
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("BATCH %s\n", stats["batch_number"])
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("Transactions:   %s\n", report.Int(stats["total_transactions"].(int)))
+	fmt.Printf("  Successful:   %s\n", report.Int(stats["successful"].(int)))
+	fmt.Printf("  Failed:       %s\n", report.Int(stats["failed"].(int)))
+	fmt.Printf("  Pending:      %s\n", report.Int(stats["pending"].(int)))
+	fmt.Printf("  Cancelled:    %s\n", report.Int(stats["cancelled"].(int)))
+	fmt.Printf("Gas used:       %s\n", report.Int(stats["total_gas_used"].(uint64)))
+	fmt.Printf("ETH spent:      %s\n", report.Float(stats["total_eth_spent"].(float64), 6))
+
+	fmt.Println()
+	fmt.Printf("%-14s", "Latency")
+	for _, p := range dbpkg.LatencyPercentiles {
+		fmt.Printf(" %9s", fmt.Sprintf("p%g", p))
+	}
+	fmt.Println()
+	fmt.Printf("%-14s", "Submission")
+	for _, p := range dbpkg.LatencyPercentiles {
+		fmt.Printf(" %9s", report.Seconds(stats[fmt.Sprintf("submission_p%g_ms", p)].(float64)/1000, 3))
+	}
+	fmt.Println()
+	fmt.Printf("%-14s", "Confirmation")
+	for _, p := range dbpkg.LatencyPercentiles {
+		fmt.Printf(" %9s", report.Seconds(stats[fmt.Sprintf("confirmation_p%g_seconds", p)].(float64), 2))
+	}
+	fmt.Println()
+
+	if spanned := stats["blocks_spanned"].(uint64); spanned > 0 {
+		fmt.Println()
+		fmt.Printf("Blocks:         %s to %s (%s spanned, %s with transactions)\n",
+			report.Int(stats["first_block"].(uint64)), report.Int(stats["last_block"].(uint64)),
+			report.Int(spanned), report.Int(stats["blocks_with_txs"].(int)))
+		fmt.Printf("Inclusion:      p50 %s, p95 %s, max %s blocks after the first\n",
+			report.Float(stats["inclusion_delay_p50_blocks"].(float64), 0),
+			report.Float(stats["inclusion_delay_p95_blocks"].(float64), 0),
+			report.Float(stats["inclusion_delay_max_blocks"].(float64), 0))
+	}
+	fmt.Println(strings.Repeat("=", 60))
+}
+
+// runFailedCommand implements `go-tps failed`: the most recent failed
+// transactions with their errors.
+func runFailedCommand(config *config.Config, args []string) int {
+	fs := flag.NewFlagSet("failed", flag.ContinueOnError)
+	dbPath := fs.String("db", config.DBPath, "SQLite database to read")
+	batch := fs.String("batch", "", "only this batch (default: every batch)")
+	limit := fs.Int("limit", 50, "show at most this many (0 = all)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	db, err := openDB(config, *dbPath)
+	if err != nil {
+		logger.Error("Error opening database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	txs, err := db.GetFailedTransactions(ctx, *batch, *limit)
+	if err != nil {
+		logger.Error("%v\n", err)
+		return 1
+	}
+	if len(txs) == 0 {
+		fmt.Println("✓ No failed transactions")
+		return 0
+	}
+
+	categories := make(map[string]int)
+	fmt.Printf("%-19s %-28s %6s %-18s %s\n", "Submitted", "Batch", "Nonce", "Category", "Error")
+	for _, t := range txs {
+		msg := t.Error
+		if len(msg) > 80 {
+			msg = msg[:77] + "..."
+		}
+		fmt.Printf("%-19s %-28s %6d %-18s %s\n", t.SubmittedAt.Format("2006-01-02 15:04:05"), t.BatchNumber, t.Nonce, t.ErrorCategory, msg)
+		categories[t.ErrorCategory]++
+	}
+
+	names := make([]string, 0, len(categories))
+	for name := range categories {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return categories[names[i]] > categories[names[j]] })
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s %s", report.Int(categories[name]), name)
+	}
+	fmt.Printf("\n%s shown: %s\n", report.Int(len(txs)), strings.Join(parts, ", "))
+	if *limit > 0 && len(txs) == *limit {
+		fmt.Println("There may be more; -limit 0 shows them all")
+	}
+	return 0
+}
+
+// runTxCommand implements `go-tps tx HASH`: everything stored about one
+// transaction.
+func runTxCommand(config *config.Config, args []string) int {
+	fs := flag.NewFlagSet("tx", flag.ContinueOnError)
+	dbPath := fs.String("db", config.DBPath, "SQLite database to read")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Println("Usage: go-tps tx [-db PATH] HASH")
+		return 2
+	}
+	db, err := openDB(config, *dbPath)
+	if err != nil {
+		logger.Error("Error opening database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	hash := strings.ToLower(fs.Arg(0))
+	if !strings.HasPrefix(hash, "0x") {
+		hash = "0x" + hash
+	}
+	t, err := db.GetTransactionByHash(ctx, hash)
+	if err != nil {
+		logger.Error("%v\n", err)
+		return 1
+	}
+	if t == nil {
+		fmt.Printf("No transaction %s in database.\n", hash)
+		return 1
+	}
+
+	row := func(label, value string) {
+		if value != "" {
+			fmt.Printf("%-20s %s\n", label+":", value)
+		}
+	}
+	row("Hash", t.TxHash)
+	row("Status", t.Status)
+	row("Batch", t.BatchNumber)
+	if t.RunID != 0 {
+		row("Run", fmt.Sprintf("#%d", t.RunID))
+	}
+	row("From", t.WalletAddress)
+	row("To", t.ToAddress)
+	row("Nonce", fmt.Sprint(t.Nonce))
+	row("Value (wei)", t.Value)
+	row("Max fee (wei)", t.GasPrice)
+	row("Gas limit", report.Int(t.GasLimit))
+	if t.GasEstimated > 0 {
+		row("Gas estimated", report.Int(t.GasEstimated))
+	}
+	row("Submitted", t.SubmittedAt.Format(time.RFC3339Nano))
+	row("Submission latency", report.Seconds(t.ExecutionTime/1000, 3))
+	row("RPC endpoint", t.RPCEndpoint)
+	if t.SubmitWorker != 0 {
+		row("Submit worker", fmt.Sprintf("[W%d]", t.SubmitWorker))
+	}
+	row("Phase", t.Phase)
+	if t.ConfirmedAt != nil {
+		row("Confirmed", t.ConfirmedAt.Format(time.RFC3339))
+		row("Confirmation", report.Seconds(t.ConfirmedAt.Sub(t.SubmittedAt).Seconds(), 2))
+	}
+	if t.ReceiptWorker != 0 {
+		row("Receipt worker", fmt.Sprintf("[W%d]", t.ReceiptWorker))
+	}
+	row("Receipt endpoint", t.ReceiptEndpoint)
+	row("Receipt source", t.ReceiptSource)
+	if t.BlockHash != "" {
+		row("Block", fmt.Sprintf("%s (%s), index %d", report.Int(t.BlockNumber), t.BlockHash, t.TxIndex))
+	}
+	if t.GasUsed > 0 {
+		row("Gas used", report.Int(t.GasUsed))
+	}
+	row("Effective gas price", t.EffectiveGasPrice)
+	row("Cost (wei)", t.Cost)
+	if t.L1Fee != "" && t.L1Fee != "0" {
+		row("L1 fee (wei)", t.L1Fee)
+		row("L2 fee (wei)", t.L2Fee)
+	}
+	row("Error", t.Error)
+	row("Error category", t.ErrorCategory)
+	row("Trace parent", t.TraceParent)
+	return 0
+}
@@ -0,0 +1,55 @@
+package rate
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// Send distributions: how the gaps between send slots are spread around the
+// mean interval of 1/rate.
+const (
+	DistributionUniform = "uniform" // evenly spaced slots
+	DistributionPoisson = "poisson" // exponentially distributed gaps, like independent users
+	DistributionBurst   = "burst"   // groups of slots at once, then a pause
+)
+
+// SetDistribution sets how the limiter spreads its slots. The mean rate is
+// unchanged: poisson draws each gap from an exponential distribution, burst
+// releases burstSize slots together and then waits burstSize intervals.
+func (l *Limiter) SetDistribution(dist string, burstSize int) error {
+	dist = strings.ToLower(dist)
+	switch dist {
+	case DistributionUniform, DistributionPoisson:
+	case DistributionBurst:
+		if burstSize < 1 {
+			return fmt.Errorf("burst size must be at least 1, got %d", burstSize)
+		}
+	default:
+		return fmt.Errorf("unknown send distribution %q (available: uniform, poisson, burst)", dist)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.dist = dist
+	l.burstSize = burstSize
+	l.inBurst = 0
+	return nil
+}
+
+// gap returns the time from the slot just reserved to the next one. Callers
+// hold l.mu.
+func (l *Limiter) gap() time.Duration {
+	switch l.dist {
+	case DistributionPoisson:
+		return time.Duration(rand.ExpFloat64() * float64(l.interval))
+	case DistributionBurst:
+		l.inBurst++
+		if l.inBurst < l.burstSize {
+			return 0
+		}
+		l.inBurst = 0
+		return time.Duration(l.burstSize) * l.interval
+	}
+	return l.interval
+}
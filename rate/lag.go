@@ -0,0 +1,148 @@
+package rate
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LagMonitor compares when submissions were scheduled with when they
+// actually went out. A lag that keeps growing means the generator itself
+// cannot keep up with the requested rate, so the achieved rate says nothing
+// about the chain.
+type LagMonitor struct {
+	requestedRate float64 // transactions per second
+
+	mu      sync.Mutex
+	start   time.Time
+	last    time.Time
+	sent    int
+	samples []lagSample
+}
+
+type lagSample struct {
+	at  float64 // seconds since start
+	lag float64 // seconds behind schedule
+}
+
+// LagReport summarises a run's achieved-vs-requested submission rate.
+type LagReport struct {
+	RequestedRate float64
+	AchievedRate  float64
+	Sent          int
+	Duration      time.Duration
+	MaxLag        time.Duration
+	FinalLag      time.Duration
+	LagGrowth     float64 // seconds of lag gained per second of run
+}
+
+// Lag growth above this, with the achieved rate below shedThreshold of the
+// requested one, is reported as generator-limited.
+const (
+	maxLagGrowth  = 0.05
+	shedThreshold = 0.95
+	minLagSamples = 3
+)
+
+func NewLagMonitor(requestedRate float64) *LagMonitor {
+	return &LagMonitor{requestedRate: requestedRate}
+}
+
+// Observe records that n transactions scheduled for scheduled were sent by
+// actual.
+func (m *LagMonitor) Observe(scheduled, actual time.Time, n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.start.IsZero() {
+		m.start = scheduled
+	}
+	if actual.After(m.last) {
+		m.last = actual
+	}
+	m.sent += n
+
+	lag := actual.Sub(scheduled).Seconds()
+	if lag < 0 {
+		lag = 0
+	}
+	m.samples = append(m.samples, lagSample{at: actual.Sub(m.start).Seconds(), lag: lag})
+}
+
+func (m *LagMonitor) Report() LagReport {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r := LagReport{RequestedRate: m.requestedRate, Sent: m.sent}
+	if m.start.IsZero() {
+		return r
+	}
+	r.Duration = m.last.Sub(m.start)
+	if r.Duration > 0 {
+		r.AchievedRate = float64(m.sent) / r.Duration.Seconds()
+	}
+
+	for _, s := range m.samples {
+		if lag := time.Duration(s.lag * float64(time.Second)); lag > r.MaxLag {
+			r.MaxLag = lag
+		}
+	}
+	if n := len(m.samples); n > 0 {
+		r.FinalLag = time.Duration(m.samples[n-1].lag * float64(time.Second))
+	}
+	r.LagGrowth = slope(m.samples)
+	return r
+}
+
+// slope is the least-squares slope of lag over time.
+func slope(samples []lagSample) float64 {
+	n := float64(len(samples))
+	if len(samples) < minLagSamples {
+		return 0
+	}
+	var sumX, sumY, sumXY, sumXX float64
+	for _, s := range samples {
+		sumX += s.at
+		sumY += s.lag
+		sumXY += s.at * s.lag
+		sumXX += s.at * s.at
+	}
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denom
+}
+
+// GeneratorLimited reports whether the scheduler fell steadily behind while
+// delivering noticeably less than the requested rate.
+func (r LagReport) GeneratorLimited() bool {
+	if r.RequestedRate <= 0 || r.AchievedRate <= 0 {
+		return false
+	}
+	return r.LagGrowth > maxLagGrowth && r.AchievedRate < r.RequestedRate*shedThreshold
+}
+
+func PrintLagReport(r LagReport) {
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("SUBMISSION RATE")
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("Requested rate: %.2f tx/s\n", r.RequestedRate)
+	fmt.Printf("Achieved rate:  %.2f tx/s (%d txs in %.1fs)\n", r.AchievedRate, r.Sent, r.Duration.Seconds())
+	if r.RequestedRate > 0 {
+		fmt.Printf("Achieved/requested: %.1f%%\n", r.AchievedRate/r.RequestedRate*100)
+	}
+	fmt.Printf("Scheduler lag: final %.2fs, max %.2fs, growth %.3fs/s\n",
+		r.FinalLag.Seconds(), r.MaxLag.Seconds(), r.LagGrowth)
+
+	if r.GeneratorLimited() {
+		fmt.Println()
+		fmt.Println("⚠️  GENERATOR-LIMITED: the submission scheduler fell steadily behind")
+		fmt.Println("   the requested rate. The achieved rate reflects this machine's")
+		fmt.Println("   ability to sign and submit, not the chain's throughput.")
+		fmt.Println("   Use fewer wallets/txs, more CPU, or a closer RPC endpoint.")
+	}
+	fmt.Println(strings.Repeat("=", 60))
+}
@@ -0,0 +1,93 @@
+package rate
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter paces submissions to a rate shared by every wallet goroutine:
+// constant, or following a profile such as a ramp. It is a token bucket kept
+// as a schedule: each Wait reserves the next send slot, 1/rate after the
+// previous one, so the load is open-loop and does not slow down when the
+// chain does. Up to burst slots that were missed while nobody was waiting
+// may be used at once. Slots are evenly spaced unless SetDistribution says
+// otherwise.
+type Limiter struct {
+	profile profile // nil = constant rate
+	burst   int
+
+	mu        sync.Mutex
+	interval  time.Duration // mean time between slots
+	slack     time.Duration // how far behind now the schedule may fall
+	next      time.Time     // next free slot
+	dist      string        // how slots are spread, see SetDistribution
+	burstSize int           // slots per group with the burst distribution
+	inBurst   int           // slots reserved in the current group
+}
+
+// NewLimiter returns a limiter for tps transactions per second, or nil if tps
+// is not positive. burst below 1 is treated as 1.
+func NewLimiter(tps float64, burst int) *Limiter {
+	if tps <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	l := &Limiter{burst: burst, dist: DistributionUniform}
+	l.setRate(tps)
+	return l
+}
+
+// Wait blocks until the caller's send slot and returns when it was
+// scheduled, for measuring how far submissions lag behind the schedule. It
+// returns false without waiting the full time if stop is closed.
+func (l *Limiter) Wait(stop <-chan struct{}) (time.Time, bool) {
+	l.mu.Lock()
+	now := time.Now()
+	if l.profile != nil {
+		l.profile.begin(now)
+		l.setRate(l.profile.RateAt(now))
+	}
+	slot := l.next
+	if earliest := now.Add(-l.slack); slot.Before(earliest) {
+		slot = earliest
+	}
+	if l.profile != nil {
+		l.setRate(l.profile.RateAt(slot))
+	}
+	l.next = slot.Add(l.gap())
+	l.mu.Unlock()
+
+	wait := time.Until(slot)
+	if wait <= 0 {
+		return slot, true
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return slot, true
+	case <-stop:
+		return slot, false
+	}
+}
+
+// profile is a rate that changes over the run, e.g. a ramp. Its clock
+// starts with the first send.
+type profile interface {
+	begin(t time.Time)
+	RateAt(t time.Time) float64
+}
+
+func (l *Limiter) setRate(tps float64) {
+	l.interval = time.Duration(float64(time.Second) / tps)
+	l.slack = time.Duration(l.burst-1) * l.interval
+}
+
+// SetRate changes the limiter's rate for the slots reserved from now on.
+func (l *Limiter) SetRate(tps float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.setRate(tps)
+}
@@ -0,0 +1,125 @@
+package rate
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"go-tps/db"
+	"go-tps/report"
+)
+
+// Ramp is a load profile whose rate rises (or falls) linearly from Start to
+// End tx/s over Duration, then holds End. The clock starts with the first
+// send, so wallet setup does not eat into the ramp.
+type Ramp struct {
+	Start    float64
+	End      float64
+	Duration time.Duration
+
+	once  sync.Once
+	began time.Time
+}
+
+// begin starts the ramp's clock at t unless it is already running.
+func (r *Ramp) begin(t time.Time) {
+	r.once.Do(func() { r.began = t })
+}
+
+// RateAt returns the offered rate at t.
+func (r *Ramp) RateAt(t time.Time) float64 {
+	elapsed := t.Sub(r.began)
+	if r.began.IsZero() || elapsed <= 0 {
+		return r.Start
+	}
+	if elapsed >= r.Duration {
+		return r.End
+	}
+	return r.Start + (r.End-r.Start)*elapsed.Seconds()/r.Duration.Seconds()
+}
+
+// NewRampLimiter returns a limiter whose rate follows ramp, or nil if the
+// ramp has no duration or a rate that is not positive.
+func NewRampLimiter(ramp *Ramp, burst int) *Limiter {
+	if ramp.Duration <= 0 || ramp.Start <= 0 || ramp.End <= 0 {
+		return nil
+	}
+	l := NewLimiter(ramp.Start, burst)
+	l.profile = ramp
+	return l
+}
+
+// RampBucket aggregates the transactions submitted while the offered rate
+// was within [From, To) tx/s.
+type RampBucket struct {
+	From, To  float64
+	Txs       int
+	Included  int
+	Failed    int
+	latencies []float64
+}
+
+// P50 and P95 return inclusion latency percentiles in seconds.
+func (b *RampBucket) P50() float64 { return report.Percentile(b.latencies, 50) }
+func (b *RampBucket) P95() float64 { return report.Percentile(b.latencies, 95) }
+
+// BuildRampReport buckets txs by the rate the ramp offered when each was
+// submitted, into n equal-width rate ranges.
+func BuildRampReport(ramp *Ramp, txs []*db.Transaction, n int) []*RampBucket {
+	low, high := math.Min(ramp.Start, ramp.End), math.Max(ramp.Start, ramp.End)
+	if high == low {
+		n = 1
+	}
+	width := (high - low) / float64(n)
+	buckets := make([]*RampBucket, n)
+	for i := range buckets {
+		buckets[i] = &RampBucket{From: low + float64(i)*width, To: low + float64(i+1)*width}
+	}
+
+	for _, tx := range txs {
+		if tx.TxHash == "" && tx.Status != "failed" {
+			continue // never sent (budget, abort)
+		}
+		i := n - 1
+		if width > 0 {
+			i = min(int((ramp.RateAt(tx.SubmittedAt)-low)/width), n-1)
+		}
+		b := buckets[i]
+		b.Txs++
+		if tx.Status == "failed" {
+			b.Failed++
+		}
+		if tx.ConfirmedAt != nil {
+			b.Included++
+			b.latencies = append(b.latencies, tx.ConfirmedAt.Sub(tx.SubmittedAt).Seconds())
+		}
+	}
+	return buckets
+}
+
+// PrintRampReport prints one row per offered-rate bucket, so the rate at
+// which inclusion latency starts to degrade stands out.
+func PrintRampReport(ramp *Ramp, buckets []*RampBucket) {
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("LATENCY BY OFFERED RATE")
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("Ramp: %s → %s tx/s over %s\n", report.Float(ramp.Start, 1), report.Float(ramp.End, 1), ramp.Duration)
+	fmt.Printf("%-16s %6s %8s %8s %8s %6s\n", "Offered (tx/s)", "Txs", "Included", "p50", "p95", "Fail%")
+	for _, b := range buckets {
+		label := fmt.Sprintf("%s-%s", report.Float(b.From, 1), report.Float(b.To, 1))
+		if b.Txs == 0 {
+			fmt.Printf("%-16s %6d %8s %8s %8s %6s\n", label, 0, "-", "-", "-", "-")
+			continue
+		}
+		p50, p95 := "-", "-"
+		if b.Included > 0 {
+			p50, p95 = report.Seconds(b.P50(), 2), report.Seconds(b.P95(), 2)
+		}
+		fmt.Printf("%-16s %6s %8s %8s %8s %6s\n", label, report.Int(b.Txs), report.Int(b.Included), p50, p95,
+			report.Percent(float64(b.Failed)/float64(b.Txs)*100, 1))
+	}
+	fmt.Println(strings.Repeat("=", 60))
+}
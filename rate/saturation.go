@@ -0,0 +1,56 @@
+package rate
+
+// SaturationSearch picks the offered rates for a saturation search: it
+// doubles the rate from Start while probes pass, then bisects between the
+// highest passing and the lowest failing rate until they are within
+// Precision of each other.
+type SaturationSearch struct {
+	Start     float64 // first offered rate in tx/s
+	Max       float64 // never offer more than this (0 = no cap)
+	Precision float64 // stop once (fail-pass)/fail is at most this fraction
+	MaxProbes int     // stop after this many probes (0 = no cap)
+
+	probes int
+	pass   float64 // highest passing rate (0 = none yet)
+	fail   float64 // lowest failing rate (0 = none yet)
+}
+
+// Next returns the rate to probe next, or false once the search is done.
+func (s *SaturationSearch) Next() (float64, bool) {
+	if s.MaxProbes > 0 && s.probes >= s.MaxProbes {
+		return 0, false
+	}
+	if s.fail == 0 {
+		if s.pass == 0 {
+			return s.Start, true
+		}
+		if s.Max > 0 && s.pass >= s.Max {
+			return 0, false
+		}
+		next := s.pass * 2
+		if s.Max > 0 {
+			next = min(next, s.Max)
+		}
+		return next, true
+	}
+	if (s.fail-s.pass)/s.fail <= s.Precision {
+		return 0, false
+	}
+	return (s.pass + s.fail) / 2, true
+}
+
+// Record reports whether the probe at tps passed.
+func (s *SaturationSearch) Record(tps float64, passed bool) {
+	s.probes++
+	if passed {
+		s.pass = max(s.pass, tps)
+	} else if s.fail == 0 || tps < s.fail {
+		s.fail = tps
+	}
+}
+
+// Result returns the highest rate that passed (0 if none did) and whether a
+// failing rate bounds it; without one the chain was never saturated.
+func (s *SaturationSearch) Result() (sustainable float64, saturated bool) {
+	return s.pass, s.fail > 0
+}
@@ -0,0 +1,198 @@
+package rate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-tps/db"
+	"go-tps/report"
+)
+
+// Spike phases a transaction can be submitted in.
+const (
+	PhaseBaseline = "baseline"
+	PhaseSpike    = "spike"
+	PhaseRecovery = "recovery" // the Recovery window after each spike
+)
+
+// Spike is a load profile that holds Base tx/s and, every Every, multiplies
+// it by Multiplier for Length. The first spike comes one period after the
+// first send, so the run starts with a baseline to compare against.
+type Spike struct {
+	Base       float64
+	Multiplier float64
+	Length     time.Duration
+	Every      time.Duration
+	Recovery   time.Duration
+
+	once  sync.Once
+	began time.Time
+}
+
+func (s *Spike) begin(t time.Time) {
+	s.once.Do(func() { s.began = t })
+}
+
+// PhaseAt returns the phase of the profile at t.
+func (s *Spike) PhaseAt(t time.Time) string {
+	elapsed := t.Sub(s.began)
+	if s.began.IsZero() || elapsed < s.Every {
+		return PhaseBaseline
+	}
+	offset := elapsed % s.Every
+	switch {
+	case offset < s.Length:
+		return PhaseSpike
+	case offset < s.Length+s.Recovery:
+		return PhaseRecovery
+	}
+	return PhaseBaseline
+}
+
+// RateAt returns the offered rate at t.
+func (s *Spike) RateAt(t time.Time) float64 {
+	if s.PhaseAt(t) == PhaseSpike {
+		return s.Base * s.Multiplier
+	}
+	return s.Base
+}
+
+// spikeAt returns the start of the spike t falls in or follows.
+func (s *Spike) spikeAt(t time.Time) time.Time {
+	elapsed := t.Sub(s.began)
+	return t.Add(-(elapsed % s.Every))
+}
+
+// NewSpikeLimiter returns a limiter whose rate follows spike, or nil if the
+// spike has no period, length or base rate.
+func NewSpikeLimiter(spike *Spike, burst int) *Limiter {
+	if spike.Base <= 0 || spike.Multiplier <= 0 || spike.Length <= 0 || spike.Every <= spike.Length {
+		return nil
+	}
+	l := NewLimiter(spike.Base, burst)
+	l.profile = spike
+	return l
+}
+
+// SpikePhaseStats aggregates the transactions submitted in one phase.
+type SpikePhaseStats struct {
+	Phase     string
+	Txs       int
+	Included  int
+	Failed    int
+	latencies []float64
+}
+
+// P50 and P95 return inclusion latency percentiles in seconds.
+func (p *SpikePhaseStats) P50() float64 { return report.Percentile(p.latencies, 50) }
+func (p *SpikePhaseStats) P95() float64 { return report.Percentile(p.latencies, 95) }
+
+// SpikeRecovery is how one spike's aftermath went: how long after the spike
+// ended submissions were included as fast as at baseline again.
+type SpikeRecovery struct {
+	Start     time.Time
+	Recovered time.Duration // -1 = not within the recovery window
+}
+
+// SpikeReport is the latency by phase and recovery per spike of a run.
+type SpikeReport struct {
+	Phases     []*SpikePhaseStats // baseline, spike, recovery
+	Recoveries []SpikeRecovery
+}
+
+// BuildSpikeReport groups txs by the phase they were tagged with and, for
+// each spike, finds the first transaction after it whose inclusion latency
+// was back within the baseline p95.
+func BuildSpikeReport(spike *Spike, txs []*db.Transaction) *SpikeReport {
+	r := &SpikeReport{}
+	byPhase := make(map[string]*SpikePhaseStats)
+	for _, phase := range []string{PhaseBaseline, PhaseSpike, PhaseRecovery} {
+		stats := &SpikePhaseStats{Phase: phase}
+		byPhase[phase] = stats
+		r.Phases = append(r.Phases, stats)
+	}
+
+	for _, tx := range txs {
+		stats := byPhase[tx.Phase]
+		if stats == nil || (tx.TxHash == "" && tx.Status != "failed") {
+			continue
+		}
+		stats.Txs++
+		if tx.Status == "failed" {
+			stats.Failed++
+		}
+		if tx.ConfirmedAt != nil {
+			stats.Included++
+			stats.latencies = append(stats.latencies, tx.ConfirmedAt.Sub(tx.SubmittedAt).Seconds())
+		}
+	}
+
+	baseline := byPhase[PhaseBaseline].P95()
+	if spike.began.IsZero() || baseline == 0 {
+		return r
+	}
+	sorted := make([]*db.Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if tx.Phase == PhaseRecovery && tx.ConfirmedAt != nil {
+			sorted = append(sorted, tx)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].SubmittedAt.Before(sorted[j].SubmittedAt) })
+
+	recovered := make(map[time.Time]time.Duration)
+	var starts []time.Time
+	for _, tx := range sorted {
+		start := spike.spikeAt(tx.SubmittedAt)
+		if _, seen := recovered[start]; !seen {
+			starts = append(starts, start)
+			recovered[start] = -1
+		}
+		if recovered[start] < 0 && tx.ConfirmedAt.Sub(tx.SubmittedAt).Seconds() <= baseline {
+			recovered[start] = tx.SubmittedAt.Sub(start.Add(spike.Length))
+		}
+	}
+	for _, start := range starts {
+		r.Recoveries = append(r.Recoveries, SpikeRecovery{Start: start, Recovered: recovered[start]})
+	}
+	return r
+}
+
+// PrintSpikeReport prints latency by phase and how quickly inclusion
+// latency recovered after each spike.
+func PrintSpikeReport(spike *Spike, r *SpikeReport) {
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("LATENCY BY SPIKE PHASE")
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("Profile: %s tx/s, ×%s for %s every %s\n", report.Float(spike.Base, 1),
+		report.Float(spike.Multiplier, 1), spike.Length, spike.Every)
+	fmt.Printf("%-10s %6s %8s %8s %8s %6s\n", "Phase", "Txs", "Included", "p50", "p95", "Fail%")
+	for _, p := range r.Phases {
+		if p.Txs == 0 {
+			fmt.Printf("%-10s %6d %8s %8s %8s %6s\n", p.Phase, 0, "-", "-", "-", "-")
+			continue
+		}
+		p50, p95 := "-", "-"
+		if p.Included > 0 {
+			p50, p95 = report.Seconds(p.P50(), 2), report.Seconds(p.P95(), 2)
+		}
+		fmt.Printf("%-10s %6s %8s %8s %8s %6s\n", p.Phase, report.Int(p.Txs), report.Int(p.Included), p50, p95,
+			report.Percent(float64(p.Failed)/float64(p.Txs)*100, 1))
+	}
+
+	if len(r.Recoveries) > 0 {
+		fmt.Println(strings.Repeat("-", 60))
+		fmt.Println("Recovery to baseline p95 after each spike:")
+		for i, rec := range r.Recoveries {
+			if rec.Recovered < 0 {
+				fmt.Printf("  Spike %d at %s: not within %s\n", i+1, rec.Start.Format("15:04:05"), spike.Recovery)
+				continue
+			}
+			fmt.Printf("  Spike %d at %s: %s after it ended\n", i+1, rec.Start.Format("15:04:05"), rec.Recovered.Round(100*time.Millisecond))
+		}
+	}
+	fmt.Println(strings.Repeat("=", 60))
+}
@@ -0,0 +1,48 @@
+package rate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stage is one step of a staircase load profile: TPS offered for Duration.
+type Stage struct {
+	TPS      float64
+	Duration time.Duration
+}
+
+// Label names the stage's batches, e.g. "stage2-100tps".
+func (s Stage) Label(i int) string {
+	return fmt.Sprintf("stage%d-%stps", i+1, strconv.FormatFloat(s.TPS, 'f', -1, 64))
+}
+
+// ParseStages parses a comma-separated list of tps:seconds stages, e.g.
+// "50:120,100:120,200:120".
+func ParseStages(spec string) ([]Stage, error) {
+	var stages []Stage
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		tpsPart, secondsPart, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid stage %q (expected tps:seconds)", entry)
+		}
+		tps, err := strconv.ParseFloat(strings.TrimSpace(tpsPart), 64)
+		if err != nil || tps <= 0 {
+			return nil, fmt.Errorf("invalid rate in stage %q", entry)
+		}
+		seconds, err := strconv.ParseFloat(strings.TrimSpace(secondsPart), 64)
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("invalid duration in stage %q", entry)
+		}
+		stages = append(stages, Stage{TPS: tps, Duration: time.Duration(seconds * float64(time.Second))})
+	}
+	if len(stages) == 0 {
+		return nil, fmt.Errorf("no stages in %q", spec)
+	}
+	return stages, nil
+}
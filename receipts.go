@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+
+	"go-tps/config"
+	dbpkg "go-tps/db"
+	"go-tps/logger"
+	"go-tps/worker"
+)
+
+// runReceiptsCommand implements `go-tps receipts`: confirm the pending
+// transactions of a database without sending any. Workers claim rows the
+// same way a run does, so it can finish what a crashed run left behind or
+// help a running one drain its receipts.
+func runReceiptsCommand(config *config.Config, args []string) int {
+	fs := flag.NewFlagSet("receipts", flag.ContinueOnError)
+	dbPath := fs.String("db", config.DBPath, "SQLite database to confirm")
+	workers := fs.Int("workers", config.ReceiptWorkers, "number of receipt confirmation workers")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	db, err := dbpkg.Open(*dbPath, config.DBMaxOpenConns, config.DBMaxIdleConns)
+	if err != nil {
+		logger.Error("Error opening database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	txSender, err := newTransactionSender(config, nil)
+	if err != nil {
+		logger.Error("Error connecting to RPC: %v\n", err)
+		return 1
+	}
+
+	stopTracing, err := startTracing(config)
+	if err != nil {
+		logger.Error("%v\n", err)
+		return 1
+	}
+	defer stopTracing()
+
+	stopMetrics, err := startMetrics(config)
+	if err != nil {
+		logger.Error("%v\n", err)
+		return 1
+	}
+	defer stopMetrics()
+
+	var wsManager *worker.WebSocketManager
+	if config.WSURL != "" {
+		wsManager = worker.NewWebSocketManager(config.WSURL, config.WSReconnectDelay)
+		if err := wsManager.Connect(); err != nil {
+			logger.Warn("Could not connect to WebSocket (will use RPC polling): %v\n", err)
+			wsManager = nil
+		} else {
+			defer wsManager.Close()
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	pending, err := db.GetPendingTransactionCount(ctx)
+	cancel()
+	if err != nil {
+		logger.Error("Error counting pending transactions: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Confirming %d pending transactions with %d workers...\n", pending, *workers)
+
+	var wg sync.WaitGroup
+	worker.StartReceiptWorkerPool(*workers, &wg, wsManager, db, txSender)
+	wg.Wait()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	left, err := db.GetPendingTransactionCount(ctx)
+	if err != nil {
+		logger.Error("Error counting pending transactions: %v\n", err)
+		return 1
+	}
+	fmt.Printf("✓ %d confirmed or failed, %d still pending (claimed elsewhere)\n", pending-left, left)
+	return 0
+}
@@ -0,0 +1,202 @@
+package report
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"go-tps/db"
+)
+
+// BalanceStats is one wallet's balance over a run against what its
+// transactions account for.
+type BalanceStats struct {
+	Wallet      string
+	Before      *big.Int // wei, at the start of the run
+	After       *big.Int // wei, at the end of the run
+	Consumed    *big.Int // Before minus After
+	Spent       *big.Int // value of its successful transactions plus the fees of the included ones
+	Unaccounted *big.Int // Consumed minus Spent: positive leaked, negative received
+	Batches     int      // batches it sent in
+	Pending     int      // transactions without an outcome at the end of the run
+	Flags       []string // leak, received or starved
+}
+
+// BatchSpend is what one batch's transactions took from the wallets.
+type BatchSpend struct {
+	Batch string
+	Txs   int
+	Value *big.Int // wei transferred by its successful transactions
+	Fees  *big.Int // wei paid for its included transactions
+}
+
+// Consumed is the batch's value plus fees.
+func (b BatchSpend) Consumed() *big.Int {
+	return new(big.Int).Add(b.Value, b.Fees)
+}
+
+// BalancePoint is the wallets' total balance at one snapshot.
+type BalancePoint struct {
+	Time  time.Time
+	Block uint64
+	Total *big.Int // wei
+}
+
+// txSpend is what t took from its sender: the value if it succeeded, plus
+// the fee if it was included.
+func txSpend(t *db.Transaction) (value, fee *big.Int) {
+	value, fee = new(big.Int), new(big.Int)
+	if t.Status == "success" {
+		value.SetString(t.Value, 10)
+	}
+	if c, ok := new(big.Int).SetString(t.Cost, 10); ok {
+		fee = c
+	}
+	return value, fee
+}
+
+// BuildBalanceStats sets each wallet's balances before and after the run,
+// both keyed by address, against the spend of its transactions, in address
+// order. A wallet is flagged as a leak when its balance dropped by more than
+// its transactions account for and none of them is still pending, as
+// received when it dropped by less, and as starved when any of its sends
+// failed with insufficient funds or what is left would not pay for another
+// batch like the ones it sent.
+func BuildBalanceStats(before, after map[string]*big.Int, txs []*db.Transaction) []*BalanceStats {
+	byWallet := make(map[string]*BalanceStats)
+	batches := make(map[string]map[string]bool)
+	insufficient := make(map[string]bool)
+	for address, b := range before {
+		a, ok := after[address]
+		if !ok {
+			continue
+		}
+		byWallet[address] = &BalanceStats{
+			Wallet:   address,
+			Before:   b,
+			After:    a,
+			Consumed: new(big.Int).Sub(b, a),
+			Spent:    new(big.Int),
+		}
+		batches[address] = make(map[string]bool)
+	}
+	for _, t := range txs {
+		w := byWallet[t.WalletAddress]
+		if w == nil {
+			continue
+		}
+		batches[t.WalletAddress][t.BatchNumber] = true
+		if t.Status == "pending" {
+			w.Pending++
+		}
+		if t.ErrorCategory == db.ErrorInsufficientFunds {
+			insufficient[t.WalletAddress] = true
+		}
+		value, fee := txSpend(t)
+		w.Spent.Add(w.Spent, value)
+		w.Spent.Add(w.Spent, fee)
+	}
+
+	stats := make([]*BalanceStats, 0, len(byWallet))
+	for address, w := range byWallet {
+		w.Batches = len(batches[address])
+		w.Unaccounted = new(big.Int).Sub(w.Consumed, w.Spent)
+		switch sign := w.Unaccounted.Sign(); {
+		case sign > 0 && w.Pending == 0:
+			w.Flags = append(w.Flags, "leak")
+		case sign < 0:
+			w.Flags = append(w.Flags, "received")
+		}
+		if insufficient[address] {
+			w.Flags = append(w.Flags, "starved")
+		} else if w.Batches > 0 {
+			perBatch := new(big.Int).Div(w.Spent, big.NewInt(int64(w.Batches)))
+			if w.After.Cmp(perBatch) < 0 {
+				w.Flags = append(w.Flags, "starved")
+			}
+		}
+		stats = append(stats, w)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Wallet < stats[j].Wallet })
+	return stats
+}
+
+// BuildBatchSpend adds up the spend of the transactions of batch.
+func BuildBatchSpend(batch string, txs []*db.Transaction) BatchSpend {
+	b := BatchSpend{Batch: batch, Txs: len(txs), Value: new(big.Int), Fees: new(big.Int)}
+	for _, t := range txs {
+		value, fee := txSpend(t)
+		b.Value.Add(b.Value, value)
+		b.Fees.Add(b.Fees, fee)
+	}
+	return b
+}
+
+// eth converts wei to ETH.
+func eth(wei *big.Int) float64 {
+	v, _ := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e18)).Float64()
+	return v
+}
+
+// PrintBalanceStats prints the ETH each wallet consumed over the run, what
+// its transactions account for and what is left, then the ETH consumed by
+// each batch and, with more than two snapshots, the wallets' total balance
+// at each. With the compact layout, or more than maxWalletRows wallets, only
+// flagged wallets get a row; batches get one if there are no more than
+// maxBatchRows.
+func PrintBalanceStats(stats []*BalanceStats, batches []BatchSpend, points []BalancePoint) {
+	const maxBatchRows = 20
+
+	flagged := 0
+	consumed, spent := new(big.Int), new(big.Int)
+	for _, w := range stats {
+		if len(w.Flags) > 0 {
+			flagged++
+		}
+		consumed.Add(consumed, w.Consumed)
+		spent.Add(spent, w.Spent)
+	}
+	onlyFlagged := Compact() || len(stats) > maxWalletRows
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 96))
+	fmt.Println("WALLET BALANCES")
+	fmt.Println(strings.Repeat("=", 96))
+	if !onlyFlagged || flagged > 0 {
+		fmt.Printf("%-42s %12s %12s %12s %12s  %s\n", "Wallet", "Consumed", "Spent", "Unaccounted", "Left", "Flags")
+		for _, w := range stats {
+			if onlyFlagged && len(w.Flags) == 0 {
+				continue
+			}
+			fmt.Printf("%-42s %12s %12s %12s %12s  %s\n", w.Wallet, Float(eth(w.Consumed), 6), Float(eth(w.Spent), 6),
+				Float(eth(w.Unaccounted), 6), Float(eth(w.After), 6), strings.Join(w.Flags, ", "))
+		}
+		fmt.Println(strings.Repeat("-", 96))
+	}
+	fmt.Printf("%d wallets consumed %s ETH; their transactions account for %s ETH (%d flagged)\n",
+		len(stats), Float(eth(consumed), 6), Float(eth(spent), 6), flagged)
+
+	if len(batches) > 0 && len(batches) <= maxBatchRows && !Compact() {
+		fmt.Println(strings.Repeat("-", 96))
+		fmt.Printf("%-42s %6s %12s %12s %12s\n", "Batch", "Txs", "Value", "Fees", "Consumed")
+		for _, b := range batches {
+			fmt.Printf("%-42s %6s %12s %12s %12s\n", b.Batch, Int(b.Txs), Float(eth(b.Value), 6),
+				Float(eth(b.Fees), 6), Float(eth(b.Consumed()), 6))
+		}
+	}
+
+	if len(points) > 2 {
+		fmt.Println(strings.Repeat("-", 96))
+		fmt.Printf("%-20s %12s %16s %14s\n", "Snapshot", "Block", "Total ETH", "Change")
+		for i, p := range points {
+			change := "-"
+			if i > 0 {
+				change = Float(eth(new(big.Int).Sub(p.Total, points[i-1].Total)), 6)
+			}
+			fmt.Printf("%-20s %12s %16s %14s\n", p.Time.Format("2006-01-02 15:04:05"), Int(p.Block), Float(eth(p.Total), 6), change)
+		}
+	}
+	fmt.Println(strings.Repeat("=", 96))
+}
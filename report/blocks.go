@@ -0,0 +1,95 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BlockCount is how many of a block's transactions were the run's.
+type BlockCount struct {
+	Number uint64
+	Time   time.Time
+	Ours   int
+	Total  int
+}
+
+// BlockTPS measures throughput from the blocks a run spanned rather than
+// from its submissions: what the chain actually included, and how fast.
+type BlockTPS struct {
+	Blocks         int
+	BlocksWithOurs int
+	Ours           int
+	Total          int
+	Window         float64 // seconds from the block before the first to the last
+	TPS            float64 // our transactions per second of Window
+	ChainTPS       float64 // all transactions per second of Window
+	MeanPerBlock   float64 // our transactions per spanned block
+	MedianPerBlock float64
+	MaxPerBlock    int
+}
+
+// BuildBlockTPS summarises blocks, in order. before is the timestamp of the
+// block preceding the first, which starts the window the first block's
+// transactions were produced in.
+func BuildBlockTPS(blocks []BlockCount, before time.Time) BlockTPS {
+	b := BlockTPS{Blocks: len(blocks)}
+	if len(blocks) == 0 {
+		return b
+	}
+	perBlock := make([]float64, len(blocks))
+	for i, block := range blocks {
+		b.Ours += block.Ours
+		b.Total += block.Total
+		b.MaxPerBlock = max(b.MaxPerBlock, block.Ours)
+		if block.Ours > 0 {
+			b.BlocksWithOurs++
+		}
+		perBlock[i] = float64(block.Ours)
+	}
+	b.MeanPerBlock = float64(b.Ours) / float64(len(blocks))
+	b.MedianPerBlock = Percentile(perBlock, 50)
+	if b.Window = blocks[len(blocks)-1].Time.Sub(before).Seconds(); b.Window > 0 {
+		b.TPS = float64(b.Ours) / b.Window
+		b.ChainTPS = float64(b.Total) / b.Window
+	}
+	return b
+}
+
+// PrintBlockTPS prints block-based throughput next to the submission rate,
+// with a row per block when there are only a few.
+func PrintBlockTPS(b BlockTPS, blocks []BlockCount, submissionTPS float64) {
+	const maxBlockRows = 30
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("BLOCK-BASED TPS")
+	fmt.Println(strings.Repeat("=", 60))
+	if b.Blocks == 0 {
+		fmt.Println("No blocks spanned the run.")
+		fmt.Println(strings.Repeat("=", 60))
+		return
+	}
+	fmt.Printf("Blocks spanned:            %s (#%d to #%d)\n", Int(b.Blocks), blocks[0].Number, blocks[len(blocks)-1].Number)
+	fmt.Printf("Blocks with our txs:       %s (%s)\n", Int(b.BlocksWithOurs), Percent(float64(b.BlocksWithOurs)/float64(b.Blocks)*100, 1))
+	fmt.Printf("Our txs in those blocks:   %s of %s\n", Int(b.Ours), Int(b.Total))
+	fmt.Printf("Our txs per block:         mean %s, median %s, max %s\n",
+		Float(b.MeanPerBlock, 2), Float(b.MedianPerBlock, 0), Int(b.MaxPerBlock))
+	if b.Window > 0 {
+		fmt.Printf("Chain time:                %s\n", Seconds(b.Window, 0))
+		fmt.Printf("Chain-side TPS:            %s (all txs: %s)\n", Float(b.TPS, 2), Float(b.ChainTPS, 2))
+	}
+	if submissionTPS > 0 {
+		fmt.Printf("Submission TPS:            %s\n", Float(submissionTPS, 2))
+	}
+
+	if len(blocks) <= maxBlockRows && !Compact() {
+		fmt.Println()
+		fmt.Printf("%-12s %-19s %6s %6s\n", "Block", "Time", "Ours", "Total")
+		for _, block := range blocks {
+			fmt.Printf("%-12d %-19s %6s %6s\n", block.Number, block.Time.Local().Format("2006-01-02 15:04:05"),
+				Int(block.Ours), Int(block.Total))
+		}
+	}
+	fmt.Println(strings.Repeat("=", 60))
+}
@@ -0,0 +1,208 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go-tps/db"
+	"go-tps/tx"
+)
+
+// EndpointStats is how one RPC endpoint handled the submissions sent to it.
+// Submission latency is the RPC layer's share of a transaction's life;
+// inclusion latency, from submission to the block, is the chain's.
+type EndpointStats struct {
+	Endpoint    string
+	Sends       int
+	Failed      int // rejected at submission
+	RateLimited int // rejected for exceeding the provider's rate limit
+	Included    int
+	Share       float64   // percent of all sends
+	Throughput  float64   // sends per second over the run's submission window
+	submit      []float64 // milliseconds
+	inclusion   []float64 // seconds
+}
+
+// SubmitPercentile returns a percentile of submission latency in seconds.
+func (e *EndpointStats) SubmitPercentile(p float64) float64 {
+	return Percentile(e.submit, p) / 1000
+}
+
+// InclusionP95 returns p95 inclusion latency in seconds.
+func (e *EndpointStats) InclusionP95() float64 {
+	return Percentile(e.inclusion, 95)
+}
+
+// BuildEndpointStats groups submitted transactions by the endpoint that
+// took them, busiest first.
+func BuildEndpointStats(txs []*db.Transaction) []*EndpointStats {
+	byEndpoint := make(map[string]*EndpointStats)
+	var first, last time.Time
+	sends := 0
+	for _, t := range txs {
+		if t.TxHash == "" && t.Status != "failed" {
+			continue // never sent (budget, abort)
+		}
+		sends++
+		if first.IsZero() || t.SubmittedAt.Before(first) {
+			first = t.SubmittedAt
+		}
+		if t.SubmittedAt.After(last) {
+			last = t.SubmittedAt
+		}
+		name := t.RPCEndpoint
+		if name == "" {
+			name = "-"
+		}
+		e := byEndpoint[name]
+		if e == nil {
+			e = &EndpointStats{Endpoint: name}
+			byEndpoint[name] = e
+		}
+		e.Sends++
+		if t.TxHash == "" {
+			e.Failed++
+			if tx.RateLimited(errors.New(t.Error)) {
+				e.RateLimited++
+			}
+			continue
+		}
+		e.submit = append(e.submit, t.ExecutionTime)
+		if t.ConfirmedAt != nil {
+			e.Included++
+			e.inclusion = append(e.inclusion, t.ConfirmedAt.Sub(t.SubmittedAt).Seconds())
+		}
+	}
+
+	window := last.Sub(first).Seconds()
+	stats := make([]*EndpointStats, 0, len(byEndpoint))
+	for _, e := range byEndpoint {
+		e.Share = float64(e.Sends) / float64(sends) * 100
+		if window > 0 {
+			e.Throughput = float64(e.Sends) / window
+		}
+		stats = append(stats, e)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Sends != stats[j].Sends {
+			return stats[i].Sends > stats[j].Sends
+		}
+		return stats[i].Endpoint < stats[j].Endpoint
+	})
+	return stats
+}
+
+// ReceiptStats is how many receipts one endpoint delivered one way.
+type ReceiptStats struct {
+	Endpoint  string
+	Source    string // poll or ws
+	Receipts  int
+	Failed    int       // receipt lookups that errored
+	inclusion []float64 // seconds
+}
+
+// InclusionP95 returns p95 inclusion latency in seconds of the
+// transactions whose receipt came this way.
+func (r *ReceiptStats) InclusionP95() float64 {
+	return Percentile(r.inclusion, 95)
+}
+
+// BuildReceiptStats groups resolved transactions by the endpoint and way
+// their receipt came from, busiest first. Transactions recorded before
+// receipts were attributed are left out.
+func BuildReceiptStats(txs []*db.Transaction) []*ReceiptStats {
+	type key struct{ endpoint, source string }
+	byOrigin := make(map[key]*ReceiptStats)
+	for _, t := range txs {
+		if t.ReceiptEndpoint == "" && t.ReceiptSource == "" {
+			continue
+		}
+		k := key{t.ReceiptEndpoint, t.ReceiptSource}
+		r := byOrigin[k]
+		if r == nil {
+			r = &ReceiptStats{Endpoint: k.endpoint, Source: k.source}
+			if r.Endpoint == "" {
+				r.Endpoint = "-"
+			}
+			byOrigin[k] = r
+		}
+		if t.ConfirmedAt == nil {
+			r.Failed++
+			continue
+		}
+		r.Receipts++
+		r.inclusion = append(r.inclusion, t.ConfirmedAt.Sub(t.SubmittedAt).Seconds())
+	}
+
+	stats := make([]*ReceiptStats, 0, len(byOrigin))
+	for _, r := range byOrigin {
+		stats = append(stats, r)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Receipts != stats[j].Receipts {
+			return stats[i].Receipts > stats[j].Receipts
+		}
+		if stats[i].Endpoint != stats[j].Endpoint {
+			return stats[i].Endpoint < stats[j].Endpoint
+		}
+		return stats[i].Source < stats[j].Source
+	})
+	return stats
+}
+
+// PrintEndpointStats prints submission latency percentiles, errors and
+// rate-limit hits per endpoint next to the inclusion latency of what each
+// endpoint took, and which endpoints, polled or over a WebSocket
+// subscription, delivered the receipts. With weights, keyed by endpoint,
+// each endpoint's observed share of the sends is shown next to the share
+// its weight asked for.
+func PrintEndpointStats(stats []*EndpointStats, receipts []*ReceiptStats, weights map[string]int) {
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("RPC ENDPOINTS")
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("%-24s %6s %8s %8s %8s %6s %7s %9s\n", "Endpoint", "Sends", "p50", "p95", "p99", "Errors", "Limited", "Incl p95")
+	for _, e := range stats {
+		p50, p95, p99, incl := "-", "-", "-", "-"
+		if len(e.submit) > 0 {
+			p50, p95, p99 = Seconds(e.SubmitPercentile(50), 3), Seconds(e.SubmitPercentile(95), 3), Seconds(e.SubmitPercentile(99), 3)
+		}
+		if e.Included > 0 {
+			incl = Seconds(e.InclusionP95(), 2)
+		}
+		fmt.Printf("%-24s %6s %8s %8s %8s %6s %7s %9s\n", e.Endpoint, Int(e.Sends), p50, p95, p99,
+			Int(e.Failed), Int(e.RateLimited), incl)
+	}
+	fmt.Println(strings.Repeat("-", 60))
+	fmt.Println("p50-p99: submission latency (RPC layer); Incl p95: submission to inclusion (chain)")
+
+	if len(receipts) > 0 {
+		fmt.Println()
+		fmt.Printf("%-24s %6s %8s %6s %9s\n", "Receipts from", "Via", "Receipts", "Errors", "Incl p95")
+		for _, r := range receipts {
+			incl := "-"
+			if r.Receipts > 0 {
+				incl = Seconds(r.InclusionP95(), 2)
+			}
+			fmt.Printf("%-24s %6s %8s %6s %9s\n", r.Endpoint, r.Source, Int(r.Receipts), Int(r.Failed), incl)
+		}
+	}
+
+	totalWeight := 0
+	for _, w := range weights {
+		totalWeight += w
+	}
+	if totalWeight > 0 {
+		fmt.Println()
+		fmt.Printf("%-24s %6s %8s %8s %8s\n", "Endpoint", "Weight", "Target", "Share", "Sends/s")
+		for _, e := range stats {
+			weight := weights[e.Endpoint]
+			fmt.Printf("%-24s %6s %8s %8s %8s\n", e.Endpoint, Int(weight),
+				Percent(float64(weight)/float64(totalWeight)*100, 1), Percent(e.Share, 1), Float(e.Throughput, 2))
+		}
+	}
+	fmt.Println(strings.Repeat("=", 60))
+}
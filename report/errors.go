@@ -0,0 +1,82 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"go-tps/db"
+)
+
+// CategoryCount is how many transactions failed with one category of error.
+type CategoryCount struct {
+	Category string
+	Count    int
+	Messages int    // distinct messages in the category
+	Example  string // the most frequent message
+}
+
+// BuildErrorCategories counts the errors of txs by category, in the order
+// of db.ErrorCategories, leaving out categories with no errors.
+func BuildErrorCategories(txs []*db.Transaction) []CategoryCount {
+	messages := make(map[string]map[string]int)
+	for _, t := range txs {
+		if t.Error == "" {
+			continue
+		}
+		category := t.ErrorCategory
+		if category == "" {
+			category = db.ErrorCategory(t.Error)
+		}
+		if messages[category] == nil {
+			messages[category] = make(map[string]int)
+		}
+		messages[category][t.Error]++
+	}
+
+	var counts []CategoryCount
+	for _, category := range db.ErrorCategories {
+		byMessage := messages[category]
+		if len(byMessage) == 0 {
+			continue
+		}
+		c := CategoryCount{Category: category, Messages: len(byMessage)}
+		most := 0
+		for msg, n := range byMessage {
+			c.Count += n
+			if n > most || n == most && msg < c.Example {
+				most, c.Example = n, msg
+			}
+		}
+		counts = append(counts, c)
+	}
+	return counts
+}
+
+// PrintErrorCategories prints the error count and share of each category,
+// with its most frequent message. The compact layout leaves out the
+// messages.
+func PrintErrorCategories(counts []CategoryCount) {
+	total := 0
+	for _, c := range counts {
+		total += c.Count
+	}
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("ERRORS BY CATEGORY")
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("%-20s %8s %7s %9s\n", "Category", "Txs", "Share", "Messages")
+	for _, c := range counts {
+		fmt.Printf("%-20s %8s %7s %9s\n", c.Category, Int(c.Count), Percent(float64(c.Count)/float64(total)*100, 1), Int(c.Messages))
+		if !Compact() {
+			example := c.Example
+			if len(example) > 100 {
+				example = example[:100] + "…"
+			}
+			fmt.Printf("  e.g. %s\n", example)
+		}
+	}
+	fmt.Println(strings.Repeat("-", 60))
+	fmt.Printf("%-20s %8s\n", "Total", Int(total))
+	fmt.Println(strings.Repeat("=", 60))
+}
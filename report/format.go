@@ -0,0 +1,138 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Report layouts.
+const (
+	LayoutDetailed = "detailed" // per-batch rows and totals
+	LayoutCompact  = "compact"  // totals only
+)
+
+// Duration units.
+const (
+	UnitSeconds      = "s"
+	UnitMilliseconds = "ms"
+)
+
+// locales maps a locale to its thousands separator and decimal mark.
+var locales = map[string][2]string{
+	"en": {",", "."},
+	"de": {".", ","},
+	"fr": {" ", ","}, // narrow no-break space
+	"ch": {"'", "."},
+}
+
+// Format controls how numbers, durations and summaries are printed by the
+// end-of-run reports. The zero value matches the historic output: no
+// thousands separators, each report's own decimals, durations in seconds.
+type Format struct {
+	Thousands string // thousands separator ("" = none)
+	Decimal   string // decimal mark ("" = ".")
+	Decimals  int    // fixed decimals for every fractional number (-1 = each report's default)
+	Unit      string // duration unit: s or ms
+	Layout    string // detailed or compact
+}
+
+// current is the format used by the package-level helpers.
+var current = Format{Decimals: -1, Unit: UnitSeconds, Layout: LayoutDetailed}
+
+// NewFormat builds a Format from the REPORT_* settings.
+func NewFormat(locale string, thousands bool, decimals int, unit, layout string) (Format, error) {
+	locale = strings.ToLower(locale)
+	marks, ok := locales[locale]
+	if !ok {
+		return Format{}, fmt.Errorf("unknown report locale %q (available: en, de, fr, ch)", locale)
+	}
+	f := Format{Decimal: marks[1], Decimals: decimals, Unit: strings.ToLower(unit), Layout: strings.ToLower(layout)}
+	if thousands {
+		f.Thousands = marks[0]
+	}
+	if f.Unit != UnitSeconds && f.Unit != UnitMilliseconds {
+		return Format{}, fmt.Errorf("unknown report duration unit %q (available: s, ms)", unit)
+	}
+	if f.Layout != LayoutDetailed && f.Layout != LayoutCompact {
+		return Format{}, fmt.Errorf("unknown report layout %q (available: detailed, compact)", layout)
+	}
+	return f, nil
+}
+
+// SetFormat configures how reports print numbers from here on.
+func SetFormat(f Format) {
+	current = f
+}
+
+// Compact reports whether reports should print totals only.
+func Compact() bool {
+	return current.Layout == LayoutCompact
+}
+
+// Int formats a whole number.
+func Int[T int | int64 | uint64](n T) string {
+	return current.group(strconv.FormatUint(uint64(abs(n)), 10), n < 0)
+}
+
+// Float formats v with the configured fixed decimals, or decimals if none
+// are configured.
+func Float(v float64, decimals int) string {
+	if current.Decimals >= 0 {
+		decimals = current.Decimals
+	}
+	s := strconv.FormatFloat(math.Abs(v), 'f', decimals, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+	out := current.group(whole, v < 0 && s != strconv.FormatFloat(0, 'f', decimals, 64))
+	if frac != "" {
+		mark := current.Decimal
+		if mark == "" {
+			mark = "."
+		}
+		out += mark + frac
+	}
+	return out
+}
+
+// Percent formats a percentage with a trailing %.
+func Percent(v float64, decimals int) string {
+	return Float(v, decimals) + "%"
+}
+
+// Seconds formats a duration given in seconds in the configured unit, with
+// its unit suffix. decimals applies to seconds; milliseconds get three fewer.
+func Seconds(v float64, decimals int) string {
+	if current.Unit == UnitMilliseconds {
+		return Float(v*1000, max(decimals-3, 0)) + "ms"
+	}
+	return Float(v, decimals) + "s"
+}
+
+func (f Format) group(digits string, negative bool) string {
+	if f.Thousands != "" && len(digits) > 3 {
+		var b strings.Builder
+		lead := len(digits) % 3
+		if lead > 0 {
+			b.WriteString(digits[:lead])
+		}
+		for i := lead; i < len(digits); i += 3 {
+			if b.Len() > 0 {
+				b.WriteString(f.Thousands)
+			}
+			b.WriteString(digits[i : i+3])
+		}
+		digits = b.String()
+	}
+	if negative {
+		return "-" + digits
+	}
+	return digits
+}
+
+func abs[T int | int64 | uint64](n T) T {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
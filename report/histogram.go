@@ -0,0 +1,76 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	histogramBarWidth   = 40
+	histogramMaxBuckets = 40
+)
+
+// HistogramBucket counts the values in [Start, End).
+type HistogramBucket struct {
+	Start float64
+	End   float64
+	Count int
+}
+
+// BuildHistogram buckets values into contiguous buckets of width, empty ones
+// included so gaps between modes show. If that takes more than
+// histogramMaxBuckets, the width is doubled until it does not.
+func BuildHistogram(values []float64, width float64) []HistogramBucket {
+	if len(values) == 0 || width <= 0 {
+		return nil
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	first := math.Floor(lo / width)
+	for math.Floor(hi/width)-first+1 > histogramMaxBuckets {
+		width *= 2
+		first = math.Floor(lo / width)
+	}
+
+	buckets := make([]HistogramBucket, int(math.Floor(hi/width)-first)+1)
+	for i := range buckets {
+		buckets[i].Start = (first + float64(i)) * width
+		buckets[i].End = buckets[i].Start + width
+	}
+	for _, v := range values {
+		i := min(int(math.Floor(v/width)-first), len(buckets)-1)
+		buckets[i].Count++
+	}
+	return buckets
+}
+
+// PrintHistogram prints buckets of seconds as horizontal bars scaled to the
+// fullest bucket.
+func PrintHistogram(title string, buckets []HistogramBucket) {
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println(title)
+	fmt.Println(strings.Repeat("=", 60))
+	if len(buckets) == 0 {
+		fmt.Println("No confirmed transactions.")
+		fmt.Println(strings.Repeat("=", 60))
+		return
+	}
+
+	most := 0
+	for _, b := range buckets {
+		most = max(most, b.Count)
+	}
+	for _, b := range buckets {
+		bar := int(math.Round(float64(b.Count) / float64(most) * histogramBarWidth))
+		if bar == 0 && b.Count > 0 {
+			bar = 1
+		}
+		label := Seconds(b.Start, 2) + " - " + Seconds(b.End, 2)
+		fmt.Printf("%-20s %6s %s\n", label, Int(b.Count), strings.Repeat("█", bar))
+	}
+	fmt.Println(strings.Repeat("=", 60))
+}
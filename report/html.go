@@ -0,0 +1,227 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"go-tps/db"
+)
+
+// maxErrorRows is how many distinct errors the HTML report lists.
+const maxErrorRows = 15
+
+// BatchReport is everything the HTML report shows about one batch.
+type BatchReport struct {
+	Batch     string
+	Generated string
+	Start     time.Time
+	Summary   TrendPoint
+	Success   int
+	Reverted  int
+	Rejected  int // failed at submission
+	Pending   int
+	EthSpent  float64
+	Submit    []float64 // p50, p90, p95, p99 in seconds
+	Inclusion []float64
+	Series    *seriesChart
+	Histogram []histogramBar
+	Errors    []ErrorCount
+}
+
+// ErrorCount is how many of a batch's transactions failed with one error.
+type ErrorCount struct {
+	Error string
+	Count int
+}
+
+// histogramBar is one latency bucket drawn as an SVG bar.
+type histogramBar struct {
+	Label  string
+	Count  int
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+}
+
+// BuildBatchReport summarises a batch's transactions for the HTML report,
+// with confirmation latency bucketed by bucket.
+func BuildBatchReport(batch string, txs []*db.Transaction, bucket float64) *BatchReport {
+	r := &BatchReport{
+		Batch:     batch,
+		Generated: time.Now().Format(time.RFC1123),
+		Summary:   BuildTrendPoint(batch, txs),
+	}
+	r.Start = r.Summary.Start
+
+	var submit, inclusion []float64
+	errors := make(map[string]int)
+	cost := new(big.Int)
+	for _, t := range txs {
+		switch {
+		case t.Status == "success":
+			r.Success++
+		case t.Status == "failed" && t.TxHash == "":
+			r.Rejected++
+		case t.Status == "failed":
+			r.Reverted++
+		case t.Status == "pending":
+			r.Pending++
+		}
+		if t.Error != "" {
+			msg := t.Error
+			if len(msg) > 160 {
+				msg = msg[:160] + "…"
+			}
+			errors[msg]++
+		}
+		if t.TxHash != "" {
+			submit = append(submit, t.ExecutionTime/1000)
+		}
+		if t.ConfirmedAt != nil {
+			inclusion = append(inclusion, t.ConfirmedAt.Sub(t.SubmittedAt).Seconds())
+		}
+		if c, ok := new(big.Int).SetString(t.Cost, 10); ok {
+			cost.Add(cost, c)
+		}
+	}
+	r.EthSpent, _ = new(big.Float).Quo(new(big.Float).SetInt(cost), big.NewFloat(1e18)).Float64()
+
+	for _, p := range db.LatencyPercentiles {
+		r.Submit = append(r.Submit, Percentile(submit, p))
+		r.Inclusion = append(r.Inclusion, Percentile(inclusion, p))
+	}
+
+	for msg, n := range errors {
+		r.Errors = append(r.Errors, ErrorCount{Error: msg, Count: n})
+	}
+	sort.Slice(r.Errors, func(i, j int) bool {
+		if r.Errors[i].Count != r.Errors[j].Count {
+			return r.Errors[i].Count > r.Errors[j].Count
+		}
+		return r.Errors[i].Error < r.Errors[j].Error
+	})
+	if len(r.Errors) > maxErrorRows {
+		r.Errors = r.Errors[:maxErrorRows]
+	}
+
+	r.Series = newSeriesChart(BuildTPSSeries(txs, time.Second))
+	r.Histogram = newHistogramBars(BuildHistogram(inclusion, bucket))
+	return r
+}
+
+func newHistogramBars(buckets []HistogramBucket) []histogramBar {
+	most := 0
+	for _, b := range buckets {
+		most = max(most, b.Count)
+	}
+	bars := make([]histogramBar, len(buckets))
+	for i, b := range buckets {
+		width := float64(chartWidth) / float64(len(buckets))
+		height := 0.0
+		if most > 0 {
+			height = float64(b.Count) / float64(most) * chartHeight
+		}
+		bars[i] = histogramBar{
+			Label:  fmt.Sprintf("%.2f–%.2f s", b.Start, b.End),
+			Count:  b.Count,
+			X:      float64(i) * width,
+			Y:      chartHeight - height,
+			Width:  width * 0.9,
+			Height: height,
+		}
+	}
+	return bars
+}
+
+// WriteBatchHTML writes a self-contained HTML report of a batch: summary
+// numbers, submissions and confirmations over time, the confirmation
+// latency distribution and the most frequent errors.
+func WriteBatchHTML(w io.Writer, r *BatchReport) error {
+	return batchTemplate.Execute(w, r)
+}
+
+var batchTemplate = template.Must(template.New("batch").Funcs(template.FuncMap{
+	"percent": func(n, total int) string {
+		if total == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.1f%%", float64(n)/float64(total)*100)
+	},
+	"join": func(values []float64) string {
+		parts := make([]string, len(values))
+		for i, v := range values {
+			parts[i] = fmt.Sprintf("%.3f s", v)
+		}
+		return strings.Join(parts, " / ")
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>go-tps report: {{.Batch}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; max-width: 960px; }
+svg { background: #fafafa; border: 1px solid #ddd; }
+table { border-collapse: collapse; margin-top: 1em; }
+th, td { padding: 4px 10px; border-bottom: 1px solid #eee; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+.errors td:first-child { font-family: monospace; font-size: 90%; max-width: 720px; word-break: break-all; }
+</style>
+</head>
+<body>
+<h1>Batch {{.Batch}}</h1>
+<p>Started {{.Start.Local.Format "2006-01-02 15:04:05"}}. Generated {{.Generated}}.</p>
+
+<h2>Summary</h2>
+<table>
+<tr><th>Transactions</th><td>{{.Summary.Txs}}</td></tr>
+<tr><th>Included</th><td>{{.Summary.Included}} ({{percent .Summary.Included .Summary.Txs}})</td></tr>
+<tr><th>Successful</th><td>{{.Success}}</td></tr>
+<tr><th>Reverted</th><td>{{.Reverted}}</td></tr>
+<tr><th>Rejected at submission</th><td>{{.Rejected}}</td></tr>
+<tr><th>Still pending</th><td>{{.Pending}}</td></tr>
+<tr><th>TPS (included)</th><td>{{printf "%.2f" .Summary.TPS}}</td></tr>
+<tr><th>Failure rate</th><td>{{printf "%.1f" .Summary.FailureRate}}%</td></tr>
+<tr><th>Submission latency p50 / p90 / p95 / p99</th><td>{{join .Submit}}</td></tr>
+<tr><th>Confirmation latency p50 / p90 / p95 / p99</th><td>{{join .Inclusion}}</td></tr>
+<tr><th>ETH spent</th><td>{{printf "%.6f" .EthSpent}}</td></tr>
+</table>
+
+{{with .Series}}
+<h2>Submitted <span style="color:#2b7bb9">&#9632;</span> and confirmed <span style="color:#29a35a">&#9632;</span> per second <small>(max {{.Max}})</small></h2>
+<svg width="900" height="180" viewBox="0 0 900 180" preserveAspectRatio="none">
+<polyline fill="none" stroke="#2b7bb9" stroke-width="1" points="{{.Submitted}}"/>
+<polyline fill="none" stroke="#29a35a" stroke-width="1" points="{{.Confirmed}}"/>
+</svg>
+<p><small>{{.From}} to {{.To}}</small></p>
+{{end}}
+
+{{if .Histogram}}
+<h2>Confirmation latency distribution</h2>
+<svg width="900" height="180" viewBox="0 0 900 180" preserveAspectRatio="none">
+{{range .Histogram}}<rect x="{{printf "%.1f" .X}}" y="{{printf "%.1f" .Y}}" width="{{printf "%.1f" .Width}}" height="{{printf "%.1f" .Height}}" fill="#d9822b"><title>{{.Label}}: {{.Count}}</title></rect>
+{{end}}</svg>
+<table>
+<tr><th>Latency</th><th>Txs</th></tr>
+{{range .Histogram}}{{if .Count}}<tr><td>{{.Label}}</td><td>{{.Count}}</td></tr>
+{{end}}{{end}}</table>
+{{end}}
+
+<h2>Errors</h2>
+{{if .Errors}}
+<table class="errors">
+<tr><th>Error</th><th>Txs</th></tr>
+{{range .Errors}}<tr><td>{{.Error}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+{{else}}
+<p>No errors.</p>
+{{end}}
+</body>
+</html>
+`))
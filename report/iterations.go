@@ -0,0 +1,116 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Degradation thresholds against the baseline of the first iterations.
+const (
+	baselineIterations = 3   // iterations the baseline is the median of
+	tpsDropRatio       = 0.8 // TPS below this share of the baseline is flagged
+	latencyRiseRatio   = 1.5 // p95 above this multiple of the baseline is flagged
+	failureRisePoints  = 5.0 // failure rate this many points above the baseline is flagged
+)
+
+// IterationBaseline is what later loop iterations are compared against: the
+// median TPS, p95 latency and failure rate of the first iterations, so one
+// slow warm-up iteration does not skew it.
+type IterationBaseline struct {
+	TPS         float64
+	P95Latency  float64
+	FailureRate float64
+}
+
+// BuildIterationBaseline takes the baseline from the first iterations.
+func BuildIterationBaseline(points []TrendPoint) IterationBaseline {
+	var tps, p95, failures []float64
+	for _, p := range points[:min(baselineIterations, len(points))] {
+		tps = append(tps, p.TPS)
+		p95 = append(p95, p.P95Latency)
+		failures = append(failures, p.FailureRate)
+	}
+	return IterationBaseline{
+		TPS:         Percentile(tps, 50),
+		P95Latency:  Percentile(p95, 50),
+		FailureRate: Percentile(failures, 50),
+	}
+}
+
+// Degradation lists how an iteration fell behind the baseline, if it did.
+func (b IterationBaseline) Degradation(p TrendPoint) []string {
+	var flags []string
+	if b.TPS > 0 && p.TPS < b.TPS*tpsDropRatio {
+		flags = append(flags, fmt.Sprintf("TPS %+.0f%%", (p.TPS/b.TPS-1)*100))
+	}
+	if b.P95Latency > 0 && p.P95Latency > b.P95Latency*latencyRiseRatio {
+		flags = append(flags, fmt.Sprintf("p95 %+.0f%%", (p.P95Latency/b.P95Latency-1)*100))
+	}
+	if p.FailureRate > b.FailureRate+failureRisePoints {
+		flags = append(flags, fmt.Sprintf("fail %+.1fpt", p.FailureRate-b.FailureRate))
+	}
+	return flags
+}
+
+// PrintIterations prints one row per loop iteration with the ways it
+// degraded against the first iterations, then how the last third of the run
+// compares with the first. The compact layout prints the comparison only.
+func PrintIterations(points []TrendPoint) {
+	if len(points) == 0 {
+		return
+	}
+	baseline := BuildIterationBaseline(points)
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 80))
+	fmt.Println("ITERATIONS")
+	fmt.Println(strings.Repeat("=", 80))
+	degraded := 0
+	if !Compact() {
+		fmt.Printf("%5s %-8s %6s %8s %8s %8s %6s  %s\n", "#", "Start", "Txs", "Included", "TPS", "p95", "Fail%", "Degradation")
+	}
+	for i, p := range points {
+		flags := baseline.Degradation(p)
+		if len(flags) > 0 {
+			degraded++
+		}
+		if Compact() {
+			continue
+		}
+		marker := ""
+		if len(flags) > 0 {
+			marker = "⚠ " + strings.Join(flags, ", ")
+		}
+		fmt.Printf("%5d %-8s %6s %8s %8s %8s %6s  %s\n", i+1, p.Start.Local().Format("15:04:05"), Int(p.Txs),
+			Int(p.Included), Float(p.TPS, 2), Seconds(p.P95Latency, 2), Percent(p.FailureRate, 1), marker)
+	}
+	if !Compact() {
+		fmt.Println(strings.Repeat("-", 80))
+	}
+
+	fmt.Printf("Baseline (median of first %d): %s tx/s, p95 %s, %s failed\n", min(baselineIterations, len(points)),
+		Float(baseline.TPS, 2), Seconds(baseline.P95Latency, 2), Percent(baseline.FailureRate, 1))
+	if third := len(points) / 3; third > 0 {
+		first, last := averageTrend(points[:third]), averageTrend(points[len(points)-third:])
+		fmt.Printf("Last third vs first third: TPS %s → %s, p95 %s → %s, failed %s → %s\n",
+			Float(first.TPS, 2), Float(last.TPS, 2), Seconds(first.P95Latency, 2), Seconds(last.P95Latency, 2),
+			Percent(first.FailureRate, 1), Percent(last.FailureRate, 1))
+	}
+	fmt.Printf("%d of %d iterations degraded\n", degraded, len(points))
+	fmt.Println(strings.Repeat("=", 80))
+}
+
+// averageTrend averages the TPS, p95 latency and failure rate of points.
+func averageTrend(points []TrendPoint) TrendPoint {
+	var avg TrendPoint
+	for _, p := range points {
+		avg.TPS += p.TPS
+		avg.P95Latency += p.P95Latency
+		avg.FailureRate += p.FailureRate
+	}
+	n := float64(len(points))
+	avg.TPS /= n
+	avg.P95Latency /= n
+	avg.FailureRate /= n
+	return avg
+}
@@ -0,0 +1,112 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go-tps/db"
+)
+
+// BuildTPSSeries counts submissions and confirmations per bucket of width,
+// from the first submission to the last confirmation. Empty buckets are
+// kept, since a stall is exactly what the series is for.
+func BuildTPSSeries(txs []*db.Transaction, width time.Duration) []db.SeriesPoint {
+	if width <= 0 {
+		return nil
+	}
+	var first, last time.Time
+	for _, t := range txs {
+		if t.TxHash == "" {
+			continue
+		}
+		if first.IsZero() || t.SubmittedAt.Before(first) {
+			first = t.SubmittedAt
+		}
+		last = latest(last, t.SubmittedAt)
+		if t.ConfirmedAt != nil {
+			last = latest(last, *t.ConfirmedAt)
+		}
+	}
+	if first.IsZero() {
+		return nil
+	}
+
+	first = first.Truncate(width)
+	points := make([]db.SeriesPoint, int(last.Sub(first)/width)+1)
+	for i := range points {
+		points[i].Time = first.Add(time.Duration(i) * width)
+	}
+	for _, t := range txs {
+		if t.TxHash == "" {
+			continue
+		}
+		points[int(t.SubmittedAt.Sub(first)/width)].Submitted++
+		if t.ConfirmedAt != nil && !t.ConfirmedAt.Before(first) {
+			points[int(t.ConfirmedAt.Sub(first)/width)].Confirmed++
+		}
+	}
+	return points
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// SeriesStats are what a TPS series shows that an aggregate TPS hides.
+type SeriesStats struct {
+	Buckets       int
+	PeakSubmitted float64 // per second, in the busiest bucket
+	PeakConfirmed float64
+	LongestStall  time.Duration // longest run of buckets without a confirmation after the first one
+	StallStart    time.Time
+}
+
+// BuildSeriesStats finds the peaks and the longest confirmation stall of a
+// series of buckets of width.
+func BuildSeriesStats(points []db.SeriesPoint, width time.Duration) SeriesStats {
+	s := SeriesStats{Buckets: len(points)}
+	perSecond := width.Seconds()
+	confirmedYet := false
+	var stall time.Duration
+	var stallStart time.Time
+	for _, p := range points {
+		s.PeakSubmitted = max(s.PeakSubmitted, float64(p.Submitted)/perSecond)
+		s.PeakConfirmed = max(s.PeakConfirmed, float64(p.Confirmed)/perSecond)
+		if p.Confirmed > 0 {
+			confirmedYet = true
+			stall = 0
+			continue
+		}
+		if !confirmedYet {
+			continue
+		}
+		if stall == 0 {
+			stallStart = p.Time
+		}
+		stall += width
+		if stall > s.LongestStall {
+			s.LongestStall, s.StallStart = stall, stallStart
+		}
+	}
+	return s
+}
+
+// PrintSeriesStats prints the peaks and the longest stall of a run's series.
+func PrintSeriesStats(s SeriesStats, width time.Duration) {
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("TPS TIME SERIES")
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("Buckets:                   %s of %s\n", Int(s.Buckets), width)
+	fmt.Printf("Peak submitted:            %s tx/s\n", Float(s.PeakSubmitted, 2))
+	fmt.Printf("Peak confirmed:            %s tx/s\n", Float(s.PeakConfirmed, 2))
+	if s.LongestStall > 0 {
+		fmt.Printf("Longest confirmation gap:  %s from %s\n", Seconds(s.LongestStall.Seconds(), 0), s.StallStart.Local().Format("15:04:05"))
+	}
+	fmt.Println("Stored per batch in the tps_series table; charted by `trend -html`.")
+	fmt.Println(strings.Repeat("=", 60))
+}
@@ -0,0 +1,245 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"go-tps/db"
+)
+
+// TrendPoint is one batch's headline numbers in a long-term trend.
+type TrendPoint struct {
+	Batch       string
+	Start       time.Time
+	Txs         int
+	Included    int     // confirmed on chain, successful or reverted
+	TPS         float64 // included txs over first submission to last inclusion
+	P95Latency  float64 // seconds from submission to inclusion
+	FailureRate float64 // percent of txs that failed to send or reverted
+}
+
+// BuildTrendPoint summarises one batch's transactions.
+func BuildTrendPoint(batch string, txs []*db.Transaction) TrendPoint {
+	p := TrendPoint{Batch: batch, Txs: len(txs)}
+
+	var first, last time.Time
+	var latencies []float64
+	failed := 0
+	for _, tx := range txs {
+		if first.IsZero() || tx.SubmittedAt.Before(first) {
+			first = tx.SubmittedAt
+		}
+		if tx.Status == "failed" {
+			failed++
+		}
+		if tx.ConfirmedAt == nil {
+			continue
+		}
+		p.Included++
+		latencies = append(latencies, tx.ConfirmedAt.Sub(tx.SubmittedAt).Seconds())
+		if tx.ConfirmedAt.After(last) {
+			last = *tx.ConfirmedAt
+		}
+	}
+
+	p.Start = first
+	if window := last.Sub(first).Seconds(); p.Included > 0 && window > 0 {
+		p.TPS = float64(p.Included) / window
+	}
+	p.P95Latency = Percentile(latencies, 95)
+	if p.Txs > 0 {
+		p.FailureRate = float64(failed) / float64(p.Txs) * 100
+	}
+	return p
+}
+
+// Percentile returns the pth percentile (0-100) of values using the
+// nearest-rank method, or 0 for no values. values is sorted in place.
+func Percentile(values []float64, p float64) float64 {
+	sort.Float64s(values)
+	return db.Percentile(values, p)
+}
+
+// trendBarWidth is the width of the TPS bar in the ASCII trend.
+const trendBarWidth = 30
+
+// PrintTrend prints one row per batch with a bar scaled to the highest TPS.
+func PrintTrend(points []TrendPoint) {
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 100))
+	fmt.Println("TPS TREND")
+	fmt.Println(strings.Repeat("=", 100))
+	if len(points) == 0 {
+		fmt.Println("No batches in database.")
+		fmt.Println(strings.Repeat("=", 100))
+		return
+	}
+
+	maxTPS := 0.0
+	for _, p := range points {
+		maxTPS = math.Max(maxTPS, p.TPS)
+	}
+
+	fmt.Printf("%-19s %-32s %6s %8s %8s %6s  %s\n", "Start", "Batch", "Txs", "TPS", "p95", "Fail%", "TPS")
+	for _, p := range points {
+		bar := 0
+		if maxTPS > 0 {
+			bar = int(math.Round(p.TPS / maxTPS * trendBarWidth))
+		}
+		fmt.Printf("%-19s %-32s %6s %8s %8s %6s  %s\n",
+			p.Start.Local().Format("2006-01-02 15:04:05"), p.Batch, Int(p.Txs), Float(p.TPS, 2), Seconds(p.P95Latency, 2),
+			Percent(p.FailureRate, 1), strings.Repeat("█", bar))
+	}
+	fmt.Println(strings.Repeat("=", 100))
+}
+
+// WriteTrendHTML writes a self-contained HTML page charting TPS, p95 latency
+// and failure rate per batch, and submissions and confirmations over time
+// from the batches' stored TPS series.
+func WriteTrendHTML(w io.Writer, points []TrendPoint, series []db.SeriesPoint) error {
+	data := struct {
+		Generated string
+		Points    []TrendPoint
+		Series    *seriesChart
+		Charts    []trendChart
+	}{
+		Generated: time.Now().Format(time.RFC1123),
+		Points:    points,
+		Series:    newSeriesChart(series),
+		Charts: []trendChart{
+			newTrendChart("TPS", "#2b7bb9", points, func(p TrendPoint) float64 { return p.TPS }),
+			newTrendChart("p95 latency (s)", "#d9822b", points, func(p TrendPoint) float64 { return p.P95Latency }),
+			newTrendChart("Failure rate (%)", "#c23030", points, func(p TrendPoint) float64 { return p.FailureRate }),
+		},
+	}
+	return trendTemplate.Execute(w, data)
+}
+
+const (
+	chartWidth  = 900
+	chartHeight = 180
+)
+
+type trendChart struct {
+	Title  string
+	Color  string
+	Max    float64
+	Points string // SVG polyline points
+}
+
+func newTrendChart(title, color string, points []TrendPoint, value func(TrendPoint) float64) trendChart {
+	c := trendChart{Title: title, Color: color}
+	for _, p := range points {
+		c.Max = math.Max(c.Max, value(p))
+	}
+
+	coords := make([]string, 0, len(points))
+	for i, p := range points {
+		x := 0.0
+		if len(points) > 1 {
+			x = float64(i) / float64(len(points)-1) * chartWidth
+		}
+		y := float64(chartHeight)
+		if c.Max > 0 {
+			y = chartHeight - value(p)/c.Max*chartHeight
+		}
+		coords = append(coords, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+	c.Points = strings.Join(coords, " ")
+	return c
+}
+
+// seriesChart draws submissions and confirmations against time, with
+// neighbouring buckets merged so there is at most one point per pixel.
+type seriesChart struct {
+	From      string
+	To        string
+	Max       int
+	Submitted string // SVG polyline points
+	Confirmed string
+}
+
+func newSeriesChart(series []db.SeriesPoint) *seriesChart {
+	if len(series) == 0 {
+		return nil
+	}
+	merge := (len(series) + chartWidth - 1) / chartWidth
+	var merged []db.SeriesPoint
+	for i := 0; i < len(series); i += merge {
+		p := db.SeriesPoint{Time: series[i].Time}
+		for _, q := range series[i:min(i+merge, len(series))] {
+			p.Submitted += q.Submitted
+			p.Confirmed += q.Confirmed
+		}
+		merged = append(merged, p)
+	}
+
+	c := &seriesChart{
+		From: series[0].Time.Local().Format("2006-01-02 15:04:05"),
+		To:   series[len(series)-1].Time.Local().Format("2006-01-02 15:04:05"),
+	}
+	for _, p := range merged {
+		c.Max = max(c.Max, p.Submitted, p.Confirmed)
+	}
+	span := merged[len(merged)-1].Time.Sub(merged[0].Time).Seconds()
+	var submitted, confirmed []string
+	for _, p := range merged {
+		x := 0.0
+		if span > 0 {
+			x = p.Time.Sub(merged[0].Time).Seconds() / span * chartWidth
+		}
+		y := func(v int) float64 {
+			if c.Max == 0 {
+				return chartHeight
+			}
+			return chartHeight - float64(v)/float64(c.Max)*chartHeight
+		}
+		submitted = append(submitted, fmt.Sprintf("%.1f,%.1f", x, y(p.Submitted)))
+		confirmed = append(confirmed, fmt.Sprintf("%.1f,%.1f", x, y(p.Confirmed)))
+	}
+	c.Submitted = strings.Join(submitted, " ")
+	c.Confirmed = strings.Join(confirmed, " ")
+	return c
+}
+
+var trendTemplate = template.Must(template.New("trend").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>go-tps trend</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+svg { background: #fafafa; border: 1px solid #ddd; }
+table { border-collapse: collapse; margin-top: 2em; }
+th, td { padding: 4px 10px; border-bottom: 1px solid #eee; text-align: right; }
+th:nth-child(-n+2), td:nth-child(-n+2) { text-align: left; }
+</style>
+</head>
+<body>
+<h1>TPS trend</h1>
+<p>{{len .Points}} batches. Generated {{.Generated}}.</p>
+{{range .Charts}}
+<h2>{{.Title}} <small>(max {{printf "%.2f" .Max}})</small></h2>
+<svg width="900" height="180" viewBox="0 0 900 180" preserveAspectRatio="none">
+<polyline fill="none" stroke="{{.Color}}" stroke-width="2" points="{{.Points}}"/>
+</svg>
+{{end}}
+{{with .Series}}
+<h2>Submitted <span style="color:#2b7bb9">&#9632;</span> and confirmed <span style="color:#29a35a">&#9632;</span> over time <small>(max {{.Max}} per point, {{.From}} to {{.To}})</small></h2>
+<svg width="900" height="180" viewBox="0 0 900 180" preserveAspectRatio="none">
+<polyline fill="none" stroke="#2b7bb9" stroke-width="1" points="{{.Submitted}}"/>
+<polyline fill="none" stroke="#29a35a" stroke-width="1" points="{{.Confirmed}}"/>
+</svg>
+{{end}}
+<table>
+<tr><th>Start</th><th>Batch</th><th>Txs</th><th>Included</th><th>TPS</th><th>p95 (s)</th><th>Fail %</th></tr>
+{{range .Points}}<tr><td>{{.Start.Format "2006-01-02 15:04:05"}}</td><td>{{.Batch}}</td><td>{{.Txs}}</td><td>{{.Included}}</td><td>{{printf "%.2f" .TPS}}</td><td>{{printf "%.2f" .P95Latency}}</td><td>{{printf "%.1f" .FailureRate}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
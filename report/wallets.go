@@ -0,0 +1,156 @@
+package report
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"go-tps/db"
+)
+
+// WalletStats is how one wallet's transactions fared.
+type WalletStats struct {
+	Wallet     string
+	Txs        int
+	Submitted  int // accepted by the RPC
+	Confirmed  int // included, successful or reverted
+	Successful int
+	Failed     int // rejected at submission or reverted
+	Pending    int
+	GasUsed    uint64
+	Cost       *big.Int // wei
+	Flags      []string // why the wallet underperformed its peers, set by FlagWallets
+	inclusion  []float64
+}
+
+// AvgLatency returns the mean confirmation latency in seconds, or 0 when
+// nothing was confirmed.
+func (w *WalletStats) AvgLatency() float64 {
+	if len(w.inclusion) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range w.inclusion {
+		sum += v
+	}
+	return sum / float64(len(w.inclusion))
+}
+
+// BuildWalletStats groups transactions by the wallet that sent them, in
+// address order.
+func BuildWalletStats(txs []*db.Transaction) []*WalletStats {
+	byWallet := make(map[string]*WalletStats)
+	for _, t := range txs {
+		w := byWallet[t.WalletAddress]
+		if w == nil {
+			w = &WalletStats{Wallet: t.WalletAddress, Cost: new(big.Int)}
+			byWallet[t.WalletAddress] = w
+		}
+		w.Txs++
+		if t.TxHash != "" {
+			w.Submitted++
+		}
+		switch t.Status {
+		case "success":
+			w.Successful++
+		case "failed":
+			w.Failed++
+		case "pending":
+			w.Pending++
+		}
+		if t.ConfirmedAt != nil {
+			w.Confirmed++
+			w.inclusion = append(w.inclusion, t.ConfirmedAt.Sub(t.SubmittedAt).Seconds())
+		}
+		w.GasUsed += t.GasUsed
+		if c, ok := new(big.Int).SetString(t.Cost, 10); ok {
+			w.Cost.Add(w.Cost, c)
+		}
+	}
+
+	stats := make([]*WalletStats, 0, len(byWallet))
+	for _, w := range byWallet {
+		stats = append(stats, w)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Wallet < stats[j].Wallet })
+	return stats
+}
+
+// maxWalletRows is how many wallets the per-wallet report lists before it
+// lists only the flagged ones.
+const maxWalletRows = 50
+
+// FlagWallets marks the wallets that did markedly worse than the median
+// wallet: nothing accepted (usually unfunded), confirmed under half the
+// median, failed over a tenth of their transactions while the median wallet
+// did not, or confirmed at over twice the median latency. Skew between
+// wallets sending the same load points at nonce gaps or empty accounts.
+func FlagWallets(stats []*WalletStats) {
+	var confirmed, failureRates, latencies []float64
+	for _, w := range stats {
+		confirmed = append(confirmed, float64(w.Confirmed))
+		failureRates = append(failureRates, float64(w.Failed)/float64(w.Txs))
+		if w.Confirmed > 0 {
+			latencies = append(latencies, w.AvgLatency())
+		}
+	}
+	medianConfirmed := Percentile(confirmed, 50)
+	medianFailures := Percentile(failureRates, 50)
+	medianLatency := Percentile(latencies, 50)
+
+	for _, w := range stats {
+		w.Flags = nil
+		if w.Submitted == 0 {
+			w.Flags = append(w.Flags, "none sent")
+		} else if float64(w.Confirmed) < medianConfirmed/2 {
+			w.Flags = append(w.Flags, "few confirmed")
+		}
+		if rate := float64(w.Failed) / float64(w.Txs); rate > 0.1 && rate > 2*medianFailures {
+			w.Flags = append(w.Flags, "failures")
+		}
+		if w.Confirmed > 0 && medianLatency > 0 && w.AvgLatency() > 2*medianLatency {
+			w.Flags = append(w.Flags, "slow")
+		}
+	}
+}
+
+// PrintWalletStats prints each wallet's sent, confirmed and failed counts,
+// average confirmation latency and gas, flagged by FlagWallets. With the
+// compact layout, or more than maxWalletRows wallets, only flagged wallets
+// get a row.
+func PrintWalletStats(stats []*WalletStats) {
+	flagged := 0
+	for _, w := range stats {
+		if len(w.Flags) > 0 {
+			flagged++
+		}
+	}
+	onlyFlagged := Compact() || len(stats) > maxWalletRows
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 80))
+	fmt.Println("PER-WALLET STATISTICS")
+	fmt.Println(strings.Repeat("=", 80))
+	if !onlyFlagged || flagged > 0 {
+		fmt.Printf("%-42s %5s %5s %5s %5s %8s %10s  %s\n", "Wallet", "Txs", "Sent", "Conf", "Fail", "Avg lat", "Gas", "Flags")
+		for _, w := range stats {
+			if onlyFlagged && len(w.Flags) == 0 {
+				continue
+			}
+			latency := "-"
+			if w.Confirmed > 0 {
+				latency = Seconds(w.AvgLatency(), 2)
+			}
+			fmt.Printf("%-42s %5s %5s %5s %5s %8s %10s  %s\n", w.Wallet, Int(w.Txs), Int(w.Submitted), Int(w.Confirmed),
+				Int(w.Failed), latency, Int(w.GasUsed), strings.Join(w.Flags, ", "))
+		}
+		fmt.Println(strings.Repeat("-", 80))
+	}
+	if onlyFlagged && flagged < len(stats) {
+		fmt.Printf("%d of %d wallets flagged; `go-tps export` writes every wallet to wallets.csv\n", flagged, len(stats))
+	} else {
+		fmt.Printf("%d of %d wallets flagged\n", flagged, len(stats))
+	}
+	fmt.Println(strings.Repeat("=", 80))
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"runtime/debug"
+	"time"
+
+	"go-tps/config"
+	dbpkg "go-tps/db"
+	"go-tps/logger"
+	txpkg "go-tps/tx"
+)
+
+// startRun records the run in the runs table with its effective settings,
+// secrets redacted, and the build and node it ran with, and returns its ID.
+// A run that cannot be recorded goes ahead with ID 0.
+func startRun(config *config.Config, db dbpkg.Store, txSender *txpkg.TransactionSender, startedAt time.Time) int64 {
+	settings, err := json.Marshal(redactConfig(*config))
+	if err != nil {
+		logger.Warn("Could not encode the run configuration: %v\n", err)
+		return 0
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := txSender.ClientVersion(ctx)
+	if err != nil {
+		logger.Debug("%v\n", err)
+		client = "unknown"
+	}
+	id, err := db.InsertRun(ctx, &dbpkg.Run{
+		StartedAt:     startedAt,
+		Config:        string(settings),
+		Version:       toolVersion(),
+		ChainID:       txSender.ChainID().String(),
+		ClientVersion: client,
+	})
+	if err != nil {
+		logger.Warn("Could not record the run: %v\n", err)
+		return 0
+	}
+	logger.Info("🗂  Run #%d (%s on %s)\n", id, toolVersion(), client)
+	return id
+}
+
+// finishRun records how run id ended.
+func finishRun(db dbpkg.Store, id int64, mode string, aborted bool) {
+	if id == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := db.FinishRun(ctx, id, time.Now(), mode, aborted); err != nil {
+		logger.Warn("%v\n", err)
+	}
+}
+
+// toolVersion identifies this build of go-tps: the module version if it was
+// installed as one, otherwise the VCS revision it was built from, marked
+// dirty if it had local changes.
+func toolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+	var revision string
+	var modified bool
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	if revision == "" {
+		return "devel"
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if modified {
+		revision += "-dirty"
+	}
+	return revision
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-tps/config"
+	dbpkg "go-tps/db"
+	"go-tps/logger"
+	"go-tps/rate"
+	"go-tps/report"
+	txpkg "go-tps/tx"
+	"go-tps/worker"
+)
+
+// saturationProbe is one offered rate tried by the saturation search.
+type saturationProbe struct {
+	tps     float64
+	batches []string
+	stats   report.TrendPoint
+	passed  bool
+}
+
+// runSaturationSearch looks for the highest rate the chain sustains. Each
+// probe offers one rate for SATURATION_PROBE_SECONDS, waits for every
+// receipt and passes if p95 inclusion latency and the failure rate stay
+// within their thresholds. Rates double until a probe fails, then the
+// search bisects between the last pass and the first failure.
+func runSaturationSearch(config *config.Config, broadcaster *txpkg.P2PBroadcaster, run *runState, db dbpkg.Store, wsManager *worker.WebSocketManager) ([]string, []*saturationProbe, *rate.SaturationSearch) {
+	search := &rate.SaturationSearch{
+		Start:     config.SaturationStartTPS,
+		Max:       config.SaturationMaxTPS,
+		Precision: config.SaturationPrecision / 100,
+		MaxProbes: config.SaturationMaxProbes,
+	}
+	duration := time.Duration(config.SaturationProbe) * time.Second
+
+	var batches []string
+	var probes []*saturationProbe
+	for {
+		tps, ok := search.Next()
+		if !ok || run.abort.Aborted() || budgetSpent(run) {
+			break
+		}
+		probe := &saturationProbe{tps: tps}
+		probes = append(probes, probe)
+
+		fmt.Printf("\n\n[PROBE %d] %g tx/s for %s\n", len(probes), tps, duration)
+		fmt.Println(strings.Repeat("-", 60))
+
+		label := fmt.Sprintf("probe%d-%stps", len(probes), strconv.FormatFloat(tps, 'f', -1, 64))
+		var submitted int
+		probe.batches, submitted = runStage(config, broadcaster, run, rate.Stage{TPS: tps, Duration: duration}, label)
+		batches = append(batches, probe.batches...)
+
+		// Judge the probe on confirmed results, which also lets the
+		// mempool drain before the next one
+		waitForBatchRows(db, probe.batches, submitted)
+		confirmReceipts(config, db, wsManager, run)
+		if run.abort.Aborted() {
+			break
+		}
+
+		probe.stats = loadTrendPoint(db, label, probe.batches)
+		probe.passed = probe.stats.Txs > 0 &&
+			probe.stats.P95Latency <= config.SaturationMaxP95 &&
+			probe.stats.FailureRate <= config.SaturationMaxFail
+		search.Record(tps, probe.passed)
+
+		verdict := "✓ sustained"
+		if !probe.passed {
+			verdict = "✗ saturated"
+		}
+		fmt.Printf("\n%s at %g tx/s: p95 %s, %s failed\n", verdict, tps,
+			report.Seconds(probe.stats.P95Latency, 2), report.Percent(probe.stats.FailureRate, 1))
+	}
+	return batches, probes, search
+}
+
+// waitForBatchRows waits until the DB writers have stored the submitted
+// transactions of batches, so receipts can be claimed for all of them.
+func waitForBatchRows(db dbpkg.Store, batches []string, submitted int) {
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		stored := 0
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		for _, batch := range batches {
+			txs, err := db.GetBatchTransactions(ctx, batch)
+			if err != nil {
+				logger.Warn("Could not load transactions for %s: %v\n", batch, err)
+				continue
+			}
+			for _, tx := range txs {
+				if tx.TxHash != "" {
+					stored++
+				}
+			}
+		}
+		cancel()
+		if stored >= submitted {
+			return
+		}
+		if time.Now().After(deadline) {
+			logger.Warn("Only %d of %d submitted transactions stored after 30s; judging the probe on those\n", stored, submitted)
+			return
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// confirmReceipts runs a receipt worker pool until nothing is left pending,
+// or until the run is aborted.
+func confirmReceipts(config *config.Config, db dbpkg.Store, wsManager *worker.WebSocketManager, run *runState) {
+	txSender, err := newTransactionSender(config, nil)
+	if err != nil {
+		logger.Error("Error connecting to RPC: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Waiting for the probe's receipts...")
+	var wg sync.WaitGroup
+	worker.StartReceiptWorkerPool(config.ReceiptWorkers, &wg, wsManager, db, txSender)
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-run.abort.Done():
+	}
+}
+
+// loadTrendPoint summarises the transactions of batches as one point.
+func loadTrendPoint(db dbpkg.Store, label string, batches []string) report.TrendPoint {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var txs []*dbpkg.Transaction
+	for _, batch := range batches {
+		batchTxs, err := db.GetBatchTransactions(ctx, batch)
+		if err != nil {
+			logger.Warn("Could not load transactions for %s: %v\n", batch, err)
+			continue
+		}
+		txs = append(txs, batchTxs...)
+	}
+	return report.BuildTrendPoint(label, txs)
+}
+
+// printSaturationReport prints every probe and the saturation point found.
+func printSaturationReport(config *config.Config, probes []*saturationProbe, search *rate.SaturationSearch) {
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 80))
+	fmt.Println("SATURATION SEARCH")
+	fmt.Println(strings.Repeat("=", 80))
+	fmt.Printf("Thresholds: p95 ≤ %s, failures ≤ %s\n",
+		report.Seconds(config.SaturationMaxP95, 2), report.Percent(config.SaturationMaxFail, 1))
+	fmt.Printf("%-6s %10s %8s %9s %10s %8s %6s  %s\n", "Probe", "Offered", "Txs", "Included", "Incl. TPS", "p95", "Fail%", "Result")
+	for i, probe := range probes {
+		result := "sustained"
+		if !probe.passed {
+			result = "saturated"
+		}
+		p := probe.stats
+		fmt.Printf("%-6d %10s %8s %9s %10s %8s %6s  %s\n", i+1, report.Float(probe.tps, 1),
+			report.Int(p.Txs), report.Int(p.Included), report.Float(p.TPS, 2),
+			report.Seconds(p.P95Latency, 2), report.Percent(p.FailureRate, 1), result)
+	}
+	fmt.Println(strings.Repeat("-", 80))
+
+	sustainable, saturated := search.Result()
+	switch {
+	case sustainable == 0:
+		fmt.Println("No probed rate was sustained; lower SATURATION_START_TPS")
+	case saturated:
+		fmt.Printf("Saturation point: %s tx/s sustained (highest passing offered rate)\n", report.Float(sustainable, 1))
+	default:
+		fmt.Printf("Not saturated: %s tx/s sustained, the highest rate probed; raise SATURATION_MAX_TPS or SATURATION_MAX_PROBES\n", report.Float(sustainable, 1))
+	}
+	fmt.Println(strings.Repeat("=", 80))
+}
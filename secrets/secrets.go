@@ -0,0 +1,100 @@
+// Package secrets reads a mnemonic or private keys from a secrets manager,
+// so they never have to sit in the environment or on the disk of the
+// machine running go-tps: HashiCorp Vault and AWS Secrets Manager.
+//
+// A source names a secret as vault://PATH#FIELD or aws-sm://ID#FIELD. The
+// managers are reached with the variables their own CLIs use: VAULT_ADDR,
+// VAULT_TOKEN and VAULT_NAMESPACE, and AWS_REGION, AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Source schemes.
+const (
+	SchemeVault = "vault"
+	SchemeAWS   = "aws-sm"
+)
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// Fetch returns the secret source names, with surrounding whitespace
+// trimmed:
+//
+//   - vault://PATH#FIELD reads the secret at PATH of the Vault API, e.g.
+//     secret/data/go-tps for the go-tps secret of a KV version 2 engine
+//     mounted at secret/, or secret/go-tps for version 1
+//   - aws-sm://ID#FIELD reads the current version of secret ID, its name
+//     or ARN, from AWS Secrets Manager
+//
+// FIELD picks a key of the secret's key/value pairs (JSON, for an AWS
+// secret). Without it a Vault secret must hold a single key, and an AWS
+// secret is its whole secret string.
+func Fetch(ctx context.Context, source string) (string, error) {
+	scheme, rest, ok := strings.Cut(source, "://")
+	if !ok || rest == "" {
+		return "", fmt.Errorf("invalid secret source %q: want vault://PATH#FIELD or aws-sm://ID#FIELD", source)
+	}
+	path, field, _ := strings.Cut(rest, "#")
+
+	var value string
+	var err error
+	switch scheme {
+	case SchemeVault:
+		value, err = fetchVault(ctx, path, field)
+	case SchemeAWS:
+		value, err = fetchAWS(ctx, path, field)
+	default:
+		return "", fmt.Errorf("unknown secret source %q (available: %s://, %s://)", scheme, SchemeVault, SchemeAWS)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", source, err)
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", fmt.Errorf("%s: secret is empty", source)
+	}
+	return value, nil
+}
+
+// pick returns field of fields, or the only value if field is empty.
+func pick(fields map[string]any, field string) (string, error) {
+	if field == "" {
+		if len(fields) != 1 {
+			names := make([]string, 0, len(fields))
+			for name := range fields {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return "", fmt.Errorf("secret has keys %s; name one with #FIELD", strings.Join(names, ", "))
+		}
+		for name := range fields {
+			field = name
+		}
+	}
+	value, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("secret has no key %q", field)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("key %q of the secret is not a string", field)
+	}
+	return s, nil
+}
+
+// decodeJSON decodes a JSON object in the body of resp into out.
+func decodeJSON(resp *http.Response, out any) error {
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// fetchVault reads field of the secret at path of the Vault at VAULT_ADDR.
+func fetchVault(ctx context.Context, path, field string) (string, error) {
+	addr := strings.TrimRight(os.Getenv("VAULT_ADDR"), "/")
+	if addr == "" {
+		return "", fmt.Errorf("VAULT_ADDR is not set")
+	}
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		return "", fmt.Errorf("VAULT_TOKEN is not set")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach Vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Errors []string `json:"errors"`
+		}
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(raw, &body) == nil && len(body.Errors) > 0 {
+			return "", fmt.Errorf("Vault answered %s: %s", resp.Status, strings.Join(body.Errors, "; "))
+		}
+		return "", fmt.Errorf("Vault answered %s", resp.Status)
+	}
+
+	// KV version 2 nests the pairs in data.data, with the version's
+	// metadata next to them; version 1 has them in data
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := decodeJSON(resp, &body); err != nil {
+		return "", err
+	}
+	fields := body.Data
+	if nested, ok := fields["data"].(map[string]any); ok {
+		if _, versioned := fields["metadata"]; versioned {
+			fields = nested
+		}
+	}
+	return pick(fields, field)
+}
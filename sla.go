@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"go-tps/config"
+	"go-tps/report"
+)
+
+// exitSLAFailed is the exit status of a run that did not meet its SLA
+// thresholds, apart from 1 for runs that could not be carried out.
+const exitSLAFailed = 3
+
+// slaCheck is one SLA threshold and how the run measured up to it.
+type slaCheck struct {
+	Name      string  `json:"name"`
+	Threshold float64 `json:"threshold"`
+	Value     float64 `json:"value"`
+	Passed    bool    `json:"passed"`
+}
+
+// slaResult is the outcome of every configured SLA threshold.
+type slaResult struct {
+	Passed bool       `json:"passed"`
+	Checks []slaCheck `json:"checks"`
+}
+
+// slaConfigured says whether any SLA threshold is set.
+func slaConfigured(config *config.Config) bool {
+	return config.SLAMinTPS > 0 || config.SLAMaxP95 > 0 || config.SLAMaxFailureRate >= 0
+}
+
+// evaluateSLA checks the run's totals against the SLA thresholds. A run
+// that was aborted fails, as what it measured is not the configured load.
+func evaluateSLA(config *config.Config, totals batchSummary, aborted bool) *slaResult {
+	r := &slaResult{Passed: true}
+	add := func(name string, threshold, value float64, passed bool) {
+		r.Checks = append(r.Checks, slaCheck{Name: name, Threshold: threshold, Value: value, Passed: passed})
+		r.Passed = r.Passed && passed
+	}
+	if config.SLAMinTPS > 0 {
+		add("min_tps", config.SLAMinTPS, totals.TPS, totals.TPS >= config.SLAMinTPS)
+	}
+	if config.SLAMaxP95 > 0 {
+		p95 := totals.ConfirmationLatency.P95
+		add("max_p95_seconds", config.SLAMaxP95, p95, totals.ConfirmationLatency.Count > 0 && p95 <= config.SLAMaxP95)
+	}
+	if config.SLAMaxFailureRate >= 0 {
+		add("max_failure_rate", config.SLAMaxFailureRate, totals.FailureRate, totals.FailureRate <= config.SLAMaxFailureRate)
+	}
+	if aborted {
+		add("completed", 1, 0, false)
+	}
+	return r
+}
+
+// printSLAReport prints each check with its threshold and measured value.
+func printSLAReport(r *slaResult) {
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("SLA ASSERTIONS")
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("%-24s %12s %12s %8s\n", "Check", "Threshold", "Actual", "Result")
+	failed := 0
+	for _, c := range r.Checks {
+		result := "PASS"
+		if !c.Passed {
+			result = "FAIL"
+			failed++
+		}
+		var threshold, actual string
+		switch c.Name {
+		case "min_tps":
+			threshold, actual = "≥ "+report.Float(c.Threshold, 2), report.Float(c.Value, 2)
+		case "max_p95_seconds":
+			threshold, actual = "≤ "+report.Seconds(c.Threshold, 2), report.Seconds(c.Value, 2)
+		case "max_failure_rate":
+			threshold, actual = "≤ "+report.Percent(c.Threshold, 1), report.Percent(c.Value, 1)
+		case "completed":
+			threshold, actual = "yes", "aborted"
+		}
+		fmt.Printf("%-24s %12s %12s %8s\n", c.Name, threshold, actual, result)
+	}
+	fmt.Println(strings.Repeat("-", 60))
+	if failed == 0 {
+		fmt.Printf("✓ SLA met: all %d checks passed\n", len(r.Checks))
+	} else {
+		fmt.Printf("✗ SLA NOT MET: %d of %d checks failed (exit status %d)\n", failed, len(r.Checks), exitSLAFailed)
+	}
+	fmt.Println(strings.Repeat("=", 60))
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go-tps/config"
+	dbpkg "go-tps/db"
+	"go-tps/logger"
+	"go-tps/report"
+	txpkg "go-tps/tx"
+	"go-tps/worker"
+)
+
+// soakInterval is one SOAK_INTERVAL_MINUTES slice of a soak test.
+type soakInterval struct {
+	label      string
+	start, end time.Time
+	batches    []string
+}
+
+// runSoak runs loop mode in intervals of SOAK_INTERVAL_MINUTES. Each
+// interval's batches carry its label (soak1, soak2, …), and when it ends an
+// interim summary is printed and stored in soak_intervals. Receipts are
+// confirmed in the background throughout, so the summaries have latencies.
+func runSoak(config *config.Config, broadcaster *txpkg.P2PBroadcaster, run *runState, db dbpkg.Store, wsManager *worker.WebSocketManager, duration time.Duration) ([]string, []*soakInterval) {
+	length := time.Duration(config.SoakIntervalMinutes) * time.Minute
+	end := time.Now().Add(duration)
+
+	confirmer := startReceiptConfirmer(config, db, wsManager)
+	defer confirmer.stop(run.abort)
+
+	var batches []string
+	var intervals []*soakInterval
+	for n := 1; time.Now().Before(end); n++ {
+		if run.abort.Aborted() || budgetSpent(run) {
+			break
+		}
+		now := time.Now()
+		iv := &soakInterval{label: fmt.Sprintf("soak%d", n), start: now, end: now.Add(length)}
+		if iv.end.After(end) {
+			iv.end = end
+		}
+		intervals = append(intervals, iv)
+
+		fmt.Printf("\n\n[SOAK INTERVAL %d] %s – %s\n", n, iv.start.Format("15:04:05"), iv.end.Format("15:04:05"))
+		fmt.Println(strings.Repeat("-", 60))
+
+		run.batchLabel = iv.label
+		iv.batches = runInLoopMode(config, broadcaster, run, time.Until(iv.end))
+		run.batchLabel = ""
+		batches = append(batches, iv.batches...)
+
+		summary := summarizeSoakInterval(db, iv)
+		printSoakInterval(summary)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := db.InsertSoakInterval(ctx, summary); err != nil {
+			logger.Warn("Could not store soak interval %s: %v\n", iv.label, err)
+		}
+		cancel()
+	}
+	return batches, intervals
+}
+
+// summarizeSoakInterval summarises an interval's transactions as they stand.
+func summarizeSoakInterval(db dbpkg.Store, iv *soakInterval) *dbpkg.SoakInterval {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	s := &dbpkg.SoakInterval{Label: iv.label, StartedAt: iv.start, EndedAt: time.Now()}
+	var latencies []float64
+	for _, batch := range iv.batches {
+		txs, err := db.GetBatchTransactions(ctx, batch)
+		if err != nil {
+			logger.Warn("Could not load transactions for %s: %v\n", batch, err)
+			continue
+		}
+		for _, tx := range txs {
+			if tx.TxHash == "" && tx.Status != "failed" {
+				continue // never sent (budget, abort)
+			}
+			s.Txs++
+			switch {
+			case tx.ConfirmedAt != nil:
+				s.Included++
+				latencies = append(latencies, tx.ConfirmedAt.Sub(tx.SubmittedAt).Seconds())
+			case tx.Status == "pending":
+				s.Pending++
+			}
+			if tx.Status == "failed" {
+				s.Failed++
+			}
+		}
+	}
+	if secs := s.EndedAt.Sub(s.StartedAt).Seconds(); secs > 0 {
+		s.TPS = float64(s.Included) / secs
+	}
+	s.P50 = report.Percentile(latencies, 50)
+	s.P95 = report.Percentile(latencies, 95)
+	s.P99 = report.Percentile(latencies, 99)
+	return s
+}
+
+func printSoakInterval(s *dbpkg.SoakInterval) {
+	fmt.Println()
+	fmt.Printf("📊 %s (%s – %s): %s txs, %s included (%s tx/s), %s failed, %s pending\n",
+		s.Label, s.StartedAt.Format("15:04:05"), s.EndedAt.Format("15:04:05"),
+		report.Int(s.Txs), report.Int(s.Included), report.Float(s.TPS, 2), report.Int(s.Failed), report.Int(s.Pending))
+	if s.Included > 0 {
+		fmt.Printf("   Inclusion latency: p50 %s, p95 %s, p99 %s\n",
+			report.Seconds(s.P50, 2), report.Seconds(s.P95, 2), report.Seconds(s.P99, 2))
+	}
+}
+
+// printSoakReport prints every interval again at the end of the run, once
+// the receipts still pending at the interim summaries are in.
+func printSoakReport(db dbpkg.Store, intervals []*soakInterval) {
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 80))
+	fmt.Println("SOAK TEST BY INTERVAL")
+	fmt.Println(strings.Repeat("=", 80))
+	fmt.Printf("%-8s %-9s %8s %9s %10s %7s %8s %8s %8s\n", "Interval", "Start", "Txs", "Included", "Incl. TPS", "Failed", "p50", "p95", "p99")
+	for _, iv := range intervals {
+		s := summarizeSoakInterval(db, iv)
+		s.EndedAt = iv.end // rate over the interval, not up to now
+		if secs := s.EndedAt.Sub(s.StartedAt).Seconds(); secs > 0 {
+			s.TPS = float64(s.Included) / secs
+		}
+		fmt.Printf("%-8s %-9s %8s %9s %10s %7s %8s %8s %8s\n", s.Label, s.StartedAt.Format("15:04:05"),
+			report.Int(s.Txs), report.Int(s.Included), report.Float(s.TPS, 2), report.Int(s.Failed),
+			report.Seconds(s.P50, 2), report.Seconds(s.P95, 2), report.Seconds(s.P99, 2))
+	}
+	fmt.Println(strings.Repeat("=", 80))
+}
+
+// soakReceiptPause is how long the receipt confirmer waits before starting
+// a new pool once the previous one found nothing left to claim.
+const soakReceiptPause = 2 * time.Second
+
+// receiptConfirmer keeps a receipt worker pool running while batches are
+// still being sent: a pool exits once nothing is left to claim, so a new one
+// is started after a short pause.
+type receiptConfirmer struct {
+	done     chan struct{}
+	finished chan struct{}
+}
+
+func startReceiptConfirmer(config *config.Config, db dbpkg.Store, wsManager *worker.WebSocketManager) *receiptConfirmer {
+	c := &receiptConfirmer{done: make(chan struct{}), finished: make(chan struct{})}
+	go func() {
+		defer close(c.finished)
+		for {
+			txSender, err := newTransactionSender(config, nil)
+			if err != nil {
+				logger.Error("Error connecting to RPC: %v\n", err)
+				os.Exit(1)
+			}
+			var wg sync.WaitGroup
+			worker.StartReceiptWorkerPool(config.ReceiptWorkers, &wg, wsManager, db, txSender)
+			wg.Wait()
+
+			select {
+			case <-c.done:
+				return
+			case <-time.After(soakReceiptPause):
+			}
+		}
+	}()
+	return c
+}
+
+// stop waits for the running pool to finish, or for the run to be aborted.
+func (c *receiptConfirmer) stop(abort *abortController) {
+	close(c.done)
+	select {
+	case <-c.finished:
+	case <-abort.Done():
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go-tps/config"
+	dbpkg "go-tps/db"
+	"go-tps/logger"
+	"go-tps/rate"
+	"go-tps/report"
+	txpkg "go-tps/tx"
+)
+
+// stageBatches are the batches sent during one stage of a staircase run.
+type stageBatches struct {
+	stage   rate.Stage
+	batches []string
+}
+
+// runInStagedMode runs LOAD_STAGES one after another: each stage paces
+// submissions at its rate and sends batches until its duration is up. A
+// batch in flight when the stage ends is finished, so batches should be
+// small next to a stage. Every batch is labelled with its stage, so stages
+// can be compared in the database as well as in the stage report.
+func runInStagedMode(config *config.Config, broadcaster *txpkg.P2PBroadcaster, run *runState, stages []rate.Stage) ([]string, []stageBatches) {
+	var batches []string
+	results := make([]stageBatches, 0, len(stages))
+
+	for i, stage := range stages {
+		if run.abort.Aborted() || budgetSpent(run) {
+			break
+		}
+		fmt.Printf("\n\n[STAGE %d/%d] %g tx/s for %s\n", i+1, len(stages), stage.TPS, stage.Duration)
+		fmt.Println(strings.Repeat("-", 60))
+
+		result := stageBatches{stage: stage}
+		result.batches, _ = runStage(config, broadcaster, run, stage, stage.Label(i))
+		batches = append(batches, result.batches...)
+		results = append(results, result)
+	}
+
+	return batches, results
+}
+
+// runStage paces submissions at stage.TPS and sends batches labelled
+// label-1, label-2, … until the stage's time is up, the run is aborted or
+// the budget is spent. It returns the batches and how many transactions
+// were submitted.
+func runStage(config *config.Config, broadcaster *txpkg.P2PBroadcaster, run *runState, stage rate.Stage, label string) ([]string, int) {
+	var batches []string
+	total := 0
+	run.limiter.SetRate(stage.TPS)
+	defer func() { run.batchLabel = "" }()
+
+	stageEnd := time.Now().Add(stage.Duration)
+	for iteration := 1; time.Now().Before(stageEnd); iteration++ {
+		if run.abort.Aborted() {
+			break
+		}
+		if budgetSpent(run) {
+			fmt.Println("\n💰 Spend budget exhausted. Stopping.")
+			break
+		}
+		txSender, err := newTransactionSender(config, broadcaster)
+		if err != nil {
+			logger.Error("Error connecting to RPC: %v\n", err)
+			os.Exit(1)
+		}
+		run.batchLabel = fmt.Sprintf("%s-%d", label, iteration)
+		batchNumber, submitted := runSingleExecution(config, txSender, run)
+		txSender.Close()
+		batches = append(batches, batchNumber)
+		total += submitted
+	}
+	return batches, total
+}
+
+// printStageReport prints one row per stage: offered rate against the rate
+// actually included, with latency and failures.
+func printStageReport(db dbpkg.Store, results []stageBatches) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 80))
+	fmt.Println("STAGES")
+	fmt.Println(strings.Repeat("=", 80))
+	fmt.Printf("%-6s %10s %8s %8s %9s %10s %8s %6s\n", "Stage", "Offered", "Batches", "Txs", "Included", "Incl. TPS", "p95", "Fail%")
+	for i, result := range results {
+		var txs []*dbpkg.Transaction
+		for _, batch := range result.batches {
+			batchTxs, err := db.GetBatchTransactions(ctx, batch)
+			if err != nil {
+				logger.Warn("Could not load transactions for %s: %v\n", batch, err)
+				continue
+			}
+			txs = append(txs, batchTxs...)
+		}
+		p := report.BuildTrendPoint(result.stage.Label(i), txs)
+		fmt.Printf("%-6d %10s %8d %8s %9s %10s %8s %6s\n", i+1, report.Float(result.stage.TPS, 1),
+			len(result.batches), report.Int(p.Txs), report.Int(p.Included), report.Float(p.TPS, 2),
+			report.Seconds(p.P95Latency, 2), report.Percent(p.FailureRate, 1))
+	}
+	fmt.Println(strings.Repeat("=", 80))
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"go-tps/config"
+	dbpkg "go-tps/db"
+	"go-tps/logger"
+	"go-tps/report"
+)
+
+// runSummary is the machine-readable summary written to SUMMARY_JSON at the
+// end of a run, for CI pipelines to assert on.
+type runSummary struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	StartedAt   time.Time       `json:"started_at"`
+	Mode        string          `json:"mode"`
+	Aborted     bool            `json:"aborted"`
+	Config      config.Config   `json:"config"`
+	Totals      batchSummary    `json:"totals"`
+	Batches     []batchSummary  `json:"batches"`
+	Errors      []errorSummary  `json:"errors"`
+	Categories  map[string]int  `json:"error_categories"`          // error counts by db.ErrorCategory
+	SLA         *slaResult      `json:"sla,omitempty"`             // with SLA thresholds set
+	Skipped     []skippedWallet `json:"skipped_wallets,omitempty"` // left out by SKIP_UNFUNDED_WALLETS
+}
+
+// batchSummary is the outcome of one batch, or of the whole run.
+type batchSummary struct {
+	Batch         string  `json:"batch,omitempty"`
+	Transactions  int     `json:"transactions"`
+	Submitted     int     `json:"submitted"`
+	Included      int     `json:"included"`
+	Successful    int     `json:"successful"`
+	Reverted      int     `json:"reverted"`
+	Rejected      int     `json:"rejected"` // failed at submission
+	Pending       int     `json:"pending"`
+	Cancelled     int     `json:"cancelled"`
+	TPS           float64 `json:"tps"`          // included txs over first submission to last inclusion
+	FailureRate   float64 `json:"failure_rate"` // percent rejected or reverted
+	GasUsed       uint64  `json:"gas_used"`
+	CostWei       string  `json:"cost_wei"`
+	BlocksSpanned uint64  `json:"blocks_spanned"` // first to last block holding an included tx
+
+	SubmissionLatency   latencySummary `json:"submission_latency_seconds"`
+	ConfirmationLatency latencySummary `json:"confirmation_latency_seconds"`
+}
+
+type latencySummary struct {
+	Count int     `json:"count"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P95   float64 `json:"p95"`
+	P99   float64 `json:"p99"`
+	Max   float64 `json:"max"`
+}
+
+type errorSummary struct {
+	Error    string `json:"error"`
+	Category string `json:"category"`
+	Count    int    `json:"count"`
+}
+
+// buildRunSummary summarizes batches from the database. Secrets in the
+// config snapshot (mnemonic, RPC, tracing, webhook and chat credentials) are
+// redacted.
+func buildRunSummary(cfg *config.Config, db dbpkg.Store, batches []string, mode string, startedAt time.Time, aborted bool) *runSummary {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	summary := runSummary{
+		GeneratedAt: time.Now(),
+		StartedAt:   startedAt,
+		Mode:        mode,
+		Aborted:     aborted,
+		Config:      redactConfig(*cfg),
+		Batches:     []batchSummary{},
+		Errors:      []errorSummary{},
+		Categories:  make(map[string]int),
+	}
+
+	var all []*dbpkg.Transaction
+	for _, batch := range batches {
+		txs, err := db.GetBatchTransactions(ctx, batch)
+		if err != nil {
+			logger.Warn("Could not load transactions for %s: %v\n", batch, err)
+			continue
+		}
+		all = append(all, txs...)
+		summary.Batches = append(summary.Batches, summarizeBatch(batch, txs))
+	}
+	summary.Totals = summarizeBatch("", all)
+
+	errors := make(map[string]int)
+	for _, t := range all {
+		if t.Error != "" {
+			errors[t.Error]++
+		}
+	}
+	for msg, n := range errors {
+		summary.Errors = append(summary.Errors, errorSummary{Error: msg, Category: dbpkg.ErrorCategory(msg), Count: n})
+	}
+	for _, c := range report.BuildErrorCategories(all) {
+		summary.Categories[c.Category] = c.Count
+	}
+	sort.Slice(summary.Errors, func(i, j int) bool {
+		if summary.Errors[i].Count != summary.Errors[j].Count {
+			return summary.Errors[i].Count > summary.Errors[j].Count
+		}
+		return summary.Errors[i].Error < summary.Errors[j].Error
+	})
+	return &summary
+}
+
+// writeSummaryJSON writes summary to path.
+func writeSummaryJSON(path string, summary *runSummary) {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		logger.Warn("Could not encode the JSON summary: %v\n", err)
+		return
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		logger.Warn("Could not write the JSON summary: %v\n", err)
+		return
+	}
+	fmt.Printf("✓ JSON summary: %s\n", path)
+}
+
+// summarizeBatch counts txs by outcome and takes their latency percentiles.
+func summarizeBatch(batch string, txs []*dbpkg.Transaction) batchSummary {
+	trend := report.BuildTrendPoint(batch, txs)
+	s := batchSummary{
+		Batch:        batch,
+		Transactions: len(txs),
+		Included:     trend.Included,
+		TPS:          trend.TPS,
+		FailureRate:  trend.FailureRate,
+	}
+
+	var submission, confirmation []float64
+	var firstBlock, lastBlock uint64
+	cost := new(big.Int)
+	for _, t := range txs {
+		if t.BlockHash != "" {
+			if s.BlocksSpanned == 0 || t.BlockNumber < firstBlock {
+				firstBlock = t.BlockNumber
+			}
+			if s.BlocksSpanned == 0 || t.BlockNumber > lastBlock {
+				lastBlock = t.BlockNumber
+			}
+			s.BlocksSpanned = lastBlock - firstBlock + 1
+		}
+		switch {
+		case t.Status == "success":
+			s.Successful++
+		case t.Status == "failed" && t.TxHash == "":
+			s.Rejected++
+		case t.Status == "failed":
+			s.Reverted++
+		case t.Status == "pending":
+			s.Pending++
+		case t.Status == "cancelled":
+			s.Cancelled++
+		}
+		if t.TxHash != "" {
+			s.Submitted++
+			submission = append(submission, t.ExecutionTime/1000)
+		}
+		if t.ConfirmedAt != nil {
+			confirmation = append(confirmation, t.ConfirmedAt.Sub(t.SubmittedAt).Seconds())
+		}
+		s.GasUsed += t.GasUsed
+		if c, ok := new(big.Int).SetString(t.Cost, 10); ok {
+			cost.Add(cost, c)
+		}
+	}
+	s.CostWei = cost.String()
+	s.SubmissionLatency = summarizeLatency(submission)
+	s.ConfirmationLatency = summarizeLatency(confirmation)
+	return s
+}
+
+func summarizeLatency(values []float64) latencySummary {
+	if len(values) == 0 {
+		return latencySummary{}
+	}
+	return latencySummary{
+		Count: len(values),
+		P50:   report.Percentile(values, 50),
+		P90:   report.Percentile(values, 90),
+		P95:   report.Percentile(values, 95),
+		P99:   report.Percentile(values, 99),
+		Max:   report.Percentile(values, 100),
+	}
+}
+
+// redactConfig blanks the settings that hold secrets.
+func redactConfig(cfg config.Config) config.Config {
+	const redacted = "<redacted>"
+	if cfg.Mnemonic != "" {
+		cfg.Mnemonic = redacted
+	}
+	if cfg.MnemonicPassphrase != "" {
+		cfg.MnemonicPassphrase = redacted
+	}
+	if cfg.MnemonicPassword != "" {
+		cfg.MnemonicPassword = redacted
+	}
+	if cfg.FunderPrivateKey != "" {
+		cfg.FunderPrivateKey = redacted
+	}
+	if cfg.FunderKeystorePass != "" {
+		cfg.FunderKeystorePass = redacted
+	}
+	if cfg.KeystorePassword != "" {
+		cfg.KeystorePassword = redacted
+	}
+	if cfg.RPCHeaders != "" {
+		cfg.RPCHeaders = redacted
+	}
+	if cfg.RPCBasicAuth != "" {
+		cfg.RPCBasicAuth = redacted
+	}
+	if cfg.TraceHeaders != "" {
+		cfg.TraceHeaders = redacted
+	}
+	if cfg.InfluxToken != "" {
+		cfg.InfluxToken = redacted
+	}
+	if cfg.WebhookURL != "" {
+		cfg.WebhookURL = redacted // chat webhook URLs carry their token
+	}
+	if cfg.ChatWebhookURL != "" {
+		cfg.ChatWebhookURL = redacted
+	}
+	if cfg.WebhookHeaders != "" {
+		cfg.WebhookHeaders = redacted
+	}
+	return cfg
+}
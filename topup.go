@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"go-tps/config"
+	"go-tps/logger"
+	txpkg "go-tps/tx"
+	"go-tps/wallet"
+	"go-tps/workload"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// topUpMonitor keeps the wallets funded while a long run goes on: every
+// TOPUP_INTERVAL_SECONDS it reads their pending balances and sends each
+// wallet below its threshold enough from the funding wallet to bring it to
+// its target. A wallet being topped up sends nothing until the transfer is
+// included.
+type topUpMonitor struct {
+	config   *config.Config
+	txSender *txpkg.TransactionSender
+	funder   *wallet.Wallet
+	load     workload.Workload
+	wallets  []*wallet.Wallet
+	interval time.Duration
+	below    *big.Int // nil = the most one batch may cost the wallet
+	target   *big.Int // nil, or not above the threshold = twice the threshold
+
+	round sync.Mutex // one round of top-ups at a time
+
+	mu     sync.Mutex
+	paused map[common.Address]chan struct{} // closed once the wallet's top-up is included
+	sent   int
+	failed int
+	total  *big.Int // wei sent
+
+	poke chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newTopUpMonitor sets up the top-ups of wallets from funder, or returns nil
+// if TOPUP_INTERVAL_SECONDS is not positive. Start begins the checks.
+func newTopUpMonitor(config *config.Config, txSender *txpkg.TransactionSender, funder *wallet.Wallet, load workload.Workload, wallets []*wallet.Wallet) (*topUpMonitor, error) {
+	if config.TopUpInterval <= 0 {
+		return nil, nil
+	}
+	if funder == nil {
+		return nil, fmt.Errorf("TOPUP_INTERVAL_SECONDS needs a funding wallet: FUNDER_PRIVATE_KEY, FUNDER_KEY_SOURCE, FUNDER_KEYSTORE or FUNDER_ADDRESS")
+	}
+	below, ok := new(big.Int).SetString(config.TopUpBelowWei, 10)
+	if !ok || below.Sign() < 0 {
+		return nil, fmt.Errorf("invalid TOPUP_BELOW_WEI %q", config.TopUpBelowWei)
+	}
+	target, ok := new(big.Int).SetString(config.TopUpTargetWei, 10)
+	if !ok || target.Sign() < 0 {
+		return nil, fmt.Errorf("invalid TOPUP_TARGET_WEI %q", config.TopUpTargetWei)
+	}
+	if below.Sign() > 0 && target.Sign() > 0 && target.Cmp(below) <= 0 {
+		return nil, fmt.Errorf("TOPUP_TARGET_WEI (%s) must be above TOPUP_BELOW_WEI (%s)", target.String(), below.String())
+	}
+	m := &topUpMonitor{
+		config:   config,
+		txSender: txSender,
+		funder:   funder,
+		load:     load,
+		wallets:  wallets,
+		interval: time.Duration(config.TopUpInterval) * time.Second,
+		paused:   make(map[common.Address]chan struct{}),
+		total:    new(big.Int),
+		poke:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if below.Sign() > 0 {
+		m.below = below
+	}
+	if target.Sign() > 0 {
+		m.target = target
+	}
+	return m, nil
+}
+
+// Start begins checking the balances every interval.
+func (m *topUpMonitor) Start() {
+	if m == nil {
+		return
+	}
+	threshold := "the most one batch may cost it"
+	if m.below != nil {
+		threshold = m.below.String() + " wei"
+	}
+	logger.Info("💧 Topping up wallets below %s every %s from %s\n", threshold, m.interval, m.funder.Address.Hex())
+	go m.run()
+}
+
+func (m *topUpMonitor) run() {
+	defer close(m.done)
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-m.poke:
+		case <-m.stop:
+			return
+		}
+		m.Check()
+	}
+}
+
+// Poke asks for a check now rather than at the next interval, e.g. after a
+// send failed with insufficient funds.
+func (m *topUpMonitor) Poke() {
+	if m == nil {
+		return
+	}
+	select {
+	case m.poke <- struct{}{}:
+	default:
+	}
+}
+
+// Wait blocks while a top-up of address is under way, or until abort is
+// closed.
+func (m *topUpMonitor) Wait(address common.Address, abort <-chan struct{}) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	included := m.paused[address]
+	m.mu.Unlock()
+	if included == nil {
+		return
+	}
+	select {
+	case <-included:
+	case <-abort:
+	}
+}
+
+// Stop ends the checks and returns how many top-ups were sent and failed,
+// and the wei sent.
+func (m *topUpMonitor) Stop() (sent, failed int, total *big.Int) {
+	if m == nil {
+		return 0, 0, nil
+	}
+	close(m.stop)
+	<-m.done
+	m.round.Lock()
+	defer m.round.Unlock()
+	return m.sent, m.failed, m.total
+}
+
+// Check tops up every wallet below its threshold and returns once the
+// transfers are included. Errors are logged: the run goes on, and wallets
+// that stay short fail their sends as without top-ups.
+func (m *topUpMonitor) Check() {
+	if m == nil {
+		return
+	}
+	m.round.Lock()
+	defer m.round.Unlock()
+
+	transfers, baseFee, tip, err := m.plan()
+	if err != nil {
+		logger.Warn("Could not check wallet balances for top-ups: %v\n", err)
+		return
+	}
+	if len(transfers) == 0 {
+		return
+	}
+
+	// Pause the wallets before anything is sent, and resume each once its
+	// top-up is included or has failed
+	m.mu.Lock()
+	for _, t := range transfers {
+		m.paused[t.to] = make(chan struct{})
+	}
+	m.mu.Unlock()
+	resume := func(t fundingTransfer, ok bool) {
+		m.mu.Lock()
+		close(m.paused[t.to])
+		delete(m.paused, t.to)
+		if ok {
+			m.sent++
+			m.total.Add(m.total, t.amount)
+		} else {
+			m.failed++
+		}
+		m.mu.Unlock()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fundingReceiptTimeout)
+	defer cancel()
+
+	needed := new(big.Int)
+	for _, t := range transfers {
+		needed.Add(needed, t.amount)
+	}
+	balance, err := m.txSender.GetPendingBalance(ctx, m.funder.Address)
+	if err == nil && balance.Cmp(needed) < 0 {
+		err = fmt.Errorf("funding wallet %s holds %s wei, %s wei short of the top-ups", m.funder.Address.Hex(), balance.String(), new(big.Int).Sub(needed, balance).String())
+	}
+	var nonce uint64
+	if err == nil {
+		nonce, err = m.txSender.GetNonce(ctx, m.funder.Address)
+	}
+	if err != nil {
+		logger.Warn("Could not top up %d wallets: %v\n", len(transfers), err)
+		for _, t := range transfers {
+			resume(t, false)
+		}
+		return
+	}
+
+	logger.Info("💧 Topping up %d wallets from %s...\n", len(transfers), m.funder.Address.Hex())
+	hashes := make([]common.Hash, len(transfers))
+	for i, t := range transfers {
+		req := &txpkg.TxRequest{
+			ToAddress: t.to,
+			Value:     t.amount,
+			Nonce:     nonce,
+			GasLimit:  m.config.GasLimit,
+			BaseFee:   baseFee,
+			Tip:       tip,
+		}
+		hashes[i], err = m.txSender.SendRequest(ctx, req, m.funder.Signer())
+		if err != nil {
+			logger.Warn("Could not top up wallet %d: %v\n", t.index+1, err)
+			resume(t, false)
+			continue
+		}
+		nonce++
+	}
+	for i, t := range transfers {
+		if hashes[i] == (common.Hash{}) {
+			continue
+		}
+		receipt, err := m.txSender.WaitForReceipt(ctx, hashes[i], fundingReceiptTimeout)
+		switch {
+		case err != nil:
+			logger.Warn("Top-up of wallet %d (%s): %v\n", t.index+1, hashes[i].Hex(), err)
+			resume(t, false)
+		case receipt.Status != 1:
+			logger.Warn("Top-up of wallet %d (%s) reverted\n", t.index+1, hashes[i].Hex())
+			resume(t, false)
+		default:
+			logger.Info("  💧 [W%d] +%s wei\n", t.index+1, t.amount.String())
+			resume(t, true)
+		}
+	}
+}
+
+// plan reads the pending balance of every wallet and returns the transfers
+// that bring the ones below their threshold to their target, and the fees
+// to send them at.
+func (m *topUpMonitor) plan() ([]fundingTransfer, *big.Int, *big.Int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.ContextTimeout)*time.Second)
+	defer cancel()
+
+	baseFee, tip, err := batchFees(ctx, m.config, m.txSender, nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var costs []*big.Int
+	if m.below == nil {
+		costs, err = batchCosts(ctx, m.config, m.txSender, m.load, m.wallets, m.txSender.MaxFeePerGas(baseFee, tip))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	var transfers []fundingTransfer
+	for i, w := range m.wallets {
+		below := m.below
+		if below == nil {
+			below = costs[i]
+		}
+		if below.Sign() == 0 {
+			continue // sends nothing, e.g. the signers of the meta workload
+		}
+		balance, err := m.txSender.GetPendingBalance(ctx, w.Address)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if balance.Cmp(below) >= 0 {
+			continue
+		}
+		target := m.target
+		if target == nil || target.Cmp(below) <= 0 {
+			target = new(big.Int).Mul(below, big.NewInt(2))
+		}
+		transfers = append(transfers, fundingTransfer{index: i, to: w.Address, amount: new(big.Int).Sub(target, balance)})
+	}
+	return transfers, baseFee, tip, nil
+}
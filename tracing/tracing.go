@@ -0,0 +1,59 @@
+// Package tracing exports OpenTelemetry spans of each transaction's life
+// (prepare, sign, submit, receipt) to an OTLP/HTTP collector.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// serviceName is the service.name the spans are reported under.
+const serviceName = "go-tps"
+
+// Setup installs a global tracer provider that samples sampleRate (0-1) of
+// the transactions and exports their spans to the OTLP/HTTP collector at
+// endpoint, e.g. http://localhost:4318, with headers on every request. The
+// returned function flushes the spans still buffered; call it before exit.
+func Setup(endpoint string, headers http.Header, sampleRate float64) (func(context.Context) error, error) {
+	if sampleRate < 0 || sampleRate > 1 {
+		return nil, fmt.Errorf("sample rate %g is not between 0 and 1", sampleRate)
+	}
+	target, err := tracesURL(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(newExporter(target, headers)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRate))),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+// tracesURL returns the traces URL of an OTLP/HTTP endpoint: the endpoint
+// itself if it already names /v1/traces, otherwise with that path appended.
+func tracesURL(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid OTLP endpoint %q: %w", endpoint, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("invalid OTLP endpoint %q: want http(s)://host:port", endpoint)
+	}
+	if !strings.HasSuffix(u.Path, "/v1/traces") {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/v1/traces"
+	}
+	return u.String(), nil
+}
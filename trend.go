@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"go-tps/config"
+	dbpkg "go-tps/db"
+	"go-tps/logger"
+	"go-tps/report"
+)
+
+// runTrendCommand implements `go-tps trend`: TPS, p95 latency and failure
+// rate for every batch in the database, as an ASCII table or an HTML chart.
+func runTrendCommand(config *config.Config, args []string) int {
+	fs := flag.NewFlagSet("trend", flag.ContinueOnError)
+	dbPath := fs.String("db", config.DBPath, "SQLite database to read")
+	htmlPath := fs.String("html", "", "write an HTML chart to this file instead of printing")
+	last := fs.Int("last", 0, "only include the most recent N batches (0 = all)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	db, err := dbpkg.Open(*dbPath, config.DBMaxOpenConns, config.DBMaxIdleConns)
+	if err != nil {
+		logger.Error("Error opening database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	batches, err := db.ListBatches(ctx)
+	if err != nil {
+		logger.Error("Error listing batches: %v\n", err)
+		return 1
+	}
+	if *last > 0 && len(batches) > *last {
+		batches = batches[len(batches)-*last:]
+	}
+
+	points := make([]report.TrendPoint, 0, len(batches))
+	for _, batch := range batches {
+		txs, err := db.GetBatchTransactions(ctx, batch)
+		if err != nil {
+			logger.Error("Error loading %s: %v\n", batch, err)
+			return 1
+		}
+		points = append(points, report.BuildTrendPoint(batch, txs))
+	}
+
+	if *htmlPath == "" {
+		report.PrintTrend(points)
+		return 0
+	}
+
+	series, err := db.GetTPSSeries(ctx, batches)
+	if err != nil {
+		logger.Error("Error loading TPS series: %v\n", err)
+		return 1
+	}
+
+	file, err := os.Create(*htmlPath)
+	if err != nil {
+		logger.Error("Error creating %s: %v\n", *htmlPath, err)
+		return 1
+	}
+	defer file.Close()
+	if err := report.WriteTrendHTML(file, points, series); err != nil {
+		logger.Error("Error writing %s: %v\n", *htmlPath, err)
+		return 1
+	}
+	fmt.Printf("✓ Trend of %d batches written to %s\n", len(points), *htmlPath)
+	return 0
+}
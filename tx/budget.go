@@ -0,0 +1,158 @@
+package tx
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// SpendBudget caps the wei a run may commit, in total and per wallet. Each
+// transaction is charged its worst-case cost (value plus gas limit times the
+// max fee per gas) before it is sent, so the cap holds however fees settle.
+type SpendBudget struct {
+	maxRun    *big.Int // nil = no run cap
+	maxWallet *big.Int // nil = no per-wallet cap
+
+	mu              sync.Mutex
+	spent           *big.Int
+	walletSpent     map[common.Address]*big.Int
+	exhausted       bool
+	walletExhausted map[common.Address]bool
+}
+
+// NewSpendBudget returns a budget with the given caps in wei. A nil or zero
+// cap is unlimited; if both are, it returns nil.
+func NewSpendBudget(maxRun, maxWallet *big.Int) *SpendBudget {
+	if maxRun != nil && maxRun.Sign() == 0 {
+		maxRun = nil
+	}
+	if maxWallet != nil && maxWallet.Sign() == 0 {
+		maxWallet = nil
+	}
+	if maxRun == nil && maxWallet == nil {
+		return nil
+	}
+	return &SpendBudget{
+		maxRun:          maxRun,
+		maxWallet:       maxWallet,
+		spent:           new(big.Int),
+		walletSpent:     make(map[common.Address]*big.Int),
+		walletExhausted: make(map[common.Address]bool),
+	}
+}
+
+// MaxCost is the most a signed request can cost its sender, including the
+// estimated L1 data fee on rollups that charge it on top of the gas.
+func (req *TxRequest) MaxCost() *big.Int {
+	cost := new(big.Int).SetUint64(req.GasLimit)
+	if feeCap := req.GasFeeCap(); feeCap != nil {
+		cost.Mul(cost, feeCap)
+	}
+	if req.Value != nil {
+		cost.Add(cost, req.Value)
+	}
+	if req.L1Fee != nil && !req.L1FeeInGas {
+		cost.Add(cost, req.L1Fee)
+	}
+	return cost
+}
+
+// Reserve charges req's worst-case cost to from. It returns false, charging
+// nothing, if that would exceed the run or wallet cap; the budget (or the
+// wallet's share of it) is then exhausted and refuses everything after.
+func (b *SpendBudget) Reserve(from common.Address, req *TxRequest) bool {
+	cost := req.MaxCost()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.charge(from, cost) {
+		return false
+	}
+	req.reserved = cost
+	return true
+}
+
+// Reprice charges from the rise in req's worst-case cost since it was
+// reserved, after it was re-signed at a higher price. It returns false,
+// charging nothing, if that would exceed a cap, as Reserve does.
+func (b *SpendBudget) Reprice(from common.Address, req *TxRequest) bool {
+	cost := req.MaxCost()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	extra := new(big.Int).Set(cost)
+	if req.reserved != nil {
+		extra.Sub(extra, req.reserved)
+	}
+	if extra.Sign() <= 0 {
+		return true
+	}
+	if !b.charge(from, extra) {
+		return false
+	}
+	req.reserved = cost
+	return true
+}
+
+// charge adds cost to the run's and from's spend unless that exceeds a cap.
+// Callers hold b.mu.
+func (b *SpendBudget) charge(from common.Address, cost *big.Int) bool {
+	if b.exhausted || b.walletExhausted[from] {
+		return false
+	}
+	if b.maxRun != nil && new(big.Int).Add(b.spent, cost).Cmp(b.maxRun) > 0 {
+		b.exhausted = true
+		return false
+	}
+	walletSpent := b.walletSpent[from]
+	if walletSpent == nil {
+		walletSpent = new(big.Int)
+		b.walletSpent[from] = walletSpent
+	}
+	if b.maxWallet != nil && new(big.Int).Add(walletSpent, cost).Cmp(b.maxWallet) > 0 {
+		b.walletExhausted[from] = true
+		return false
+	}
+
+	b.spent.Add(b.spent, cost)
+	walletSpent.Add(walletSpent, cost)
+	return true
+}
+
+// Release returns the reservation of a transaction that was never accepted.
+func (b *SpendBudget) Release(from common.Address, req *TxRequest) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	cost := req.reserved
+	if cost == nil {
+		return
+	}
+	req.reserved = nil
+	b.spent.Sub(b.spent, cost)
+	if walletSpent := b.walletSpent[from]; walletSpent != nil {
+		walletSpent.Sub(walletSpent, cost)
+	}
+}
+
+// Exhausted reports whether the run cap has been hit.
+func (b *SpendBudget) Exhausted() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.exhausted
+}
+
+// WalletExhausted reports whether from can no longer send, because either
+// the run cap or its own cap has been hit.
+func (b *SpendBudget) WalletExhausted(from common.Address) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.exhausted || b.walletExhausted[from]
+}
+
+// Spent returns the total worst-case cost reserved so far.
+func (b *SpendBudget) Spent() *big.Int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return new(big.Int).Set(b.spent)
+}
@@ -0,0 +1,72 @@
+package tx
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// cancelGasLimit is the gas limit of the zero-value self-transfer that
+// replaces a stuck transaction.
+const cancelGasLimit = 21_000
+
+// CancelNonce replaces whatever the signer's address has pending at nonce with a
+// zero-value self-transfer priced at baseFee and tip. If pendingHash is known
+// and the node still has that transaction, its fee cap and tip are raised by
+// at least bumpPercent (and the node's 10% replacement minimum) so the
+// replacement is accepted. It returns the hash of the cancel transaction.
+func (ts *TransactionSender) CancelNonce(ctx context.Context, signer Signer, nonce uint64, pendingHash common.Hash, baseFee, tip *big.Int, bumpPercent float64) (common.Hash, error) {
+	from := signer.Address()
+	feeCap, tipCap := ts.fees(baseFee, tip)
+
+	if pendingHash != (common.Hash{}) {
+		if pending, _, err := ts.client.TransactionByHash(ctx, pendingHash); err == nil {
+			feeCap = maxBig(feeCap, replacementFee(pending.GasFeeCap(), bumpPercent))
+			tipCap = maxBig(tipCap, replacementFee(pending.GasTipCap(), bumpPercent))
+		}
+	}
+	if ts.maxGasPrice != nil && feeCap.Cmp(ts.maxGasPrice) > 0 {
+		return common.Hash{}, fmt.Errorf("replacement fee cap %s wei exceeds MAX_GAS_PRICE_WEI", feeCap.String())
+	}
+	if tipCap.Cmp(feeCap) > 0 {
+		feeCap = new(big.Int).Set(tipCap)
+	}
+
+	cancel := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   ts.chainID,
+		Nonce:     nonce,
+		GasTipCap: tipCap,
+		GasFeeCap: feeCap,
+		Gas:       cancelGasLimit,
+		To:        &from,
+		Value:     new(big.Int),
+	})
+	signed, err := ts.SignTransaction(cancel, signer)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if _, err := ts.sendRaw(ctx, signed); err != nil {
+		return common.Hash{}, fmt.Errorf("failed to send cancel for nonce %d: %w", nonce, err)
+	}
+	return signed.Hash(), nil
+}
+
+// replacementFee is fee raised by percent, and by at least the minimum bump
+// nodes require to replace a pending transaction.
+func replacementFee(fee *big.Int, percent float64) *big.Int {
+	if percent < minReplacementBump {
+		percent = minReplacementBump
+	}
+	bumped := BumpFee(BumpPercentage, percent, fee, fee)
+	return bumped.Add(bumped, big.NewInt(1))
+}
+
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,77 @@
+package tx
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// headers are added to every HTTP and WebSocket RPC request, see SetHeaders.
+var headers http.Header
+
+// SetHeaders adds h to the requests of every HTTP and WebSocket RPC
+// connection opened from here on, e.g. a provider's API key. IPC
+// connections have no headers.
+func SetHeaders(h http.Header) {
+	headers = h
+}
+
+// ParseHeaders builds the RPC request headers from RPC_HEADERS,
+// comma-separated "Name: value" pairs, and RPC_BASIC_AUTH, "user:password".
+func ParseHeaders(spec, basicAuth string) (http.Header, error) {
+	h := make(http.Header)
+	for _, pair := range strings.Split(spec, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("header %q is not Name: value", strings.TrimSpace(pair))
+		}
+		h.Add(name, strings.TrimSpace(value))
+	}
+	if basicAuth != "" {
+		if !strings.Contains(basicAuth, ":") {
+			return nil, fmt.Errorf("basic auth must be user:password")
+		}
+		h.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(basicAuth)))
+	}
+	return h, nil
+}
+
+// Dial connects to an HTTP, WebSocket or IPC endpoint, sending the headers
+// set with SetHeaders. IPC endpoints are given as a file path.
+func Dial(rawurl string, opts ...rpc.ClientOption) (*ethclient.Client, error) {
+	client, err := dialRPC(rawurl, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return ethclient.NewClient(client), nil
+}
+
+func dialRPC(rawurl string, opts ...rpc.ClientOption) (*rpc.Client, error) {
+	if len(headers) > 0 {
+		opts = append(opts, rpc.WithHeaders(headers))
+	}
+	return rpc.DialOptions(context.Background(), rawurl, opts...)
+}
+
+// EndpointName is how an endpoint is recorded: its host, or the path of an
+// IPC socket. Credentials in the URL are left out.
+func EndpointName(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return ""
+	}
+	if u.Host == "" {
+		return u.Path
+	}
+	return u.Host
+}
@@ -0,0 +1,86 @@
+package tx
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"go-tps/logger"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// GasEstimator sets gas limits from eth_estimateGas. Estimates are cached per
+// transaction type (recipient plus 4-byte selector), so a batch costs one
+// estimate per distinct call rather than one per transaction.
+type GasEstimator struct {
+	ts       *TransactionSender
+	margin   float64 // percent added on top of the estimate
+	override uint64  // fixed gas limit for every transaction, 0 = estimate
+
+	mu    sync.Mutex
+	cache map[string]uint64
+}
+
+func NewGasEstimator(ts *TransactionSender, marginPercent float64, override uint64) *GasEstimator {
+	return &GasEstimator{
+		ts:       ts,
+		margin:   marginPercent,
+		override: override,
+		cache:    make(map[string]uint64),
+	}
+}
+
+// Apply fills in GasLimit and Estimated for each call sent from from. With an
+// override every call gets that limit; otherwise calls get the estimate plus
+// the safety margin. If estimation fails the call's own limit (or the
+// configured default, when it has none) is left in place.
+func (e *GasEstimator) Apply(ctx context.Context, from common.Address, calls []Call) {
+	for i := range calls {
+		if e.override > 0 {
+			calls[i].GasLimit = e.override
+			continue
+		}
+
+		estimate, err := e.estimate(ctx, from, &calls[i])
+		if err != nil {
+			logger.Warn("[GasEstimator] Could not estimate gas for call to %s, using fallback limit: %v\n", calls[i].To.Hex(), err)
+			continue
+		}
+		calls[i].Estimated = estimate
+		calls[i].GasLimit = estimate + uint64(float64(estimate)*e.margin/100)
+	}
+}
+
+func (e *GasEstimator) estimate(ctx context.Context, from common.Address, call *Call) (uint64, error) {
+	key := call.To.Hex()
+	if len(call.Data) >= 4 {
+		key += ":" + hex.EncodeToString(call.Data[:4])
+	}
+
+	e.mu.Lock()
+	cached, ok := e.cache[key]
+	e.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	to := call.To
+	estimate, err := e.ts.client.EstimateGas(ctx, ethereum.CallMsg{
+		From:  from,
+		To:    &to,
+		Value: call.Value,
+		Data:  call.Data,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to estimate gas: %w", err)
+	}
+
+	e.mu.Lock()
+	e.cache[key] = estimate
+	e.mu.Unlock()
+	logger.Debug("[GasEstimator] %s estimated at %d gas\n", key, estimate)
+	return estimate, nil
+}
@@ -0,0 +1,466 @@
+package tx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-tps/logger"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Failover is an http.RoundTripper that sends every JSON-RPC request to one
+// active endpoint out of a list. When the active endpoint fails with a
+// connection error, a timeout or a 5xx response, it moves on to the next
+// endpoint and, unless the caller's context is done, retries the request
+// there. Re-sending a raw transaction is safe: a node that already has it
+// answers "already known". With health checks running, endpoints that fail
+// them are skipped until they pass again. With weights set, requests are
+// spread over the endpoints in proportion to their weights instead, and a
+// failed request moves on to the next endpoint without changing the others.
+type Failover struct {
+	endpoints []*endpoint
+	base      http.RoundTripper
+
+	mu        sync.Mutex
+	active    int
+	failovers int
+	weighted  bool
+
+	maxLag uint64 // blocks an endpoint may trail the highest one
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// endpoint is one RPC endpoint and its health check record.
+type endpoint struct {
+	url    *url.URL
+	client *rpc.Client // health checks; bypasses the failover
+
+	excluded   bool
+	excludedAt time.Time
+	EndpointHealth
+
+	weight  int // share of requests when weighted; 0 = only on failover
+	current int // smooth weighted round-robin credit
+}
+
+// EndpointHealth summarises the health checks of one endpoint.
+type EndpointHealth struct {
+	Host         string
+	Checks       int
+	Failed       int           // checks that errored, found the node syncing or lagging
+	TotalLatency time.Duration // of eth_blockNumber over the checks that answered
+	MaxLatency   time.Duration
+	Answered     int
+	MaxLag       uint64 // most blocks behind the highest endpoint
+	Exclusions   int
+	Excluded     time.Duration // total time out of the rotation
+	LastError    string        // reason of the last failed check
+	Healthy      bool          // passed the last check
+}
+
+// AvgLatency returns the mean eth_blockNumber latency.
+func (h EndpointHealth) AvgLatency() time.Duration {
+	if h.Answered == 0 {
+		return 0
+	}
+	return h.TotalLatency / time.Duration(h.Answered)
+}
+
+// NewFailover returns a transport over rpcURLs, tried in order. Only HTTP
+// endpoints can be failed over between.
+func NewFailover(rpcURLs []string) (*Failover, error) {
+	f := &Failover{base: http.DefaultTransport}
+	for _, raw := range rpcURLs {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RPC endpoint %q: %w", raw, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("RPC endpoint %q: failover needs http or https endpoints", raw)
+		}
+		f.endpoints = append(f.endpoints, &endpoint{url: u, EndpointHealth: EndpointHealth{Host: u.Host, Healthy: true}})
+	}
+	if len(f.endpoints) == 0 {
+		return nil, fmt.Errorf("no RPC endpoints")
+	}
+	return f, nil
+}
+
+// ParseWeights parses comma-separated url=weight pairs, such as
+// "https://rpc-a.example.com=3,https://rpc-b.example.com=1", into a weight
+// per endpoint of rpcURLs. An endpoint may be named by its URL or its host;
+// endpoints left out get weight 0 and only take requests on failover.
+func ParseWeights(spec string, rpcURLs []string) ([]int, error) {
+	weights := make([]int, len(rpcURLs))
+	positive := false
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		i := strings.LastIndex(pair, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid endpoint weight %q: expected url=weight", pair)
+		}
+		name, value := strings.TrimSpace(pair[:i]), strings.TrimSpace(pair[i+1:])
+		weight, err := strconv.Atoi(value)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid endpoint weight %q: weight must be a non-negative integer", pair)
+		}
+		found := false
+		for j, rpcURL := range rpcURLs {
+			if name == rpcURL || name == EndpointName(rpcURL) {
+				weights[j] = weight
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("endpoint weight %q names no RPC endpoint", pair)
+		}
+		positive = positive || weight > 0
+	}
+	if !positive {
+		return nil, fmt.Errorf("no endpoint has a positive weight")
+	}
+	return weights, nil
+}
+
+// SetWeights spreads requests over the endpoints in proportion to weights,
+// one per endpoint in order, rather than sending them all to the active one.
+func (f *Failover) SetWeights(weights []int) error {
+	if len(weights) != len(f.endpoints) {
+		return fmt.Errorf("%d weights for %d endpoints", len(weights), len(f.endpoints))
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, e := range f.endpoints {
+		e.weight = weights[i]
+	}
+	f.weighted = true
+	return nil
+}
+
+// Weights returns the weight of every endpoint by host, or nil when requests
+// are not weighted.
+func (f *Failover) Weights() map[string]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.weighted {
+		return nil
+	}
+	weights := make(map[string]int, len(f.endpoints))
+	for _, e := range f.endpoints {
+		weights[e.url.Host] += e.weight
+	}
+	return weights
+}
+
+// servedByKey is the context key under which a *string receives the host of
+// the endpoint that answered a request, or that was tried last if none did.
+type servedByKey struct{}
+
+// withServedBy returns a context whose requests record the host of the
+// endpoint that answered them in the returned string.
+func withServedBy(ctx context.Context) (context.Context, *string) {
+	host := new(string)
+	return context.WithValue(ctx, servedByKey{}, host), host
+}
+
+// RoundTrip implements http.RoundTripper.
+func (f *Failover) RoundTrip(req *http.Request) (*http.Response, error) {
+	order := f.rotation()
+
+	var lastErr error
+	for i, idx := range order {
+		endpoint := f.endpoints[idx].url
+
+		attempt := req.Clone(req.Context())
+		attempt.URL = endpoint
+		attempt.Host = endpoint.Host
+		if i > 0 {
+			if req.GetBody == nil {
+				break
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attempt.Body = body
+		}
+
+		if host, ok := req.Context().Value(servedByKey{}).(*string); ok {
+			*host = endpoint.Host
+		}
+		resp, err := f.base.RoundTrip(attempt)
+		if err == nil && resp.StatusCode < http.StatusInternalServerError {
+			return resp, nil
+		}
+		if err == nil {
+			lastErr = fmt.Errorf("%s: %s", endpoint.Host, resp.Status)
+			if i == len(order)-1 {
+				return resp, nil
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		} else {
+			lastErr = err
+		}
+
+		// A caller giving up says nothing about the endpoint
+		if errors.Is(req.Context().Err(), context.Canceled) {
+			return nil, lastErr
+		}
+		f.fail(idx, lastErr)
+		if req.Context().Err() != nil {
+			return nil, lastErr
+		}
+	}
+	return nil, lastErr
+}
+
+// rotation returns the endpoint indexes a request tries, in order: the
+// active one, or with weights the next weighted pick, first, then the others
+// that are not excluded. If every endpoint is excluded, all of them are
+// tried rather than none.
+func (f *Failover) rotation() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	first := f.active
+	if f.weighted {
+		if pick := f.pick(); pick >= 0 {
+			first = pick
+		}
+	}
+	order := make([]int, 0, len(f.endpoints))
+	for i := range f.endpoints {
+		idx := (first + i) % len(f.endpoints)
+		if !f.endpoints[idx].excluded {
+			order = append(order, idx)
+		}
+	}
+	if len(order) == 0 {
+		for i := range f.endpoints {
+			order = append(order, (first+i)%len(f.endpoints))
+		}
+	}
+	return order
+}
+
+// pick chooses the endpoint for the next weighted request by smooth
+// weighted round-robin over the endpoints that are not excluded, so an
+// endpoint of weight 3 next to one of weight 1 takes three requests of
+// every four without taking them in a row. It returns -1 if no endpoint
+// with weight is left. Callers hold mu.
+func (f *Failover) pick() int {
+	pick, total := -1, 0
+	for i, e := range f.endpoints {
+		if e.excluded || e.weight == 0 {
+			continue
+		}
+		e.current += e.weight
+		total += e.weight
+		if pick < 0 || e.current > f.endpoints[pick].current {
+			pick = i
+		}
+	}
+	if pick >= 0 {
+		f.endpoints[pick].current -= total
+	}
+	return pick
+}
+
+// fail moves the active endpoint past idx, unless another request already
+// failed over from it. Weighted requests have no active endpoint to move.
+func (f *Failover) fail(idx int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.weighted || f.active != idx || !f.advance() {
+		return
+	}
+	logger.Warn("RPC endpoint %s failed (%v); failing over to %s\n",
+		f.endpoints[idx].url.Host, err, f.endpoints[f.active].url.Host)
+}
+
+// advance makes the next endpoint that is not excluded active and reports
+// whether the active endpoint changed. Callers hold mu.
+func (f *Failover) advance() bool {
+	for i := 1; i < len(f.endpoints); i++ {
+		next := (f.active + i) % len(f.endpoints)
+		if !f.endpoints[next].excluded {
+			f.active = next
+			f.failovers++
+			return true
+		}
+	}
+	return false
+}
+
+// Active returns the host of the endpoint requests currently go to.
+func (f *Failover) Active() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.endpoints[f.active].url.Host
+}
+
+// Failovers returns how many times the active endpoint changed.
+func (f *Failover) Failovers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.failovers
+}
+
+// StartHealthChecks probes every endpoint each interval with eth_blockNumber
+// and eth_syncing, and takes an endpoint out of the rotation while it errors,
+// is syncing or trails the highest endpoint by more than maxLag blocks.
+func (f *Failover) StartHealthChecks(interval time.Duration, maxLag uint64) {
+	f.maxLag = maxLag
+	f.stop = make(chan struct{})
+	f.done = make(chan struct{})
+	for _, e := range f.endpoints {
+		e.client, _ = dialRPC(e.url.String(), rpc.WithHTTPClient(&http.Client{Transport: f.base}))
+	}
+	go func() {
+		defer close(f.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		f.checkHealth(interval)
+		for {
+			select {
+			case <-f.stop:
+				return
+			case <-ticker.C:
+				f.checkHealth(interval)
+			}
+		}
+	}()
+}
+
+// StopHealthChecks halts the health checks, if they run.
+func (f *Failover) StopHealthChecks() {
+	if f.stop == nil {
+		return
+	}
+	close(f.stop)
+	<-f.done
+	f.stop = nil
+	for _, e := range f.endpoints {
+		if e.client != nil {
+			e.client.Close()
+		}
+	}
+}
+
+// Health returns the health check record of every endpoint, in order.
+func (f *Failover) Health() []EndpointHealth {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	health := make([]EndpointHealth, len(f.endpoints))
+	for i, e := range f.endpoints {
+		health[i] = e.EndpointHealth
+		if e.excluded {
+			health[i].Excluded += time.Since(e.excludedAt)
+		}
+	}
+	return health
+}
+
+// probe is the outcome of one endpoint's health check.
+type probe struct {
+	block   uint64
+	latency time.Duration // of eth_blockNumber; 0 if it did not answer
+	err     error
+}
+
+func (f *Failover) checkHealth(timeout time.Duration) {
+	probes := make([]probe, len(f.endpoints))
+	var wg sync.WaitGroup
+	for i, e := range f.endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probes[i] = e.probe(timeout)
+		}()
+	}
+	wg.Wait()
+
+	var highest uint64
+	for _, p := range probes {
+		if p.err == nil {
+			highest = max(highest, p.block)
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, e := range f.endpoints {
+		p := probes[i]
+		e.Checks++
+		if p.latency > 0 {
+			e.Answered++
+			e.TotalLatency += p.latency
+			e.MaxLatency = max(e.MaxLatency, p.latency)
+		}
+		if p.err == nil {
+			lag := highest - p.block
+			e.MaxLag = max(e.MaxLag, lag)
+			if lag > f.maxLag {
+				p.err = fmt.Errorf("%d blocks behind", lag)
+			}
+		}
+
+		e.Healthy = p.err == nil
+		if !e.Healthy {
+			e.Failed++
+			e.LastError = p.err.Error()
+		}
+		switch {
+		case !e.Healthy && !e.excluded:
+			e.excluded = true
+			e.excludedAt = time.Now()
+			e.Exclusions++
+			logger.Warn("RPC endpoint %s is unhealthy (%v); taking it out of the rotation\n", e.url.Host, p.err)
+			if !f.weighted && f.active == i && f.advance() {
+				logger.Warn("Failing over to %s\n", f.endpoints[f.active].url.Host)
+			}
+		case e.Healthy && e.excluded:
+			e.excluded = false
+			e.Excluded += time.Since(e.excludedAt)
+			logger.Info("RPC endpoint %s is healthy again; back in the rotation\n", e.url.Host)
+		}
+	}
+}
+
+// probe checks that the endpoint answers eth_blockNumber and is not syncing.
+func (e *endpoint) probe(timeout time.Duration) probe {
+	if e.client == nil {
+		return probe{err: fmt.Errorf("not connected")}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var block hexutil.Uint64
+	start := time.Now()
+	if err := e.client.CallContext(ctx, &block, "eth_blockNumber"); err != nil {
+		return probe{err: err}
+	}
+	p := probe{block: uint64(block), latency: time.Since(start)}
+
+	var syncing interface{}
+	if err := e.client.CallContext(ctx, &syncing, "eth_syncing"); err != nil {
+		p.err = err
+	} else if syncing != false {
+		p.err = fmt.Errorf("node is syncing")
+	}
+	return p
+}
@@ -0,0 +1,201 @@
+package tx
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"go-tps/logger"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// FeeController steers the fee level of new transactions towards an
+// inclusion-latency target. It watches the nonces of the wallets that sent
+// tracked transactions, and once per interval compares the p95 latency seen
+// since the last adjustment with the target: above it the level rises by one
+// step, below half of it the level falls by one step, in between it holds.
+// Transactions still unmined past the target count as slow even before they
+// are included, so a stalled chain raises fees too.
+type FeeController struct {
+	ts       *TransactionSender
+	target   time.Duration
+	step     float64 // percent per adjustment
+	minLevel float64
+	maxLevel float64
+
+	mu        sync.Mutex
+	level     float64
+	pending   map[common.Address]map[uint64]time.Time // sent at, by nonce
+	latencies []float64                               // seconds, since the last adjustment
+	raised    int
+	lowered   int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewFeeController creates a controller starting at level 1.0 (the fees the
+// run would otherwise use) and kept within [minLevel, maxLevel].
+func NewFeeController(ts *TransactionSender, target time.Duration, stepPercent, minLevel, maxLevel float64) *FeeController {
+	return &FeeController{
+		ts:       ts,
+		target:   target,
+		step:     stepPercent,
+		minLevel: minLevel,
+		maxLevel: maxLevel,
+		level:    math.Min(math.Max(1.0, minLevel), maxLevel),
+		pending:  make(map[common.Address]map[uint64]time.Time),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Track registers a transaction that was accepted by the node.
+func (c *FeeController) Track(from common.Address, nonce uint64, sentAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	byNonce, ok := c.pending[from]
+	if !ok {
+		byNonce = make(map[uint64]time.Time)
+		c.pending[from] = byNonce
+	}
+	byNonce[nonce] = sentAt
+}
+
+// Level returns the current fee level, a factor applied to both the fee cap
+// and the tip of new transactions.
+func (c *FeeController) Level() float64 {
+	if c == nil {
+		return 1.0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.level
+}
+
+// Scale returns fee multiplied by the current level. A nil controller leaves
+// fee unchanged.
+func (c *FeeController) Scale(fee *big.Int) *big.Int {
+	level := c.Level()
+	if level == 1.0 || fee == nil {
+		return fee
+	}
+	scaled, _ := new(big.Float).Mul(new(big.Float).SetInt(fee), big.NewFloat(level)).Int(nil)
+	return scaled
+}
+
+// Start polls for inclusions every pollInterval and adjusts the level every
+// adjustInterval until Stop is called.
+func (c *FeeController) Start(pollInterval, adjustInterval time.Duration) {
+	go func() {
+		defer close(c.done)
+		poll := time.NewTicker(pollInterval)
+		defer poll.Stop()
+		adjust := time.NewTicker(adjustInterval)
+		defer adjust.Stop()
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-poll.C:
+				c.poll()
+			case <-adjust.C:
+				c.adjust()
+			}
+		}
+	}()
+}
+
+// Stop halts the controller and returns its final level and how many times
+// it raised and lowered fees.
+func (c *FeeController) Stop() (level float64, raised, lowered int) {
+	close(c.stop)
+	<-c.done
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.level, c.raised, c.lowered
+}
+
+// poll records the latency of every tracked transaction whose nonce has been
+// mined since the last poll.
+func (c *FeeController) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	c.mu.Lock()
+	wallets := make([]common.Address, 0, len(c.pending))
+	for addr := range c.pending {
+		wallets = append(wallets, addr)
+	}
+	c.mu.Unlock()
+
+	for _, addr := range wallets {
+		mined, err := c.ts.client.NonceAt(ctx, addr, nil)
+		if err != nil {
+			logger.Warn("[FeeController] Could not read nonce for %s: %v\n", addr.Hex(), err)
+			continue
+		}
+		now := time.Now()
+
+		c.mu.Lock()
+		byNonce := c.pending[addr]
+		for nonce, sentAt := range byNonce {
+			if nonce < mined {
+				c.latencies = append(c.latencies, now.Sub(sentAt).Seconds())
+				delete(byNonce, nonce)
+			}
+		}
+		if len(byNonce) == 0 {
+			delete(c.pending, addr)
+		}
+		c.mu.Unlock()
+	}
+}
+
+func (c *FeeController) adjust() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Unmined transactions already older than the target are as slow as any
+	// included one, and are the only signal while nothing is being mined.
+	now := time.Now()
+	samples := c.latencies
+	for _, byNonce := range c.pending {
+		for _, sentAt := range byNonce {
+			if age := now.Sub(sentAt); age > c.target {
+				samples = append(samples, age.Seconds())
+			}
+		}
+	}
+	c.latencies = nil
+	if len(samples) == 0 {
+		return
+	}
+
+	sort.Float64s(samples)
+	p95 := samples[int(math.Ceil(0.95*float64(len(samples))))-1]
+	target := c.target.Seconds()
+
+	old := c.level
+	switch {
+	case p95 > target:
+		c.level = math.Min(c.level*(1+c.step/100), c.maxLevel)
+	case p95 < target/2:
+		c.level = math.Max(c.level/(1+c.step/100), c.minLevel)
+	}
+	if c.level > old {
+		c.raised++
+		logger.Info("[FeeController] p95 inclusion %.1fs > target %.1fs: fee level %.2f → %.2f\n", p95, target, old, c.level)
+	} else if c.level < old {
+		c.lowered++
+		logger.Info("[FeeController] p95 inclusion %.1fs < target/2 %.1fs: fee level %.2f → %.2f\n", p95, target/2, old, c.level)
+	} else {
+		logger.Debug("[FeeController] p95 inclusion %.1fs (target %.1fs): fee level holds at %.2f\n", p95, target, c.level)
+	}
+}
@@ -0,0 +1,214 @@
+package tx
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"go-tps/logger"
+)
+
+// GasPriceRefresher polls the latest base fee in the background so that
+// transactions prepared or sent later in a run use a current price instead
+// of the one seen when the batch started.
+type GasPriceRefresher struct {
+	ts       *TransactionSender
+	interval time.Duration
+
+	mu        sync.RWMutex
+	baseFee   *big.Int
+	updatedAt time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewGasPriceRefresher(ts *TransactionSender, interval time.Duration) *GasPriceRefresher {
+	return &GasPriceRefresher{
+		ts:       ts,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start fetches the base fee once synchronously and then keeps refreshing it
+// every interval until Stop is called.
+func (r *GasPriceRefresher) Start() {
+	r.refresh()
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				r.refresh()
+			}
+		}
+	}()
+}
+
+func (r *GasPriceRefresher) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), r.interval)
+	defer cancel()
+
+	feeHistory, err := r.ts.FeeHistory(ctx)
+	if err != nil {
+		logger.Warn("[GasRefresher] Could not refresh base fee: %v\n", err)
+		return
+	}
+	baseFee := feeHistory.BaseFee[len(feeHistory.BaseFee)-1]
+
+	r.mu.Lock()
+	old := r.baseFee
+	r.baseFee = baseFee
+	r.updatedAt = time.Now()
+	r.mu.Unlock()
+
+	if old == nil || old.Cmp(baseFee) != 0 {
+		logger.Debug("[GasRefresher] Base fee now %s wei\n", baseFee.String())
+	}
+}
+
+// BaseFee returns the most recently observed base fee, or nil if none has
+// been fetched successfully yet.
+func (r *GasPriceRefresher) BaseFee() *big.Int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.baseFee
+}
+
+func (r *GasPriceRefresher) Stop() {
+	close(r.stop)
+	<-r.done
+}
+
+// Fee bump strategies applied after underpriced errors.
+const (
+	BumpFixed      = "fixed"      // linear: add FEE_BUMP_PERCENT of the starting fee per bump
+	BumpPercentage = "percentage" // compounding: multiply by 1+FEE_BUMP_PERCENT per bump
+	BumpAggressive = "aggressive" // double per bump
+)
+
+// DefaultPriorityFee is the tip used when no priority fee is configured.
+var DefaultPriorityFee = big.NewInt(1_000_000_000) // 1 gwei
+
+// FeeBumper tracks the fee level applied on top of the network base fee and
+// the configured priority fee. Both are raised together on a bump, since a
+// replacement transaction must out-bid the original on tip and fee cap.
+type FeeBumper struct {
+	strategy string
+	percent  float64
+
+	mu              sync.RWMutex
+	startMultiplier float64
+	multiplier      float64
+	startTip        *big.Int
+	tip             *big.Int
+}
+
+func NewFeeBumper(strategy string, percent float64, multiplier float64, tip *big.Int) (*FeeBumper, error) {
+	switch strategy {
+	case BumpFixed, BumpPercentage, BumpAggressive:
+	default:
+		return nil, fmt.Errorf("unknown fee bump strategy %q (expected fixed, percentage or aggressive)", strategy)
+	}
+	if percent <= 0 && strategy != BumpAggressive {
+		return nil, fmt.Errorf("fee bump percent must be positive")
+	}
+	if tip == nil {
+		tip = DefaultPriorityFee
+	}
+
+	return &FeeBumper{
+		strategy:        strategy,
+		percent:         percent,
+		startMultiplier: multiplier,
+		multiplier:      multiplier,
+		startTip:        new(big.Int).Set(tip),
+		tip:             new(big.Int).Set(tip),
+	}, nil
+}
+
+// GasPrice applies the current multiplier to a base fee. Once the fee has
+// been adjusted it never drops below 2 gwei, to avoid underpriced errors.
+func (b *FeeBumper) GasPrice(baseFee *big.Int) *big.Int {
+	b.mu.RLock()
+	multiplier := b.multiplier
+	b.mu.RUnlock()
+
+	if multiplier == 1.0 {
+		return baseFee
+	}
+
+	adjusted, _ := new(big.Float).Mul(new(big.Float).SetInt(baseFee), big.NewFloat(multiplier)).Int(nil)
+
+	minGasPrice := big.NewInt(2_000_000_000) // 2 gwei
+	if adjusted.Cmp(minGasPrice) < 0 {
+		return minGasPrice
+	}
+	return adjusted
+}
+
+// Tip returns the current priority fee.
+func (b *FeeBumper) Tip() *big.Int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return new(big.Int).Set(b.tip)
+}
+
+func (b *FeeBumper) Multiplier() float64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.multiplier
+}
+
+// Bump raises the multiplier and tip according to the strategy.
+func (b *FeeBumper) Bump() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	oldMultiplier, oldTip := b.multiplier, b.tip
+	b.multiplier = bumpFloat(b.strategy, b.percent, b.multiplier, b.startMultiplier)
+	b.tip = BumpFee(b.strategy, b.percent, b.tip, b.startTip)
+
+	logger.Warn("Fee level bumped (%s): multiplier %.2f → %.2f, tip %s → %s wei\n",
+		b.strategy, oldMultiplier, b.multiplier, oldTip.String(), b.tip.String())
+}
+
+// BumpFee returns value raised once by strategy. start is the fee before any
+// bumps and sets the step size of the fixed strategy.
+func BumpFee(strategy string, percent float64, value, start *big.Int) *big.Int {
+	switch strategy {
+	case BumpFixed:
+		step, _ := new(big.Float).Mul(new(big.Float).SetInt(start), big.NewFloat(percent/100)).Int(nil)
+		if step.Sign() == 0 {
+			step = big.NewInt(1)
+		}
+		return new(big.Int).Add(value, step)
+	case BumpAggressive:
+		return new(big.Int).Mul(value, big.NewInt(2))
+	default:
+		bumped, _ := new(big.Float).Mul(new(big.Float).SetInt(value), big.NewFloat(1+percent/100)).Int(nil)
+		if bumped.Cmp(value) <= 0 {
+			bumped = new(big.Int).Add(value, big.NewInt(1))
+		}
+		return bumped
+	}
+}
+
+func bumpFloat(strategy string, percent, value, start float64) float64 {
+	switch strategy {
+	case BumpFixed:
+		return value + start*percent/100
+	case BumpAggressive:
+		return value * 2
+	default:
+		return value * (1 + percent/100)
+	}
+}
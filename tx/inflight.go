@@ -0,0 +1,175 @@
+package tx
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go-tps/logger"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// InflightLimit caps how many submitted transactions may be unmined at once,
+// turning the sender into a closed loop: a send waits for a free slot, and
+// slots free up as wallet nonces are mined. Like StuckMonitor it follows each
+// wallet's mined nonce, so replacements at the same nonce count once.
+type InflightLimit struct {
+	ts  *TransactionSender
+	max int
+
+	mu       sync.Mutex
+	pending  map[common.Address]map[uint64]struct{}
+	count    int           // transactions in pending
+	reserved int           // slots handed out by Acquire, not yet sent
+	freed    chan struct{} // closed and replaced whenever slots free up
+	peak     int
+	waited   time.Duration // total time senders spent in Acquire
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewInflightLimit returns a limit of limit unmined transactions, or nil if
+// limit is not positive.
+func NewInflightLimit(ts *TransactionSender, limit int) *InflightLimit {
+	if limit <= 0 {
+		return nil
+	}
+	return &InflightLimit{
+		ts:      ts,
+		max:     limit,
+		pending: make(map[common.Address]map[uint64]struct{}),
+		freed:   make(chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Acquire blocks until fewer than max transactions are in flight and
+// reserves a slot for the caller's next send. It returns false without a
+// slot if stop is closed first. Every reserved slot must be handed back with
+// Sent or Release.
+func (l *InflightLimit) Acquire(stop <-chan struct{}) bool {
+	start := time.Now()
+	defer func() {
+		l.mu.Lock()
+		l.waited += time.Since(start)
+		l.mu.Unlock()
+	}()
+	for {
+		l.mu.Lock()
+		if l.count+l.reserved < l.max {
+			l.reserved++
+			l.mu.Unlock()
+			return true
+		}
+		freed := l.freed
+		l.mu.Unlock()
+
+		select {
+		case <-freed:
+		case <-stop:
+			return false
+		}
+	}
+}
+
+// Sent turns a reserved slot into a tracked transaction that holds the slot
+// until its nonce is mined.
+func (l *InflightLimit) Sent(from common.Address, nonce uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.reserved--
+	byNonce, ok := l.pending[from]
+	if !ok {
+		byNonce = make(map[uint64]struct{})
+		l.pending[from] = byNonce
+	}
+	if _, dup := byNonce[nonce]; !dup {
+		byNonce[nonce] = struct{}{}
+		l.count++
+	}
+	l.peak = max(l.peak, l.count)
+}
+
+// Release hands back a reserved slot whose transaction was not sent.
+func (l *InflightLimit) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.reserved--
+	l.wake()
+}
+
+// wake lets blocked Acquire calls re-check. Callers hold l.mu.
+func (l *InflightLimit) wake() {
+	close(l.freed)
+	l.freed = make(chan struct{})
+}
+
+// Start checks mined nonces every interval until Stop is called.
+func (l *InflightLimit) Start(interval time.Duration) {
+	go func() {
+		defer close(l.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.stop:
+				return
+			case <-ticker.C:
+				l.check()
+			}
+		}
+	}()
+}
+
+// Stop halts the limit and returns the most transactions that were in
+// flight at once and how long senders waited for slots in total.
+func (l *InflightLimit) Stop() (peak int, waited time.Duration) {
+	close(l.stop)
+	<-l.done
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.peak, l.waited
+}
+
+func (l *InflightLimit) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	l.mu.Lock()
+	wallets := make([]common.Address, 0, len(l.pending))
+	for addr := range l.pending {
+		wallets = append(wallets, addr)
+	}
+	l.mu.Unlock()
+
+	for _, addr := range wallets {
+		mined, err := l.ts.client.NonceAt(ctx, addr, nil)
+		if err != nil {
+			logger.Warn("[InflightLimit] Could not read nonce for %s: %v\n", addr.Hex(), err)
+			continue
+		}
+
+		l.mu.Lock()
+		byNonce := l.pending[addr]
+		freed := 0
+		for nonce := range byNonce {
+			if nonce < mined {
+				delete(byNonce, nonce)
+				freed++
+			}
+		}
+		if len(byNonce) == 0 {
+			delete(l.pending, addr)
+		}
+		if freed > 0 {
+			l.count -= freed
+			l.wake()
+		}
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,183 @@
+package tx
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Rollups whose L1 data fee is charged on top of the L2 execution fee.
+const (
+	RollupNone     = ""
+	RollupOptimism = "optimism" // OP stack: fee from GasPriceOracle, l1Fee in receipts
+	RollupArbitrum = "arbitrum" // Nitro: fee from NodeInterface, gasUsedForL1 in receipts
+)
+
+var (
+	// OP stack GasPriceOracle predeploy
+	opGasPriceOracle = common.HexToAddress("0x420000000000000000000000000000000000000F")
+	// Arbitrum NodeInterface virtual contract
+	arbNodeInterface = common.HexToAddress("0x00000000000000000000000000000000000000C8")
+
+	opGetL1Fee                = crypto.Keccak256([]byte("getL1Fee(bytes)"))[:4]
+	arbGasEstimateL1Component = crypto.Keccak256([]byte("gasEstimateL1Component(address,bool,bytes)"))[:4]
+)
+
+// SetRollup makes the sender account for the L1 data fee of the given rollup
+// (RollupOptimism or RollupArbitrum) when estimating and reporting costs.
+func (ts *TransactionSender) SetRollup(rollup string) error {
+	switch rollup = strings.ToLower(rollup); rollup {
+	case RollupNone, "none":
+		ts.rollup = RollupNone
+	case RollupOptimism, RollupArbitrum:
+		ts.rollup = rollup
+	default:
+		return fmt.Errorf("unknown rollup %q (expected none, optimism or arbitrum)", rollup)
+	}
+	return nil
+}
+
+// Rollup returns the configured rollup, or RollupNone.
+func (ts *TransactionSender) Rollup() string {
+	return ts.rollup
+}
+
+// L1FeeInGas reports whether the rollup charges the L1 data fee as part of
+// the gas (Arbitrum, whose gas estimates include it) rather than on top of
+// the gas limit (OP stack).
+func (ts *TransactionSender) L1FeeInGas() bool {
+	return ts.rollup == RollupArbitrum
+}
+
+// EstimateL1Fee returns the L1 data fee in wei the rollup would charge for a
+// transaction with the given recipient, value and calldata, or zero when no
+// rollup is configured.
+func (ts *TransactionSender) EstimateL1Fee(ctx context.Context, to common.Address, value *big.Int, data []byte) (*big.Int, error) {
+	switch ts.rollup {
+	case RollupOptimism:
+		// The oracle prices the RLP-encoded transaction; signature bytes are
+		// accounted for by the oracle itself.
+		unsigned, err := types.NewTx(&types.DynamicFeeTx{
+			ChainID: ts.chainID,
+			To:      &to,
+			Value:   value,
+			Data:    data,
+		}).MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode transaction: %w", err)
+		}
+		out, err := ts.client.CallContract(ctx, ethereum.CallMsg{
+			To:   &opGasPriceOracle,
+			Data: append(append([]byte{}, opGetL1Fee...), encodeBytesArg(unsigned, 1)...),
+		}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to call GasPriceOracle.getL1Fee: %w", err)
+		}
+		if len(out) < 32 {
+			return nil, fmt.Errorf("short GasPriceOracle.getL1Fee result")
+		}
+		return new(big.Int).SetBytes(out[:32]), nil
+
+	case RollupArbitrum:
+		args := append(common.LeftPadBytes(to.Bytes(), 32), make([]byte, 32)...) // contractCreation = false
+		args = append(args, encodeBytesArg(data, 3)...)
+		out, err := ts.client.CallContract(ctx, ethereum.CallMsg{
+			To:   &arbNodeInterface,
+			Data: append(append([]byte{}, arbGasEstimateL1Component...), args...),
+		}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to call NodeInterface.gasEstimateL1Component: %w", err)
+		}
+		if len(out) < 64 {
+			return nil, fmt.Errorf("short NodeInterface.gasEstimateL1Component result")
+		}
+		// (uint64 gasEstimateForL1, uint256 baseFee, uint256 l1BaseFeeEstimate)
+		l1Gas := new(big.Int).SetBytes(out[:32])
+		baseFee := new(big.Int).SetBytes(out[32:64])
+		return l1Gas.Mul(l1Gas, baseFee), nil
+
+	default:
+		return new(big.Int), nil
+	}
+}
+
+// EstimateL1Fees sets L1Fee and L1FeeInGas on each request. Requests with
+// the same recipient and calldata share one estimate.
+func (ts *TransactionSender) EstimateL1Fees(ctx context.Context, reqs []*TxRequest) error {
+	if ts.rollup == RollupNone {
+		return nil
+	}
+	cache := make(map[string]*big.Int)
+	for _, req := range reqs {
+		key := req.ToAddress.Hex() + ":" + common.Bytes2Hex(req.Data)
+		fee, ok := cache[key]
+		if !ok {
+			var err error
+			fee, err = ts.EstimateL1Fee(ctx, req.ToAddress, req.Value, req.Data)
+			if err != nil {
+				return err
+			}
+			cache[key] = fee
+		}
+		req.L1Fee = fee
+		req.L1FeeInGas = ts.L1FeeInGas()
+	}
+	return nil
+}
+
+// ReceiptFees splits what a confirmed transaction paid into the L1 data fee
+// and the L2 execution fee, both in wei. Without a rollup the L1 fee is nil.
+func (ts *TransactionSender) ReceiptFees(ctx context.Context, receipt *types.Receipt) (l1Fee, l2Fee *big.Int, err error) {
+	price := receipt.EffectiveGasPrice
+	if price == nil {
+		price = new(big.Int)
+	}
+	l2Fee = new(big.Int).Mul(price, new(big.Int).SetUint64(receipt.GasUsed))
+	if ts.rollup == RollupNone {
+		return nil, l2Fee, nil
+	}
+	l1Fee = new(big.Int)
+
+	// Rollup-specific receipt fields are not part of types.Receipt
+	var raw struct {
+		L1Fee        *hexutil.Big    `json:"l1Fee"`
+		GasUsedForL1 *hexutil.Uint64 `json:"gasUsedForL1"`
+	}
+	if err := ts.client.Client().CallContext(ctx, &raw, "eth_getTransactionReceipt", receipt.TxHash); err != nil {
+		return nil, nil, fmt.Errorf("failed to get rollup receipt fields: %w", err)
+	}
+
+	switch ts.rollup {
+	case RollupOptimism:
+		// gasUsed covers L2 execution only; the L1 fee is charged separately
+		if raw.L1Fee != nil {
+			l1Fee = raw.L1Fee.ToInt()
+		}
+	case RollupArbitrum:
+		// gasUsed includes the L1 component, priced at the L2 gas price
+		if raw.GasUsedForL1 != nil && uint64(*raw.GasUsedForL1) <= receipt.GasUsed {
+			l1Fee = new(big.Int).Mul(price, new(big.Int).SetUint64(uint64(*raw.GasUsedForL1)))
+			l2Fee = new(big.Int).Mul(price, new(big.Int).SetUint64(receipt.GasUsed-uint64(*raw.GasUsedForL1)))
+		}
+	}
+	return l1Fee, l2Fee, nil
+}
+
+// encodeBytesArg ABI-encodes a bytes argument that is the last of headWords
+// arguments: its offset word followed by the length and padded data.
+func encodeBytesArg(data []byte, headWords int) []byte {
+	out := common.LeftPadBytes(big.NewInt(int64(headWords*32)).Bytes(), 32)
+	out = append(out, common.LeftPadBytes(big.NewInt(int64(len(data))).Bytes(), 32)...)
+	out = append(out, data...)
+	if rem := len(data) % 32; rem != 0 {
+		out = append(out, make([]byte, 32-rem)...)
+	}
+	return out
+}
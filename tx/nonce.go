@@ -0,0 +1,242 @@
+package tx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Nonce errors reported by nodes when a transaction is sent.
+var (
+	ErrNonceTooLow  = errors.New("nonce too low")
+	ErrNonceTooHigh = errors.New("nonce too high")
+)
+
+// NonceError classifies a send error as ErrNonceTooLow or ErrNonceTooHigh, or
+// returns nil if it is not a nonce error. Besides geth's wording it knows the
+// OldNonce/NonceGap codes used by Nethermind.
+func NonceError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "nonce too low"), strings.Contains(msg, "oldnonce"):
+		return ErrNonceTooLow
+	case strings.Contains(msg, "nonce too high"), strings.Contains(msg, "noncegap"), strings.Contains(msg, "nonce gap"):
+		return ErrNonceTooHigh
+	}
+	return nil
+}
+
+// Where the next nonce of a wallet is read from.
+const (
+	NonceSourcePending = "pending" // eth_getTransactionCount at "pending"
+	NonceSourceLatest  = "latest"  // eth_getTransactionCount at "latest", ignoring the mempool
+)
+
+// SetNonceSource selects where NextNonce reads nonces from. Some providers
+// report unreliable pending nonces under load; "latest" only counts mined
+// transactions, so it is only right for wallets with nothing pending.
+func (ts *TransactionSender) SetNonceSource(source string) error {
+	switch source = strings.ToLower(source); source {
+	case "", NonceSourcePending:
+		ts.nonceSource = NonceSourcePending
+	case NonceSourceLatest:
+		ts.nonceSource = NonceSourceLatest
+	default:
+		return fmt.Errorf("unknown nonce source %q (expected pending or latest)", source)
+	}
+	return nil
+}
+
+// NextNonce returns the nonce the next transaction of addr should use, read
+// from the configured nonce source.
+func (ts *TransactionSender) NextNonce(ctx context.Context, addr common.Address) (uint64, error) {
+	if ts.nonceSource == NonceSourceLatest {
+		return ts.GetMinedNonce(ctx, addr)
+	}
+	return ts.GetNonce(ctx, addr)
+}
+
+// NonceManager hands out nonces for every wallet of a run, so that nonces of
+// transactions that were never sent are reused and a wallet whose view of
+// its nonce went wrong can be resynced from the node in one place.
+type NonceManager struct {
+	ts *TransactionSender
+
+	mu       sync.Mutex
+	next     map[common.Address]uint64
+	resyncs  int
+	onResync func(addr common.Address, nonce uint64) // nil = nothing to tell
+}
+
+func NewNonceManager(ts *TransactionSender) *NonceManager {
+	return &NonceManager{
+		ts:   ts,
+		next: make(map[common.Address]uint64),
+	}
+}
+
+// Set records nonce as the next one to hand out for addr.
+func (m *NonceManager) Set(addr common.Address, nonce uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.next[addr] = nonce
+}
+
+// OnResync calls fn with the node's nonce after every resync, e.g. to store
+// it where the next run starts from.
+func (m *NonceManager) OnResync(fn func(addr common.Address, nonce uint64)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onResync = fn
+}
+
+// Allocate reserves n consecutive nonces for addr and returns the first.
+func (m *NonceManager) Allocate(addr common.Address, n int) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	first := m.next[addr]
+	m.next[addr] = first + uint64(n)
+	return first
+}
+
+// Release hands back every nonce of addr from nonce onwards, for
+// transactions that were allocated but will never be sent.
+func (m *NonceManager) Release(addr common.Address, nonce uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if nonce < m.next[addr] {
+		m.next[addr] = nonce
+	}
+}
+
+// Resync resets addr's next nonce to the node's and returns it.
+func (m *NonceManager) Resync(ctx context.Context, addr common.Address) (uint64, error) {
+	nonce, err := m.ts.NextNonce(ctx, addr)
+	if err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	m.next[addr] = nonce
+	m.resyncs++
+	onResync := m.onResync
+	m.mu.Unlock()
+	if onResync != nil {
+		onResync(addr, nonce)
+	}
+	return nonce, nil
+}
+
+// Resyncs returns how many times a wallet was resynced from the node.
+func (m *NonceManager) Resyncs() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.resyncs
+}
+
+// Renumber re-signs reqs with consecutive nonces starting at nonce, keeping
+// their fees, so they can be sent after the wallet's nonce was resynced.
+func (ts *TransactionSender) Renumber(reqs []*TxRequest, nonce uint64, signer Signer) error {
+	for i, req := range reqs {
+		updated := *req
+		updated.Nonce = nonce + uint64(i)
+		signedTx, err := ts.signRequest(&updated, signer)
+		if err != nil {
+			return err
+		}
+		req.Nonce = updated.Nonce
+		req.signedTx = signedTx
+	}
+	return nil
+}
+
+// NonceGap is a wallet whose transactions up to Highest were accepted by the
+// node but cannot all be mined: nonce Pending is missing (e.g. a dropped or
+// never-sent transaction), so everything queued above it is stuck.
+type NonceGap struct {
+	Address common.Address
+	Mined   uint64 // next nonce counting mined transactions only
+	Pending uint64 // next nonce counting the node's executable pool
+	Highest uint64 // highest nonce submitted
+}
+
+// FindNonceGap checks addr against the highest nonce it submitted and
+// returns the gap, or nil if nothing submitted is stuck behind a missing
+// nonce.
+func (ts *TransactionSender) FindNonceGap(ctx context.Context, addr common.Address, highest uint64) (*NonceGap, error) {
+	pending, err := ts.GetNonce(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	if pending >= highest {
+		return nil, nil
+	}
+	mined, err := ts.GetMinedNonce(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &NonceGap{Address: addr, Mined: mined, Pending: pending, Highest: highest}, nil
+}
+
+// gapFillGasLimit is the gas limit of a plain self-transfer.
+const gapFillGasLimit = 21_000
+
+// FillNonceGap sends zero-value self-transfers at each missing nonce of gap,
+// priced at baseFee and tip, until every submitted nonce is mined or
+// executable. Queued transactions above a filled nonce become executable as
+// it is filled, so only real holes are filled. It returns how many
+// self-transfers were sent.
+func (ts *TransactionSender) FillNonceGap(ctx context.Context, gap *NonceGap, signer Signer, baseFee, tip *big.Int) (int, error) {
+	filled := 0
+	nonce := gap.Pending
+	for nonce <= gap.Highest {
+		req := &TxRequest{
+			ToAddress: gap.Address,
+			Value:     new(big.Int),
+			Nonce:     nonce,
+			GasLimit:  gapFillGasLimit,
+			BaseFee:   baseFee,
+			Tip:       tip,
+		}
+		if _, err := ts.SendRequest(ctx, req, signer); err != nil {
+			return filled, fmt.Errorf("failed to fill nonce %d: %w", nonce, err)
+		}
+		filled++
+
+		// Wait for the pool to promote the transactions queued behind it
+		next, err := ts.waitPendingNonceAbove(ctx, gap.Address, nonce)
+		if err != nil {
+			return filled, err
+		}
+		nonce = next
+	}
+	return filled, nil
+}
+
+// waitPendingNonceAbove polls addr's pending nonce until it passes nonce.
+func (ts *TransactionSender) waitPendingNonceAbove(ctx context.Context, addr common.Address, nonce uint64) (uint64, error) {
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		pending, err := ts.GetNonce(ctx, addr)
+		if err != nil {
+			return 0, err
+		}
+		if pending > nonce {
+			return pending, nil
+		}
+		select {
+		case <-ctx.Done():
+			return 0, fmt.Errorf("pending nonce did not advance past %d: %w", nonce, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package tx
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"go-tps/logger"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/rlpx"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// devp2p base protocol message codes. The eth sub-protocol messages are
+// offset by baseProtocolLength once the Hello exchange has completed.
+const (
+	p2pHandshakeMsg    = 0x00
+	p2pDiscMsg         = 0x01
+	p2pPingMsg         = 0x02
+	p2pPongMsg         = 0x03
+	baseProtocolLength = 16
+	snappyVersion      = 5
+	p2pDialTimeout     = 10 * time.Second
+
+	ethProtocolName = "eth"
+	eth68           = 68
+	ethStatusMsg    = 0x00
+	ethTxMsg        = 0x02
+)
+
+// protoHandshake mirrors the devp2p Hello message.
+type protoHandshake struct {
+	Version    uint64
+	Name       string
+	Caps       []p2pCap
+	ListenPort uint64
+	ID         []byte
+	Rest       []rlp.RawValue `rlp:"tail"`
+}
+
+type p2pCap struct {
+	Name    string
+	Version uint
+}
+
+// ethStatus68 mirrors the eth/68 Status message.
+type ethStatus68 struct {
+	ProtocolVersion uint32
+	NetworkID       uint64
+	TD              *big.Int
+	Head            common.Hash
+	Genesis         common.Hash
+	ForkID          struct {
+		Hash [4]byte
+		Next uint64
+	}
+}
+
+// P2PBroadcaster is an experimental sender that connects to a node's devp2p
+// port as a regular eth/68 peer and pushes signed transactions with
+// Transactions messages, bypassing JSON-RPC entirely.
+type P2PBroadcaster struct {
+	conn    *rlpx.Conn
+	writeMu sync.Mutex
+	closed  chan struct{}
+	once    sync.Once
+}
+
+// NewP2PBroadcaster dials the given enode URL and completes the RLPx, Hello
+// and eth Status handshakes. The peer's own Status is echoed back, so the
+// broadcaster always agrees with the node on network ID, genesis and fork ID.
+func NewP2PBroadcaster(enodeURL string) (*P2PBroadcaster, error) {
+	node, err := enode.ParseV4(enodeURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse enode URL: %w", err)
+	}
+	if node.TCP() == 0 {
+		return nil, fmt.Errorf("enode URL has no TCP port")
+	}
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate node key: %w", err)
+	}
+
+	addr := net.JoinHostPort(node.IP().String(), fmt.Sprintf("%d", node.TCP()))
+	fd, err := net.DialTimeout("tcp", addr, p2pDialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial peer: %w", err)
+	}
+
+	conn := rlpx.NewConn(fd, node.Pubkey())
+	conn.SetDeadline(time.Now().Add(p2pDialTimeout))
+	if _, err := conn.Handshake(key); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("rlpx handshake failed: %w", err)
+	}
+
+	if err := p2pHello(conn, key); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := ethHandshake(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	b := &P2PBroadcaster{
+		conn:   conn,
+		closed: make(chan struct{}),
+	}
+	go b.readLoop()
+
+	return b, nil
+}
+
+func p2pHello(conn *rlpx.Conn, key *ecdsa.PrivateKey) error {
+	ours := &protoHandshake{
+		Version: snappyVersion,
+		Name:    "go-tps",
+		Caps:    []p2pCap{{Name: ethProtocolName, Version: eth68}},
+		ID:      crypto.FromECDSAPub(&key.PublicKey)[1:],
+	}
+	payload, err := rlp.EncodeToBytes(ours)
+	if err != nil {
+		return fmt.Errorf("failed to encode hello: %w", err)
+	}
+	if _, err := conn.Write(p2pHandshakeMsg, payload); err != nil {
+		return fmt.Errorf("failed to send hello: %w", err)
+	}
+
+	code, data, _, err := conn.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read hello: %w", err)
+	}
+	switch code {
+	case p2pHandshakeMsg:
+	case p2pDiscMsg:
+		return fmt.Errorf("peer disconnected during hello: %s", decodeDisconnect(data))
+	default:
+		return fmt.Errorf("unexpected message code %d during hello", code)
+	}
+
+	var theirs protoHandshake
+	if err := rlp.DecodeBytes(data, &theirs); err != nil {
+		return fmt.Errorf("failed to decode hello: %w", err)
+	}
+
+	supportsEth68 := false
+	for _, c := range theirs.Caps {
+		if c.Name == ethProtocolName && c.Version == eth68 {
+			supportsEth68 = true
+		}
+	}
+	if !supportsEth68 {
+		return fmt.Errorf("peer %q does not support eth/%d", theirs.Name, eth68)
+	}
+
+	conn.SetSnappy(theirs.Version >= snappyVersion)
+	logger.Debug("[P2P] Hello exchanged with %s\n", theirs.Name)
+	return nil
+}
+
+func ethHandshake(conn *rlpx.Conn) error {
+	for {
+		code, data, _, err := conn.Read()
+		if err != nil {
+			return fmt.Errorf("failed to read status: %w", err)
+		}
+
+		switch code {
+		case p2pPingMsg:
+			conn.Write(p2pPongMsg, []byte{0xc0})
+			continue
+		case p2pDiscMsg:
+			return fmt.Errorf("peer disconnected during status: %s", decodeDisconnect(data))
+		case baseProtocolLength + ethStatusMsg:
+		default:
+			return fmt.Errorf("unexpected message code %d during status", code)
+		}
+
+		var status ethStatus68
+		if err := rlp.DecodeBytes(data, &status); err != nil {
+			return fmt.Errorf("failed to decode status: %w", err)
+		}
+		if _, err := conn.Write(code, data); err != nil {
+			return fmt.Errorf("failed to send status: %w", err)
+		}
+
+		logger.Debug("[P2P] eth/%d status ok (network %d, genesis %s)\n",
+			status.ProtocolVersion, status.NetworkID, status.Genesis.Hex())
+		return nil
+	}
+}
+
+func decodeDisconnect(data []byte) string {
+	var reason []uint
+	if err := rlp.DecodeBytes(data, &reason); err != nil || len(reason) == 0 {
+		return "unknown reason"
+	}
+	return fmt.Sprintf("reason code %d", reason[0])
+}
+
+// readLoop answers pings and drains everything else the peer sends so the
+// connection is not dropped while transactions are being pushed.
+func (b *P2PBroadcaster) readLoop() {
+	for {
+		code, data, _, err := b.conn.Read()
+		if err != nil {
+			select {
+			case <-b.closed:
+			default:
+				logger.Warn("[P2P] Connection lost: %v\n", err)
+			}
+			return
+		}
+
+		switch code {
+		case p2pPingMsg:
+			b.writeMu.Lock()
+			b.conn.Write(p2pPongMsg, []byte{0xc0})
+			b.writeMu.Unlock()
+		case p2pDiscMsg:
+			logger.Warn("[P2P] Peer disconnected: %s\n", decodeDisconnect(data))
+			return
+		}
+	}
+}
+
+// Broadcast pushes the given signed transactions to the peer in a single
+// Transactions message. A nil error only means the message was written to the
+// socket; the node does not acknowledge transactions over devp2p.
+func (b *P2PBroadcaster) Broadcast(txs ...*types.Transaction) error {
+	payload, err := rlp.EncodeToBytes(txs)
+	if err != nil {
+		return fmt.Errorf("failed to encode transactions: %w", err)
+	}
+
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+
+	if _, err := b.conn.Write(baseProtocolLength+ethTxMsg, payload); err != nil {
+		return fmt.Errorf("failed to broadcast transactions: %w", err)
+	}
+	return nil
+}
+
+func (b *P2PBroadcaster) Close() {
+	b.once.Do(func() {
+		close(b.closed)
+		b.conn.Close()
+	})
+}
@@ -0,0 +1,78 @@
+package tx
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Pricing errors reported by nodes when a transaction is sent.
+var (
+	// ErrAlreadyKnown means the node already has this exact transaction, e.g.
+	// because an earlier send timed out after reaching the pool.
+	ErrAlreadyKnown = errors.New("already known")
+	// ErrReplacementUnderpriced means another transaction with the same
+	// nonce is pending and this one does not outbid it by the pool's bump.
+	ErrReplacementUnderpriced = errors.New("replacement transaction underpriced")
+	// ErrUnderpriced means the fees are below the pool's minimum.
+	ErrUnderpriced = errors.New("transaction underpriced")
+)
+
+// SendError classifies a send error as ErrAlreadyKnown,
+// ErrReplacementUnderpriced or ErrUnderpriced, or returns nil if it is none
+// of them. Besides geth's wording it knows the AlreadyKnown and FeeTooLow
+// codes used by Nethermind.
+func SendError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "already known"), strings.Contains(msg, "alreadyknown"), strings.Contains(msg, "known transaction"):
+		return ErrAlreadyKnown
+	case strings.Contains(msg, "replacement transaction underpriced"), strings.Contains(msg, "replacementnotallowed"):
+		return ErrReplacementUnderpriced
+	case strings.Contains(msg, "transaction underpriced"), strings.Contains(msg, "feetoolow"):
+		return ErrUnderpriced
+	}
+	return nil
+}
+
+// RateLimited reports whether err is an RPC provider refusing a request for
+// exceeding its rate limit: HTTP 429, the -32005 limit exceeded JSON-RPC
+// code, or the wording providers use for it. Errors read back from the
+// database only have their message, which is matched the same way.
+func RateLimited(err error) bool {
+	if err == nil {
+		return false
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32005 {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "too many requests") ||
+		strings.Contains(msg, "rate limit") || strings.Contains(msg, "limit exceeded")
+}
+
+// Reprice re-signs req with its fee cap and tip raised by at least
+// bumpPercent and the pool's replacement minimum, so it can replace a
+// pending transaction at the same nonce or clear a pool's price floor. It
+// returns false if MAX_GAS_PRICE_WEI leaves no room to raise the fee cap.
+func (ts *TransactionSender) Reprice(req *TxRequest, bumpPercent float64, signer Signer) (bool, error) {
+	oldCap := req.GasFeeCap()
+	baseFee := req.BaseFee
+	if baseFee == nil {
+		baseFee = new(big.Int)
+	}
+	tip := req.Tip
+	if tip == nil {
+		tip = DefaultPriorityFee
+	}
+	if err := ts.Resign(req, replacementFee(baseFee, bumpPercent), replacementFee(tip, bumpPercent), signer); err != nil {
+		return false, err
+	}
+	return oldCap == nil || req.GasFeeCap().Cmp(oldCap) > 0, nil
+}
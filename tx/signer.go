@@ -0,0 +1,180 @@
+package tx
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Signer signs the transactions of one account.
+type Signer interface {
+	Address() common.Address
+	SignTx(txn *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+// KeySigner signs with a private key held in the process.
+type KeySigner struct {
+	key *ecdsa.PrivateKey
+}
+
+// NewKeySigner returns a signer for key.
+func NewKeySigner(key *ecdsa.PrivateKey) *KeySigner {
+	return &KeySigner{key: key}
+}
+
+func (s *KeySigner) Address() common.Address {
+	return crypto.PubkeyToAddress(s.key.PublicKey)
+}
+
+func (s *KeySigner) SignTx(txn *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(txn, types.NewLondonSigner(chainID), s.key)
+}
+
+// Remote signer APIs (SIGNER_API).
+const (
+	SignerAPIEth  = "eth"  // eth_accounts and eth_signTransaction: web3signer, or a node with unlocked accounts
+	SignerAPIClef = "clef" // account_list and account_signTransaction
+)
+
+// RemoteSigner is an external signing service, such as web3signer or clef,
+// holding the keys of its accounts. Transactions are posted to it unsigned
+// and come back signed; the keys never enter the process.
+type RemoteSigner struct {
+	client  *rpc.Client
+	name    string // see EndpointName
+	api     string
+	timeout time.Duration // per request
+}
+
+// DialSigner connects to the signer at rawurl, speaking api (SignerAPIEth
+// or SignerAPIClef). The RPC headers of SetHeaders are not sent to it.
+func DialSigner(rawurl, api string, timeout time.Duration) (*RemoteSigner, error) {
+	if api != SignerAPIEth && api != SignerAPIClef {
+		return nil, fmt.Errorf("unknown signer API %q (available: %s, %s)", api, SignerAPIEth, SignerAPIClef)
+	}
+	client, err := rpc.DialOptions(context.Background(), rawurl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to signer %s: %w", EndpointName(rawurl), err)
+	}
+	return &RemoteSigner{client: client, name: EndpointName(rawurl), api: api, timeout: timeout}, nil
+}
+
+// Name is the signer's endpoint, without credentials.
+func (s *RemoteSigner) Name() string {
+	return s.name
+}
+
+// Accounts returns the addresses the signer holds keys for, in ascending
+// order so that every run sees them in the same order.
+func (s *RemoteSigner) Accounts(ctx context.Context) ([]common.Address, error) {
+	method := "eth_accounts"
+	if s.api == SignerAPIClef {
+		method = "account_list"
+	}
+	var accounts []common.Address
+	if err := s.client.CallContext(ctx, &accounts, method); err != nil {
+		return nil, fmt.Errorf("failed to list accounts of signer %s: %w", s.name, err)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Cmp(accounts[j]) < 0 })
+	return accounts, nil
+}
+
+// Account returns a Signer for address, one of the signer's accounts.
+func (s *RemoteSigner) Account(address common.Address) Signer {
+	return &remoteAccount{signer: s, address: address}
+}
+
+// Close closes the connection to the signer.
+func (s *RemoteSigner) Close() {
+	s.client.Close()
+}
+
+type remoteAccount struct {
+	signer  *RemoteSigner
+	address common.Address
+}
+
+func (a *remoteAccount) Address() common.Address {
+	return a.address
+}
+
+// signTxArgs is the unsigned transaction posted to the signer, in the
+// fields both eth_signTransaction and account_signTransaction accept.
+type signTxArgs struct {
+	From                 common.Address  `json:"from"`
+	To                   *common.Address `json:"to,omitempty"`
+	Gas                  hexutil.Uint64  `json:"gas"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas"`
+	Value                *hexutil.Big    `json:"value"`
+	Nonce                hexutil.Uint64  `json:"nonce"`
+	Data                 hexutil.Bytes   `json:"data"`
+	ChainID              *hexutil.Big    `json:"chainId"`
+}
+
+// SignTx posts txn, a dynamic fee transaction, to the signer. The signed
+// transaction it returns must be txn signed by the account, so a signer
+// that changes the transaction (e.g. its nonce or fees) is an error.
+func (a *remoteAccount) SignTx(txn *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	if txn.Type() != types.DynamicFeeTxType {
+		return nil, fmt.Errorf("remote signer cannot sign transaction type %d", txn.Type())
+	}
+	args := signTxArgs{
+		From:                 a.address,
+		To:                   txn.To(),
+		Gas:                  hexutil.Uint64(txn.Gas()),
+		MaxFeePerGas:         (*hexutil.Big)(txn.GasFeeCap()),
+		MaxPriorityFeePerGas: (*hexutil.Big)(txn.GasTipCap()),
+		Value:                (*hexutil.Big)(txn.Value()),
+		Nonce:                hexutil.Uint64(txn.Nonce()),
+		Data:                 txn.Data(),
+		ChainID:              (*hexutil.Big)(chainID),
+	}
+	method := "eth_signTransaction"
+	if a.signer.api == SignerAPIClef {
+		method = "account_signTransaction"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.signer.timeout)
+	defer cancel()
+	var result json.RawMessage
+	if err := a.signer.client.CallContext(ctx, &result, method, args); err != nil {
+		return nil, fmt.Errorf("signer %s: %w", a.signer.name, err)
+	}
+
+	// web3signer answers with the raw transaction, clef and geth with an
+	// object holding it
+	var raw hexutil.Bytes
+	if err := json.Unmarshal(result, &raw); err != nil {
+		var obj struct {
+			Raw hexutil.Bytes `json:"raw"`
+		}
+		if err := json.Unmarshal(result, &obj); err != nil || len(obj.Raw) == 0 {
+			return nil, fmt.Errorf("signer %s returned no raw transaction", a.signer.name)
+		}
+		raw = obj.Raw
+	}
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("signer %s returned an invalid transaction: %w", a.signer.name, err)
+	}
+
+	signer := types.NewLondonSigner(chainID)
+	if signer.Hash(signed) != signer.Hash(txn) {
+		return nil, fmt.Errorf("signer %s changed the transaction (nonce %d) while signing it", a.signer.name, txn.Nonce())
+	}
+	if from, err := types.Sender(signer, signed); err != nil || from != a.address {
+		return nil, fmt.Errorf("signer %s did not sign as %s", a.signer.name, a.address.Hex())
+	}
+	return signed, nil
+}
@@ -0,0 +1,223 @@
+package tx
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"go-tps/logger"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// minReplacementBump is the fee increase nodes require before accepting a
+// replacement for a pending transaction (geth's default price bump).
+const minReplacementBump = 10
+
+// StuckMonitor watches submitted transactions and replaces any that stay
+// unmined for too many blocks with a higher-fee copy at the same nonce.
+// Only the lowest unmined nonce of each wallet is escalated, since the
+// transactions queued behind it are blocked rather than underpriced.
+type StuckMonitor struct {
+	ts          *TransactionSender
+	afterBlocks uint64
+	maxBumps    int
+	strategy    string
+	percent     float64
+	onReplace   func(oldHash, newHash common.Hash, req *TxRequest)
+
+	mu        sync.Mutex
+	block     uint64
+	pending   map[common.Address]map[uint64]*trackedTx
+	replaced  int
+	abandoned int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+type trackedTx struct {
+	req    *TxRequest
+	signer Signer
+	hash   common.Hash
+	block  uint64 // block number when last (re)sent
+	bumps  int
+}
+
+// NewStuckMonitor creates a monitor that escalates a transaction once it has
+// waited afterBlocks blocks, at most maxBumps times. onReplace is called after
+// each successful replacement.
+func NewStuckMonitor(ts *TransactionSender, afterBlocks uint64, maxBumps int, strategy string, percent float64, onReplace func(oldHash, newHash common.Hash, req *TxRequest)) *StuckMonitor {
+	return &StuckMonitor{
+		ts:          ts,
+		afterBlocks: afterBlocks,
+		maxBumps:    maxBumps,
+		strategy:    strategy,
+		percent:     percent,
+		onReplace:   onReplace,
+		pending:     make(map[common.Address]map[uint64]*trackedTx),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Track registers a transaction that was accepted by the node.
+func (m *StuckMonitor) Track(from common.Address, req *TxRequest, signer Signer, hash common.Hash) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	byNonce, ok := m.pending[from]
+	if !ok {
+		byNonce = make(map[uint64]*trackedTx)
+		m.pending[from] = byNonce
+	}
+	byNonce[req.Nonce] = &trackedTx{req: req, signer: signer, hash: hash, block: m.block}
+}
+
+// Start checks for stuck transactions every interval until Stop is called.
+func (m *StuckMonitor) Start(interval time.Duration) {
+	m.check()
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+				m.check()
+			}
+		}
+	}()
+}
+
+// Stop halts the monitor and returns how many replacements were sent and
+// how many stuck transactions could not be escalated further.
+func (m *StuckMonitor) Stop() (replaced, abandoned int) {
+	close(m.stop)
+	<-m.done
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.replaced, m.abandoned
+}
+
+func (m *StuckMonitor) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	block, err := m.ts.client.BlockNumber(ctx)
+	if err != nil {
+		logger.Warn("[StuckMonitor] Could not read block number: %v\n", err)
+		return
+	}
+
+	m.mu.Lock()
+	m.block = block
+	wallets := make([]common.Address, 0, len(m.pending))
+	for addr := range m.pending {
+		wallets = append(wallets, addr)
+	}
+	m.mu.Unlock()
+
+	for _, addr := range wallets {
+		mined, err := m.ts.client.NonceAt(ctx, addr, nil)
+		if err != nil {
+			logger.Warn("[StuckMonitor] Could not read nonce for %s: %v\n", addr.Hex(), err)
+			continue
+		}
+
+		m.mu.Lock()
+		byNonce := m.pending[addr]
+		for nonce := range byNonce {
+			if nonce < mined {
+				delete(byNonce, nonce)
+			}
+		}
+		if len(byNonce) == 0 {
+			delete(m.pending, addr)
+		}
+		head := byNonce[mined]
+		due := head != nil && block >= head.block+m.afterBlocks && head.bumps < m.maxBumps
+		m.mu.Unlock()
+
+		if due {
+			m.escalate(ctx, addr, head, block)
+		}
+	}
+}
+
+// escalate re-signs t at a higher fee and sends the replacement.
+func (m *StuckMonitor) escalate(ctx context.Context, from common.Address, t *trackedTx, block uint64) {
+	oldFeeCap := t.req.GasFeeCap()
+	oldTip := t.req.Tip
+	if oldTip == nil {
+		oldTip = DefaultPriorityFee
+	}
+
+	baseFee := m.bump(t.req.BaseFee)
+	tip := m.bump(oldTip)
+
+	replacement := *t.req
+	if err := m.ts.Resign(&replacement, baseFee, tip, t.signer); err != nil {
+		logger.Warn("[StuckMonitor] Could not re-sign %s (nonce %d): %v\n", from.Hex(), t.req.Nonce, err)
+		return
+	}
+	if replacement.GasFeeCap().Cmp(oldFeeCap) <= 0 {
+		// Already at MAX_GAS_PRICE_WEI; a replacement would be rejected.
+		m.giveUp(from, t, "fee cap reached")
+		return
+	}
+
+	result, err := m.ts.SendTransaction(ctx, replacement.signedTx)
+	if err != nil {
+		logger.Warn("[StuckMonitor] Replacement for %s (nonce %d) rejected: %v\n", from.Hex(), t.req.Nonce, err)
+		m.mu.Lock()
+		t.bumps++
+		if t.bumps >= m.maxBumps {
+			m.abandoned++
+		}
+		m.mu.Unlock()
+		return
+	}
+
+	newHash := common.HexToHash(result.TxHash)
+	logger.Warn("[StuckMonitor] %s nonce %d stuck for %d blocks, replaced %s → %s (max fee %s wei)\n",
+		from.Hex(), t.req.Nonce, block-t.block, t.hash.Hex()[:10], newHash.Hex()[:10], replacement.GasFeeCap().String())
+
+	oldHash := t.hash
+	m.mu.Lock()
+	*t.req = replacement
+	t.hash = newHash
+	t.block = block
+	t.bumps++
+	m.replaced++
+	m.mu.Unlock()
+
+	if m.onReplace != nil {
+		m.onReplace(oldHash, newHash, &replacement)
+	}
+}
+
+func (m *StuckMonitor) giveUp(from common.Address, t *trackedTx, reason string) {
+	logger.Warn("[StuckMonitor] Not escalating %s nonce %d: %s\n", from.Hex(), t.req.Nonce, reason)
+	m.mu.Lock()
+	t.bumps = m.maxBumps
+	m.abandoned++
+	m.mu.Unlock()
+}
+
+// bump raises a fee by the configured strategy, but never by less than the
+// minimum replacement bump nodes accept.
+func (m *StuckMonitor) bump(fee *big.Int) *big.Int {
+	bumped := BumpFee(m.strategy, m.percent, fee, fee)
+	minimum := new(big.Int).Mul(fee, big.NewInt(100+minReplacementBump))
+	minimum.Div(minimum, big.NewInt(100))
+	minimum.Add(minimum, big.NewInt(1))
+	if bumped.Cmp(minimum) < 0 {
+		return minimum
+	}
+	return bumped
+}
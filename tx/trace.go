@@ -0,0 +1,68 @@
+package tx
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer records each request's life as a "transaction" span with
+// "prepare", "sign" and "submit" children. Without a tracer provider set up
+// (TRACE_OTLP_ENDPOINT) the spans cost next to nothing and go nowhere.
+var tracer = otel.Tracer("go-tps/tx")
+
+// startTrace opens req's transaction span. It stays open across re-signs
+// and re-sends until Finish.
+func (req *TxRequest) startTrace(ctx context.Context) {
+	_, req.span = tracer.Start(ctx, "transaction", trace.WithAttributes(
+		attribute.Int64("tx.nonce", int64(req.Nonce)),
+		attribute.String("tx.to", req.ToAddress.Hex()),
+		attribute.Int64("tx.gas_limit", int64(req.GasLimit)),
+	))
+}
+
+// childSpan starts a span under req's transaction span, or a no-op span for
+// requests prepared outside a batch.
+func (req *TxRequest) childSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	if req.span == nil {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+	return tracer.Start(trace.ContextWithSpan(ctx, req.span), name)
+}
+
+// Finish ends req's transaction span once it was sent or given up on, with
+// err as the reason it was not sent.
+func (req *TxRequest) Finish(err error) {
+	if req.span == nil {
+		return
+	}
+	if req.signedTx != nil {
+		req.span.SetAttributes(attribute.String("tx.hash", req.signedTx.Hash().Hex()))
+	}
+	endSpan(req.span, err)
+}
+
+// TraceParent returns the W3C traceparent of req's transaction span, stored
+// with the transaction so the receipt span joins the same trace even when
+// another process confirms it. It is empty when the request is not traced.
+func (req *TxRequest) TraceParent() string {
+	if req.span == nil || !req.span.SpanContext().IsValid() {
+		return ""
+	}
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(trace.ContextWithSpan(context.Background(), req.span), carrier)
+	return carrier.Get("traceparent")
+}
+
+// endSpan ends span, marking it failed with err if there was one.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -2,29 +2,63 @@ package tx
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"fmt"
 	"math/big"
+	"net/http"
 	"time"
 
 	ethereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type TransactionSender struct {
-	client  *ethclient.Client
-	chainID *big.Int
+	client      *ethclient.Client
+	endpoint    string            // host of the RPC endpoint, without failover
+	failover    *Failover         // nil = single endpoint
+	submit      *ethclient.Client // eth_sendRawTransaction only; nil = client
+	submitHost  string
+	chainID     *big.Int
+	broadcaster *P2PBroadcaster
+	maxGasPrice *big.Int // hard cap on max fee per gas, nil = uncapped
+	rollup      string   // RollupNone, RollupOptimism or RollupArbitrum
+	nonceSource string   // NonceSourcePending or NonceSourceLatest
 }
 
 type TxRequest struct {
 	ToAddress common.Address
 	Value     *big.Int
+	Data      []byte
+	Create    bool // contract creation; ToAddress is ignored
 	Nonce     uint64
 	GasLimit  uint64
 	signedTx  *types.Transaction
 	BaseFee   *big.Int
+	Tip       *big.Int // priority fee; nil = DefaultPriorityFee
+
+	GasEstimated uint64   // eth_estimateGas result, 0 if not estimated
+	L1Fee        *big.Int // estimated rollup L1 data fee, nil if none
+	L1FeeInGas   bool     // L1Fee is paid out of GasLimit (Arbitrum) rather than on top of it
+
+	reserved *big.Int // worst-case cost charged to the spend budget, nil if none
+
+	span trace.Span // transaction span, nil outside a batch; see startTrace
+}
+
+// Call describes the recipient, value and calldata of one transaction a
+// workload wants sent. A zero GasLimit means the configured default.
+type Call struct {
+	To       common.Address
+	Value    *big.Int
+	Data     []byte
+	GasLimit uint64
+
+	Estimated uint64 // set by GasEstimator
 }
 
 type TxResult struct {
@@ -33,15 +67,29 @@ type TxResult struct {
 	Status        string
 	SubmittedAt   time.Time
 	ExecutionTime float64 // in milliseconds
+	Endpoint      string  // host of the RPC endpoint that accepted it; empty over devp2p
 	Error         error
 }
 
 func NewTransactionSender(rpcURL string) (*TransactionSender, error) {
-	client, err := ethclient.Dial(rpcURL)
+	client, err := Dial(rpcURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to RPC: %w", err)
 	}
+	return newTransactionSender(client, EndpointName(rpcURL), nil)
+}
 
+// NewFailoverTransactionSender connects through failover, so requests move
+// to the next endpoint when the active one stops answering.
+func NewFailoverTransactionSender(failover *Failover) (*TransactionSender, error) {
+	client, err := Dial(failover.endpoints[0].url.String(), rpc.WithHTTPClient(&http.Client{Transport: failover}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to RPC: %w", err)
+	}
+	return newTransactionSender(client, "", failover)
+}
+
+func newTransactionSender(client *ethclient.Client, endpoint string, failover *Failover) (*TransactionSender, error) {
 	// Use a reasonable timeout for chain ID retrieval
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -52,11 +100,49 @@ func NewTransactionSender(rpcURL string) (*TransactionSender, error) {
 	}
 
 	return &TransactionSender{
-		client:  client,
-		chainID: chainID,
+		client:   client,
+		endpoint: endpoint,
+		failover: failover,
+		chainID:  chainID,
 	}, nil
 }
 
+// Failover returns the sender's failover transport, or nil with a single
+// endpoint.
+func (ts *TransactionSender) Failover() *Failover {
+	return ts.failover
+}
+
+// SetBroadcaster routes SendTransaction through the given devp2p peer
+// connection instead of eth_sendRawTransaction. Queries still use RPC.
+func (ts *TransactionSender) SetBroadcaster(b *P2PBroadcaster) {
+	ts.broadcaster = b
+}
+
+// ChainID returns the chain ID the sender signs for.
+func (ts *TransactionSender) ChainID() *big.Int {
+	return new(big.Int).Set(ts.chainID)
+}
+
+// ClientVersion returns the node software the sender talks to, as reported
+// by web3_clientVersion.
+func (ts *TransactionSender) ClientVersion(ctx context.Context) (string, error) {
+	var version string
+	if err := ts.client.Client().CallContext(ctx, &version, "web3_clientVersion"); err != nil {
+		return "", fmt.Errorf("failed to get client version: %w", err)
+	}
+	return version, nil
+}
+
+// SetMaxGasPrice caps the max fee per gas (and tip) of every transaction the
+// sender creates. A nil or zero value removes the cap.
+func (ts *TransactionSender) SetMaxGasPrice(maxGasPrice *big.Int) {
+	if maxGasPrice != nil && maxGasPrice.Sign() == 0 {
+		maxGasPrice = nil
+	}
+	ts.maxGasPrice = maxGasPrice
+}
+
 func (ts *TransactionSender) GetNonce(ctx context.Context, address common.Address) (uint64, error) {
 	nonce, err := ts.client.PendingNonceAt(ctx, address)
 	if err != nil {
@@ -65,6 +151,16 @@ func (ts *TransactionSender) GetNonce(ctx context.Context, address common.Addres
 	return nonce, nil
 }
 
+// GetMinedNonce returns the next nonce of address counting only mined
+// transactions, i.e. the lowest nonce still pending in the mempool.
+func (ts *TransactionSender) GetMinedNonce(ctx context.Context, address common.Address) (uint64, error) {
+	nonce, err := ts.client.NonceAt(ctx, address, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get mined nonce: %w", err)
+	}
+	return nonce, nil
+}
+
 func (ts *TransactionSender) GetGasPrice(ctx context.Context) (*big.Int, error) {
 	gasPrice, err := ts.client.SuggestGasPrice(ctx)
 	if err != nil {
@@ -89,17 +185,39 @@ func (ts *TransactionSender) GetBalance(ctx context.Context, address common.Addr
 	return balance, nil
 }
 
+// BalanceAt returns the balance of address at the block number.
+func (ts *TransactionSender) BalanceAt(ctx context.Context, address common.Address, number uint64) (*big.Int, error) {
+	balance, err := ts.client.BalanceAt(ctx, address, new(big.Int).SetUint64(number))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance at block %d: %w", number, err)
+	}
+	return balance, nil
+}
+
+// GetPendingBalance returns the balance including transactions still in the
+// pool, so value and gas already committed by earlier batches are excluded.
+func (ts *TransactionSender) GetPendingBalance(ctx context.Context, address common.Address) (*big.Int, error) {
+	balance, err := ts.client.PendingBalanceAt(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending balance: %w", err)
+	}
+	return balance, nil
+}
+
 func (ts *TransactionSender) CreateTransaction(req *TxRequest) (*types.Transaction, error) {
 
-	tip := big.NewInt(1_000_000_000) // 1 gwei tip
+	feeCap, tip := ts.fees(req.BaseFee, req.Tip)
 
-	feeCap := new(big.Int).Mul(req.BaseFee, big.NewInt(3)) // 3x base fee
-	feeCap.Add(feeCap, tip)
+	to := &req.ToAddress
+	if req.Create {
+		to = nil
+	}
 
 	tx := types.NewTx(&types.DynamicFeeTx{
 		Nonce:     req.Nonce,
-		To:        &req.ToAddress,
+		To:        to,
 		Value:     req.Value,
+		Data:      req.Data,
 		Gas:       req.GasLimit,
 		GasTipCap: tip,
 		GasFeeCap: feeCap,
@@ -107,9 +225,34 @@ func (ts *TransactionSender) CreateTransaction(req *TxRequest) (*types.Transacti
 	return tx, nil
 }
 
-func (ts *TransactionSender) SignTransaction(txn *types.Transaction, prv *ecdsa.PrivateKey) (*types.Transaction, error) {
-	signer := types.NewLondonSigner(ts.chainID)
-	signedTx, err := types.SignTx(txn, signer, prv)
+// MaxFeePerGas returns the fee cap a transaction priced at baseFee and tip is
+// signed with. It is the most the transaction can cost per unit of gas, and
+// what the node checks the sender's balance against.
+func (ts *TransactionSender) MaxFeePerGas(baseFee, tip *big.Int) *big.Int {
+	feeCap, _ := ts.fees(baseFee, tip)
+	return feeCap
+}
+
+func (ts *TransactionSender) fees(baseFee, tip *big.Int) (feeCap, tipCap *big.Int) {
+	tipCap = DefaultPriorityFee
+	if tip != nil {
+		tipCap = tip
+	}
+
+	feeCap = new(big.Int).Mul(baseFee, big.NewInt(3)) // 3x base fee
+	feeCap.Add(feeCap, tipCap)
+
+	if ts.maxGasPrice != nil && feeCap.Cmp(ts.maxGasPrice) > 0 {
+		feeCap = new(big.Int).Set(ts.maxGasPrice)
+		if tipCap.Cmp(feeCap) > 0 {
+			tipCap = new(big.Int).Set(feeCap)
+		}
+	}
+	return feeCap, tipCap
+}
+
+func (ts *TransactionSender) SignTransaction(txn *types.Transaction, signer Signer) (*types.Transaction, error) {
+	signedTx, err := signer.SignTx(txn, ts.chainID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign transaction: %w", err)
 	}
@@ -120,15 +263,27 @@ func (ts *TransactionSender) SignTransaction(txn *types.Transaction, prv *ecdsa.
 func (ts *TransactionSender) SendTransaction(ctx context.Context, signedTx *types.Transaction) (*TxResult, error) {
 	startTime := time.Now()
 
-	err := ts.client.SendTransaction(ctx, signedTx)
+	var err error
+	var endpoint string
+	if ts.broadcaster != nil {
+		err = ts.broadcaster.Broadcast(signedTx)
+	} else {
+		endpoint, err = ts.sendRaw(ctx, signedTx)
+	}
 
 	executionTime := time.Since(startTime).Seconds() * 1000
 
+	// The node already holding this exact transaction means it was sent
+	if SendError(err) == ErrAlreadyKnown {
+		err = nil
+	}
+
 	result := &TxResult{
 		TxHash:        signedTx.Hash().Hex(),
 		Nonce:         signedTx.Nonce(),
 		SubmittedAt:   startTime,
 		ExecutionTime: executionTime,
+		Endpoint:      endpoint,
 	}
 
 	if err != nil {
@@ -142,7 +297,13 @@ func (ts *TransactionSender) SendTransaction(ctx context.Context, signedTx *type
 }
 
 func (ts *TransactionSender) CreateAndSendTransaction(ctx context.Context, req *TxRequest) (*TxResult, error) {
+	ctx, span := req.childSpan(ctx, "submit")
 	result, err := ts.SendTransaction(ctx, req.signedTx)
+	span.SetAttributes(
+		attribute.String("tx.hash", result.TxHash),
+		attribute.String("rpc.endpoint", result.Endpoint),
+	)
+	endSpan(span, err)
 	if err != nil {
 		return result, err
 	}
@@ -165,13 +326,77 @@ func (ts *TransactionSender) SendMultipleTransactions(ctx context.Context, reque
 	return results, nil
 }
 
+// SetSubmitEndpoint sends transactions to rpcURL, e.g. a sequencer, while
+// receipts, balances and nonces are still read from the query endpoint.
+func (ts *TransactionSender) SetSubmitEndpoint(rpcURL string) error {
+	client, err := Dial(rpcURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to submission RPC: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		client.Close()
+		return fmt.Errorf("failed to get submission RPC chain ID: %w", err)
+	}
+	if chainID.Cmp(ts.chainID) != 0 {
+		client.Close()
+		return fmt.Errorf("submission RPC is on chain %s, query RPC on chain %s", chainID, ts.chainID)
+	}
+	if ts.submit != nil {
+		ts.submit.Close()
+	}
+	ts.submit = client
+	ts.submitHost = EndpointName(rpcURL)
+	return nil
+}
+
+// sendRaw sends signedTx with eth_sendRawTransaction and returns the host of
+// the endpoint that took it.
+func (ts *TransactionSender) sendRaw(ctx context.Context, signedTx *types.Transaction) (string, error) {
+	if ts.submit != nil {
+		return ts.submitHost, ts.submit.SendTransaction(ctx, signedTx)
+	}
+	endpoint := ts.endpoint
+	servedBy := &endpoint
+	if ts.failover != nil {
+		ctx, servedBy = withServedBy(ctx)
+	}
+	err := ts.client.SendTransaction(ctx, signedTx)
+	return *servedBy, err
+}
+
 func (ts *TransactionSender) Close() {
 	if ts.client != nil {
 		ts.client.Close()
 	}
+	if ts.submit != nil {
+		ts.submit.Close()
+	}
+}
+
+// Ways a receipt is obtained, see ReceiptOrigin.
+const (
+	ReceiptPolled     = "poll" // eth_getTransactionReceipt
+	ReceiptSubscribed = "ws"   // WebSocket receipt subscription
+)
+
+// ReceiptOrigin is where a receipt came from: the host of the endpoint that
+// returned it and how. Endpoint is empty for subscribed receipts, as the
+// WebSocket client is the caller's.
+type ReceiptOrigin struct {
+	Endpoint string
+	Source   string // ReceiptPolled or ReceiptSubscribed
 }
 
 func (ts *TransactionSender) WaitForReceipt(ctx context.Context, txHash common.Hash, timeout time.Duration) (*types.Receipt, error) {
+	receipt, _, err := ts.waitForReceipt(ctx, txHash, timeout)
+	return receipt, err
+}
+
+// waitForReceipt polls for txHash's receipt every second until timeout.
+func (ts *TransactionSender) waitForReceipt(ctx context.Context, txHash common.Hash, timeout time.Duration) (*types.Receipt, ReceiptOrigin, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
@@ -181,11 +406,11 @@ func (ts *TransactionSender) WaitForReceipt(ctx context.Context, txHash common.H
 	for {
 		select {
 		case <-ctx.Done():
-			return nil, fmt.Errorf("timeout waiting for transaction receipt")
+			return nil, ReceiptOrigin{}, fmt.Errorf("timeout waiting for transaction receipt")
 		case <-ticker.C:
-			receipt, err := ts.client.TransactionReceipt(ctx, txHash)
+			receipt, origin, err := ts.pollReceipt(ctx, txHash)
 			if err == nil {
-				return receipt, nil
+				return receipt, origin, nil
 			}
 			if err.Error() != "not found" {
 				continue
@@ -194,26 +419,38 @@ func (ts *TransactionSender) WaitForReceipt(ctx context.Context, txHash common.H
 	}
 }
 
-// GetTransactionReceipt gets the receipt for a transaction hash
-func (ts *TransactionSender) GetTransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+// pollReceipt fetches txHash's receipt with eth_getTransactionReceipt.
+func (ts *TransactionSender) pollReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, ReceiptOrigin, error) {
+	endpoint := ts.endpoint
+	servedBy := &endpoint
+	if ts.failover != nil {
+		ctx, servedBy = withServedBy(ctx)
+	}
 	receipt, err := ts.client.TransactionReceipt(ctx, txHash)
+	return receipt, ReceiptOrigin{Endpoint: *servedBy, Source: ReceiptPolled}, err
+}
+
+// GetTransactionReceipt gets the receipt for a transaction hash. The origin
+// is set on error too, to tell which endpoint failed.
+func (ts *TransactionSender) GetTransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, ReceiptOrigin, error) {
+	receipt, origin, err := ts.pollReceipt(ctx, txHash)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get transaction receipt: %w", err)
+		return nil, origin, fmt.Errorf("failed to get transaction receipt: %w", err)
 	}
-	return receipt, nil
+	return receipt, origin, nil
 }
 
-func (ts *TransactionSender) WaitForReceiptWithSharedWebSocket(ctx context.Context, wsClient *ethclient.Client, txHash common.Hash, timeout time.Duration) (*types.Receipt, error) {
+func (ts *TransactionSender) WaitForReceiptWithSharedWebSocket(ctx context.Context, wsClient *ethclient.Client, txHash common.Hash, timeout time.Duration) (*types.Receipt, ReceiptOrigin, error) {
 	if wsClient == nil {
-		return ts.WaitForReceipt(ctx, txHash, timeout)
+		return ts.waitForReceipt(ctx, txHash, timeout)
 	}
 
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	// Check immediately before subscribing — tx may already be mined.
-	if receipt, err := ts.client.TransactionReceipt(ctx, txHash); err == nil {
-		return receipt, nil
+	if receipt, origin, err := ts.pollReceipt(ctx, txHash); err == nil {
+		return receipt, origin, nil
 	}
 
 	// SubscribeTransactionReceipts streams []*types.Receipt batches to the channel
@@ -225,60 +462,168 @@ func (ts *TransactionSender) WaitForReceiptWithSharedWebSocket(ctx context.Conte
 	sub, err := wsClient.SubscribeTransactionReceipts(ctx, query, receiptCh)
 	if err != nil {
 		// WebSocket subscription failed; fall back to RPC polling.
-		return ts.WaitForReceipt(ctx, txHash, timeout)
+		return ts.waitForReceipt(ctx, txHash, timeout)
 	}
 	defer sub.Unsubscribe()
 
 	for {
 		select {
 		case <-ctx.Done():
-			return nil, fmt.Errorf("timeout waiting for transaction receipt")
+			return nil, ReceiptOrigin{}, fmt.Errorf("timeout waiting for transaction receipt")
 		case err := <-sub.Err():
 			// Subscription broken; fall back to polling.
 			_ = err
-			return ts.WaitForReceipt(ctx, txHash, timeout)
+			return ts.waitForReceipt(ctx, txHash, timeout)
 		case receipts := <-receiptCh:
 			// A batch of receipts arrived; find the one matching our tx.
 			for _, r := range receipts {
 				if r.TxHash == txHash {
-					return r, nil
+					return r, ReceiptOrigin{Source: ReceiptSubscribed}, nil
 				}
 			}
 		}
 	}
 }
 
-func (ts *TransactionSender) PrepareBatchTransactions(ctx context.Context, toAddress common.Address, value *big.Int, count int, baseFee *big.Int, gasLimit uint64, prv *ecdsa.PrivateKey, nonce uint64) ([]*TxRequest, uint64, error) {
+func (ts *TransactionSender) PrepareBatchTransactions(ctx context.Context, calls []Call, baseFee, tip *big.Int, gasLimit uint64, signer Signer, nonce uint64) ([]*TxRequest, uint64, error) {
 
 	startNonce := nonce
 
-	requests := make([]*TxRequest, 0, count)
-	for i := 0; i < count; i++ {
+	requests := make([]*TxRequest, 0, len(calls))
+	for i, call := range calls {
 		req := TxRequest{
-			ToAddress: toAddress,
-			Value:     value,
+			ToAddress: call.To,
+			Value:     call.Value,
+			Data:      call.Data,
 			Nonce:     startNonce + uint64(i),
 			GasLimit:  gasLimit,
 			BaseFee:   baseFee,
-		}
+			Tip:       tip,
 
-		tx, err := ts.CreateTransaction(&req)
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to create transaction: %w", err)
+			GasEstimated: call.Estimated,
+		}
+		if call.GasLimit != 0 {
+			req.GasLimit = call.GasLimit
 		}
+		req.startTrace(ctx)
 
-		signedTx, err := ts.SignTransaction(tx, prv)
+		signedTx, err := ts.signRequest(&req, signer)
 		if err != nil {
-			return nil, 0, fmt.Errorf("failed to sign transaction: %w", err)
+			req.Finish(err)
+			for _, prepared := range requests {
+				prepared.Finish(err)
+			}
+			return nil, 0, err
 		}
 
 		req.signedTx = signedTx
 		requests = append(requests, &req)
 
 	}
-	return requests, startNonce + uint64(count), nil
+	return requests, startNonce + uint64(len(calls)), nil
+}
+
+// Resign rebuilds an already prepared request with a new base fee and tip,
+// keeping its nonce, so it can be sent at a fresher price.
+func (ts *TransactionSender) Resign(req *TxRequest, baseFee, tip *big.Int, signer Signer) error {
+	updated := *req
+	updated.BaseFee = baseFee
+	updated.Tip = tip
+	signedTx, err := ts.signRequest(&updated, signer)
+	if err != nil {
+		return err
+	}
+	req.BaseFee = baseFee
+	req.Tip = tip
+	req.signedTx = signedTx
+	return nil
+}
+
+// Hash returns the hash of the signed request, or the zero hash if it is not
+// signed yet.
+func (req *TxRequest) Hash() common.Hash {
+	if req.signedTx == nil {
+		return common.Hash{}
+	}
+	return req.signedTx.Hash()
+}
+
+// GasFeeCap returns the max fee per gas the request was signed with.
+func (req *TxRequest) GasFeeCap() *big.Int {
+	if req.signedTx == nil {
+		return nil
+	}
+	return req.signedTx.GasFeeCap()
+}
+
+func (ts *TransactionSender) signRequest(req *TxRequest, signer Signer) (*types.Transaction, error) {
+	_, span := req.childSpan(context.Background(), "prepare")
+	tx, err := ts.CreateTransaction(req)
+	if err != nil {
+		err = fmt.Errorf("failed to create transaction: %w", err)
+		endSpan(span, err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("tx.max_fee_per_gas", tx.GasFeeCap().String()))
+	span.End()
+
+	_, span = req.childSpan(context.Background(), "sign")
+	signedTx, err := ts.SignTransaction(tx, signer)
+	if err != nil {
+		err = fmt.Errorf("failed to sign transaction: %w", err)
+		endSpan(span, err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("tx.hash", signedTx.Hash().Hex()))
+	span.End()
+	return signedTx, nil
+}
+
+// SendRequest signs and submits a single request outside of a batch, e.g. for
+// workload setup such as contract deployment. It returns the transaction hash.
+func (ts *TransactionSender) SendRequest(ctx context.Context, req *TxRequest, signer Signer) (common.Hash, error) {
+	signedTx, err := ts.signRequest(req, signer)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if _, err := ts.sendRaw(ctx, signedTx); err != nil && SendError(err) != ErrAlreadyKnown {
+		return common.Hash{}, fmt.Errorf("failed to send transaction: %w", err)
+	}
+	return signedTx.Hash(), nil
+}
+
+// LatestHeader returns the header of the chain head.
+func (ts *TransactionSender) LatestHeader(ctx context.Context) (*types.Header, error) {
+	header, err := ts.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest header: %w", err)
+	}
+	return header, nil
+}
+
+// HeaderByNumber returns the header of the block at number.
+func (ts *TransactionSender) HeaderByNumber(ctx context.Context, number uint64) (*types.Header, error) {
+	header, err := ts.client.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get header %d: %w", number, err)
+	}
+	return header, nil
 }
 
 func (ts *TransactionSender) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
 	return ts.client.HeaderByHash(ctx, hash)
 }
+
+// BlockTxHashes returns the timestamp and transaction hashes of the block at
+// number. Only hashes are fetched, so blocks with transaction types this
+// client cannot decode (e.g. rollup deposits) are read all the same.
+func (ts *TransactionSender) BlockTxHashes(ctx context.Context, number uint64) (time.Time, []common.Hash, error) {
+	var block struct {
+		Timestamp    hexutil.Uint64 `json:"timestamp"`
+		Transactions []common.Hash  `json:"transactions"`
+	}
+	if err := ts.client.Client().CallContext(ctx, &block, "eth_getBlockByNumber", hexutil.EncodeUint64(number), false); err != nil {
+		return time.Time{}, nil, fmt.Errorf("failed to get block %d: %w", number, err)
+	}
+	return time.Unix(int64(block.Timestamp), 0), block.Transactions, nil
+}
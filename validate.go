@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"go-tps/config"
+	"go-tps/rate"
+	"go-tps/workload"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// maxWorkers is the most DB_WORKERS and RECEIPT_WORKERS may start; past it
+// the workers only contend for the database and the RPC endpoint.
+const maxWorkers = 1024
+
+// validateConfig returns every problem with the settings of run and fund
+// that can be found without connecting to anything: the settings that did
+// not parse, counts and rates out of range, and modes that cannot be
+// combined. An empty list means the run may start.
+func validateConfig(config *config.Config) []error {
+	problems := append([]error(nil), config.Errors()...)
+	problem := func(format string, args ...any) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	// Where the transactions go
+	switch to := config.ToAddress; {
+	case !common.IsHexAddress(to):
+		problem("invalid TO_ADDRESS %q: expected 0x and 40 hex digits", to)
+	case isMixedCase(to) && common.HexToAddress(to).Hex() != with0x(to):
+		problem("invalid TO_ADDRESS %s: the checksum does not match, so it likely has a typo (write it in lower case to skip the check)", to)
+	}
+	if _, err := workload.New(config.Workload, common.Address{}, nil); err != nil {
+		problem("invalid WORKLOAD: %v", err)
+	}
+
+	// Counts
+	for _, s := range []struct {
+		name  string
+		value int
+	}{
+		{"WALLET_COUNT", config.WalletCount},
+		{"TX_PER_WALLET", config.TxPerWallet},
+		{"DB_WORKERS", config.DBWorkers},
+		{"DB_BATCH_SIZE", config.DBBatchSize},
+		{"RECEIPT_WORKERS", config.ReceiptWorkers},
+		{"DB_MAX_OPEN_CONNS", config.DBMaxOpenConns},
+		{"CONTEXT_TIMEOUT", config.ContextTimeout},
+	} {
+		if s.value < 1 {
+			problem("invalid %s %d: must be at least 1", s.name, s.value)
+		}
+	}
+	for _, s := range []struct {
+		name  string
+		value int
+	}{
+		{"DB_WORKERS", config.DBWorkers},
+		{"RECEIPT_WORKERS", config.ReceiptWorkers},
+	} {
+		if s.value > maxWorkers {
+			problem("invalid %s %d: at most %d workers, more only contend for the database and the RPC endpoint", s.name, s.value, maxWorkers)
+		}
+	}
+	for _, s := range []struct {
+		name  string
+		value float64
+	}{
+		{"RECEIVER_COUNT", float64(config.ReceiverCount)},
+		{"DERIVATION_START_INDEX", float64(config.DerivationStart)},
+		{"RUN_DURATION_MINUTES", float64(config.RunDurationMinutes)},
+		{"SLEEP_MINUTES", float64(config.SleepMinutes)},
+		{"TARGET_TPS", config.TargetTPS},
+		{"WALLET_TPS", config.WalletTPS},
+		{"PRIORITY_FEE_GWEI", config.PriorityFeeGwei},
+	} {
+		if s.value < 0 {
+			problem("invalid %s %g: must not be negative", s.name, s.value)
+		}
+	}
+	if config.GasLimit == 0 {
+		problem("invalid GAS_LIMIT 0: must be positive")
+	}
+	if config.GasPriceMultiplier <= 0 {
+		problem("invalid GAS_PRICE_MULTIPLIER %g: must be positive", config.GasPriceMultiplier)
+	}
+	if bits := config.MnemonicBits; bits < 128 || bits > 256 || bits%32 != 0 {
+		problem("invalid MNEMONIC_BITS %d: must be 128, 160, 192, 224 or 256", bits)
+	}
+	if pacing := strings.ToLower(config.LoopPacing); pacing != loopPacingInterval && pacing != loopPacingGap && pacing != loopPacingStream {
+		problem("invalid LOOP_PACING %q: must be %s, %s or %s", config.LoopPacing, loopPacingInterval, loopPacingGap, loopPacingStream)
+	}
+
+	// Modes that cannot be combined
+	if n := countSet(config.KeysFile, config.KeysSource, config.KeystoreDir, config.SignerURL); n > 1 {
+		problem("set only one of KEYS_FILE, KEYS_SOURCE, KEYSTORE_DIR and SIGNER_URL")
+	}
+	if config.Mnemonic != "" && config.MnemonicSource != "" {
+		problem("set MNEMONIC or MNEMONIC_SOURCE, not both")
+	}
+	if config.ReceiverCount > 0 && walletsImported(config) {
+		problem("RECEIVER_COUNT derives the receivers from the mnemonic, which KEYS_FILE, KEYS_SOURCE, KEYSTORE_DIR and SIGNER_URL do without")
+	}
+	if n := countSet(config.FunderPrivateKey, config.FunderKeySource, config.FunderKeystore, config.FunderAddress); n > 1 {
+		problem("set only one of FUNDER_PRIVATE_KEY, FUNDER_KEY_SOURCE, FUNDER_KEYSTORE and FUNDER_ADDRESS")
+	}
+	var modes []string
+	for _, m := range []struct {
+		name string
+		set  bool
+	}{
+		{"COMPARE_RPC_URLS", config.CompareRPCURLs != ""},
+		{"SATURATION_SEARCH", config.SaturationSearch},
+		{"LOAD_STAGES", config.LoadStages != ""},
+		{"SPIKE_MULTIPLIER", config.SpikeMultiplier > 0},
+		{"RAMP_DURATION_SECONDS", config.RampDuration > 0},
+	} {
+		if m.set {
+			modes = append(modes, m.name)
+		}
+	}
+	if len(modes) > 1 {
+		problem("%s each shape the whole run; set only one of them", strings.Join(modes, " and "))
+	}
+
+	// The settings of each mode
+	if config.CompareRPCURLs != "" {
+		if len(splitList(config.CompareRPCURLs)) < 2 {
+			problem("COMPARE_RPC_URLS needs at least two providers")
+		}
+		if order := strings.ToLower(config.CompareOrder); order != compareSequence && order != compareInterleaved {
+			problem("invalid COMPARE_ORDER %q: must be %s or %s", config.CompareOrder, compareSequence, compareInterleaved)
+		}
+	}
+	if config.SaturationSearch && (config.SaturationStartTPS <= 0 || config.SaturationProbe <= 0) {
+		problem("SATURATION_START_TPS and SATURATION_PROBE_SECONDS must be positive")
+	}
+	if config.LoadStages != "" {
+		if _, err := rate.ParseStages(config.LoadStages); err != nil {
+			problem("invalid LOAD_STAGES: %v", err)
+		}
+	}
+	if config.SpikeMultiplier > 0 && (config.TargetTPS <= 0 || config.SpikeSeconds <= 0 || config.SpikeSeconds >= config.SpikeEvery) {
+		problem("SPIKE_MULTIPLIER needs a positive TARGET_TPS and SPIKE_SECONDS shorter than SPIKE_EVERY_SECONDS")
+	}
+	if config.RampDuration > 0 && (config.RampStartTPS <= 0 || config.RampEndTPS <= 0) {
+		problem("RAMP_START_TPS and RAMP_END_TPS must be positive when RAMP_DURATION_SECONDS is set")
+	}
+	return problems
+}
+
+// countSet returns how many of values are not empty.
+func countSet(values ...string) int {
+	n := 0
+	for _, v := range values {
+		if v != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// isMixedCase reports whether the hex digits of address use both cases,
+// which makes them an EIP-55 checksum.
+func isMixedCase(address string) bool {
+	digits := strings.TrimPrefix(strings.TrimPrefix(address, "0x"), "0X")
+	return digits != strings.ToLower(digits) && digits != strings.ToUpper(digits)
+}
+
+// with0x returns address with a 0x prefix.
+func with0x(address string) string {
+	return "0x" + strings.TrimPrefix(strings.TrimPrefix(address, "0x"), "0X")
+}
@@ -5,10 +5,15 @@ import (
 	"crypto/ecdsa"
 	"fmt"
 	"go-tps/tx"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	hdwallet "github.com/miguelmota/go-ethereum-hdwallet"
@@ -17,15 +22,33 @@ import (
 
 type Wallet struct {
 	Address        common.Address
-	PrivateKey     *ecdsa.PrivateKey
+	PrivateKey     *ecdsa.PrivateKey // nil for a wallet of a remote signer
 	DerivationPath string
 	Nonce          uint64
+	remote         tx.Signer
 	sync.Mutex
 }
 
-// GenerateMnemonic generates a new mnemonic phrase
-func GenerateMnemonic() (string, error) {
-	entropy, err := bip39.NewEntropy(128)
+// Signer returns what signs the wallet's transactions: its remote signer,
+// or its private key.
+func (w *Wallet) Signer() tx.Signer {
+	if w.remote != nil {
+		return w.remote
+	}
+	return tx.NewKeySigner(w.PrivateKey)
+}
+
+// FromSigner returns the wallet of an account of a remote signer, which
+// holds its key; source records where it came from. Its Nonce is left at
+// zero.
+func FromSigner(s tx.Signer, source string) *Wallet {
+	return &Wallet{Address: s.Address(), DerivationPath: source, remote: s}
+}
+
+// GenerateMnemonic generates a new mnemonic phrase from bits of entropy:
+// 128 for 12 words up to 256 for 24, in steps of 32.
+func GenerateMnemonic(bits int) (string, error) {
+	entropy, err := bip39.NewEntropy(bits)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate entropy: %w", err)
 	}
@@ -38,46 +61,277 @@ func GenerateMnemonic() (string, error) {
 	return mnemonic, nil
 }
 
+// Derivation is where in the HD tree of a mnemonic wallets are derived:
+// wallet i at Path with {index} replaced by Start+i, e.g. m/44'/60'/0'/0/7
+// for wallet 2 with Start 5. Disjoint ranges of Start give several
+// load generators sharing a mnemonic distinct wallets. The tree is the one
+// of the mnemonic and Passphrase, so a different passphrase gives entirely
+// different wallets.
+type Derivation struct {
+	Path       string // template with one {index}
+	Start      int
+	Passphrase string // BIP39 passphrase (empty = none)
+}
+
+// path returns the derivation path of wallet i.
+func (d Derivation) path(i int) (accounts.DerivationPath, error) {
+	if strings.Count(d.Path, "{index}") != 1 {
+		return nil, fmt.Errorf("derivation path %q must contain {index} once", d.Path)
+	}
+	if d.Start < 0 {
+		return nil, fmt.Errorf("negative derivation start index %d", d.Start)
+	}
+	path, err := hdwallet.ParseDerivationPath(strings.Replace(d.Path, "{index}", strconv.Itoa(d.Start+i), 1))
+	if err != nil {
+		return nil, fmt.Errorf("invalid derivation path %q: %w", d.Path, err)
+	}
+	return path, nil
+}
+
 // DeriveWalletsFromMnemonic derives multiple wallets from a single mnemonic.
-func DeriveWalletsFromMnemonic(mnemonic string, count int, txSender *tx.TransactionSender) ([]*Wallet, error) {
-	w, err := hdwallet.NewFromMnemonic(mnemonic)
+func DeriveWalletsFromMnemonic(mnemonic string, d Derivation, count int, txSender *tx.TransactionSender) ([]*Wallet, error) {
+	wallets, err := DeriveWallets(mnemonic, d, count)
+	if err != nil {
+		return nil, err
+	}
+	if err := FetchNonces(wallets, txSender); err != nil {
+		return nil, err
+	}
+	return wallets, nil
+}
+
+// FetchNonces sets the Nonce of every wallet to the next nonce the node
+// reports for it.
+func FetchNonces(wallets []*Wallet, txSender *tx.TransactionSender) error {
+	for i, w := range wallets {
+		// context with 30 timeout
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+
+		nonce, err := txSender.NextNonce(ctx, w.Address)
+		cancel() // Call cancel immediately instead of deferring
+		if err != nil {
+			return fmt.Errorf("failed to get nonce for wallet %d: %w", i, err)
+		}
+		w.Nonce = nonce
+	}
+	return nil
+}
+
+// DeriveWallets derives count wallets without touching the network; their
+// Nonce is left at zero.
+func DeriveWallets(mnemonic string, d Derivation, count int) ([]*Wallet, error) {
+	hd, err := hdwallet.NewFromMnemonic(mnemonic, d.Passphrase)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HD wallet: %w", err)
 	}
 
 	wallets := make([]*Wallet, 0, count)
-
 	for i := 0; i < count; i++ {
-		// Standard Ethereum derivation path: m/44'/60'/0'/0/i
-		path := hdwallet.MustParseDerivationPath(fmt.Sprintf("m/44'/60'/0'/0/%d", i))
-
-		account, err := w.Derive(path, false)
+		w, err := deriveWallet(hd, d, i)
 		if err != nil {
-			return nil, fmt.Errorf("failed to derive account %d: %w", i, err)
+			return nil, err
 		}
+		wallets = append(wallets, w)
+	}
+	return wallets, nil
+}
+
+// DeriveWallet derives wallet i of d without touching the network; its
+// Nonce is left at zero.
+func DeriveWallet(mnemonic string, d Derivation, i int) (*Wallet, error) {
+	hd, err := hdwallet.NewFromMnemonic(mnemonic, d.Passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HD wallet: %w", err)
+	}
+	return deriveWallet(hd, d, i)
+}
+
+func deriveWallet(hd *hdwallet.Wallet, d Derivation, i int) (*Wallet, error) {
+	path, err := d.path(i)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := hd.Derive(path, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive account %d: %w", i, err)
+	}
+
+	privateKey, err := hd.PrivateKey(account)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get private key for account %d: %w", i, err)
+	}
 
-		privateKey, err := w.PrivateKey(account)
+	return &Wallet{
+		Address:        account.Address,
+		PrivateKey:     privateKey,
+		DerivationPath: path.String(),
+	}, nil
+}
+
+// FromPrivateKey returns the wallet of a hex private key, with or without
+// 0x; its Nonce is left at zero.
+func FromPrivateKey(hexKey string) (*Wallet, error) {
+	privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(hexKey), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	return &Wallet{Address: crypto.PubkeyToAddress(privateKey.PublicKey), PrivateKey: privateKey}, nil
+}
+
+// LoadKeysFile reads one hex private key per line from path, skipping blank
+// lines and lines starting with #. At most count keys are read (0 = all).
+// Each wallet's DerivationPath records where its key came from, e.g.
+// "keys.txt:3" for line 3; its Nonce is left at zero.
+func LoadKeysFile(path string, count int) ([]*Wallet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keys file: %w", err)
+	}
+	return ParseKeys(data, path, count)
+}
+
+// ParseKeys reads keys in the format of LoadKeysFile from data, which came
+// from name.
+func ParseKeys(data []byte, name string, count int) ([]*Wallet, error) {
+	var wallets []*Wallet
+	seen := make(map[common.Address]int)
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if count > 0 && len(wallets) == count {
+			break
+		}
+		w, err := FromPrivateKey(line)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get private key for account %d: %w", i, err)
+			// The error never includes the key itself
+			return nil, fmt.Errorf("%s line %d: invalid private key", name, n+1)
 		}
-		// context with 30 timeout
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if first, ok := seen[w.Address]; ok {
+			return nil, fmt.Errorf("%s line %d: same key as line %d", name, n+1, first)
+		}
+		seen[w.Address] = n + 1
+		w.DerivationPath = fmt.Sprintf("%s:%d", name, n+1)
+		wallets = append(wallets, w)
+	}
+	if len(wallets) == 0 {
+		return nil, fmt.Errorf("no private keys in %s", name)
+	}
+	return wallets, nil
+}
 
-		nonce, err := txSender.GetNonce(ctx, account.Address)
-		cancel() // Call cancel immediately instead of deferring
+// FromKeystore decrypts a JSON keystore file (as written by geth, clef or
+// MetaMask exports) with password; its Nonce is left at zero.
+func FromKeystore(path, password string) (*Wallet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore: %w", err)
+	}
+	key, err := keystore.DecryptKey(data, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore %s: %w", path, err)
+	}
+	return &Wallet{Address: key.Address, PrivateKey: key.PrivateKey}, nil
+}
+
+// LoadKeystoreDir decrypts the JSON keystore files in dir, in file name
+// order (geth's UTC--<time>--<address> names sort by creation), with
+// password. At most count files are read (0 = all); hidden files and
+// subdirectories are skipped. Each wallet's DerivationPath is its file;
+// its Nonce is left at zero.
+func LoadKeystoreDir(dir, password string, count int) ([]*Wallet, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore directory: %w", err)
+	}
+	var wallets []*Wallet
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if count > 0 && len(wallets) == count {
+			break
+		}
+		path := filepath.Join(dir, entry.Name())
+		w, err := FromKeystore(path, password)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get nonce for wallet %d: %w", i, err)
+			return nil, err
 		}
+		w.DerivationPath = path
+		wallets = append(wallets, w)
+	}
+	if len(wallets) == 0 {
+		return nil, fmt.Errorf("no keystore files in %s", dir)
+	}
+	return wallets, nil
+}
 
-		wallets = append(wallets, &Wallet{
-			Address:        account.Address,
-			PrivateKey:     privateKey,
-			DerivationPath: path.String(),
-			Nonce:          nonce,
-		})
+// ExportKeystore writes each wallet to dir as a JSON keystore file encrypted
+// with password, named like geth names them. light uses scrypt parameters
+// that take milliseconds rather than about a second to decrypt, for
+// throwaway test keys. Wallets already in dir are left as they are; it
+// returns how many files were written.
+func ExportKeystore(dir, password string, wallets []*Wallet, light bool) (int, error) {
+	n, p := keystore.StandardScryptN, keystore.StandardScryptP
+	if light {
+		n, p = keystore.LightScryptN, keystore.LightScryptP
 	}
+	ks := keystore.NewKeyStore(dir, n, p)
+	written := 0
+	for i, w := range wallets {
+		if ks.HasAddress(w.Address) {
+			continue
+		}
+		if _, err := ks.ImportECDSA(w.PrivateKey, password); err != nil {
+			return written, fmt.Errorf("failed to export wallet %d: %w", i, err)
+		}
+		written++
+	}
+	return written, nil
+}
 
-	return wallets, nil
+// ApplyNonceOverrides sets the starting nonce of wallets named in spec, a
+// comma-separated list of key=nonce where key is a 0-based wallet index or a
+// wallet address, e.g. "0=15,0xAbC...=42". It returns how many wallets were
+// overridden.
+func ApplyNonceOverrides(wallets []*Wallet, spec string) (int, error) {
+	applied := 0
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return applied, fmt.Errorf("invalid nonce override %q (expected index=nonce or address=nonce)", entry)
+		}
+		nonce, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return applied, fmt.Errorf("invalid nonce in override %q: %w", entry, err)
+		}
+
+		key = strings.TrimSpace(key)
+		var w *Wallet
+		if common.IsHexAddress(key) {
+			addr := common.HexToAddress(key)
+			for _, candidate := range wallets {
+				if candidate.Address == addr {
+					w = candidate
+					break
+				}
+			}
+		} else if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(wallets) {
+			w = wallets[i]
+		}
+		if w == nil {
+			return applied, fmt.Errorf("nonce override %q does not match any of the %d wallets", entry, len(wallets))
+		}
+		w.Nonce = nonce
+		applied++
+	}
+	return applied, nil
 }
 
 // GetPublicAddress returns the Ethereum address from a private key
@@ -0,0 +1,749 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"go-tps/config"
+	dbpkg "go-tps/db"
+	"go-tps/logger"
+	"go-tps/secrets"
+	txpkg "go-tps/tx"
+	"go-tps/wallet"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// runWalletsCommand implements `go-tps wallets <subcommand>`.
+func runWalletsCommand(config *config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Println("Usage: go-tps wallets export-keys -index N")
+		fmt.Println("       go-tps wallets export-keystore -dir DIR [-count N] [-light]")
+		fmt.Println("       go-tps wallets cancel-stuck [-count N] [-bump PERCENT] [-yes]")
+		fmt.Println("       go-tps wallets sweep [-to ADDRESS] [-count N] [-yes]")
+		return 2
+	}
+	switch args[0] {
+	case "export-keys":
+		return runExportKeys(config, args[1:])
+	case "export-keystore":
+		return runExportKeystore(config, args[1:])
+	case "cancel-stuck":
+		return runCancelStuck(config, args[1:])
+	case "sweep":
+		return runSweep(config, args[1:])
+	default:
+		fmt.Printf("Unknown wallets command %q (available: export-keys, export-keystore, cancel-stuck, sweep)\n", args[0])
+		return 2
+	}
+}
+
+// loadMnemonic returns MNEMONIC or that of MNEMONIC_SOURCE, or the
+// mnemonic saved in file by a run.
+func loadMnemonic(config *config.Config, file string) (string, error) {
+	if err := fetchMnemonic(config); err != nil {
+		return "", err
+	}
+	if config.Mnemonic != "" {
+		return config.Mnemonic, nil
+	}
+	mnemonic, err := LoadMnemonicFromFile(file, config.MnemonicPassword)
+	if err != nil {
+		return "", fmt.Errorf("no MNEMONIC set and could not read %s: %w", file, err)
+	}
+	return mnemonic, nil
+}
+
+// fetchMnemonic sets MNEMONIC from MNEMONIC_SOURCE, if that is set.
+func fetchMnemonic(config *config.Config) error {
+	if config.MnemonicSource == "" {
+		return nil
+	}
+	if config.Mnemonic != "" {
+		return fmt.Errorf("set MNEMONIC or MNEMONIC_SOURCE, not both")
+	}
+	logger.Info("\nFetching mnemonic from %s...\n", config.MnemonicSource)
+	mnemonic, err := fetchSecret(config, config.MnemonicSource)
+	if err != nil {
+		return err
+	}
+	config.Mnemonic = mnemonic
+	return nil
+}
+
+// fetchSecret reads the secret source names from its secrets manager.
+func fetchSecret(config *config.Config, source string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.ContextTimeout)*time.Second)
+	defer cancel()
+	return secrets.Fetch(ctx, source)
+}
+
+// derivation returns where DERIVATION_PATH and DERIVATION_START_INDEX put
+// the wallets in the HD tree of the mnemonic and MNEMONIC_PASSPHRASE.
+func derivation(config *config.Config) wallet.Derivation {
+	return wallet.Derivation{Path: config.DerivationPath, Start: config.DerivationStart, Passphrase: config.MnemonicPassphrase}
+}
+
+// deriveReceivers derives the RECEIVER_COUNT receivers of the transfers from
+// mnemonic at RECEIVER_DERIVATION_PATH, from index 0. None of them may be a
+// wallet of the run.
+func deriveReceivers(config *config.Config, mnemonic string, wallets []*wallet.Wallet) ([]common.Address, error) {
+	derived, err := wallet.DeriveWallets(mnemonic, wallet.Derivation{Path: config.ReceiverPath, Passphrase: config.MnemonicPassphrase}, config.ReceiverCount)
+	if err != nil {
+		return nil, err
+	}
+	senders := make(map[common.Address]int, len(wallets))
+	for i, w := range wallets {
+		senders[w.Address] = i + 1
+	}
+	receivers := make([]common.Address, len(derived))
+	for i, r := range derived {
+		if n, ok := senders[r.Address]; ok {
+			return nil, fmt.Errorf("receiver %d (%s) is wallet %d of the run: RECEIVER_DERIVATION_PATH must not overlap DERIVATION_PATH", i, r.Address.Hex(), n)
+		}
+		receivers[i] = r.Address
+	}
+	return receivers, nil
+}
+
+// walletsImported reports whether the wallets come from KEYS_FILE,
+// KEYS_SOURCE, KEYSTORE_DIR or SIGNER_URL rather than a mnemonic.
+func walletsImported(config *config.Config) bool {
+	return config.KeysFile != "" || config.KeysSource != "" || config.KeystoreDir != "" || config.SignerURL != ""
+}
+
+// importedWallets reads the first count wallets of KEYS_FILE, KEYS_SOURCE,
+// KEYSTORE_DIR or SIGNER_URL, and names where they came from. Their Nonce
+// is left at zero.
+func importedWallets(config *config.Config, count int) ([]*wallet.Wallet, string, error) {
+	sources := 0
+	for _, s := range []string{config.KeysFile, config.KeysSource, config.KeystoreDir, config.SignerURL} {
+		if s != "" {
+			sources++
+		}
+	}
+	switch {
+	case sources > 1:
+		return nil, "", fmt.Errorf("set only one of KEYS_FILE, KEYS_SOURCE, KEYSTORE_DIR and SIGNER_URL")
+	case config.SignerURL != "":
+		return signerWallets(config, count)
+	case config.KeysSource != "":
+		logger.Info("\nFetching private keys from %s...\n", config.KeysSource)
+		keys, err := fetchSecret(config, config.KeysSource)
+		if err != nil {
+			return nil, "", err
+		}
+		wallets, err := wallet.ParseKeys([]byte(keys), config.KeysSource, count)
+		return wallets, config.KeysSource, err
+	case config.KeysFile != "":
+		logger.Info("\nLoading private keys from %s...\n", config.KeysFile)
+		wallets, err := wallet.LoadKeysFile(config.KeysFile, count)
+		return wallets, config.KeysFile, err
+	}
+	password, err := keystorePassword(config, false)
+	if err != nil {
+		return nil, "", err
+	}
+	logger.Info("\nDecrypting keystore files in %s...\n", config.KeystoreDir)
+	wallets, err := wallet.LoadKeystoreDir(config.KeystoreDir, password, count)
+	return wallets, config.KeystoreDir, err
+}
+
+// importWallets returns the first WALLET_COUNT wallets of KEYS_FILE,
+// KEYS_SOURCE, KEYSTORE_DIR or SIGNER_URL with their next nonces, which are left to the wallets table
+// when NONCE_SOURCE is local. A source with fewer keys runs that many
+// wallets.
+func importWallets(config *config.Config, txSender *txpkg.TransactionSender) ([]*wallet.Wallet, error) {
+	wallets, source, err := importedWallets(config, config.WalletCount)
+	if err != nil {
+		return nil, err
+	}
+	if len(wallets) < config.WalletCount {
+		logger.Warn("⚠️  %s holds %d keys, fewer than WALLET_COUNT (%d); running %d wallets\n",
+			source, len(wallets), config.WalletCount, len(wallets))
+		config.WalletCount = len(wallets)
+	}
+	if !strings.EqualFold(config.NonceSource, nonceSourceLocal) {
+		if err := wallet.FetchNonces(wallets, txSender); err != nil {
+			return nil, err
+		}
+	}
+	return wallets, nil
+}
+
+// loadWallets returns the first count wallets of a run for a wallets
+// command: from KEYS_FILE, KEYS_SOURCE, KEYSTORE_DIR or SIGNER_URL if set, else derived
+// from the mnemonic of loadMnemonic. Their Nonce is left at zero.
+func loadWallets(config *config.Config, mnemonicFile string, count int) ([]*wallet.Wallet, error) {
+	if walletsImported(config) {
+		wallets, _, err := importedWallets(config, count)
+		return wallets, err
+	}
+	mnemonic, err := loadMnemonic(config, mnemonicFile)
+	if err != nil {
+		return nil, err
+	}
+	return wallet.DeriveWallets(mnemonic, derivation(config), count)
+}
+
+// dialSigner connects to the remote signer of SIGNER_URL.
+func dialSigner(config *config.Config) (*txpkg.RemoteSigner, error) {
+	if config.SignerURL == "" {
+		return nil, fmt.Errorf("SIGNER_URL is not set")
+	}
+	return txpkg.DialSigner(config.SignerURL, strings.ToLower(config.SignerAPI), time.Duration(config.ContextTimeout)*time.Second)
+}
+
+// signerWallets returns the first count accounts of SIGNER_ADDRESSES, or
+// of every account the remote signer lists, and names the signer. The keys
+// stay with the signer; their Nonce is left at zero.
+func signerWallets(config *config.Config, count int) ([]*wallet.Wallet, string, error) {
+	signer, err := dialSigner(config)
+	if err != nil {
+		return nil, "", err
+	}
+	var addresses []common.Address
+	if config.SignerAddresses != "" {
+		for _, s := range strings.Split(config.SignerAddresses, ",") {
+			s = strings.TrimSpace(s)
+			if !common.IsHexAddress(s) {
+				return nil, "", fmt.Errorf("invalid address %q in SIGNER_ADDRESSES", s)
+			}
+			addresses = append(addresses, common.HexToAddress(s))
+		}
+	} else {
+		logger.Info("\nListing the accounts of signer %s...\n", signer.Name())
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.ContextTimeout)*time.Second)
+		defer cancel()
+		if addresses, err = signer.Accounts(ctx); err != nil {
+			return nil, "", err
+		}
+	}
+	if count > 0 && len(addresses) > count {
+		addresses = addresses[:count]
+	}
+	wallets := make([]*wallet.Wallet, len(addresses))
+	for i, address := range addresses {
+		wallets[i] = wallet.FromSigner(signer.Account(address), signer.Name())
+	}
+	return wallets, "signer " + signer.Name(), nil
+}
+
+// keystorePassword returns KEYSTORE_PASSWORD, or asks for it on stdin
+// (twice with confirm, for a new keystore). AUTOMATED_MODE never asks.
+func keystorePassword(config *config.Config, confirm bool) (string, error) {
+	if config.KeystorePassword != "" {
+		return config.KeystorePassword, nil
+	}
+	if config.AutomatedMode {
+		return "", fmt.Errorf("KEYSTORE_PASSWORD is not set")
+	}
+	fmt.Print("Keystore password: ")
+	password, ok := readAnswer()
+	if !ok {
+		fmt.Println()
+		return "", fmt.Errorf("KEYSTORE_PASSWORD is not set and nobody can answer on stdin")
+	}
+	if confirm {
+		fmt.Print("Repeat password: ")
+		repeated, ok := readAnswer()
+		if !ok {
+			fmt.Println()
+			return "", fmt.Errorf("KEYSTORE_PASSWORD is not set and nobody can answer on stdin")
+		}
+		if repeated != password {
+			return "", fmt.Errorf("passwords do not match")
+		}
+	}
+	if password == "" {
+		return "", fmt.Errorf("no keystore password given")
+	}
+	return password, nil
+}
+
+// exportConfirmation is what the user must type before a key is printed.
+const exportConfirmation = "EXPORT"
+
+// runExportKeys prints the private key and derivation path of one derived
+// wallet, so a stuck account can be imported into MetaMask or used with cast
+// to replace or cancel transactions by hand. It always asks for confirmation
+// on stdin; there is deliberately no flag to skip it.
+func runExportKeys(config *config.Config, args []string) int {
+	fs := flag.NewFlagSet("wallets export-keys", flag.ContinueOnError)
+	index := fs.Int("index", -1, "derived wallet to export, 0-based ([Wallet N/...] in the run logs is index N-1)")
+	mnemonicFile := fs.String("mnemonic-file", mnemonicFileName, "file written by a previous run; used when MNEMONIC is not set")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *index < 0 {
+		fmt.Println("Missing -index: which derived wallet to export (0-based)")
+		return 2
+	}
+
+	mnemonic, err := loadMnemonic(config, *mnemonicFile)
+	if err != nil {
+		logger.Error("%v\n", err)
+		return 1
+	}
+
+	w, err := wallet.DeriveWallet(mnemonic, derivation(config), *index)
+	if err != nil {
+		logger.Error("Error deriving wallet %d: %v\n", *index, err)
+		return 1
+	}
+
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("⚠️  WARNING: this prints a PRIVATE KEY in plain text.")
+	fmt.Println("Anyone who sees it controls every asset held by this address,")
+	fmt.Println("on every chain. Do not run this on a shared screen or in CI,")
+	fmt.Println("and clear your terminal scrollback afterwards.")
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("Wallet %d: %s (%s)\n", *index, w.Address.Hex(), w.DerivationPath)
+	fmt.Printf("\nType %s to print its private key: ", exportConfirmation)
+	if answer, _ := readAnswer(); strings.TrimSpace(answer) != exportConfirmation {
+		fmt.Println("\nExport cancelled.")
+		return 1
+	}
+
+	fmt.Println()
+	fmt.Printf("Address:         %s\n", w.Address.Hex())
+	fmt.Printf("Derivation path: %s\n", w.DerivationPath)
+	fmt.Printf("Private key:     %s\n", hexutil.Encode(crypto.FromECDSA(w.PrivateKey)))
+
+	// Nonces are what usually needs fixing; show them if the node is reachable
+	if txSender, err := newTransactionSender(config, nil); err == nil {
+		defer txSender.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		mined, minedErr := txSender.GetMinedNonce(ctx, w.Address)
+		pending, pendingErr := txSender.GetNonce(ctx, w.Address)
+		if minedErr == nil && pendingErr == nil {
+			fmt.Printf("Nonce:           %d mined, %d pending\n", mined, pending)
+			if pending > mined {
+				fmt.Printf("\nTo clear nonce %d, replace it with a higher-fee self-transfer, e.g.:\n", mined)
+				fmt.Printf("  cast send %s --value 0 --nonce %d --priority-gas-price <higher tip> --private-key <key> --rpc-url %s\n",
+					w.Address.Hex(), mined, config.RPCURL)
+			}
+		}
+	}
+	return 0
+}
+
+// runExportKeystore writes the derived wallets as encrypted JSON keystore
+// files, so later runs can use KEYSTORE_DIR and no plaintext mnemonic or key
+// has to stay on disk.
+func runExportKeystore(config *config.Config, args []string) int {
+	fs := flag.NewFlagSet("wallets export-keystore", flag.ContinueOnError)
+	dir := fs.String("dir", config.KeystoreDir, "directory to write the keystore files to")
+	count := fs.Int("count", config.WalletCount, "number of derived wallets to export")
+	light := fs.Bool("light", false, "use light scrypt parameters: much faster to decrypt, much weaker against brute force")
+	mnemonicFile := fs.String("mnemonic-file", mnemonicFileName, "file written by a previous run; used when MNEMONIC is not set")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *dir == "" {
+		fmt.Println("Missing -dir: where to write the keystore files")
+		return 2
+	}
+
+	mnemonic, err := loadMnemonic(config, *mnemonicFile)
+	if err != nil {
+		logger.Error("%v\n", err)
+		return 1
+	}
+	wallets, err := wallet.DeriveWallets(mnemonic, derivation(config), *count)
+	if err != nil {
+		logger.Error("Error deriving wallets: %v\n", err)
+		return 1
+	}
+	password, err := keystorePassword(config, true)
+	if err != nil {
+		logger.Error("%v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Encrypting %d wallets into %s...\n", len(wallets), *dir)
+	written, err := wallet.ExportKeystore(*dir, password, wallets, *light)
+	if err != nil {
+		logger.Error("%v\n", err)
+		return 1
+	}
+	fmt.Printf("✓ Wrote %d keystore files", written)
+	if skipped := len(wallets) - written; skipped > 0 {
+		fmt.Printf(" (%d wallets were already there)", skipped)
+	}
+	fmt.Println()
+	fmt.Printf("Run with KEYSTORE_DIR=%s; the mnemonic is no longer needed on this machine\n", *dir)
+	return 0
+}
+
+// runCancelStuck replaces every transaction the derived wallets still have
+// pending (nonces between the mined and the pending nonce) with a zero-value
+// self-transfer at a higher fee, clearing them from the mempool.
+func runCancelStuck(config *config.Config, args []string) int {
+	fs := flag.NewFlagSet("wallets cancel-stuck", flag.ContinueOnError)
+	count := fs.Int("count", config.WalletCount, "number of wallets to check")
+	bump := fs.Float64("bump", config.FeeBumpPercent, "minimum fee increase over the pending transaction, in percent (at least 10)")
+	mnemonicFile := fs.String("mnemonic-file", mnemonicFileName, "file written by a previous run; used when MNEMONIC is not set")
+	yes := fs.Bool("yes", config.AutomatedMode, "send cancels without asking")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	wallets, err := loadWallets(config, *mnemonicFile, *count)
+	if err != nil {
+		logger.Error("Error loading wallets: %v\n", err)
+		return 1
+	}
+	txSender, err := newTransactionSender(config, nil)
+	if err != nil {
+		logger.Error("Error connecting to RPC: %v\n", err)
+		return 1
+	}
+	defer txSender.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	type stuckWallet struct {
+		w     *wallet.Wallet
+		mined uint64
+	}
+	var stuck []stuckWallet
+	total := 0
+	for i, w := range wallets {
+		mined, err := txSender.GetMinedNonce(ctx, w.Address)
+		if err == nil {
+			// Whatever NONCE_SOURCE says, stuck means pending in the pool
+			w.Nonce, err = txSender.GetNonce(ctx, w.Address)
+		}
+		if err != nil {
+			logger.Error("Error reading nonce of wallet %d: %v\n", i, err)
+			return 1
+		}
+		if w.Nonce > mined {
+			fmt.Printf("Wallet %d %s: nonces %d-%d pending\n", i, w.Address.Hex(), mined, w.Nonce-1)
+			stuck = append(stuck, stuckWallet{w: w, mined: mined})
+			total += int(w.Nonce - mined)
+		}
+	}
+	if len(stuck) == 0 {
+		fmt.Println("✓ No pending transactions")
+		return 0
+	}
+
+	if !*yes {
+		fmt.Println()
+		proceed, err := confirm(fmt.Sprintf("Replace %d pending transactions with zero-value self-transfers?", total))
+		if err != nil {
+			logger.Error("%v\n", err)
+			return 1
+		}
+		if !proceed {
+			fmt.Println("\nCancel aborted.")
+			return 1
+		}
+	}
+
+	feeHistory, err := txSender.FeeHistory(ctx)
+	if err != nil {
+		logger.Error("Error fetching fee history: %v\n", err)
+		return 1
+	}
+	baseFee := feeHistory.BaseFee[len(feeHistory.BaseFee)-1]
+	tip, err := gweiToWei(config.PriorityFeeGwei)
+	if err != nil {
+		logger.Error("Invalid PRIORITY_FEE_GWEI: %v\n", err)
+		return 1
+	}
+
+	// The database tells us which transaction each nonce holds, so its fees
+	// can be outbid; without it the current price is used.
+	db, err := dbpkg.Open(config.DBPath, config.DBMaxOpenConns, config.DBMaxIdleConns)
+	if err != nil {
+		logger.Warn("Could not open database, cancelling at the current price: %v\n", err)
+		db = nil
+	} else {
+		defer db.Close()
+	}
+
+	sent, failed := 0, 0
+	for _, s := range stuck {
+		for nonce := s.mined; nonce < s.w.Nonce; nonce++ {
+			var pendingHash common.Hash
+			if db != nil {
+				if h, err := db.GetPendingTransactionHash(ctx, s.w.Address.Hex(), nonce); err == nil && h != "" {
+					pendingHash = common.HexToHash(h)
+				}
+			}
+			hash, err := txSender.CancelNonce(ctx, s.w.Signer(), nonce, pendingHash, baseFee, tip, *bump)
+			if err != nil {
+				logger.Error("%s nonce %d: %v\n", s.w.Address.Hex(), nonce, err)
+				failed++
+				continue
+			}
+			sent++
+			fmt.Printf("%s nonce %d: cancel sent %s\n", s.w.Address.Hex(), nonce, hash.Hex())
+			if db != nil && pendingHash != (common.Hash{}) {
+				// The pending transaction may have been sent by something else
+				err := db.UpdateTransactionStatus(ctx, pendingHash.Hex(), "cancelled", nil, 0, "", "", "", "", "replaced by cancel "+hash.Hex(), nil, nil)
+				if err != nil && !errors.Is(err, dbpkg.ErrTransactionNotFound) {
+					logger.Error("%s nonce %d: %v\n", s.w.Address.Hex(), nonce, err)
+				}
+			}
+		}
+	}
+
+	fmt.Printf("\n✓ %d cancels sent, %d failed\n", sent, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// sweepReceiptTimeout is how long the sweep transfers may take to be
+// included.
+const sweepReceiptTimeout = 5 * time.Minute
+
+// runSweep sends what the derived wallets hold, less the gas of the transfer,
+// to one address, by default the funding wallet, so testnet funds are not
+// stranded in throwaway accounts. The transfers are stored as a sweep-
+// batch in the transactions table.
+func runSweep(config *config.Config, args []string) int {
+	fs := flag.NewFlagSet("wallets sweep", flag.ContinueOnError)
+	to := fs.String("to", "", "address to send the funds to (default: the FUNDER_PRIVATE_KEY, FUNDER_KEYSTORE or FUNDER_ADDRESS wallet)")
+	count := fs.Int("count", config.WalletCount, "number of wallets to sweep")
+	mnemonicFile := fs.String("mnemonic-file", mnemonicFileName, "file written by a previous run; used when MNEMONIC is not set")
+	yes := fs.Bool("yes", config.AutomatedMode, "sweep without asking")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	var target common.Address
+	switch {
+	case *to != "":
+		if !common.IsHexAddress(*to) {
+			fmt.Printf("Invalid -to address %q\n", *to)
+			return 2
+		}
+		target = common.HexToAddress(*to)
+	default:
+		funder, err := loadFunder(config)
+		if err != nil {
+			logger.Error("Error loading funding wallet: %v\n", err)
+			return 1
+		}
+		if funder == nil {
+			fmt.Println("Missing -to: where to send the funds (or set FUNDER_PRIVATE_KEY, FUNDER_KEYSTORE or FUNDER_ADDRESS)")
+			return 2
+		}
+		target = funder.Address
+	}
+
+	wallets, err := loadWallets(config, *mnemonicFile, *count)
+	if err != nil {
+		logger.Error("Error loading wallets: %v\n", err)
+		return 1
+	}
+	txSender, err := newTransactionSender(config, nil)
+	if err != nil {
+		logger.Error("Error connecting to RPC: %v\n", err)
+		return 1
+	}
+	defer txSender.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), sweepReceiptTimeout+5*time.Minute)
+	defer cancel()
+
+	baseFee, tip, err := batchFees(ctx, config, txSender, nil)
+	if err != nil {
+		logger.Error("Error fetching fees: %v\n", err)
+		return 1
+	}
+	// A plain transfer uses exactly its estimate; anything else gets the
+	// margin. The unused part of the gas reserve stays behind in each wallet.
+	calls := []txpkg.Call{{To: target, Value: new(big.Int), GasLimit: config.GasLimit}}
+	txpkg.NewGasEstimator(txSender, config.GasEstimateMargin, config.GasLimitOverride).Apply(ctx, target, calls)
+	gasLimit := calls[0].GasLimit
+	if calls[0].Estimated == params.TxGas {
+		gasLimit = params.TxGas
+	}
+	reserve := new(big.Int).Mul(txSender.MaxFeePerGas(baseFee, tip), new(big.Int).SetUint64(gasLimit))
+
+	type sweep struct {
+		w      *wallet.Wallet
+		amount *big.Int
+	}
+	var sweeps []sweep
+	total := new(big.Int)
+	for i, w := range wallets {
+		if w.Address == target {
+			continue
+		}
+		mined, err := txSender.GetMinedNonce(ctx, w.Address)
+		if err == nil {
+			w.Nonce, err = txSender.GetNonce(ctx, w.Address)
+		}
+		if err != nil {
+			logger.Error("Error reading nonce of wallet %d: %v\n", i, err)
+			return 1
+		}
+		if w.Nonce > mined {
+			logger.Warn("Wallet %d %s has %d pending transactions, skipped (see `go-tps wallets cancel-stuck`)\n", i, w.Address.Hex(), w.Nonce-mined)
+			continue
+		}
+		balance, err := txSender.GetBalance(ctx, w.Address)
+		if err != nil {
+			logger.Error("Error reading balance of wallet %d: %v\n", i, err)
+			return 1
+		}
+		l1Fee, err := txSender.EstimateL1Fee(ctx, target, balance, nil)
+		if err != nil {
+			logger.Error("Error estimating L1 fee: %v\n", err)
+			return 1
+		}
+		amount := new(big.Int).Sub(balance, reserve)
+		amount.Sub(amount, l1Fee)
+		if amount.Sign() <= 0 {
+			continue
+		}
+		fmt.Printf("Wallet %d %s: %s wei\n", i, w.Address.Hex(), amount.String())
+		sweeps = append(sweeps, sweep{w: w, amount: amount})
+		total.Add(total, amount)
+	}
+	if len(sweeps) == 0 {
+		fmt.Println("✓ Nothing to sweep: no wallet holds more than the gas of a transfer")
+		return 0
+	}
+
+	if !*yes {
+		fmt.Println()
+		proceed, err := confirm(fmt.Sprintf("Send %s wei from %d wallets to %s?", total.String(), len(sweeps), target.Hex()))
+		if err != nil {
+			logger.Error("%v\n", err)
+			return 1
+		}
+		if !proceed {
+			fmt.Println("\nSweep cancelled.")
+			return 1
+		}
+	}
+
+	// Record the sweep like a batch; it works without a database too
+	db, err := dbpkg.Open(config.DBPath, config.DBMaxOpenConns, config.DBMaxIdleConns)
+	if err != nil {
+		logger.Warn("Could not open database, sweep transactions are not recorded: %v\n", err)
+		db = nil
+	} else {
+		defer db.Close()
+	}
+	batchNumber := fmt.Sprintf("sweep-%s", time.Now().Format("20060102-150405"))
+
+	type sent struct {
+		hash common.Hash
+		tx   *dbpkg.Transaction
+	}
+	var pending []sent
+	failed := 0
+	for _, s := range sweeps {
+		req := &txpkg.TxRequest{
+			ToAddress: target,
+			Value:     s.amount,
+			Nonce:     s.w.Nonce,
+			GasLimit:  gasLimit,
+			BaseFee:   baseFee,
+			Tip:       tip,
+		}
+		start := time.Now()
+		hash, err := txSender.SendRequest(ctx, req, s.w.Signer())
+		record := &dbpkg.Transaction{
+			BatchNumber:   batchNumber,
+			WalletAddress: s.w.Address.Hex(),
+			Nonce:         s.w.Nonce,
+			ToAddress:     target.Hex(),
+			Value:         s.amount.String(),
+			GasPrice:      txSender.MaxFeePerGas(baseFee, tip).String(),
+			GasLimit:      gasLimit,
+			GasEstimated:  calls[0].Estimated,
+			Status:        "pending",
+			SubmittedAt:   start,
+			ExecutionTime: float64(time.Since(start).Microseconds()) / 1000,
+		}
+		if err != nil {
+			logger.Error("%s: %v\n", s.w.Address.Hex(), err)
+			record.Status, record.Error = "failed", err.Error()
+			failed++
+		} else {
+			record.TxHash = hash.Hex()
+			pending = append(pending, sent{hash: hash, tx: record})
+		}
+		if db != nil {
+			if _, err := db.InsertTransaction(ctx, record); err != nil {
+				logger.Warn("Could not record sweep from %s: %v\n", s.w.Address.Hex(), err)
+			}
+			if record.TxHash != "" {
+				db.AdvanceWalletNonce(ctx, s.w.Address.Hex(), s.w.Nonce+1)
+			}
+		}
+	}
+
+	swept := new(big.Int)
+	for _, p := range pending {
+		receipt, err := txSender.WaitForReceipt(ctx, p.hash, sweepReceiptTimeout)
+		if err != nil {
+			logger.Error("%s: %v\n", p.hash.Hex(), err)
+			failed++
+			continue
+		}
+		status, errMsg := "success", ""
+		if receipt.Status != 1 {
+			status, errMsg = "failed", "transaction reverted"
+			failed++
+		} else {
+			value, _ := new(big.Int).SetString(p.tx.Value, 10)
+			swept.Add(swept, value)
+		}
+		if db == nil {
+			continue
+		}
+		confirmedAt := time.Now()
+		if header, err := txSender.HeaderByHash(ctx, receipt.BlockHash); err == nil {
+			confirmedAt = time.Unix(int64(header.Time), 0)
+		}
+		var effectiveGasPrice, l1Fee, l2Fee, cost string
+		if receipt.EffectiveGasPrice != nil {
+			effectiveGasPrice = receipt.EffectiveGasPrice.String()
+		}
+		if l1, l2, err := txSender.ReceiptFees(ctx, receipt); err == nil {
+			l2Fee, cost = l2.String(), l2.String()
+			if l1 != nil {
+				l1Fee, cost = l1.String(), new(big.Int).Add(l1, l2).String()
+			}
+		}
+		inclusion := &dbpkg.Inclusion{
+			BlockNumber: receipt.BlockNumber.Uint64(),
+			BlockHash:   receipt.BlockHash.Hex(),
+			TxIndex:     receipt.TransactionIndex,
+		}
+		if err := db.UpdateTransactionStatus(ctx, p.tx.TxHash, status, &confirmedAt, receipt.GasUsed, effectiveGasPrice, l1Fee, l2Fee, cost, errMsg, inclusion, nil); err != nil {
+			logger.Error("%s: %v\n", p.hash.Hex(), err)
+		}
+	}
+
+	fmt.Printf("\n✓ Swept %s wei to %s, %d failed\n", swept.String(), target.Hex(), failed)
+	if db != nil {
+		fmt.Printf("Recorded as %s\n", batchNumber)
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
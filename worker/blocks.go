@@ -0,0 +1,168 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"go-tps/db"
+	"go-tps/logger"
+	"go-tps/tx"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// maxGapFill bounds how many missed blocks are backfilled in one poll, so a
+// long outage does not stall the recorder behind a flood of header requests.
+const maxGapFill = 64
+
+// BlockRecorder stores the header of every new block (base fee, gas used and
+// gas limit) in the block_metrics table while a run is active, so load can be
+// correlated with fee pressure. It subscribes to new heads over the shared
+// WebSocket connection and falls back to polling the RPC endpoint.
+type BlockRecorder struct {
+	wsManager    *WebSocketManager
+	txSender     *tx.TransactionSender
+	database     db.Store
+	pollInterval time.Duration
+
+	last     uint64 // highest block number recorded
+	recorded int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewBlockRecorder(wsManager *WebSocketManager, txSender *tx.TransactionSender, database db.Store, pollInterval time.Duration) *BlockRecorder {
+	return &BlockRecorder{
+		wsManager:    wsManager,
+		txSender:     txSender,
+		database:     database,
+		pollInterval: pollInterval,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+func (r *BlockRecorder) Start() {
+	go func() {
+		defer close(r.done)
+		if r.wsManager != nil && r.subscribe() {
+			return
+		}
+		r.poll()
+	}()
+}
+
+// Stop halts the recorder and returns how many blocks it recorded.
+func (r *BlockRecorder) Stop() int {
+	close(r.stop)
+	<-r.done
+	return r.recorded
+}
+
+// subscribe records heads from a WebSocket subscription. It returns true when
+// stopped, or false if the subscription could not be set up or broke.
+func (r *BlockRecorder) subscribe() bool {
+	client := r.wsManager.GetClient()
+	if client == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	heads := make(chan *types.Header, 16)
+	sub, err := client.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		logger.Warn("[BlockRecorder] Could not subscribe to new heads, polling instead: %v\n", err)
+		return false
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case <-r.stop:
+			return true
+		case err := <-sub.Err():
+			logger.Warn("[BlockRecorder] Head subscription dropped, polling instead: %v\n", err)
+			return false
+		case header := <-heads:
+			r.fillGap(header.Number.Uint64())
+			r.record(header)
+		}
+	}
+}
+
+func (r *BlockRecorder) poll() {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), r.pollInterval+5*time.Second)
+			header, err := r.txSender.LatestHeader(ctx)
+			cancel()
+			if err != nil {
+				logger.Warn("[BlockRecorder] Could not read latest block: %v\n", err)
+				continue
+			}
+			if number := header.Number.Uint64(); number > r.last {
+				r.fillGap(number)
+				r.record(header)
+			}
+		}
+	}
+}
+
+// fillGap records blocks between the last recorded one and number that were
+// missed, e.g. while switching from the subscription to polling.
+func (r *BlockRecorder) fillGap(number uint64) {
+	if r.last == 0 || number <= r.last+1 {
+		return
+	}
+	from := r.last + 1
+	if number-from > maxGapFill {
+		from = number - maxGapFill
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for n := from; n < number; n++ {
+		header, err := r.txSender.HeaderByNumber(ctx, n)
+		if err != nil {
+			logger.Warn("[BlockRecorder] Could not backfill block %d: %v\n", n, err)
+			return
+		}
+		r.record(header)
+	}
+}
+
+func (r *BlockRecorder) record(header *types.Header) {
+	metric := &db.BlockMetric{
+		BlockNumber: header.Number.Uint64(),
+		BlockHash:   header.Hash().Hex(),
+		Timestamp:   time.Unix(int64(header.Time), 0),
+		GasUsed:     header.GasUsed,
+		GasLimit:    header.GasLimit,
+		ObservedAt:  time.Now(),
+	}
+	if header.BaseFee != nil {
+		metric.BaseFee = header.BaseFee.String()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := r.database.InsertBlockMetric(ctx, metric); err != nil {
+		logger.Warn("[BlockRecorder] Could not record block %d: %v\n", metric.BlockNumber, err)
+		return
+	}
+
+	if metric.BlockNumber > r.last {
+		r.last = metric.BlockNumber
+	}
+	r.recorded++
+	logger.Debug("[BlockRecorder] Block %d: base fee %s wei, gas used %d/%d\n",
+		metric.BlockNumber, metric.BaseFee, metric.GasUsed, metric.GasLimit)
+}
@@ -2,7 +2,11 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/big"
+	"os"
+	"runtime/debug"
 	"strings"
 	"sync"
 	"time"
@@ -13,13 +17,52 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ReceiptJob struct {
+	ID         int64 // transactions row
 	TxHash     string
 	Nonce      uint64
 	StartTime  time.Time
 	RetryCount int
+
+	TraceParent string // the transaction's span, for the receipt span to join
+
+	// Echoed to the observers with the outcome
+	BatchNumber string
+	Phase       string
+	RPCEndpoint string
+}
+
+// Observer is told about every transaction the workers store and every
+// receipt outcome they record, e.g. to mirror them to a metrics sink or to
+// watch for alert thresholds.
+type Observer interface {
+	Stored(tx *db.Transaction)
+	Resolved(tx *db.Transaction)
+}
+
+var observers []Observer
+
+// AddObserver adds an Observer to all worker pools. Call it, and
+// RemoveObserver, only while no pool is running.
+func AddObserver(o Observer) {
+	observers = append(observers, o)
+}
+
+// RemoveObserver removes an Observer added with AddObserver.
+func RemoveObserver(o Observer) {
+	for i, registered := range observers {
+		if registered == o {
+			observers = append(observers[:i:i], observers[i+1:]...)
+			return
+		}
+	}
 }
 
 type WebSocketManager struct {
@@ -38,7 +81,7 @@ func NewWebSocketManager(url string, reconnectDelay int) *WebSocketManager {
 }
 
 func (wm *WebSocketManager) Connect() error {
-	client, err := ethclient.Dial(wm.url)
+	client, err := tx.Dial(wm.url)
 	if err != nil {
 		return err
 	}
@@ -46,6 +89,11 @@ func (wm *WebSocketManager) Connect() error {
 	return nil
 }
 
+// Endpoint returns the host of the WebSocket endpoint.
+func (wm *WebSocketManager) Endpoint() string {
+	return tx.EndpointName(wm.url)
+}
+
 func (wm *WebSocketManager) GetClient() *ethclient.Client {
 	wm.reconnectMu.Lock()
 	defer wm.reconnectMu.Unlock()
@@ -70,7 +118,7 @@ func (wm *WebSocketManager) Reconnect() error {
 		wm.client.Close()
 	}
 
-	client, err := ethclient.Dial(wm.url)
+	client, err := tx.Dial(wm.url)
 	if err != nil {
 		return err
 	}
@@ -89,73 +137,307 @@ func (wm *WebSocketManager) Close() {
 }
 
 type DBWriteJob struct {
-	Tx *db.Transaction
+	Tx      *db.Transaction
+	Replace *HashReplacement // instead of Tx: a replacement sent for a saved record
+}
+
+// HashReplacement points the record of a transaction at the replacement sent
+// for it (same nonce, higher fee) and records its max fee.
+type HashReplacement struct {
+	OldHash  string
+	NewHash  string
+	GasPrice string
+}
+
+// replacements holds the hash replacements whose record the DB writers have
+// not saved yet, by the hash they replace. Trying a replacement and saving
+// records both take mu, so a record saved meanwhile is never missed.
+type replacements struct {
+	mu      sync.Mutex
+	parked  map[string]*HashReplacement
+	writers int // DB writers still running
+}
+
+// apply replaces the hash now, or parks the replacement until the record
+// of r.OldHash is saved.
+func (q *replacements) apply(workerID int, database db.Store, r *HashReplacement) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.replace(workerID, database, r)
+}
+
+// saved applies the parked replacements of the records just saved.
+func (q *replacements) saved(workerID int, database db.Store, saved []*db.Transaction) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, tx := range saved {
+		if r := q.parked[tx.TxHash]; r != nil {
+			delete(q.parked, tx.TxHash)
+			q.replace(workerID, database, r)
+		}
+	}
+}
+
+// done is called by each DB writer as it exits; the last one reports the
+// replacements whose record was never saved.
+func (q *replacements) done() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.writers--; q.writers > 0 {
+		return
+	}
+	for oldHash, r := range q.parked {
+		logger.Warn("[DB] Could not record replacement %s: %s was never saved\n", r.NewHash, oldHash)
+	}
 }
 
-func StartDBWriterPool(workerCount int, jobChan <-chan DBWriteJob, database *db.Database, wg *sync.WaitGroup) {
+// replace points the record at r.NewHash, then applies a replacement of the
+// replacement parked meanwhile. Callers hold q.mu.
+func (q *replacements) replace(workerID int, database db.Store, r *HashReplacement) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := database.ReplaceTransactionHash(ctx, r.OldHash, r.NewHash, r.GasPrice)
+	switch {
+	case errors.Is(err, db.ErrTransactionNotFound):
+		q.parked[r.OldHash] = r
+		return
+	case err != nil:
+		logger.Warn("[DBWriter %d] Could not record replacement %s: %v\n", workerID, r.NewHash, err)
+		return
+	}
+	if next := q.parked[r.NewHash]; next != nil {
+		delete(q.parked, r.NewHash)
+		q.replace(workerID, database, next)
+	}
+}
+
+// StartDBWriterPool starts workers that save the records sent on jobChan.
+// Each worker saves up to batchSize records at a time, in one database
+// transaction, and no record waits longer than flushInterval for the
+// batch to fill up. A batchSize of 1 saves every record on its own. Hash
+// replacements sent on jobChan are applied once the record they replace is
+// saved, whichever worker saves it.
+func StartDBWriterPool(workerCount, batchSize int, flushInterval time.Duration, jobChan <-chan DBWriteJob, database db.Store, wg *sync.WaitGroup) {
+	batchSize = max(batchSize, 1)
+	pending := &replacements{parked: make(map[string]*HashReplacement), writers: workerCount}
 	for i := 0; i < workerCount; i++ {
 		wg.Add(1)
-		go dbWriterWorker(i+1, jobChan, database, wg)
+		go dbWriterWorker(i+1, batchSize, flushInterval, jobChan, database, pending, wg)
 	}
 }
 
-func dbWriterWorker(workerID int, jobChan <-chan DBWriteJob, database *db.Database, wg *sync.WaitGroup) {
+func dbWriterWorker(workerID, batchSize int, flushInterval time.Duration, jobChan <-chan DBWriteJob, database db.Store, pending *replacements, wg *sync.WaitGroup) {
 	defer wg.Done()
-	for job := range jobChan {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		if _, err := database.InsertTransaction(ctx, job.Tx); err != nil {
-			logger.Warn("[DBWriter %d] Could not save transaction to DB: %v\n", workerID, err)
-			cancel()
-			continue
+	defer pending.done()
+
+	batch := make([]*db.Transaction, 0, batchSize)
+	flush := time.NewTimer(flushInterval)
+	flush.Stop()
+	for {
+		select {
+		case job, ok := <-jobChan:
+			if !ok {
+				saveTransactions(workerID, database, pending, batch)
+				return
+			}
+			if job.Replace != nil {
+				// Save what this worker holds first: it may be the record
+				flush.Stop()
+				saveTransactions(workerID, database, pending, batch)
+				batch = batch[:0]
+				pending.apply(workerID, database, job.Replace)
+				continue
+			}
+			if len(batch) == 0 {
+				flush.Reset(flushInterval)
+			}
+			batch = append(batch, job.Tx)
+			if len(batch) < batchSize {
+				continue
+			}
+			flush.Stop()
+		case <-flush.C:
 		}
-		cancel()
+		saveTransactions(workerID, database, pending, batch)
+		batch = batch[:0]
+	}
+}
+
+// saveTransactions saves batch, tells the observers and advances the stored
+// nonce of each wallet past its highest submitted transaction. If the batch
+// cannot be saved as a whole, its records are saved one by one so a single
+// bad record does not lose the rest.
+func saveTransactions(workerID int, database db.Store, pending *replacements, batch []*db.Transaction) {
+	if len(batch) == 0 {
+		return
+	}
+	saved := batch
+	if err := insertTransactions(workerID, database, batch); err != nil {
+		if len(batch) > 1 {
+			logger.Warn("[DBWriter %d] Could not save %d transactions at once, saving them one by one: %v\n", workerID, len(batch), err)
+		}
+		saved = nil
+		for _, tx := range batch {
+			if err := insertTransaction(workerID, database, tx); err != nil {
+				logger.Warn("[DBWriter %d] Could not save transaction to DB: %v\n", workerID, err)
+				continue
+			}
+			saved = append(saved, tx)
+		}
+	}
 
-		// Only dispatch a receipt job for transactions that were actually
-		// submitted (have a hash). Failed submissions have no on-chain receipt.
-		if job.Tx.TxHash == "" {
-			logger.Debug("[DBWriter %d] Skipping receipt dispatch for failed submission (nonce %d)\n", workerID, job.Tx.Nonce)
+	nonces := make(map[string]uint64)
+	for _, tx := range saved {
+		for _, o := range observers {
+			o.Stored(tx)
+		}
+
+		// Only transactions that were actually submitted (have a hash) use
+		// up their nonce; failed submissions have no on-chain receipt either.
+		if tx.TxHash == "" {
+			logger.Debug("[DBWriter %d] Skipping receipt dispatch for failed submission (nonce %d)\n", workerID, tx.Nonce)
 			continue
 		}
+		if next, ok := nonces[tx.WalletAddress]; !ok || tx.Nonce+1 > next {
+			nonces[tx.WalletAddress] = tx.Nonce + 1
+		}
+	}
 
+	pending.saved(workerID, database, saved)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := database.AdvanceWalletNonces(ctx, nonces); err != nil {
+		logger.Warn("[DBWriter %d] %v\n", workerID, err)
 	}
 }
 
-func StartReceiptWorkerPool(workerCount int, jobChan chan ReceiptJob, wg *sync.WaitGroup, wsManager *WebSocketManager, database *db.Database, txSender *tx.TransactionSender) {
+// insertTransactions saves batch in one go, turning a panic into an error
+// like insertTransaction.
+func insertTransactions(workerID int, database db.Store, batch []*db.Transaction) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("[DBWriter %d] PANIC saving %d transactions: %v\n%s\n", workerID, len(batch), r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return database.InsertTransactions(ctx, batch)
+}
+
+// insertTransaction saves one record, turning a panic into an error so a
+// single bad record cannot stop the writer.
+func insertTransaction(workerID int, database db.Store, tx *db.Transaction) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("[DBWriter %d] PANIC saving tx (nonce %d): %v\n%s\n", workerID, tx.Nonce, r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err = database.InsertTransaction(ctx, tx)
+	return err
+}
+
+// Receipt jobs are claimed from the database rather than handed out in
+// memory, so several processes can confirm the same database and a crashed
+// run's claims are picked up again once their lease runs out.
+const (
+	receiptClaimLease = 10 * time.Minute // longer than any single receipt attempt
+	receiptPollDelay  = time.Second      // wait while only retries are outstanding
+	maxReceiptRetries = 3
+)
+
+// StartReceiptWorkerPool starts workers that confirm every pending submitted
+// transaction in the database. Each worker exits once nothing is left to
+// claim and none of the pool's claims is still waiting for a retry.
+func StartReceiptWorkerPool(workerCount int, wg *sync.WaitGroup, wsManager *WebSocketManager, database db.Store, txSender *tx.TransactionSender) {
+	owner := receiptClaimOwner()
 	for i := 0; i < workerCount; i++ {
 		wg.Add(1)
-		go receiptWorker(i+1, jobChan, wg, wsManager, database, txSender)
+		go receiptWorker(i+1, owner, wg, wsManager, database, txSender)
 	}
 }
 
-const maxReceiptRetries = 3
+// receiptClaimOwner identifies this process in receipt claims.
+func receiptClaimOwner() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s/%d/%d", host, os.Getpid(), time.Now().UnixNano())
+}
 
-func receiptWorker(workerID int, jobChan chan ReceiptJob, wg *sync.WaitGroup, wsManager *WebSocketManager, database *db.Database, txSender *tx.TransactionSender) {
+func receiptWorker(workerID int, owner string, wg *sync.WaitGroup, wsManager *WebSocketManager, database db.Store, txSender *tx.TransactionSender) {
 	defer wg.Done()
 
 	jobsProcessed := 0
-	for job := range jobChan {
-		shouldRetry := processReceiptJob(workerID, txSender, job, wsManager, database)
-		if shouldRetry {
-			if job.RetryCount < maxReceiptRetries {
-				job.RetryCount++
-				logger.Warn("  [Worker %d] Re-queuing tx (nonce %d) for retry %d/%d\n", workerID, job.Nonce, job.RetryCount, maxReceiptRetries)
-
-				// Add exponential backoff delay before retry to avoid overwhelming the network
-				retryDelay := time.Duration(job.RetryCount*job.RetryCount) * 30 * time.Second // 30s, 120s, 270s
-				logger.Debug("  [Worker %d] Waiting %v before retry for tx (nonce %d)\n", workerID, retryDelay, job.Nonce)
-				time.Sleep(retryDelay)
-
-				// Block until we can re-queue - no new transactions being added during receipt processing
-				jobChan <- job
-			} else {
-				logger.Error("  [Worker %d] Tx (nonce %d) exceeded max retries (%d), marking failed\n", workerID, job.Nonce, maxReceiptRetries)
-				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-				database.UpdateTransactionStatus(ctx, job.TxHash, "failed", nil, 0, "", "timeout after max retries")
+	claimErrors := 0
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		row, attempts, err := database.ClaimReceiptJob(ctx, owner, receiptClaimLease)
+		if err == nil && row == nil {
+			var held int
+			held, err = database.CountReceiptClaims(ctx, owner)
+			if err == nil && held == 0 {
 				cancel()
+				break
 			}
-		} else {
+		}
+		cancel()
+		if err != nil {
+			claimErrors++
+			if claimErrors > maxReceiptRetries {
+				logger.Error("[Worker %d] Giving up on receipt jobs: %v\n", workerID, err)
+				break
+			}
+			logger.Warn("[Worker %d] Could not claim receipt job: %v\n", workerID, err)
+		}
+		if row == nil {
+			time.Sleep(receiptPollDelay)
+			continue
+		}
+		claimErrors = 0
+
+		job := ReceiptJob{
+			ID:         row.ID,
+			TxHash:     row.TxHash,
+			Nonce:      row.Nonce,
+			StartTime:  row.SubmittedAt,
+			RetryCount: attempts,
+
+			TraceParent: row.TraceParent,
+
+			BatchNumber: row.BatchNumber,
+			Phase:       row.Phase,
+			RPCEndpoint: row.RPCEndpoint,
+		}
+		shouldRetry := safeProcessReceiptJob(workerID, txSender, job, wsManager, database)
+		if !shouldRetry {
 			jobsProcessed++
+			continue
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+		if job.RetryCount < maxReceiptRetries {
+			job.RetryCount++
+			// Exponential backoff to avoid overwhelming the network. The claim
+			// is held until then; retries keep their submission time, so they
+			// are claimed ahead of younger transactions
+			retryDelay := time.Duration(job.RetryCount*job.RetryCount) * 30 * time.Second // 30s, 120s, 270s
+			logger.Warn("  [Worker %d] Retrying tx (nonce %d) in %v (retry %d/%d)\n", workerID, job.Nonce, retryDelay, job.RetryCount, maxReceiptRetries)
+			if err := database.RetryReceiptJob(ctx, job.ID, time.Now().Add(retryDelay)); err != nil {
+				logger.Warn("  [Worker %d] %v\n", workerID, err)
+			}
+		} else {
+			logger.Error("  [Worker %d] Tx (nonce %d) exceeded max retries (%d), marking failed\n", workerID, job.Nonce, maxReceiptRetries)
+			resolve(ctx, database, job, "failed", nil, 0, "", "", "", "", "timeout after max retries", nil, db.Resolution{Worker: workerID})
 		}
+		cancel()
 	}
 
 	if txSender != nil {
@@ -164,32 +446,121 @@ func receiptWorker(workerID int, jobChan chan ReceiptJob, wg *sync.WaitGroup, ws
 	}
 }
 
-func processReceiptJob(workerID int, txSender *tx.TransactionSender, job ReceiptJob, wsManager *WebSocketManager, database *db.Database) bool {
+// resolve stores the outcome of job's transaction, as recorded by the worker
+// and receipt in by, and passes it on to the observers. inclusion is nil
+// without a receipt. An outcome that could not be stored is logged with the
+// hash and returned, and the observers are not told.
+func resolve(ctx context.Context, database db.Store, job ReceiptJob, status string, confirmedAt *time.Time, gasUsed uint64, effectiveGasPrice, l1Fee, l2Fee, cost, errMsg string, inclusion *db.Inclusion, by db.Resolution) error {
+	if err := database.UpdateTransactionStatus(ctx, job.TxHash, status, confirmedAt, gasUsed, effectiveGasPrice, l1Fee, l2Fee, cost, errMsg, inclusion, &by); err != nil {
+		logger.Error("  [W%d] Could not store %s of tx %s (nonce %d): %v\n", by.Worker, status, job.TxHash, job.Nonce, err)
+		return err
+	}
+	if len(observers) == 0 {
+		return nil
+	}
+	tx := &db.Transaction{
+		BatchNumber:   job.BatchNumber,
+		TxHash:        job.TxHash,
+		Nonce:         job.Nonce,
+		GasUsed:       gasUsed,
+		Status:        status,
+		SubmittedAt:   job.StartTime,
+		ConfirmedAt:   confirmedAt,
+		Error:         errMsg,
+		Phase:         job.Phase,
+		RPCEndpoint:   job.RPCEndpoint,
+		ErrorCategory: db.ErrorCategory(errMsg),
+
+		ReceiptWorker:   by.Worker,
+		ReceiptEndpoint: by.Endpoint,
+		ReceiptSource:   by.Source,
+	}
+	if inclusion != nil {
+		tx.BlockNumber, tx.BlockHash, tx.TxIndex = inclusion.BlockNumber, inclusion.BlockHash, inclusion.TxIndex
+	}
+	for _, o := range observers {
+		o.Resolved(tx)
+	}
+	return nil
+}
+
+// tracer records a "receipt" span per receipt attempt under the
+// transaction's span.
+var tracer = otel.Tracer("go-tps/worker")
+
+// startReceiptSpan starts the receipt span of job, backdated to its
+// submission so it spans the wait for inclusion, or a no-op span if the
+// transaction was not traced.
+func startReceiptSpan(job ReceiptJob) trace.Span {
+	if job.TraceParent == "" {
+		return trace.SpanFromContext(context.Background())
+	}
+	ctx := propagation.TraceContext{}.Extract(context.Background(), propagation.MapCarrier{"traceparent": job.TraceParent})
+	_, span := tracer.Start(ctx, "receipt", trace.WithTimestamp(job.StartTime), trace.WithAttributes(
+		attribute.String("tx.hash", job.TxHash),
+		attribute.Int("receipt.attempt", job.RetryCount+1),
+	))
+	return span
+}
+
+// safeProcessReceiptJob runs processReceiptJob, marking the transaction failed
+// instead of crashing the worker if it panics.
+func safeProcessReceiptJob(workerID int, txSender *tx.TransactionSender, job ReceiptJob, wsManager *WebSocketManager, database db.Store) (retry bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("  [Worker %d] PANIC processing tx (nonce %d): %v\n%s\n", workerID, job.Nonce, r, debug.Stack())
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			resolve(ctx, database, job, "failed", nil, 0, "", "", "", "", fmt.Sprintf("panic: %v", r), nil, db.Resolution{Worker: workerID})
+			cancel()
+			retry = false
+		}
+	}()
+	return processReceiptJob(workerID, txSender, job, wsManager, database)
+}
+
+func processReceiptJob(workerID int, txSender *tx.TransactionSender, job ReceiptJob, wsManager *WebSocketManager, database db.Store) (retry bool) {
 	// Add timeout to prevent indefinite hanging
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
+	span := startReceiptSpan(job)
+	var outcome error
+	defer func() {
+		span.SetAttributes(attribute.Bool("receipt.retry", retry))
+		if outcome != nil {
+			span.RecordError(outcome)
+			span.SetStatus(codes.Error, outcome.Error())
+		}
+		span.End()
+	}()
+
 	var wsClient *ethclient.Client
 	if wsManager != nil {
 		wsClient = wsManager.GetClient()
 	}
 
-	receipt, receiptErr := txSender.GetTransactionReceipt(ctx, common.HexToHash(job.TxHash))
+	receipt, origin, receiptErr := txSender.GetTransactionReceipt(ctx, common.HexToHash(job.TxHash))
 
 	if receipt == nil && wsClient != nil {
-		receipt, receiptErr = txSender.WaitForReceiptWithSharedWebSocket(ctx, wsClient, common.HexToHash(job.TxHash), 60*time.Second)
+		receipt, origin, receiptErr = txSender.WaitForReceiptWithSharedWebSocket(ctx, wsClient, common.HexToHash(job.TxHash), 60*time.Second)
+		if origin.Source == tx.ReceiptSubscribed {
+			origin.Endpoint = wsManager.Endpoint()
+		}
 	}
+	by := db.Resolution{Worker: workerID, Endpoint: origin.Endpoint, Source: origin.Source}
 
 	if receiptErr != nil {
+		outcome = receiptErr
 		if strings.Contains(receiptErr.Error(), "timeout waiting for transaction receipt") {
 			logger.Warn("  [W%d] Tx (nonce %d): ⏱ timed out (retry %d/%d)\n", workerID, job.Nonce, job.RetryCount+1, maxReceiptRetries)
 			return true
 		}
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		database.UpdateTransactionStatus(ctx, job.TxHash, "failed", nil, 0, "", receiptErr.Error())
+		err := resolve(ctx, database, job, "failed", nil, 0, "", "", "", "", receiptErr.Error(), nil, by)
 		cancel()
 		logger.Warn("  [W%d] Tx (nonce %d): ✗ error - %v\n", workerID, job.Nonce, receiptErr)
-		return false
+		// An outcome that was not stored is looked up again later
+		return err != nil
 	}
 
 	blockHeader, err := txSender.HeaderByHash(ctx, receipt.BlockHash)
@@ -211,73 +582,44 @@ func processReceiptJob(workerID int, txSender *tx.TransactionSender, job Receipt
 	if receipt.EffectiveGasPrice != nil {
 		effectiveGasPrice = receipt.EffectiveGasPrice.String()
 	}
+	var l1Fee, l2Fee, cost string
+	if l1, l2, err := txSender.ReceiptFees(ctx, receipt); err != nil {
+		logger.Warn("  [W%d] Could not split fees for tx (nonce %d), recording execution fee only: %v\n", workerID, job.Nonce, err)
+		if receipt.EffectiveGasPrice != nil {
+			l2Fee = new(big.Int).Mul(receipt.EffectiveGasPrice, new(big.Int).SetUint64(gasUsed)).String()
+			cost = l2Fee
+		}
+	} else {
+		l2Fee, cost = l2.String(), l2.String()
+		if l1 != nil {
+			l1Fee, cost = l1.String(), new(big.Int).Add(l1, l2).String()
+		}
+	}
+
+	inclusion := &db.Inclusion{
+		BlockNumber: receipt.BlockNumber.Uint64(),
+		BlockHash:   receipt.BlockHash.Hex(),
+		TxIndex:     receipt.TransactionIndex,
+	}
 
 	confirmationTime := confirmedAt.Sub(job.StartTime).Seconds()
+	span.SetAttributes(
+		attribute.Int64("block.number", receipt.BlockNumber.Int64()),
+		attribute.Int64("tx.gas_used", int64(gasUsed)),
+		attribute.Float64("tx.confirmation_seconds", confirmationTime),
+	)
 
 	if receipt.Status == 1 {
-		database.UpdateTransactionStatus(ctx, job.TxHash, "success", &confirmedAt, gasUsed, effectiveGasPrice, "")
+		if err := resolve(ctx, database, job, "success", &confirmedAt, gasUsed, effectiveGasPrice, l1Fee, l2Fee, cost, "", inclusion, by); err != nil {
+			return true
+		}
 		logger.Info("  [W%d] Tx (nonce %d): ✓ confirmed in %.2fs (gas: %d)\n", workerID, job.Nonce, confirmationTime, gasUsed)
 	} else {
-		database.UpdateTransactionStatus(ctx, job.TxHash, "failed", &confirmedAt, gasUsed, effectiveGasPrice, "transaction reverted")
+		if err := resolve(ctx, database, job, "failed", &confirmedAt, gasUsed, effectiveGasPrice, l1Fee, l2Fee, cost, "transaction reverted", inclusion, by); err != nil {
+			return true
+		}
+		outcome = fmt.Errorf("transaction reverted")
 		logger.Warn("  [W%d] Tx (nonce %d): ✗ reverted (transaction failed on-chain)\n", workerID, job.Nonce)
 	}
 	return false
 }
-
-// QueuePendingTransactionsForReceipt fetches pending transactions and queues them for receipt processing
-// Processes in controlled batches of 1000, waiting for completion before queuing more
-func QueuePendingTransactionsForReceipt(database *db.Database, receiptJobChan chan ReceiptJob) error {
-	fmt.Println("\nProcessing pending transactions for receipt confirmation...")
-
-	const batchSize = 1000
-	lastTxnID := int64(0)
-	totalProcessed := 0
-
-	for {
-		// Get the next batch of pending transactions using cursor-based pagination
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		transactions, err := database.GetPendingTransactionsBatchCursor(ctx, lastTxnID, batchSize)
-		cancel()
-		if err != nil {
-			return fmt.Errorf("failed to fetch pending transactions: %w", err)
-		}
-
-		// If no transactions returned, we're done
-		if len(transactions) == 0 {
-			break
-		}
-
-		// Queue all jobs from this batch
-		for _, tx := range transactions {
-			job := ReceiptJob{
-				TxHash:     tx.TxHash,
-				Nonce:      tx.Nonce,
-				StartTime:  tx.SubmittedAt,
-				RetryCount: 0,
-			}
-
-			select {
-			case receiptJobChan <- job:
-				// Job queued successfully
-			default:
-				// Channel is full - this shouldn't happen with proper sizing
-				logger.Warn("Receipt job channel is full, this may cause delays")
-				receiptJobChan <- job // Block until we can send
-			}
-
-			// Update the cursor to the last processed transaction ID
-			lastTxnID = tx.ID
-		}
-
-		totalProcessed += len(transactions)
-		logger.Info("Queued batch of %d pending transactions for receipt processing (total: %d)", len(transactions), totalProcessed)
-
-		// If we got less than batchSize, we've processed all remaining transactions
-		if len(transactions) < batchSize {
-			break
-		}
-	}
-
-	logger.Info("✓ Completed queuing %d pending transactions for receipt processing", totalProcessed)
-	return nil
-}
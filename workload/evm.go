@@ -0,0 +1,121 @@
+package workload
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// assembler is a tiny EVM assembler for the built-in scenario contracts.
+// Jump targets are referenced by name and always encoded as PUSH2, so label
+// positions are known after a single pass and patched in by bytes().
+type assembler struct {
+	code   []byte
+	labels map[string]int
+	fixups map[int]string
+}
+
+func newAssembler() *assembler {
+	return &assembler{
+		labels: make(map[string]int),
+		fixups: make(map[int]string),
+	}
+}
+
+func (a *assembler) op(ops ...vm.OpCode) *assembler {
+	for _, op := range ops {
+		a.code = append(a.code, byte(op))
+	}
+	return a
+}
+
+// push emits the smallest PUSHn for integers and a full-width push for
+// addresses and raw byte slices.
+func (a *assembler) push(v any) *assembler {
+	var b []byte
+	switch v := v.(type) {
+	case int:
+		b = new(big.Int).SetInt64(int64(v)).Bytes()
+	case uint64:
+		b = new(big.Int).SetUint64(v).Bytes()
+	case *big.Int:
+		b = v.Bytes()
+	case common.Address:
+		b = v.Bytes()
+	case []byte:
+		b = v
+	default:
+		panic(fmt.Sprintf("unsupported push type %T", v))
+	}
+	if len(b) == 0 {
+		return a.op(vm.PUSH0)
+	}
+	a.code = append(a.code, byte(vm.PUSH1)+byte(len(b)-1))
+	a.code = append(a.code, b...)
+	return a
+}
+
+func (a *assembler) label(name string) *assembler {
+	a.labels[name] = len(a.code)
+	return a.op(vm.JUMPDEST)
+}
+
+func (a *assembler) pushLabel(name string) *assembler {
+	a.code = append(a.code, byte(vm.PUSH2))
+	a.fixups[len(a.code)] = name
+	a.code = append(a.code, 0, 0)
+	return a
+}
+
+func (a *assembler) jump(name string) *assembler {
+	return a.pushLabel(name).op(vm.JUMP)
+}
+
+// jumpi jumps to name if the value on top of the stack is non-zero.
+func (a *assembler) jumpi(name string) *assembler {
+	return a.pushLabel(name).op(vm.JUMPI)
+}
+
+// dispatch jumps to the label named after the matching function signature
+// and reverts when none match.
+func (a *assembler) dispatch(signatures ...string) *assembler {
+	a.push(0).op(vm.CALLDATALOAD).push(0xe0).op(vm.SHR)
+	for _, sig := range signatures {
+		a.op(vm.DUP1).push(selector(sig)).op(vm.EQ).jumpi(sig)
+	}
+	return a.push(0).op(vm.DUP1, vm.REVERT)
+}
+
+func (a *assembler) bytes() []byte {
+	for pos, name := range a.fixups {
+		target, ok := a.labels[name]
+		if !ok {
+			panic(fmt.Sprintf("undefined label %q", name))
+		}
+		a.code[pos] = byte(target >> 8)
+		a.code[pos+1] = byte(target)
+	}
+	return a.code
+}
+
+func selector(sig string) []byte {
+	return crypto.Keccak256([]byte(sig))[:4]
+}
+
+// initCode wraps runtime code in a 10-byte constructor that copies it to
+// memory and returns it.
+func initCode(runtime []byte) []byte {
+	ctor := []byte{
+		byte(vm.PUSH2), byte(len(runtime) >> 8), byte(len(runtime)),
+		byte(vm.DUP1),
+		byte(vm.PUSH1), 10,
+		byte(vm.PUSH0),
+		byte(vm.CODECOPY),
+		byte(vm.PUSH0),
+		byte(vm.RETURN),
+	}
+	return append(ctor, runtime...)
+}
@@ -0,0 +1,288 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+
+	"go-tps/logger"
+	"go-tps/tx"
+	"go-tps/wallet"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	sigExecute = "execute(address,address,uint256,uint256,uint256,uint8,bytes32,bytes32,bytes)"
+	sigPing    = "ping()"
+	sigPings   = "pings(address)"
+
+	forwardRequestType = "ForwardRequest(address from,address to,uint256 value,uint256 gas,uint256 nonce,bytes data)"
+	eip712DomainType   = "EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"
+	forwarderName      = "GoTPSForwarder"
+	forwarderVersion   = "1"
+
+	pingGas         = 50_000 // gas the forwarder passes on to ping()
+	executeGasLimit = 150_000
+)
+
+// Memory layout used by the forwarder's execute routine.
+const (
+	memDigest = 0x100 // 0x1901 ‖ domain separator ‖ struct hash
+	memData   = 0x200 // forwarded calldata followed by the signer address
+)
+
+// forwarderRuntime returns the runtime code of a minimal EIP-2771 trusted
+// forwarder. execute checks an EIP-712 signature over the ForwardRequest and
+// calls the target with the signer's address appended to the calldata. Used
+// request digests are marked in storage, so requests may be relayed in any
+// order but never twice.
+func forwarderRuntime(domainSeparator []byte) []byte {
+	a := newAssembler()
+	a.dispatch(sigExecute)
+
+	a.label(sigExecute)
+	// Copy data to memData
+	a.push(0x104).op(vm.CALLDATALOAD).push(4).op(vm.ADD) // lenPos
+	a.op(vm.DUP1, vm.CALLDATALOAD)                       // lenPos len
+	a.op(vm.SWAP1).push(32).op(vm.ADD)                   // len dataPos
+	a.op(vm.DUP2, vm.SWAP1).push(memData).op(vm.CALLDATACOPY)
+
+	// Prefix and domain separator of the EIP-712 digest
+	a.push(common.RightPadBytes([]byte{0x19, 0x01}, 32)).push(memDigest).op(vm.MSTORE)
+	a.push(domainSeparator).push(memDigest + 2).op(vm.MSTORE)
+
+	// structHash = keccak(typeHash ‖ from ‖ to ‖ value ‖ gas ‖ nonce ‖ keccak(data))
+	a.push(crypto.Keccak256([]byte(forwardRequestType))).push(0).op(vm.MSTORE)
+	for i := 0; i < 5; i++ {
+		a.push(4 + 32*i).op(vm.CALLDATALOAD).push(32 + 32*i).op(vm.MSTORE)
+	}
+	a.op(vm.DUP1).push(memData).op(vm.KECCAK256).push(0xc0).op(vm.MSTORE)
+	a.push(0xe0).push(0).op(vm.KECCAK256).push(memDigest + 0x22).op(vm.MSTORE)
+	a.push(66).push(memDigest).op(vm.KECCAK256) // len digest
+
+	// Replay protection: each digest is accepted once
+	a.op(vm.DUP1, vm.SLOAD).jumpi("fail")
+	a.push(1).op(vm.DUP2, vm.SSTORE)
+
+	// ecrecover(digest, v, r, s) must be the request's from
+	a.push(0).op(vm.MSTORE)
+	for i := 0; i < 3; i++ {
+		a.push(0xa4 + 32*i).op(vm.CALLDATALOAD).push(32 + 32*i).op(vm.MSTORE)
+	}
+	a.push(0).push(0x80).op(vm.MSTORE)
+	a.push(32).push(0x80).push(0x80).push(0).push(1).op(vm.GAS, vm.STATICCALL)
+	a.op(vm.ISZERO).jumpi("fail")
+	a.push(0x80).op(vm.MLOAD).push(4).op(vm.CALLDATALOAD) // len recovered from
+	a.op(vm.DUP1, vm.ISZERO).jumpi("fail")
+	a.op(vm.EQ, vm.ISZERO).jumpi("fail")
+
+	// to.call{gas: gas, value: value}(data ‖ from)
+	a.push(4).op(vm.CALLDATALOAD).push(96).op(vm.SHL)
+	a.op(vm.DUP2).push(memData).op(vm.ADD, vm.MSTORE)
+	a.push(0).push(0)
+	a.op(vm.DUP3).push(20).op(vm.ADD)
+	a.push(memData)
+	a.push(0x44).op(vm.CALLDATALOAD)
+	a.push(0x24).op(vm.CALLDATALOAD)
+	a.push(0x64).op(vm.CALLDATALOAD)
+	a.op(vm.CALL, vm.ISZERO).jumpi("fail")
+	a.op(vm.STOP)
+
+	a.label("fail")
+	a.push(0).op(vm.DUP1, vm.REVERT)
+
+	return a.bytes()
+}
+
+// recipientRuntime returns the runtime code of an EIP-2771 recipient that
+// counts pings per sender (storage slot == sender address). Calls from the
+// trusted forwarder are attributed to the address in their last 20 bytes.
+func recipientRuntime(forwarder common.Address) []byte {
+	a := newAssembler()
+	a.dispatch(sigPing, sigPings)
+
+	a.label(sigPing)
+	a.op(vm.CALLER).push(forwarder).op(vm.EQ)
+	a.push(24).op(vm.CALLDATASIZE, vm.LT, vm.ISZERO, vm.AND).jumpi("meta")
+	a.op(vm.CALLER)
+	a.jump("count")
+	a.label("meta")
+	a.push(20).op(vm.CALLDATASIZE, vm.SUB, vm.CALLDATALOAD).push(96).op(vm.SHR)
+	a.label("count")
+	a.op(vm.DUP1, vm.SLOAD).push(1).op(vm.ADD, vm.SWAP1, vm.SSTORE, vm.STOP)
+
+	a.label(sigPings)
+	a.push(4).op(vm.CALLDATALOAD, vm.SLOAD)
+	a.push(0).op(vm.MSTORE).push(32).push(0).op(vm.RETURN)
+
+	return a.bytes()
+}
+
+// Meta is a gasless workload: the first wallet is a relayer that deploys an
+// EIP-2771 forwarder and a recipient, and every other wallet only signs
+// forward requests, which the relayer submits on-chain. It measures how fast
+// a single relayer account can get meta-transactions included and how much
+// gas the forwarder adds per call.
+type Meta struct {
+	Forwarder common.Address
+	Recipient common.Address
+
+	// Overhead is the extra gas a forwarded ping() costs compared with a
+	// direct one, measured during Setup.
+	Overhead int64
+
+	signers         []*wallet.Wallet
+	domainSeparator []byte
+	nonce           atomic.Uint64 // forward request nonces, unique per run
+}
+
+func NewMeta() *Meta {
+	return &Meta{}
+}
+
+func (m *Meta) Name() string { return "meta" }
+
+func (m *Meta) Setup(ctx context.Context, txSender *tx.TransactionSender, wallets []*wallet.Wallet) error {
+	if len(wallets) < 2 {
+		return fmt.Errorf("meta workload needs at least two wallets (a relayer and a signer)")
+	}
+	m.signers = wallets[1:]
+	for _, signer := range m.signers {
+		// Forward requests are signed in process; the relayer may be remote
+		if signer.PrivateKey == nil {
+			return fmt.Errorf("meta workload signs forward requests with the wallet's key, which the remote signer holds for %s", signer.Address.Hex())
+		}
+	}
+
+	feeHistory, err := txSender.FeeHistory(ctx)
+	if err != nil {
+		return err
+	}
+	baseFee := feeHistory.BaseFee[len(feeHistory.BaseFee)-1]
+
+	relayer := wallets[0]
+	relayer.Lock()
+	defer relayer.Unlock()
+
+	// The forwarder's domain separator covers its own address, and the
+	// recipient trusts the forwarder, so predict both from the nonce.
+	m.Forwarder = crypto.CreateAddress(relayer.Address, relayer.Nonce)
+	m.Recipient = crypto.CreateAddress(relayer.Address, relayer.Nonce+1)
+	m.domainSeparator = crypto.Keccak256(
+		crypto.Keccak256([]byte(eip712DomainType)),
+		crypto.Keccak256([]byte(forwarderName)),
+		crypto.Keccak256([]byte(forwarderVersion)),
+		common.LeftPadBytes(txSender.ChainID().Bytes(), 32),
+		common.LeftPadBytes(m.Forwarder.Bytes(), 32),
+	)
+
+	// Deploy, then ping once directly and once through the forwarder so the
+	// forwarder's gas overhead can be measured.
+	forwarded, err := m.forwardCall(m.signers[0])
+	if err != nil {
+		return err
+	}
+	reqs := []*tx.TxRequest{
+		{Create: true, Data: initCode(forwarderRuntime(m.domainSeparator)), GasLimit: deployGasLimit},
+		{Create: true, Data: initCode(recipientRuntime(m.Forwarder)), GasLimit: deployGasLimit},
+		{ToAddress: m.Recipient, Data: calldata(sigPing), GasLimit: setupGasLimit},
+		{ToAddress: forwarded.To, Data: forwarded.Data, GasLimit: executeGasLimit},
+	}
+
+	logger.Info("Deploying meta-transaction scenario (forwarder %s, recipient %s, relayer %s)...\n",
+		m.Forwarder.Hex(), m.Recipient.Hex(), relayer.Address.Hex())
+
+	hashes := make([]common.Hash, 0, len(reqs))
+	for _, req := range reqs {
+		req.Value = big.NewInt(0)
+		req.Nonce = relayer.Nonce
+		req.BaseFee = baseFee
+		hash, err := txSender.SendRequest(ctx, req, relayer.Signer())
+		if err != nil {
+			return fmt.Errorf("failed to send setup transaction (nonce %d): %w", req.Nonce, err)
+		}
+		relayer.Nonce++
+		hashes = append(hashes, hash)
+	}
+
+	gasUsed := make([]uint64, len(hashes))
+	for i, hash := range hashes {
+		receipt, err := txSender.WaitForReceipt(ctx, hash, setupReceiptTimeout)
+		if err != nil {
+			return fmt.Errorf("setup transaction %d (%s): %w", i+1, hash.Hex(), err)
+		}
+		if receipt.Status != 1 {
+			return fmt.Errorf("setup transaction %d (%s) reverted", i+1, hash.Hex())
+		}
+		gasUsed[i] = receipt.GasUsed
+	}
+	direct, meta := gasUsed[2], gasUsed[3]
+	m.Overhead = int64(meta) - int64(direct)
+
+	logger.Info("✓ Meta-transaction scenario deployed: %d signers relayed by %s\n", len(m.signers), relayer.Address.Hex())
+	logger.Info("  Forwarder overhead: %+d gas per call (direct ping %d gas, forwarded %d gas)\n", m.Overhead, direct, meta)
+	return nil
+}
+
+// Calls gives every transaction of the run to the relayer (wallet 0): count
+// forward requests per signer, interleaved across signers. Signers send
+// nothing themselves.
+func (m *Meta) Calls(walletIdx int, count int) []tx.Call {
+	if walletIdx != 0 {
+		return nil
+	}
+	calls := make([]tx.Call, 0, count*len(m.signers))
+	for i := 0; i < count; i++ {
+		for _, signer := range m.signers {
+			call, err := m.forwardCall(signer)
+			if err != nil {
+				logger.Error("Could not sign forward request for %s: %v\n", signer.Address.Hex(), err)
+				continue
+			}
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// forwardCall signs a request for signer to ping the recipient and returns
+// the relayer's call to the forwarder's execute.
+func (m *Meta) forwardCall(signer *wallet.Wallet) (tx.Call, error) {
+	nonce := m.nonce.Add(1)
+	data := calldata(sigPing)
+
+	structHash := crypto.Keccak256(
+		crypto.Keccak256([]byte(forwardRequestType)),
+		common.LeftPadBytes(signer.Address.Bytes(), 32),
+		common.LeftPadBytes(m.Recipient.Bytes(), 32),
+		make([]byte, 32), // value
+		common.LeftPadBytes(big.NewInt(pingGas).Bytes(), 32),
+		common.LeftPadBytes(new(big.Int).SetUint64(nonce).Bytes(), 32),
+		crypto.Keccak256(data),
+	)
+	digest := crypto.Keccak256([]byte{0x19, 0x01}, m.domainSeparator, structHash)
+	sig, err := crypto.Sign(digest, signer.PrivateKey) // [r ‖ s ‖ v], v in {0, 1}
+	if err != nil {
+		return tx.Call{}, fmt.Errorf("failed to sign forward request: %w", err)
+	}
+
+	execute := calldata(sigExecute,
+		signer.Address.Bytes(),
+		m.Recipient.Bytes(),
+		nil, // value
+		big.NewInt(pingGas).Bytes(),
+		new(big.Int).SetUint64(nonce).Bytes(),
+		[]byte{sig[64] + 27},
+		sig[:32],
+		sig[32:64],
+		big.NewInt(9*32).Bytes(), // offset of data
+		big.NewInt(int64(len(data))).Bytes(),
+	)
+	execute = append(execute, common.RightPadBytes(data, (len(data)+31)/32*32)...)
+
+	return tx.Call{To: m.Forwarder, Value: big.NewInt(0), Data: execute, GasLimit: executeGasLimit}, nil
+}
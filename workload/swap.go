@@ -0,0 +1,298 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"go-tps/logger"
+	"go-tps/tx"
+	"go-tps/wallet"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	sigBalanceOf    = "balanceOf(address)"
+	sigMint         = "mint(address,uint256)"
+	sigTransfer     = "transfer(address,uint256)"
+	sigTransferFrom = "transferFrom(address,address,uint256)"
+	sigGetReserves  = "getReserves()"
+	sigSync         = "sync()"
+	sigSwap         = "swap(uint256,bool)"
+
+	deployGasLimit = 1_000_000
+	setupGasLimit  = 100_000
+	swapGasLimit   = 150_000
+
+	setupReceiptTimeout = 2 * time.Minute
+)
+
+var (
+	// Every wallet and the pair itself are minted this much of each token.
+	swapMintAmount = new(big.Int).Exp(big.NewInt(10), big.NewInt(30), nil)
+	// Each swap trades this much of the input token (1e18).
+	swapAmountIn = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+)
+
+// tokenRuntime returns the runtime code of a minimal test token. Balances
+// live at storage slot == holder address, mint is open to anyone, and
+// transferFrom is only honoured for the trusted pair (no allowances).
+func tokenRuntime(pair common.Address) []byte {
+	a := newAssembler()
+	a.dispatch(sigBalanceOf, sigMint, sigTransfer, sigTransferFrom)
+
+	a.label(sigBalanceOf)
+	a.push(4).op(vm.CALLDATALOAD, vm.SLOAD)
+	a.push(0).op(vm.MSTORE).push(32).push(0).op(vm.RETURN)
+
+	a.label(sigMint)
+	a.push(4).op(vm.CALLDATALOAD, vm.DUP1, vm.SLOAD) // to bal
+	a.push(36).op(vm.CALLDATALOAD, vm.ADD)           // to bal+amt
+	a.op(vm.SWAP1, vm.SSTORE)
+	a.jump("ok")
+
+	a.label(sigTransfer)
+	a.push(36).op(vm.CALLDATALOAD) // amt
+	a.push(4).op(vm.CALLDATALOAD)  // amt to
+	a.op(vm.CALLER)                // amt to from
+	a.jump("move")
+
+	a.label(sigTransferFrom)
+	a.push(pair).op(vm.CALLER, vm.EQ, vm.ISZERO).jumpi("fail")
+	a.push(68).op(vm.CALLDATALOAD) // amt
+	a.push(36).op(vm.CALLDATALOAD) // amt to
+	a.push(4).op(vm.CALLDATALOAD)  // amt to from
+	a.jump("move")
+
+	// move expects [amt, to, from] with from on top.
+	a.label("move")
+	a.op(vm.DUP1, vm.SLOAD)           // amt to from balFrom
+	a.op(vm.DUP4, vm.DUP2, vm.LT)     // balFrom < amt
+	a.jumpi("fail")                   // amt to from balFrom
+	a.op(vm.DUP4, vm.SWAP1, vm.SUB)   // amt to from balFrom-amt
+	a.op(vm.SWAP1, vm.SSTORE)         // amt to
+	a.op(vm.DUP1, vm.SLOAD, vm.DUP3)  // amt to balTo amt
+	a.op(vm.ADD, vm.SWAP1, vm.SSTORE) // amt
+	a.op(vm.POP)
+
+	a.label("ok")
+	a.push(1).push(0).op(vm.MSTORE).push(32).push(0).op(vm.RETURN)
+
+	a.label("fail")
+	a.push(0).op(vm.DUP1, vm.REVERT)
+
+	return a.bytes()
+}
+
+// Memory layout used by the pair's swap routine.
+const (
+	memTokenOut = 0x100
+	memSlotOut  = 0x120
+	memTokenIn  = 0x140
+	memSlotIn   = 0x160
+	memAmountIn = 0x180
+	memRIn      = 0x1a0
+	memROut     = 0x1c0
+	memInFee    = 0x1e0
+	memOut      = 0x200
+)
+
+// pairRuntime returns the runtime code of a constant-product pair with a
+// 0.3% fee over token0/token1. Reserves live in slots 0 and 1, so every swap
+// contends on the same storage.
+func pairRuntime(token0, token1 common.Address) []byte {
+	a := newAssembler()
+	a.dispatch(sigGetReserves, sigSync, sigSwap)
+
+	a.label(sigGetReserves)
+	a.push(0).op(vm.SLOAD).push(0).op(vm.MSTORE)
+	a.push(1).op(vm.SLOAD).push(32).op(vm.MSTORE)
+	a.push(64).push(0).op(vm.RETURN)
+
+	a.label(sigSync)
+	balanceOfSelf(a, token0)
+	a.push(0).op(vm.SSTORE)
+	balanceOfSelf(a, token1)
+	a.push(1).op(vm.SSTORE)
+	a.op(vm.STOP)
+
+	a.label(sigSwap)
+	a.push(36).op(vm.CALLDATALOAD).jumpi("zeroForOne")
+	a.push(token0).push(0).push(token1).push(1) // tokenOut slotOut tokenIn slotIn
+	a.jump("doSwap")
+	a.label("zeroForOne")
+	a.push(token1).push(1).push(token0).push(0)
+
+	a.label("doSwap")
+	a.push(memSlotIn).op(vm.MSTORE)
+	a.push(memTokenIn).op(vm.MSTORE)
+	a.push(memSlotOut).op(vm.MSTORE)
+	a.push(memTokenOut).op(vm.MSTORE)
+	a.push(4).op(vm.CALLDATALOAD).push(memAmountIn).op(vm.MSTORE)
+	a.push(memSlotIn).op(vm.MLOAD, vm.SLOAD).push(memRIn).op(vm.MSTORE)
+	a.push(memSlotOut).op(vm.MLOAD, vm.SLOAD).push(memROut).op(vm.MSTORE)
+
+	// amountOut = in*997*rOut / (rIn*1000 + in*997)
+	a.push(997).push(memAmountIn).op(vm.MLOAD, vm.MUL).push(memInFee).op(vm.MSTORE)
+	a.push(memROut).op(vm.MLOAD).push(memInFee).op(vm.MLOAD, vm.MUL)
+	a.push(memInFee).op(vm.MLOAD).push(1000).push(memRIn).op(vm.MLOAD, vm.MUL, vm.ADD)
+	a.op(vm.DUP1, vm.ISZERO).jumpi("fail")
+	a.op(vm.SWAP1, vm.DIV).push(memOut).op(vm.MSTORE)
+
+	// tokenIn.transferFrom(msg.sender, this, amountIn)
+	a.push(selectorWord(sigTransferFrom)).push(0).op(vm.MSTORE)
+	a.op(vm.CALLER).push(4).op(vm.MSTORE)
+	a.op(vm.ADDRESS).push(36).op(vm.MSTORE)
+	a.push(memAmountIn).op(vm.MLOAD).push(68).op(vm.MSTORE)
+	a.push(32).push(0).push(100).push(0).push(0).push(memTokenIn).op(vm.MLOAD, vm.GAS, vm.CALL)
+	a.op(vm.ISZERO).jumpi("fail")
+
+	// tokenOut.transfer(msg.sender, amountOut)
+	a.push(selectorWord(sigTransfer)).push(0).op(vm.MSTORE)
+	a.op(vm.CALLER).push(4).op(vm.MSTORE)
+	a.push(memOut).op(vm.MLOAD).push(36).op(vm.MSTORE)
+	a.push(32).push(0).push(68).push(0).push(0).push(memTokenOut).op(vm.MLOAD, vm.GAS, vm.CALL)
+	a.op(vm.ISZERO).jumpi("fail")
+
+	// reserveIn += amountIn; reserveOut -= amountOut
+	a.push(memAmountIn).op(vm.MLOAD).push(memRIn).op(vm.MLOAD, vm.ADD)
+	a.push(memSlotIn).op(vm.MLOAD, vm.SSTORE)
+	a.push(memOut).op(vm.MLOAD).push(memROut).op(vm.MLOAD, vm.SUB)
+	a.push(memSlotOut).op(vm.MLOAD, vm.SSTORE)
+
+	a.push(memOut).op(vm.MLOAD).push(0).op(vm.MSTORE).push(32).push(0).op(vm.RETURN)
+
+	a.label("fail")
+	a.push(0).op(vm.DUP1, vm.REVERT)
+
+	return a.bytes()
+}
+
+// balanceOfSelf leaves token.balanceOf(address(this)) on the stack.
+func balanceOfSelf(a *assembler, token common.Address) {
+	a.push(selectorWord(sigBalanceOf)).push(0).op(vm.MSTORE)
+	a.op(vm.ADDRESS).push(4).op(vm.MSTORE)
+	a.push(32).push(0).push(36).push(0).push(token).op(vm.GAS, vm.STATICCALL)
+	a.op(vm.ISZERO).jumpi("fail")
+	a.push(0).op(vm.MLOAD)
+}
+
+// selectorWord left-aligns a selector in a 32-byte word for MSTORE at 0.
+func selectorWord(sig string) []byte {
+	word := make([]byte, 32)
+	copy(word, selector(sig))
+	return word
+}
+
+func calldata(sig string, args ...[]byte) []byte {
+	data := selector(sig)
+	for _, arg := range args {
+		data = append(data, common.LeftPadBytes(arg, 32)...)
+	}
+	return data
+}
+
+// Swap deploys two test tokens and a constant-product pair from the first
+// wallet, seeds liquidity, mints both tokens to every wallet and then has all
+// wallets swap back and forth through the pair.
+type Swap struct {
+	Token0 common.Address
+	Token1 common.Address
+	Pair   common.Address
+}
+
+func NewSwap() *Swap {
+	return &Swap{}
+}
+
+func (s *Swap) Name() string { return "swap" }
+
+func (s *Swap) Setup(ctx context.Context, txSender *tx.TransactionSender, wallets []*wallet.Wallet) error {
+	if len(wallets) == 0 {
+		return fmt.Errorf("swap workload needs at least one wallet")
+	}
+
+	feeHistory, err := txSender.FeeHistory(ctx)
+	if err != nil {
+		return err
+	}
+	baseFee := feeHistory.BaseFee[len(feeHistory.BaseFee)-1]
+
+	deployer := wallets[0]
+	deployer.Lock()
+	defer deployer.Unlock()
+
+	// Token contracts embed the pair address, so predict it from the
+	// deployer's nonce: token0, token1, then the pair.
+	s.Token0 = crypto.CreateAddress(deployer.Address, deployer.Nonce)
+	s.Token1 = crypto.CreateAddress(deployer.Address, deployer.Nonce+1)
+	s.Pair = crypto.CreateAddress(deployer.Address, deployer.Nonce+2)
+
+	reqs := []*tx.TxRequest{
+		{Create: true, Data: initCode(tokenRuntime(s.Pair)), GasLimit: deployGasLimit},
+		{Create: true, Data: initCode(tokenRuntime(s.Pair)), GasLimit: deployGasLimit},
+		{Create: true, Data: initCode(pairRuntime(s.Token0, s.Token1)), GasLimit: deployGasLimit},
+		{ToAddress: s.Token0, Data: calldata(sigMint, s.Pair.Bytes(), swapMintAmount.Bytes()), GasLimit: setupGasLimit},
+		{ToAddress: s.Token1, Data: calldata(sigMint, s.Pair.Bytes(), swapMintAmount.Bytes()), GasLimit: setupGasLimit},
+		{ToAddress: s.Pair, Data: calldata(sigSync), GasLimit: setupGasLimit},
+	}
+	for _, w := range wallets {
+		for _, token := range []common.Address{s.Token0, s.Token1} {
+			reqs = append(reqs, &tx.TxRequest{
+				ToAddress: token,
+				Data:      calldata(sigMint, w.Address.Bytes(), swapMintAmount.Bytes()),
+				GasLimit:  setupGasLimit,
+			})
+		}
+	}
+
+	logger.Info("Deploying swap scenario (token0 %s, token1 %s, pair %s) with %d setup transactions...\n",
+		s.Token0.Hex(), s.Token1.Hex(), s.Pair.Hex(), len(reqs))
+
+	hashes := make([]common.Hash, 0, len(reqs))
+	for _, req := range reqs {
+		req.Value = big.NewInt(0)
+		req.Nonce = deployer.Nonce
+		req.BaseFee = baseFee
+		hash, err := txSender.SendRequest(ctx, req, deployer.Signer())
+		if err != nil {
+			return fmt.Errorf("failed to send setup transaction (nonce %d): %w", req.Nonce, err)
+		}
+		deployer.Nonce++
+		hashes = append(hashes, hash)
+	}
+
+	for i, hash := range hashes {
+		receipt, err := txSender.WaitForReceipt(ctx, hash, setupReceiptTimeout)
+		if err != nil {
+			return fmt.Errorf("setup transaction %d (%s): %w", i+1, hash.Hex(), err)
+		}
+		if receipt.Status != 1 {
+			return fmt.Errorf("setup transaction %d (%s) reverted", i+1, hash.Hex())
+		}
+	}
+
+	logger.Info("✓ Swap scenario deployed, %d wallets funded with test tokens\n", len(wallets))
+	return nil
+}
+
+// Calls alternates swap direction per transaction so reserves stay balanced
+// and wallets never run out of either token.
+func (s *Swap) Calls(walletIdx int, count int) []tx.Call {
+	zeroForOne := calldata(sigSwap, swapAmountIn.Bytes(), []byte{1})
+	oneForZero := calldata(sigSwap, swapAmountIn.Bytes(), nil)
+
+	calls := make([]tx.Call, count)
+	for i := range calls {
+		data := oneForZero
+		if (walletIdx+i)%2 == 0 {
+			data = zeroForOne
+		}
+		calls[i] = tx.Call{To: s.Pair, Value: big.NewInt(0), Data: data, GasLimit: swapGasLimit}
+	}
+	return calls
+}
@@ -0,0 +1,62 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"go-tps/tx"
+	"go-tps/wallet"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Workload decides what each transaction of a run does. Setup runs once
+// before the first batch (e.g. to deploy contracts); Calls is invoked per
+// wallet per batch and must be safe for concurrent use.
+type Workload interface {
+	Name() string
+	Setup(ctx context.Context, txSender *tx.TransactionSender, wallets []*wallet.Wallet) error
+	Calls(walletIdx int, count int) []tx.Call
+}
+
+// New returns the workload registered under name.
+func New(name string, toAddress common.Address, value *big.Int) (Workload, error) {
+	switch strings.ToLower(name) {
+	case "", "transfer":
+		return &Transfer{To: toAddress, Value: value}, nil
+	case "swap":
+		return NewSwap(), nil
+	case "meta":
+		return NewMeta(), nil
+	default:
+		return nil, fmt.Errorf("unknown workload %q (expected transfer, swap or meta)", name)
+	}
+}
+
+// Transfer sends plain value transfers to a single address, or spreads them
+// across Receivers when it is set.
+type Transfer struct {
+	To        common.Address
+	Value     *big.Int
+	Receivers []common.Address // transaction i of wallet w goes to receiver (w*count+i) mod len
+}
+
+func (t *Transfer) Name() string { return "transfer" }
+
+func (t *Transfer) Setup(ctx context.Context, txSender *tx.TransactionSender, wallets []*wallet.Wallet) error {
+	return nil
+}
+
+func (t *Transfer) Calls(walletIdx int, count int) []tx.Call {
+	calls := make([]tx.Call, count)
+	for i := range calls {
+		to := t.To
+		if len(t.Receivers) > 0 {
+			to = t.Receivers[(walletIdx*count+i)%len(t.Receivers)]
+		}
+		calls[i] = tx.Call{To: to, Value: t.Value}
+	}
+	return calls
+}